@@ -820,8 +820,10 @@ type filterBackend struct {
 	bc *core.BlockChain
 }
 
-func (fb *filterBackend) ChainDb() ethdb.Database  { return fb.db }
-func (fb *filterBackend) EventMux() *event.TypeMux { panic("not supported") }
+func (fb *filterBackend) ChainDb() ethdb.Database          { return fb.db }
+func (fb *filterBackend) ChainConfig() *params.ChainConfig { return fb.bc.Config() }
+func (fb *filterBackend) CurrentHeader() *types.Header     { return fb.bc.CurrentHeader() }
+func (fb *filterBackend) EventMux() *event.TypeMux         { panic("not supported") }
 
 func (fb *filterBackend) HeaderByNumber(ctx context.Context, block rpc.BlockNumber) (*types.Header, error) {
 	if block == rpc.LatestBlockNumber {
@@ -0,0 +1,120 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
+)
+
+// blsSignatureDST is the domain separation tag of the proof of possession BLS
+// signature scheme used by the beacon chain.
+var blsSignatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
+// PublicKey is a compressed BLS public key.
+type PublicKey [48]byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (pk PublicKey) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(pk[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (pk *PublicKey) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("PublicKey", input, pk[:])
+}
+
+// String returns the hex encoding of the public key.
+func (pk PublicKey) String() string {
+	return hexutil.Encode(pk[:])
+}
+
+// Signature is a compressed BLS signature.
+type Signature [96]byte
+
+// MarshalText implements encoding.TextMarshaler.
+func (sig Signature) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(sig[:]).MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (sig *Signature) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("Signature", input, sig[:])
+}
+
+// SecretKey is a BLS secret key signing messages for a public key in G1.
+type SecretKey struct {
+	scalar *big.Int
+}
+
+// NewSecretKey creates a secret key from its 32 byte big endian encoding.
+func NewSecretKey(key []byte) (*SecretKey, error) {
+	if len(key) != 32 {
+		return nil, errors.New("secret key should be 32 bytes")
+	}
+	scalar := new(big.Int).SetBytes(key)
+	if scalar.Sign() == 0 || scalar.Cmp(bls12381.NewG1().Q()) >= 0 {
+		return nil, errors.New("secret key out of range")
+	}
+	return &SecretKey{scalar: scalar}, nil
+}
+
+// PublicKey returns the public key belonging to the secret key.
+func (sk *SecretKey) PublicKey() PublicKey {
+	g := bls12381.NewG1()
+	var pk PublicKey
+	copy(pk[:], g.EncodeCompressed(g.MulScalar(g.New(), g.One(), sk.scalar)))
+	return pk
+}
+
+// Sign signs the message.
+func (sk *SecretKey) Sign(msg []byte) (Signature, error) {
+	g := bls12381.NewG2()
+	h, err := g.HashToCurve(msg, blsSignatureDST)
+	if err != nil {
+		return Signature{}, err
+	}
+	var sig Signature
+	copy(sig[:], g.EncodeCompressed(g.MulScalar(g.New(), h, sk.scalar)))
+	return sig, nil
+}
+
+// VerifySignature checks whether the signature of the message was made by the
+// secret key of the public key.
+func VerifySignature(pk PublicKey, msg []byte, sig Signature) bool {
+	g1, g2 := bls12381.NewG1(), bls12381.NewG2()
+	pkPoint, err := g1.DecodeCompressed(pk[:])
+	if err != nil || g1.IsZero(pkPoint) {
+		return false
+	}
+	sigPoint, err := g2.DecodeCompressed(sig[:])
+	if err != nil {
+		return false
+	}
+	h, err := g2.HashToCurve(msg, blsSignatureDST)
+	if err != nil {
+		return false
+	}
+	// e(pk, H(msg)) == e(g1, sig)
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(pkPoint, h)
+	engine.AddPairInv(g1.One(), sigPoint)
+	return engine.Check()
+}
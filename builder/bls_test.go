@@ -0,0 +1,64 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestSign(t *testing.T) {
+	// Test vector of the consensus spec BLS tests (sign_case_84d45c9c7cca6b92).
+	var (
+		key     = common.FromHex("0x263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+		msg     = make([]byte, 32)
+		wantPk  PublicKey
+		wantSig Signature
+	)
+	copy(wantPk[:], common.FromHex("0xa491d1b0ecd9bb917989f0e74f0dea0422eac4a873e5e2644f368dffb9a6e20fd6e10c1b77654d067c0618f6e5a7f79a"))
+	copy(wantSig[:], common.FromHex("0xb6ed936746e01f8ecf281f020953fbf1f01debd5657c4a383940b020b26507f6076334f91e2366c96e9ab279fb5158090352ea1c5b0c9274504f4f0e7053af24802e51e4568d164fe986834f41e55c8e850ce1f98458c0cfc9ab380b55285a55"))
+
+	sk, err := NewSecretKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pk := sk.PublicKey(); pk != wantPk {
+		t.Fatalf("public key mismatch: have %x, want %x", pk, wantPk)
+	}
+	sig, err := sk.Sign(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sig != wantSig {
+		t.Fatalf("signature mismatch: have %x, want %x", sig, wantSig)
+	}
+	if !VerifySignature(wantPk, msg, sig) {
+		t.Fatal("valid signature rejected")
+	}
+	msg[0] = 1
+	if VerifySignature(wantPk, msg, sig) {
+		t.Fatal("signature of other message accepted")
+	}
+}
+
+func TestBuilderDomain(t *testing.T) {
+	want := common.HexToHash("0x00000001f5a5fd42d16a20302798ef6ed309979b43003d2320d9f0e8ea9831a9")
+	if have := ComputeBuilderDomain([4]byte{}); have != want {
+		t.Fatalf("mainnet builder domain mismatch: have %x, want %x", have, want)
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package builder implements a block builder for external proposers: it builds
+// payloads for the configured proposers and submits them to a relay together
+// with a signed bid, following the builder specs.
+package builder
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
+)
+
+// relayTimeout is the maximum time allowed for a request to the relay.
+const relayTimeout = 5 * time.Second
+
+// Backend wraps the methods required by the builder.
+type Backend interface {
+	Miner() *miner.Miner
+}
+
+// Proposer is a validator the builder builds blocks for.
+type Proposer struct {
+	Pubkey       PublicKey      // Public key of the validator
+	FeeRecipient common.Address // Recipient of the fees of the blocks built
+}
+
+// Config contains the configuration of the builder.
+type Config struct {
+	SecretKey          hexutil.Bytes `toml:"-"` // BLS secret key signing the bids, read from SecretKeyFile if empty
+	SecretKeyFile      string        // File holding the hex encoded BLS secret key
+	RelayURL           string        // Endpoint of the relay receiving the blocks
+	Proposers          []Proposer    // Proposers the blocks are built for in their slots
+	GenesisForkVersion hexutil.Bytes // Genesis fork version of the beacon chain
+	GenesisTime        uint64        // Genesis time of the beacon chain
+	SecondsPerSlot     uint64        // Slot duration of the beacon chain
+}
+
+// DefaultConfig contains the beacon chain parameters of the mainnet.
+var DefaultConfig = Config{
+	GenesisForkVersion: hexutil.Bytes{0x00, 0x00, 0x00, 0x00},
+	GenesisTime:        1606824023,
+	SecondsPerSlot:     12,
+}
+
+// Builder builds a block whenever the consensus client updates the forkchoice
+// with payload attributes for a slot whose proposer, as registered at the relay,
+// is one of the configured proposers, and submits the block to the relay.
+type Builder struct {
+	eth       Backend
+	relay     Relay
+	config    Config
+	secretKey *SecretKey
+	pubkey    PublicKey
+	domain    [32]byte
+
+	mu     sync.Mutex // Lock protecting the closed flag
+	closed bool
+	wg     sync.WaitGroup
+	exitCh chan struct{}
+}
+
+// New creates a builder submitting the blocks built by the backend to the relay.
+func New(eth Backend, relay Relay, config Config) (*Builder, error) {
+	key := []byte(config.SecretKey)
+	if len(key) == 0 && config.SecretKeyFile != "" {
+		var err error
+		if key, err = readSecretKey(config.SecretKeyFile); err != nil {
+			return nil, err
+		}
+	}
+	secretKey, err := NewSecretKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid builder secret key: %v", err)
+	}
+	if len(config.GenesisForkVersion) != 4 {
+		return nil, errors.New("genesis fork version should be 4 bytes")
+	}
+	if config.SecondsPerSlot == 0 {
+		return nil, errors.New("zero slot duration")
+	}
+	var version [4]byte
+	copy(version[:], config.GenesisForkVersion)
+
+	return &Builder{
+		eth:       eth,
+		relay:     relay,
+		config:    config,
+		secretKey: secretKey,
+		pubkey:    secretKey.PublicKey(),
+		domain:    ComputeBuilderDomain(version),
+		exitCh:    make(chan struct{}),
+	}, nil
+}
+
+// readSecretKey reads a hex encoded BLS secret key from a file.
+func readSecretKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read builder secret key: %v", err)
+	}
+	key, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(data)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid builder secret key in %s: %v", path, err)
+	}
+	return key, nil
+}
+
+// Pubkey returns the public key of the builder.
+func (b *Builder) Pubkey() PublicKey {
+	return b.pubkey
+}
+
+// Start implements node.Lifecycle.
+func (b *Builder) Start() error {
+	log.Info("Started block builder", "pubkey", b.pubkey, "relay", b.config.RelayURL, "proposers", len(b.config.Proposers))
+	return nil
+}
+
+// Stop implements node.Lifecycle, aborting the blocks being built.
+func (b *Builder) Stop() error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.exitCh)
+	}
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	return nil
+}
+
+// ForkchoiceUpdated builds a block on top of the new head if payload attributes
+// are given and the proposer of their slot is a configured one. The proposer is
+// looked up, and the block built and submitted, in the background.
+func (b *Builder) ForkchoiceUpdated(update beacon.ForkchoiceStateV1, attributes *beacon.PayloadAttributesV1) {
+	if attributes == nil {
+		return
+	}
+	if attributes.Timestamp < b.config.GenesisTime {
+		log.Warn("Payload timestamp before beacon genesis", "timestamp", attributes.Timestamp, "genesis", b.config.GenesisTime)
+		return
+	}
+	slot := (attributes.Timestamp - b.config.GenesisTime) / b.config.SecondsPerSlot
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		proposer, err := b.slotProposer(slot)
+		if err != nil {
+			log.Warn("Failed to retrieve slot proposer", "slot", slot, "err", err)
+			return
+		}
+		if proposer == nil {
+			log.Debug("Skipping block for unknown proposer", "slot", slot)
+			return
+		}
+		if err := b.submitBlock(update.HeadBlockHash, attributes, slot, *proposer); err != nil {
+			log.Warn("Failed to submit block", "slot", slot, "proposer", proposer.Pubkey, "err", err)
+		}
+	}()
+}
+
+// slotProposer returns the configured proposer registered at the relay for the
+// slot, or nil if the slot is proposed by someone else.
+func (b *Builder) slotProposer(slot uint64) (*Proposer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+
+	duties, err := b.relay.ProposerDuties(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, duty := range duties {
+		if duty.Slot != slot || duty.Entry == nil || duty.Entry.Message == nil {
+			continue
+		}
+		for _, proposer := range b.config.Proposers {
+			if proposer.Pubkey == duty.Entry.Message.Pubkey {
+				proposer := proposer
+				return &proposer, nil
+			}
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// submitBlock builds a block for the proposer and submits it to the relay.
+func (b *Builder) submitBlock(parent common.Hash, attributes *beacon.PayloadAttributesV1, slot uint64, proposer Proposer) error {
+	payload, err := b.eth.Miner().BuildPayload(&miner.BuildPayloadArgs{
+		Parent:       parent,
+		Timestamp:    attributes.Timestamp,
+		FeeRecipient: proposer.FeeRecipient,
+		Random:       attributes.Random,
+	})
+	if err != nil {
+		return err
+	}
+	// Abort the building on shutdown, the payload is useless afterwards.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-b.exitCh:
+			payload.Cancel()
+		case <-done:
+		}
+	}()
+	envelope := payload.ResolveFull()
+	if envelope == nil {
+		return errors.New("payload building aborted")
+	}
+	submission, err := b.newSubmission(slot, proposer, envelope)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), relayTimeout)
+	defer cancel()
+
+	if err := b.relay.SubmitBlock(ctx, submission); err != nil {
+		return err
+	}
+	log.Info("Submitted block to relay", "slot", slot, "number", envelope.ExecutionPayload.Number, "hash", envelope.ExecutionPayload.BlockHash,
+		"txs", len(envelope.ExecutionPayload.Transactions), "value", envelope.BlockValue)
+	return nil
+}
+
+// newSubmission creates the submission of the payload, signing the bid.
+func (b *Builder) newSubmission(slot uint64, proposer Proposer, envelope *beacon.ExecutionPayloadEnvelope) (*SignedBidSubmission, error) {
+	data := envelope.ExecutionPayload
+	bid := &BidTrace{
+		Slot:                 slot,
+		ParentHash:           data.ParentHash,
+		BlockHash:            data.BlockHash,
+		BuilderPubkey:        b.pubkey,
+		ProposerPubkey:       proposer.Pubkey,
+		ProposerFeeRecipient: proposer.FeeRecipient,
+		GasLimit:             data.GasLimit,
+		GasUsed:              data.GasUsed,
+		Value:                NewUint256(envelope.BlockValue),
+	}
+	root := computeSigningRoot(bid.HashTreeRoot(), b.domain)
+	sig, err := b.secretKey.Sign(root[:])
+	if err != nil {
+		return nil, err
+	}
+	return &SignedBidSubmission{
+		Message:          bid,
+		ExecutionPayload: NewExecutionPayload(data),
+		Signature:        sig,
+	}, nil
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/builder"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/catalyst"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
+	testBLSKey  = common.FromHex("0x263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+	testVersion = [4]byte{0x00, 0x00, 0x10, 0x20}
+)
+
+func startEthService(t *testing.T) (*node.Node, *eth.Ethereum) {
+	t.Helper()
+
+	n, err := node.New(&node.Config{})
+	if err != nil {
+		t.Fatal("can't create node:", err)
+	}
+	ethcfg := &ethconfig.Config{
+		Genesis:        core.DeveloperBeaconGenesisBlock(11_500_000, testAddr),
+		Ethash:         ethash.Config{PowMode: ethash.ModeFake},
+		TrieTimeout:    time.Minute,
+		TrieDirtyCache: 256,
+		TrieCleanCache: 256,
+	}
+	ethservice, err := eth.New(n, ethcfg)
+	if err != nil {
+		t.Fatal("can't create eth service:", err)
+	}
+	if err := n.Start(); err != nil {
+		t.Fatal("can't start node:", err)
+	}
+	ethservice.SetSynced()
+	return n, ethservice
+}
+
+func TestBuilderSubmitsBlocks(t *testing.T) {
+	n, ethservice := startEthService(t)
+	defer n.Close()
+
+	// Add a transaction paying a tip to the fee recipient.
+	signer := types.LatestSigner(ethservice.BlockChain().Config())
+	tx := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+		ChainID:   ethservice.BlockChain().Config().ChainID,
+		Nonce:     0,
+		To:        &common.Address{0x01},
+		Value:     big.NewInt(1),
+		Gas:       params.TxGas,
+		GasFeeCap: big.NewInt(10 * params.InitialBaseFee),
+		GasTipCap: big.NewInt(params.GWei),
+	})
+	if err := ethservice.TxPool().AddLocal(tx); err != nil {
+		t.Fatal("can't add transaction:", err)
+	}
+	relay := builder.NewLocalRelay(testVersion)
+	server := httptest.NewServer(relay)
+	defer server.Close()
+
+	var (
+		head     = ethservice.BlockChain().CurrentBlock()
+		proposer = builder.Proposer{FeeRecipient: common.Address{0xfe}}
+	)
+	proposer.Pubkey[0] = 0xc0
+
+	// Blocks are only built for the slots of configured proposers.
+	other := builder.Proposer{FeeRecipient: common.Address{0xfd}}
+	other.Pubkey[0] = 0xc1
+	relay.RegisterProposer(2, proposer)
+	relay.RegisterProposer(3, other)
+
+	b, err := builder.New(ethservice, builder.NewRemoteRelay(server.URL), builder.Config{
+		SecretKey:          testBLSKey,
+		RelayURL:           server.URL,
+		Proposers:          []builder.Proposer{proposer},
+		GenesisForkVersion: testVersion[:],
+		GenesisTime:        head.Time(),
+		SecondsPerSlot:     12,
+	})
+	if err != nil {
+		t.Fatal("can't create builder:", err)
+	}
+	defer b.Stop()
+
+	// Payloads are only built if attributes are given.
+	update := beacon.ForkchoiceStateV1{HeadBlockHash: head.Hash()}
+	b.ForkchoiceUpdated(update, nil)
+	for slot := uint64(2); slot <= 4; slot++ {
+		b.ForkchoiceUpdated(update, &beacon.PayloadAttributesV1{
+			Timestamp:             head.Time() + slot*12,
+			Random:                common.Hash{0x01},
+			SuggestedFeeRecipient: testAddr,
+		})
+	}
+	var bid *builder.SignedBidSubmission
+	for deadline := time.Now().Add(10 * time.Second); bid == nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("no bid submitted to the relay")
+		}
+		time.Sleep(50 * time.Millisecond)
+		bid = relay.BestBid(2)
+	}
+	b.Stop()
+	for _, slot := range []uint64{1, 3, 4} {
+		if len(relay.Bids(slot)) != 0 {
+			t.Fatalf("bid submitted for slot %d", slot)
+		}
+	}
+	msg, payload := bid.Message, bid.ExecutionPayload
+	if msg.BuilderPubkey != b.Pubkey() || msg.ProposerPubkey != proposer.Pubkey {
+		t.Fatalf("wrong bid pubkeys: builder %v, proposer %v", msg.BuilderPubkey, msg.ProposerPubkey)
+	}
+	if msg.ProposerFeeRecipient != proposer.FeeRecipient || payload.FeeRecipient != proposer.FeeRecipient {
+		t.Fatalf("wrong fee recipient: bid %x, payload %x", msg.ProposerFeeRecipient, payload.FeeRecipient)
+	}
+	if len(payload.Transactions) != 1 {
+		t.Fatalf("wrong number of transactions: have %d, want 1", len(payload.Transactions))
+	}
+	if want := new(big.Int).Mul(big.NewInt(params.GWei), big.NewInt(int64(params.TxGas))); msg.Value.ToBig().Cmp(want) != 0 {
+		t.Fatalf("wrong bid value: have %v, want %v", msg.Value.ToBig(), want)
+	}
+	// The submitted payload must be valid.
+	status, err := catalyst.NewConsensusAPI(ethservice).NewPayloadV1(*payload.ExecutableData())
+	if err != nil || status.Status != beacon.VALID {
+		t.Fatalf("submitted payload rejected: %v %v", status.Status, err)
+	}
+	// The relay lists the received bids.
+	resp, err := http.Get(server.URL + "/relay/v1/data/bidtraces/builder_blocks_received?slot=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var traces []*builder.BidTrace
+	if err := json.NewDecoder(resp.Body).Decode(&traces); err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 1 || traces[0].BlockHash != payload.BlockHash {
+		t.Fatalf("wrong bid traces: %v", traces)
+	}
+}
+
+func TestBuilderSecretKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := ioutil.WriteFile(path, []byte(hexutil.Encode(testBLSKey)+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	config := builder.Config{GenesisForkVersion: testVersion[:], SecondsPerSlot: 12}
+	config.SecretKey = testBLSKey
+	want, err := builder.New(nil, nil, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.SecretKey, config.SecretKeyFile = nil, path
+	have, err := builder.New(nil, nil, config)
+	if err != nil {
+		t.Fatal("can't create builder from key file:", err)
+	}
+	if have.Pubkey() != want.Pubkey() {
+		t.Fatalf("wrong pubkey: have %v, want %v", have.Pubkey(), want.Pubkey())
+	}
+	config.SecretKeyFile = filepath.Join(t.TempDir(), "missing")
+	if _, err := builder.New(nil, nil, config); err == nil {
+		t.Fatal("builder created without secret key")
+	}
+}
@@ -0,0 +1,284 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/core/beacon"
+)
+
+const (
+	// submitBlockPath is the relay endpoint receiving the blocks of builders.
+	submitBlockPath = "/relay/v1/builder/blocks"
+
+	// proposerDutiesPath is the relay endpoint listing the registered proposers
+	// of the upcoming slots.
+	proposerDutiesPath = "/relay/v1/builder/validators"
+
+	// receivedBidsPath is the relay endpoint listing the bids received.
+	receivedBidsPath = "/relay/v1/data/bidtraces/builder_blocks_received"
+
+	// maxRelayResponseSize is the maximum size of a relay error response read.
+	maxRelayResponseSize = 64 * 1024
+)
+
+// Relay receives the blocks built for the proposers.
+type Relay interface {
+	// SubmitBlock submits the block together with the signed bid of the builder.
+	SubmitBlock(ctx context.Context, submission *SignedBidSubmission) error
+
+	// ProposerDuties returns the registered proposers of the upcoming slots.
+	ProposerDuties(ctx context.Context) ([]*ProposerDuty, error)
+}
+
+// relayError is the error response of the relay API.
+type relayError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// RemoteRelay is a relay accessed over the HTTP relay API.
+type RemoteRelay struct {
+	url    string
+	client *http.Client
+}
+
+// NewRemoteRelay creates a client of the relay API served at the given URL.
+func NewRemoteRelay(url string) *RemoteRelay {
+	return &RemoteRelay{
+		url:    strings.TrimSuffix(url, "/"),
+		client: new(http.Client),
+	}
+}
+
+// SubmitBlock implements Relay.
+func (r *RemoteRelay) SubmitBlock(ctx context.Context, submission *SignedBidSubmission) error {
+	body, err := json.Marshal(submission)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url+submitBlockPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		var apiErr relayError
+		if err := json.NewDecoder(io.LimitReader(resp.Body, maxRelayResponseSize)).Decode(&apiErr); err == nil && apiErr.Message != "" {
+			return fmt.Errorf("relay rejected block: %s (code %d)", apiErr.Message, apiErr.Code)
+		}
+		return fmt.Errorf("relay rejected block: %s", resp.Status)
+	}
+	return nil
+}
+
+// ProposerDuties implements Relay.
+func (r *RemoteRelay) ProposerDuties(ctx context.Context) ([]*ProposerDuty, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url+proposerDutiesPath, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("failed to retrieve proposer duties: %s", resp.Status)
+	}
+	var duties []*ProposerDuty
+	if err := json.NewDecoder(resp.Body).Decode(&duties); err != nil {
+		return nil, fmt.Errorf("invalid proposer duties: %v", err)
+	}
+	return duties, nil
+}
+
+// LocalRelay is a minimal in-process relay serving the block submission part of
+// the relay API, meant to stand in for a real relay in tests. It checks the
+// submitted blocks and bid signatures, and keeps the bids of each slot.
+// Proposers are registered for slots by RegisterProposer.
+type LocalRelay struct {
+	domain [32]byte
+
+	mu     sync.Mutex
+	bids   map[uint64][]*SignedBidSubmission // Bids received, grouped by slot
+	duties map[uint64]*ProposerDuty          // Registered proposers by slot
+}
+
+// NewLocalRelay creates a relay accepting bids signed for the beacon chain with
+// the given genesis fork version.
+func NewLocalRelay(genesisForkVersion [4]byte) *LocalRelay {
+	return &LocalRelay{
+		domain: ComputeBuilderDomain(genesisForkVersion),
+		bids:   make(map[uint64][]*SignedBidSubmission),
+		duties: make(map[uint64]*ProposerDuty),
+	}
+}
+
+// RegisterProposer registers the proposer of a slot. Registration signatures
+// are not checked by the local relay.
+func (r *LocalRelay) RegisterProposer(slot uint64, proposer Proposer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.duties[slot] = &ProposerDuty{
+		Slot: slot,
+		Entry: &SignedValidatorRegistration{
+			Message: &ValidatorRegistration{FeeRecipient: proposer.FeeRecipient, Pubkey: proposer.Pubkey},
+		},
+	}
+}
+
+// ProposerDuties implements Relay.
+func (r *LocalRelay) ProposerDuties(ctx context.Context) ([]*ProposerDuty, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	duties := make([]*ProposerDuty, 0, len(r.duties))
+	for _, duty := range r.duties {
+		duties = append(duties, duty)
+	}
+	sort.Slice(duties, func(i, j int) bool { return duties[i].Slot < duties[j].Slot })
+	return duties, nil
+}
+
+// SubmitBlock implements Relay.
+func (r *LocalRelay) SubmitBlock(ctx context.Context, submission *SignedBidSubmission) error {
+	if err := r.verify(submission); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	slot := submission.Message.Slot
+	r.bids[slot] = append(r.bids[slot], submission)
+	return nil
+}
+
+// verify checks that the bid matches the block and was signed by the builder.
+func (r *LocalRelay) verify(submission *SignedBidSubmission) error {
+	bid, payload := submission.Message, submission.ExecutionPayload
+	if bid == nil || payload == nil {
+		return errors.New("missing bid or execution payload")
+	}
+	if bid.Value == nil {
+		return errors.New("missing bid value")
+	}
+	if bid.BlockHash != payload.BlockHash || bid.ParentHash != payload.ParentHash {
+		return errors.New("bid does not match payload")
+	}
+	if bid.GasLimit != payload.GasLimit || bid.GasUsed != payload.GasUsed {
+		return errors.New("bid gas does not match payload")
+	}
+	if _, err := beacon.ExecutableDataToBlock(*payload.ExecutableData()); err != nil {
+		return fmt.Errorf("invalid execution payload: %v", err)
+	}
+	root := computeSigningRoot(bid.HashTreeRoot(), r.domain)
+	if !VerifySignature(bid.BuilderPubkey, root[:], submission.Signature) {
+		return errors.New("invalid bid signature")
+	}
+	return nil
+}
+
+// Bids returns the bids received for the given slot, in order of arrival.
+func (r *LocalRelay) Bids(slot uint64) []*SignedBidSubmission {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]*SignedBidSubmission(nil), r.bids[slot]...)
+}
+
+// BestBid returns the most valuable bid received for the given slot, or nil if
+// there is none.
+func (r *LocalRelay) BestBid(slot uint64) *SignedBidSubmission {
+	var best *SignedBidSubmission
+	for _, bid := range r.Bids(slot) {
+		if best == nil || bid.Message.Value.ToBig().Cmp(best.Message.Value.ToBig()) > 0 {
+			best = bid
+		}
+	}
+	return best
+}
+
+// ServeHTTP implements http.Handler, serving the block submission endpoint, the
+// proposer duties and the listing of the received bids.
+func (r *LocalRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch {
+	case req.Method == http.MethodPost && req.URL.Path == submitBlockPath:
+		var submission SignedBidSubmission
+		if err := json.NewDecoder(req.Body).Decode(&submission); err != nil {
+			writeRelayError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err := r.SubmitBlock(req.Context(), &submission); err != nil {
+			writeRelayError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+
+	case req.Method == http.MethodGet && req.URL.Path == proposerDutiesPath:
+		duties, _ := r.ProposerDuties(req.Context())
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(duties)
+
+	case req.Method == http.MethodGet && req.URL.Path == receivedBidsPath:
+		slot, err := strconv.ParseUint(req.URL.Query().Get("slot"), 10, 64)
+		if err != nil {
+			writeRelayError(w, http.StatusBadRequest, errors.New("invalid slot"))
+			return
+		}
+		bids := r.Bids(slot)
+		traces := make([]*BidTrace, len(bids))
+		for i, bid := range bids {
+			traces[i] = bid.Message
+		}
+		sort.SliceStable(traces, func(i, j int) bool {
+			return traces[i].Value.ToBig().Cmp(traces[j].Value.ToBig()) > 0
+		})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(traces)
+
+	default:
+		writeRelayError(w, http.StatusNotFound, errors.New("not found"))
+	}
+}
+
+// writeRelayError writes an error response of the relay API.
+func writeRelayError(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(&relayError{Code: code, Message: err.Error()})
+}
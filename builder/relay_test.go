@@ -0,0 +1,96 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestRelayRejectsInvalidBids(t *testing.T) {
+	var (
+		version = [4]byte{0x00, 0x00, 0x10, 0x20}
+		relay   = NewLocalRelay(version)
+		server  = httptest.NewServer(relay)
+		remote  = NewRemoteRelay(server.URL)
+		key     = common.FromHex("0x263dbd792f5b1be47ed85f8938c0f29586af0d3ac7b977f21c278fe1462040e3")
+	)
+	defer server.Close()
+
+	newBuilder := func(version []byte) *Builder {
+		b, err := New(nil, relay, Config{SecretKey: key, GenesisForkVersion: version, SecondsPerSlot: 12})
+		if err != nil {
+			t.Fatal("can't create builder:", err)
+		}
+		return b
+	}
+	block := types.NewBlockWithHeader(&types.Header{
+		ParentHash:  common.Hash{0x01},
+		UncleHash:   types.EmptyUncleHash,
+		Root:        common.Hash{0x02},
+		TxHash:      types.EmptyRootHash,
+		ReceiptHash: types.EmptyRootHash,
+		Difficulty:  common.Big0,
+		Number:      big.NewInt(1),
+		GasLimit:    params.GenesisGasLimit,
+		Time:        12,
+		BaseFee:     big.NewInt(params.InitialBaseFee),
+	})
+	envelope := &beacon.ExecutionPayloadEnvelope{
+		ExecutionPayload: beacon.BlockToExecutableData(block),
+		BlockValue:       big.NewInt(1),
+	}
+	submission, err := newBuilder(version[:]).newSubmission(1, Proposer{}, envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.SubmitBlock(context.Background(), submission); err != nil {
+		t.Fatal("valid bid rejected:", err)
+	}
+	// Increasing the value invalidates the signature.
+	submission.Message.Value = NewUint256(big.NewInt(2))
+	if err := remote.SubmitBlock(context.Background(), submission); err == nil || !strings.Contains(err.Error(), "invalid bid signature") {
+		t.Fatalf("bid with invalid signature accepted: %v", err)
+	}
+	// Bids signed for another chain are rejected.
+	if submission, err = newBuilder([]byte{0x00, 0x00, 0x00, 0x00}).newSubmission(1, Proposer{}, envelope); err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.SubmitBlock(context.Background(), submission); err == nil {
+		t.Fatal("bid signed for other chain accepted")
+	}
+	// Payloads not matching their block hash are rejected.
+	if submission, err = newBuilder(version[:]).newSubmission(1, Proposer{}, envelope); err != nil {
+		t.Fatal(err)
+	}
+	submission.ExecutionPayload.GasUsed = 1
+	submission.Message.GasUsed = 1
+	if err := remote.SubmitBlock(context.Background(), submission); err == nil || !strings.Contains(err.Error(), "invalid execution payload") {
+		t.Fatalf("invalid payload accepted: %v", err)
+	}
+	if bids := relay.Bids(1); len(bids) != 1 {
+		t.Fatalf("wrong number of bids: have %d, want 1", len(bids))
+	}
+}
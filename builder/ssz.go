@@ -0,0 +1,107 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// domainTypeAppBuilder is the signature domain type of the builder API.
+var domainTypeAppBuilder = [4]byte{0x00, 0x00, 0x00, 0x01}
+
+// merkleize computes the root of the SSZ merkle tree of the given chunks,
+// padding them with zero chunks to the next power of two.
+func merkleize(chunks [][32]byte) [32]byte {
+	size := 1
+	for size < len(chunks) {
+		size *= 2
+	}
+	layer := make([][32]byte, size)
+	copy(layer, chunks)
+	for len(layer) > 1 {
+		next := make([][32]byte, len(layer)/2)
+		for i := range next {
+			next[i] = sha256.Sum256(append(layer[2*i][:], layer[2*i+1][:]...))
+		}
+		layer = next
+	}
+	return layer[0]
+}
+
+// uint64Chunk returns the SSZ chunk of a uint64.
+func uint64Chunk(v uint64) (chunk [32]byte) {
+	binary.LittleEndian.PutUint64(chunk[:], v)
+	return chunk
+}
+
+// pubkeyRoot returns the hash tree root of a 48 byte public key.
+func pubkeyRoot(pk PublicKey) [32]byte {
+	var chunks [2][32]byte
+	copy(chunks[0][:], pk[:32])
+	copy(chunks[1][:], pk[32:])
+	return merkleize(chunks[:])
+}
+
+// HashTreeRoot returns the SSZ hash tree root of the bid.
+func (b *BidTrace) HashTreeRoot() [32]byte {
+	var fee, value [32]byte
+	copy(fee[:], b.ProposerFeeRecipient[:])
+	if b.Value != nil {
+		// Integers are encoded little endian in SSZ.
+		be := b.Value.ToBig().Bytes()
+		for i, v := range be {
+			value[len(be)-1-i] = v
+		}
+	}
+	return merkleize([][32]byte{
+		uint64Chunk(b.Slot),
+		b.ParentHash,
+		b.BlockHash,
+		pubkeyRoot(b.BuilderPubkey),
+		pubkeyRoot(b.ProposerPubkey),
+		fee,
+		uint64Chunk(b.GasLimit),
+		uint64Chunk(b.GasUsed),
+		value,
+	})
+}
+
+// ComputeDomain returns the signature domain of the given type for the fork
+// with the given version and genesis validators root.
+func ComputeDomain(domainType [4]byte, forkVersion [4]byte, genesisValidatorsRoot [32]byte) (domain [32]byte) {
+	var version [32]byte
+	copy(version[:], forkVersion[:])
+	forkDataRoot := merkleize([][32]byte{version, genesisValidatorsRoot})
+
+	copy(domain[:], domainType[:])
+	copy(domain[4:], forkDataRoot[:28])
+	return domain
+}
+
+// ComputeBuilderDomain returns the signature domain of the builder API for the
+// beacon chain with the given genesis fork version. Builder signatures are not
+// bound to the genesis validators root.
+func ComputeBuilderDomain(genesisForkVersion [4]byte) [32]byte {
+	return ComputeDomain(domainTypeAppBuilder, genesisForkVersion, [32]byte{})
+}
+
+// computeSigningRoot returns the message to be signed for an object with the
+// given hash tree root in the given domain.
+func computeSigningRoot(root [32]byte, domain [32]byte) [32]byte {
+	return merkleize([][32]byte{root, domain})
+}
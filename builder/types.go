@@ -0,0 +1,170 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package builder
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/beacon"
+)
+
+// Uint256 is a 256 bit unsigned integer encoded as a decimal string, the way
+// the builder API encodes amounts.
+type Uint256 big.Int
+
+// NewUint256 creates a Uint256 from a big integer.
+func NewUint256(v *big.Int) *Uint256 {
+	return (*Uint256)(new(big.Int).Set(v))
+}
+
+// ToBig returns the value as a big integer.
+func (u *Uint256) ToBig() *big.Int {
+	return new(big.Int).Set((*big.Int)(u))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (u *Uint256) MarshalText() ([]byte, error) {
+	return []byte((*big.Int)(u).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (u *Uint256) UnmarshalText(input []byte) error {
+	v, ok := new(big.Int).SetString(string(input), 10)
+	if !ok || v.Sign() < 0 || v.BitLen() > 256 {
+		return fmt.Errorf("invalid uint256 %q", input)
+	}
+	*u = Uint256(*v)
+	return nil
+}
+
+// BidTrace is the message signed by the builder when submitting a block to the
+// relay.
+type BidTrace struct {
+	Slot                 uint64         `json:"slot,string"`
+	ParentHash           common.Hash    `json:"parent_hash"`
+	BlockHash            common.Hash    `json:"block_hash"`
+	BuilderPubkey        PublicKey      `json:"builder_pubkey"`
+	ProposerPubkey       PublicKey      `json:"proposer_pubkey"`
+	ProposerFeeRecipient common.Address `json:"proposer_fee_recipient"`
+	GasLimit             uint64         `json:"gas_limit,string"`
+	GasUsed              uint64         `json:"gas_used,string"`
+	Value                *Uint256       `json:"value"`
+}
+
+// ExecutionPayload is the execution payload in the encoding of the builder API.
+type ExecutionPayload struct {
+	ParentHash    common.Hash     `json:"parent_hash"`
+	FeeRecipient  common.Address  `json:"fee_recipient"`
+	StateRoot     common.Hash     `json:"state_root"`
+	ReceiptsRoot  common.Hash     `json:"receipts_root"`
+	LogsBloom     hexutil.Bytes   `json:"logs_bloom"`
+	PrevRandao    common.Hash     `json:"prev_randao"`
+	BlockNumber   uint64          `json:"block_number,string"`
+	GasLimit      uint64          `json:"gas_limit,string"`
+	GasUsed       uint64          `json:"gas_used,string"`
+	Timestamp     uint64          `json:"timestamp,string"`
+	ExtraData     hexutil.Bytes   `json:"extra_data"`
+	BaseFeePerGas *Uint256        `json:"base_fee_per_gas"`
+	BlockHash     common.Hash     `json:"block_hash"`
+	Transactions  []hexutil.Bytes `json:"transactions"`
+}
+
+// NewExecutionPayload converts an execution payload of the engine API.
+func NewExecutionPayload(data *beacon.ExecutableDataV1) *ExecutionPayload {
+	txs := make([]hexutil.Bytes, len(data.Transactions))
+	for i, tx := range data.Transactions {
+		txs[i] = tx
+	}
+	return &ExecutionPayload{
+		ParentHash:    data.ParentHash,
+		FeeRecipient:  data.FeeRecipient,
+		StateRoot:     data.StateRoot,
+		ReceiptsRoot:  data.ReceiptsRoot,
+		LogsBloom:     data.LogsBloom,
+		PrevRandao:    data.Random,
+		BlockNumber:   data.Number,
+		GasLimit:      data.GasLimit,
+		GasUsed:       data.GasUsed,
+		Timestamp:     data.Timestamp,
+		ExtraData:     data.ExtraData,
+		BaseFeePerGas: NewUint256(data.BaseFeePerGas),
+		BlockHash:     data.BlockHash,
+		Transactions:  txs,
+	}
+}
+
+// ExecutableData converts the payload into an execution payload of the engine API.
+func (p *ExecutionPayload) ExecutableData() *beacon.ExecutableDataV1 {
+	txs := make([][]byte, len(p.Transactions))
+	for i, tx := range p.Transactions {
+		txs[i] = tx
+	}
+	var baseFee *big.Int
+	if p.BaseFeePerGas != nil {
+		baseFee = p.BaseFeePerGas.ToBig()
+	}
+	return &beacon.ExecutableDataV1{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.PrevRandao,
+		Number:        p.BlockNumber,
+		GasLimit:      p.GasLimit,
+		GasUsed:       p.GasUsed,
+		Timestamp:     p.Timestamp,
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: baseFee,
+		BlockHash:     p.BlockHash,
+		Transactions:  txs,
+	}
+}
+
+// SignedBidSubmission is a block submitted to the relay, together with the
+// bid signed by the builder.
+type SignedBidSubmission struct {
+	Message          *BidTrace         `json:"message"`
+	ExecutionPayload *ExecutionPayload `json:"execution_payload"`
+	Signature        Signature         `json:"signature"`
+}
+
+// ValidatorRegistration is the message signed by a validator registering its
+// fee recipient and gas limit preferences with the relay.
+type ValidatorRegistration struct {
+	FeeRecipient common.Address `json:"fee_recipient"`
+	GasLimit     uint64         `json:"gas_limit,string"`
+	Timestamp    uint64         `json:"timestamp,string"`
+	Pubkey       PublicKey      `json:"pubkey"`
+}
+
+// SignedValidatorRegistration is a validator registration together with the
+// signature of the validator.
+type SignedValidatorRegistration struct {
+	Message   *ValidatorRegistration `json:"message"`
+	Signature Signature              `json:"signature"`
+}
+
+// ProposerDuty is an entry of the relay's schedule of upcoming proposers.
+type ProposerDuty struct {
+	Slot           uint64                       `json:"slot,string"`
+	ValidatorIndex uint64                       `json:"validator_index,string"`
+	Entry          *SignedValidatorRegistration `json:"entry"`
+}
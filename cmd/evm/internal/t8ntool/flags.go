@@ -142,6 +142,22 @@ var (
 		Usage: "ChainID to use",
 		Value: 1,
 	}
+	BaseFeeChangeDenominatorFlag = cli.Uint64Flag{
+		Name:  "state.basefee.denominator",
+		Usage: "EIP-1559 base fee change denominator (0 = mainnet value)",
+	}
+	ElasticityMultiplierFlag = cli.Uint64Flag{
+		Name:  "state.basefee.elasticity",
+		Usage: "EIP-1559 elasticity multiplier (0 = mainnet value)",
+	}
+	MinBaseFeeFlag = cli.Uint64Flag{
+		Name:  "state.basefee.min",
+		Usage: "EIP-1559 minimum base fee in wei (0 = no minimum)",
+	}
+	InitialBaseFeeFlag = cli.Uint64Flag{
+		Name:  "state.basefee.initial",
+		Usage: "EIP-1559 base fee of the first London block in wei (0 = mainnet value)",
+	}
 	ForknameFlag = cli.StringFlag{
 		Name: "state.fork",
 		Usage: fmt.Sprintf("Name of ruleset to use."+
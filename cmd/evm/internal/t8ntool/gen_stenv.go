@@ -27,6 +27,9 @@ func (s stEnv) MarshalJSON() ([]byte, error) {
 		BlockHashes      map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
 		Ommers           []ommer                             `json:"ommers,omitempty"`
 		BaseFee          *math.HexOrDecimal256               `json:"currentBaseFee,omitempty"`
+		ParentBaseFee    *math.HexOrDecimal256               `json:"parentBaseFee,omitempty"`
+		ParentGasUsed    math.HexOrDecimal64                 `json:"parentGasUsed,omitempty"`
+		ParentGasLimit   math.HexOrDecimal64                 `json:"parentGasLimit,omitempty"`
 		ParentUncleHash  common.Hash                         `json:"parentUncleHash"`
 	}
 	var enc stEnv
@@ -41,6 +44,9 @@ func (s stEnv) MarshalJSON() ([]byte, error) {
 	enc.BlockHashes = s.BlockHashes
 	enc.Ommers = s.Ommers
 	enc.BaseFee = (*math.HexOrDecimal256)(s.BaseFee)
+	enc.ParentBaseFee = (*math.HexOrDecimal256)(s.ParentBaseFee)
+	enc.ParentGasUsed = math.HexOrDecimal64(s.ParentGasUsed)
+	enc.ParentGasLimit = math.HexOrDecimal64(s.ParentGasLimit)
 	enc.ParentUncleHash = s.ParentUncleHash
 	return json.Marshal(&enc)
 }
@@ -59,6 +65,9 @@ func (s *stEnv) UnmarshalJSON(input []byte) error {
 		BlockHashes      map[math.HexOrDecimal64]common.Hash `json:"blockHashes,omitempty"`
 		Ommers           []ommer                             `json:"ommers,omitempty"`
 		BaseFee          *math.HexOrDecimal256               `json:"currentBaseFee,omitempty"`
+		ParentBaseFee    *math.HexOrDecimal256               `json:"parentBaseFee,omitempty"`
+		ParentGasUsed    *math.HexOrDecimal64                `json:"parentGasUsed,omitempty"`
+		ParentGasLimit   *math.HexOrDecimal64                `json:"parentGasLimit,omitempty"`
 		ParentUncleHash  *common.Hash                        `json:"parentUncleHash"`
 	}
 	var dec stEnv
@@ -102,6 +111,15 @@ func (s *stEnv) UnmarshalJSON(input []byte) error {
 	if dec.BaseFee != nil {
 		s.BaseFee = (*big.Int)(dec.BaseFee)
 	}
+	if dec.ParentBaseFee != nil {
+		s.ParentBaseFee = (*big.Int)(dec.ParentBaseFee)
+	}
+	if dec.ParentGasUsed != nil {
+		s.ParentGasUsed = uint64(*dec.ParentGasUsed)
+	}
+	if dec.ParentGasLimit != nil {
+		s.ParentGasLimit = uint64(*dec.ParentGasLimit)
+	}
 	if dec.ParentUncleHash != nil {
 		s.ParentUncleHash = *dec.ParentUncleHash
 	}
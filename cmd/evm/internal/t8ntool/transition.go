@@ -29,6 +29,8 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -190,8 +192,9 @@ func Transition(ctx *cli.Context) error {
 		chainConfig = cConf
 		vmConfig.ExtraEips = extraEips
 	}
-	// Set the chain id
+	// Set the chain id and fee market parameters
 	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
+	chainConfig.EIP1559 = eip1559Config(ctx)
 
 	var txsWithKeys []*txWithKey
 	if txStr != stdinSelector {
@@ -247,10 +250,19 @@ func Transition(ctx *cli.Context) error {
 		return NewError(ErrorJson, fmt.Errorf("failed signing transactions: %v", err))
 	}
 	// Sanity check, to not `panic` in state_transition
-	if chainConfig.IsLondon(big.NewInt(int64(prestate.Env.Number))) {
-		if prestate.Env.BaseFee == nil {
+	var deriveBaseFee bool
+	if env := prestate.Env; chainConfig.IsLondon(big.NewInt(int64(env.Number))) && env.BaseFee == nil {
+		// If the base fee was not provided by caller, we need to calculate it.
+		if env.ParentBaseFee == nil || env.Number == 0 {
 			return NewError(ErrorConfig, errors.New("EIP-1559 config but missing 'currentBaseFee' in env section"))
 		}
+		prestate.Env.BaseFee = misc.CalcBaseFee(chainConfig, &types.Header{
+			Number:   new(big.Int).SetUint64(env.Number - 1),
+			BaseFee:  env.ParentBaseFee,
+			GasUsed:  env.ParentGasUsed,
+			GasLimit: env.ParentGasLimit,
+		})
+		deriveBaseFee = true
 	}
 	// Sanity check, to not `panic` in state_transition
 	if prestate.Env.Random != nil && !chainConfig.IsLondon(big.NewInt(int64(prestate.Env.Number))) {
@@ -275,6 +287,10 @@ func Transition(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
+	if deriveBaseFee {
+		// Report the derived base fee, the caller needs it to build the block
+		result.BaseFee = (*math.HexOrDecimal256)(prestate.Env.BaseFee)
+	}
 	body, _ := rlp.EncodeToBytes(txs)
 	// Dump the excution result
 	collector := make(Alloc)
@@ -282,6 +298,25 @@ func Transition(ctx *cli.Context) error {
 	return dispatchOutput(ctx, baseDir, result, collector, body)
 }
 
+// eip1559Config assembles the fee market parameters from the command line, or
+// returns nil if the mainnet parameters are to be used.
+func eip1559Config(ctx *cli.Context) *params.EIP1559Config {
+	config := &params.EIP1559Config{
+		BaseFeeChangeDenominator: ctx.Uint64(BaseFeeChangeDenominatorFlag.Name),
+		ElasticityMultiplier:     ctx.Uint64(ElasticityMultiplierFlag.Name),
+	}
+	if fee := ctx.Uint64(MinBaseFeeFlag.Name); fee != 0 {
+		config.MinBaseFee = new(big.Int).SetUint64(fee)
+	}
+	if fee := ctx.Uint64(InitialBaseFeeFlag.Name); fee != 0 {
+		config.InitialBaseFee = new(big.Int).SetUint64(fee)
+	}
+	if *config == (params.EIP1559Config{}) {
+		return nil
+	}
+	return config
+}
+
 // txWithKey is a helper-struct, to allow us to use the types.Transaction along with
 // a `secretKey`-field, for input
 type txWithKey struct {
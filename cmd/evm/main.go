@@ -153,6 +153,10 @@ var stateTransitionCommand = cli.Command{
 		t8ntool.ForknameFlag,
 		t8ntool.ChainIDFlag,
 		t8ntool.RewardFlag,
+		t8ntool.BaseFeeChangeDenominatorFlag,
+		t8ntool.ElasticityMultiplierFlag,
+		t8ntool.MinBaseFeeFlag,
+		t8ntool.InitialBaseFeeFlag,
 		t8ntool.VerbosityFlag,
 	},
 }
@@ -240,6 +240,61 @@ func TestT8n(t *testing.T) {
 	}
 }
 
+// Tests that the base fee is derived from the parent block using the EIP-1559
+// parameters given on the command line.
+func TestT8nEIP1559Config(t *testing.T) {
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	for i, tc := range []struct {
+		base   string
+		input  t8nInput
+		extra  []string
+		expOut string
+	}{
+		{ // Custom base fee change denominator
+			base: "./testdata/24",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "London", "",
+			},
+			extra:  []string{"--state.basefee.denominator", "2"},
+			expOut: "exp.json",
+		},
+		{ // Base fee bounded by the minimum base fee
+			base: "./testdata/24",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "London", "",
+			},
+			extra:  []string{"--state.basefee.denominator", "2", "--state.basefee.min", "700000000"},
+			expOut: "exp_min.json",
+		},
+	} {
+		output := t8nOutput{result: true}
+
+		args := []string{"t8n"}
+		args = append(args, output.get()...)
+		args = append(args, tc.input.get(tc.base)...)
+		args = append(args, tc.extra...)
+		tt.Run("evm-test", args...)
+
+		want, err := os.ReadFile(fmt.Sprintf("%v/%v", tc.base, tc.expOut))
+		if err != nil {
+			t.Fatalf("test %d: could not read expected output: %v", i, err)
+		}
+		have := tt.Output()
+		ok, err := cmpJson(have, want)
+		switch {
+		case err != nil:
+			t.Fatalf("test %d, json parsing failed: %v", i, err)
+		case !ok:
+			t.Fatalf("test %d: output wrong, have \n%v\nwant\n%v\n", i, string(have), string(want))
+		}
+		tt.WaitExit()
+		if have := tt.ExitStatus(); have != 0 {
+			t.Fatalf("test %d: wrong exit code, have %d, want %d", i, have, 0)
+		}
+	}
+}
+
 type t9nInput struct {
 	inTxs  string
 	stFork string
@@ -180,6 +180,10 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		}
 	}
 
+	// Drive the developer chain with a simulated beacon
+	if ctx.GlobalBool(utils.DeveloperFlag.Name) {
+		utils.RegisterSimulatedBeacon(ctx, stack, eth)
+	}
 	// Configure GraphQL if requested
 	if ctx.GlobalIsSet(utils.GraphQLEnabledFlag.Name) {
 		utils.RegisterGraphQLService(stack, backend, cfg.Node)
@@ -92,3 +92,39 @@ func TestCustomGenesis(t *testing.T) {
 		geth.ExpectExit()
 	}
 }
+
+// Tests that developer mode refuses to reuse data directories containing clique
+// developer chains, which can't be driven by the simulated beacon.
+func TestDeveloperCliqueDatadir(t *testing.T) {
+	datadir := tmpdir(t)
+	defer os.RemoveAll(datadir)
+
+	json := filepath.Join(datadir, "genesis.json")
+	genesis := `{
+		"alloc"      : {},
+		"difficulty" : "0x1",
+		"extraData"  : "0x000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000010000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000",
+		"gasLimit"   : "0x2fefd8",
+		"config"     : {
+			"chainId" : 1337,
+			"clique"  : {"period": 0, "epoch": 30000}
+		}
+	}`
+	if err := ioutil.WriteFile(json, []byte(genesis), 0600); err != nil {
+		t.Fatalf("failed to write genesis file: %v", err)
+	}
+	runGeth(t, "--datadir", datadir, "init", json).WaitExit()
+
+	// Run the chain once, as developer mode used to, to fully set up the database
+	geth := runGeth(t, "--networkid", "1337", "--syncmode=full", "--cache", "16",
+		"--datadir", datadir, "--maxpeers", "0", "--port", "0",
+		"--nodiscover", "--nat", "none", "--ipcdisable",
+		"--exec", "eth.blockNumber", "console")
+	geth.ExpectRegexp("0")
+	geth.ExpectExit()
+
+	geth = runGeth(t, "--dev", "--datadir", datadir, "--lightkdf", "--maxpeers", "0", "--port", "0",
+		"--nodiscover", "--nat", "none", "--ipcdisable")
+	geth.ExpectRegexp("Fatal: Data directory .* contains a clique developer chain of an older version")
+	geth.ExpectExit()
+}
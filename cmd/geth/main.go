@@ -84,6 +84,7 @@ var (
 		utils.TxPoolLocalsFlag,
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
+		utils.TxPoolSnapshotFlag,
 		utils.TxPoolRejournalFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
@@ -97,6 +98,10 @@ var (
 		utils.GCModeFlag,
 		utils.SnapshotFlag,
 		utils.TxLookupLimitFlag,
+		utils.LogIndexFlag,
+		utils.AddressIndexFlag,
+		utils.AddressIndexLimitFlag,
+		utils.AddressIndexInternalFlag,
 		utils.LightServeFlag,
 		utils.LightIngressFlag,
 		utils.LightEgressFlag,
@@ -108,6 +113,8 @@ var (
 		utils.UltraLightOnlyAnnounceFlag,
 		utils.LightNoSyncServeFlag,
 		utils.EthPeerRequiredBlocksFlag,
+		utils.ReorgMaxDepthFlag,
+		utils.ReorgCheckpointFlag,
 		utils.LegacyWhitelistFlag,
 		utils.BloomFilterSizeFlag,
 		utils.CacheFlag,
@@ -133,6 +140,14 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
+		utils.MinerOrderingFlag,
+		utils.BuilderEnabledFlag,
+		utils.BuilderSecretKeyFileFlag,
+		utils.BuilderRelayFlag,
+		utils.BuilderProposersFlag,
+		utils.BuilderGenesisForkVersionFlag,
+		utils.BuilderGenesisTimeFlag,
+		utils.BuilderSecondsPerSlotFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV5Flag,
@@ -159,6 +174,8 @@ var (
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
 		utils.MinerNotifyFullFlag,
+		utils.MinerStratumFlag,
+		utils.MinerStratumDifficultyFlag,
 		configFileFlag,
 	}
 
@@ -170,13 +187,21 @@ var (
 		utils.AuthListenFlag,
 		utils.AuthPortFlag,
 		utils.AuthVirtualHostsFlag,
+		utils.AuthTLSCertFlag,
+		utils.AuthTLSKeyFlag,
+		utils.AuthTLSClientCAFlag,
 		utils.JWTSecretFlag,
 		utils.HTTPVirtualHostsFlag,
 		utils.GraphQLEnabledFlag,
 		utils.GraphQLCORSDomainFlag,
 		utils.GraphQLVirtualHostsFlag,
+		utils.GraphQLMaxDepthFlag,
+		utils.GraphQLMaxComplexityFlag,
 		utils.HTTPApiFlag,
 		utils.HTTPPathPrefixFlag,
+		utils.HTTPTLSCertFlag,
+		utils.HTTPTLSKeyFlag,
+		utils.HTTPTLSClientCAFlag,
 		utils.WSEnabledFlag,
 		utils.WSListenAddrFlag,
 		utils.WSPortFlag,
@@ -189,6 +214,12 @@ var (
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
 		utils.RPCGlobalTxFeeCapFlag,
+		utils.RPCBatchLimitFlag,
+		utils.RPCResponseSizeLimitFlag,
+		utils.RPCBatchConcurrencyFlag,
+		utils.RPCBatchTimeoutFlag,
+		utils.RPCLogsLimitFlag,
+		utils.RPCAPIKeysFlag,
 		utils.AllowUnprotectedTxs,
 	}
 
@@ -431,6 +462,11 @@ func startNode(ctx *cli.Context, stack *node.Node, backend ethapi.Backend, isCon
 		// Set the gas price to the limits from the CLI and start mining
 		gasprice := utils.GlobalBig(ctx, utils.MinerGasPriceFlag.Name)
 		ethBackend.TxPool().SetGasPrice(gasprice)
+
+		// Blocks of the developer chain are produced by the simulated beacon.
+		if ctx.GlobalBool(utils.DeveloperFlag.Name) {
+			return
+		}
 		// start mining
 		threads := ctx.GlobalInt(utils.MinerThreadsFlag.Name)
 		if err := ethBackend.StartMining(threads); err != nil {
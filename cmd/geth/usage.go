@@ -51,10 +51,16 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.ExitWhenSyncedFlag,
 			utils.GCModeFlag,
 			utils.TxLookupLimitFlag,
+			utils.LogIndexFlag,
+			utils.AddressIndexFlag,
+			utils.AddressIndexLimitFlag,
+			utils.AddressIndexInternalFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 			utils.LightKDFFlag,
 			utils.EthPeerRequiredBlocksFlag,
+			utils.ReorgMaxDepthFlag,
+			utils.ReorgCheckpointFlag,
 		},
 	},
 	{
@@ -98,6 +104,7 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.TxPoolLocalsFlag,
 			utils.TxPoolNoLocalsFlag,
 			utils.TxPoolJournalFlag,
+			utils.TxPoolSnapshotFlag,
 			utils.TxPoolRejournalFlag,
 			utils.TxPoolPriceLimitFlag,
 			utils.TxPoolPriceBumpFlag,
@@ -142,6 +149,9 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.HTTPPortFlag,
 			utils.HTTPApiFlag,
 			utils.HTTPPathPrefixFlag,
+			utils.HTTPTLSCertFlag,
+			utils.HTTPTLSKeyFlag,
+			utils.HTTPTLSClientCAFlag,
 			utils.HTTPCORSDomainFlag,
 			utils.HTTPVirtualHostsFlag,
 			utils.WSEnabledFlag,
@@ -154,12 +164,23 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.AuthListenFlag,
 			utils.AuthPortFlag,
 			utils.AuthVirtualHostsFlag,
+			utils.AuthTLSCertFlag,
+			utils.AuthTLSKeyFlag,
+			utils.AuthTLSClientCAFlag,
 			utils.GraphQLEnabledFlag,
 			utils.GraphQLCORSDomainFlag,
 			utils.GraphQLVirtualHostsFlag,
+			utils.GraphQLMaxDepthFlag,
+			utils.GraphQLMaxComplexityFlag,
 			utils.RPCGlobalGasCapFlag,
 			utils.RPCGlobalEVMTimeoutFlag,
 			utils.RPCGlobalTxFeeCapFlag,
+			utils.RPCBatchLimitFlag,
+			utils.RPCResponseSizeLimitFlag,
+			utils.RPCBatchConcurrencyFlag,
+			utils.RPCBatchTimeoutFlag,
+			utils.RPCLogsLimitFlag,
+			utils.RPCAPIKeysFlag,
 			utils.AllowUnprotectedTxs,
 			utils.JSpathFlag,
 			utils.ExecFlag,
@@ -189,12 +210,27 @@ var AppHelpFlagGroups = []flags.FlagGroup{
 			utils.MinerThreadsFlag,
 			utils.MinerNotifyFlag,
 			utils.MinerNotifyFullFlag,
+			utils.MinerStratumFlag,
+			utils.MinerStratumDifficultyFlag,
 			utils.MinerGasPriceFlag,
 			utils.MinerGasLimitFlag,
 			utils.MinerEtherbaseFlag,
 			utils.MinerExtraDataFlag,
 			utils.MinerRecommitIntervalFlag,
 			utils.MinerNoVerifyFlag,
+			utils.MinerOrderingFlag,
+		},
+	},
+	{
+		Name: "BLOCK BUILDER",
+		Flags: []cli.Flag{
+			utils.BuilderEnabledFlag,
+			utils.BuilderSecretKeyFileFlag,
+			utils.BuilderRelayFlag,
+			utils.BuilderProposersFlag,
+			utils.BuilderGenesisForkVersionFlag,
+			utils.BuilderGenesisTimeFlag,
+			utils.BuilderSecondsPerSlotFlag,
 		},
 	},
 	{
@@ -0,0 +1,184 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Command openrpc generates the OpenRPC specification of the Geth RPC APIs.
+//
+// By default, an ephemeral in-memory developer node is started to collect the
+// descriptions of all APIs it registers. Alternatively, the document can be
+// fetched from a running node via its rpc_discover method.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+
+	// Force-load the native tracers to list all tracing options
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+// Git SHA1 commit hash of the release (set via linker flags)
+var gitCommit = ""
+var gitDate = ""
+
+var app = flags.NewApp(gitCommit, gitDate, "the Geth OpenRPC specification generator")
+
+var (
+	outputFlag = cli.StringFlag{
+		Name:  "out",
+		Usage: "File to write the OpenRPC document to (default = stdout)",
+	}
+	endpointFlag = cli.StringFlag{
+		Name:  "endpoint",
+		Usage: "RPC endpoint of a running node to fetch the document from, instead of an ephemeral node",
+	}
+	modulesFlag = cli.StringFlag{
+		Name:  "modules",
+		Usage: "Comma separated list of API namespaces to include (default = all)",
+	}
+)
+
+func init() {
+	app.Flags = []cli.Flag{
+		outputFlag,
+		endpointFlag,
+		modulesFlag,
+	}
+	app.Action = generate
+}
+
+func main() {
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// generate retrieves, filters and writes out the OpenRPC document.
+func generate(ctx *cli.Context) error {
+	var (
+		doc *rpc.OpenRPCDocument
+		err error
+	)
+	if endpoint := ctx.GlobalString(endpointFlag.Name); endpoint != "" {
+		doc, err = discoverRemote(endpoint)
+	} else {
+		doc, err = discoverLocal()
+	}
+	if err != nil {
+		return err
+	}
+	if modules := ctx.GlobalString(modulesFlag.Name); modules != "" {
+		filterModules(doc, strings.Split(modules, ","))
+	}
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	out = append(out, '\n')
+	if path := ctx.GlobalString(outputFlag.Name); path != "" {
+		return ioutil.WriteFile(path, out, 0644)
+	}
+	_, err = os.Stdout.Write(out)
+	return err
+}
+
+// discoverRemote fetches the OpenRPC document from a running node.
+func discoverRemote(endpoint string) (*rpc.OpenRPCDocument, error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc := new(rpc.OpenRPCDocument)
+	if err := client.Call(doc, "rpc_discover"); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// discoverLocal starts an ephemeral developer node without networking and
+// collects the OpenRPC document of all APIs it registers.
+func discoverLocal() (*rpc.OpenRPCDocument, error) {
+	log.Root().SetHandler(log.DiscardHandler())
+
+	stack, err := node.New(&node.Config{
+		Name: "openrpc",
+		P2P:  p2p.Config{MaxPeers: 0, NoDiscovery: true, ListenAddr: ""},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer stack.Close()
+
+	// Use the merged developer chain to have the engine API registered
+	genesis := core.DeveloperBeaconGenesisBlock(ethconfig.Defaults.Miner.GasCeil, common.Address{})
+
+	cfg := ethconfig.Defaults
+	cfg.Genesis = genesis
+	cfg.NetworkId = genesis.Config.ChainID.Uint64()
+	cfg.SyncMode = downloader.FullSync
+	cfg.Miner.GasPrice = big.NewInt(params.GWei)
+
+	utils.RegisterEthService(stack, &cfg)
+	if err := stack.Start(); err != nil {
+		return nil, err
+	}
+	client, err := stack.Attach()
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	doc := new(rpc.OpenRPCDocument)
+	if err := client.Call(doc, "rpc_discover"); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// filterModules drops all methods not belonging to the given namespaces from
+// the document.
+func filterModules(doc *rpc.OpenRPCDocument, modules []string) {
+	keep := make(map[string]bool)
+	for _, module := range modules {
+		keep[strings.TrimSpace(module)] = true
+	}
+	methods := doc.Methods[:0]
+	for _, method := range doc.Methods {
+		if keep[strings.SplitN(method.Name, "_", 2)[0]] {
+			methods = append(methods, method)
+		}
+	}
+	doc.Methods = methods
+}
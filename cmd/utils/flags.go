@@ -19,6 +19,7 @@ package utils
 
 import (
 	"crypto/ecdsa"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -35,9 +36,12 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/builder"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -167,7 +171,7 @@ var (
 	}
 	DeveloperFlag = cli.BoolFlag{
 		Name:  "dev",
-		Usage: "Ephemeral proof-of-authority network with a pre-funded developer account, mining enabled",
+		Usage: "Ephemeral proof-of-stake network with a pre-funded developer account, driven by a simulated beacon",
 	}
 	DeveloperPeriodFlag = cli.IntFlag{
 		Name:  "dev.period",
@@ -237,6 +241,23 @@ var (
 		Usage: "Number of recent blocks to maintain transactions index for (default = about one year, 0 = entire chain)",
 		Value: ethconfig.Defaults.TxLookupLimit,
 	}
+	LogIndexFlag = cli.BoolFlag{
+		Name:  "logindex",
+		Usage: "Maintain a dedicated address/topic log index for fast log filtering over wide block ranges",
+	}
+	AddressIndexFlag = cli.BoolFlag{
+		Name:  "addressindex",
+		Usage: "Maintain an index of transactions by participating address (eth_getTransactionsByAddress)",
+	}
+	AddressIndexLimitFlag = cli.Uint64Flag{
+		Name:  "addressindex.limit",
+		Usage: "Number of recent blocks to maintain the address index for (default = about one year, 0 = entire chain)",
+		Value: ethconfig.Defaults.AddressIndexLimit,
+	}
+	AddressIndexInternalFlag = cli.BoolFlag{
+		Name:  "addressindex.internal",
+		Usage: "Trace blocks to index addresses participating in internal calls too (requires state of indexed blocks)",
+	}
 	LightKDFFlag = cli.BoolFlag{
 		Name:  "lightkdf",
 		Usage: "Reduce key-derivation RAM & CPU usage at some expense of KDF strength",
@@ -245,6 +266,14 @@ var (
 		Name:  "eth.requiredblocks",
 		Usage: "Comma separated block number-to-hash mappings to require for peering (<number>=<hash>)",
 	}
+	ReorgMaxDepthFlag = cli.Uint64Flag{
+		Name:  "reorg.maxdepth",
+		Usage: "Maximum number of canonical blocks a chain reorg may drop (0 = unlimited)",
+	}
+	ReorgCheckpointFlag = cli.StringFlag{
+		Name:  "reorg.checkpoint",
+		Usage: "JSON file or RPC endpoint of a trusted node feeding finality checkpoints ({\"number\":<number>,\"hash\":<hash>})",
+	}
 	LegacyWhitelistFlag = cli.StringFlag{
 		Name:  "whitelist",
 		Usage: "Comma separated block number-to-hash mappings to enforce (<number>=<hash>) (deprecated in favor of --peer.requiredblocks)",
@@ -357,6 +386,10 @@ var (
 		Usage: "Disk journal for local transaction to survive node restarts",
 		Value: core.DefaultTxPoolConfig.Journal,
 	}
+	TxPoolSnapshotFlag = cli.StringFlag{
+		Name:  "txpool.snapshot",
+		Usage: "Disk snapshot of the entire (local and remote) transaction pool to survive node restarts (disabled if empty)",
+	}
 	TxPoolRejournalFlag = cli.DurationFlag{
 		Name:  "txpool.rejournal",
 		Usage: "Time interval to regenerate the local transaction journal",
@@ -463,6 +496,15 @@ var (
 		Name:  "miner.notify.full",
 		Usage: "Notify with pending block headers instead of work packages",
 	}
+	MinerStratumFlag = cli.StringFlag{
+		Name:  "miner.stratum",
+		Usage: "Listening address of the stratum mining server (e.g. 127.0.0.1:8008, disabled if empty)",
+	}
+	MinerStratumDifficultyFlag = cli.Float64Flag{
+		Name:  "miner.stratum.difficulty",
+		Usage: "Default share difficulty of stratum workers (1 = 2^32 hashes)",
+		Value: ethconfig.Defaults.Miner.StratumDifficulty,
+	}
 	MinerGasLimitFlag = cli.Uint64Flag{
 		Name:  "miner.gaslimit",
 		Usage: "Target gas ceiling for mined blocks",
@@ -491,6 +533,43 @@ var (
 		Name:  "miner.noverify",
 		Usage: "Disable remote sealing verification",
 	}
+	MinerOrderingFlag = cli.StringFlag{
+		Name:  "miner.ordering",
+		Usage: `Transaction ordering of mined blocks ("price", "fifo" or "roundrobin")`,
+		Value: miner.OrderingPrice,
+	}
+	// Block builder settings
+	BuilderEnabledFlag = cli.BoolFlag{
+		Name:  "builder",
+		Usage: "Enable building blocks for external proposers (requires the merge)",
+	}
+	BuilderSecretKeyFileFlag = cli.StringFlag{
+		Name:  "builder.secretkeyfile",
+		Usage: "Path to a file holding the hex encoded BLS secret key signing the builder bids",
+	}
+	BuilderRelayFlag = cli.StringFlag{
+		Name:  "builder.relay",
+		Usage: "URL of the relay the built blocks are submitted to",
+	}
+	BuilderProposersFlag = cli.StringFlag{
+		Name:  "builder.proposers",
+		Usage: "Comma separated list of <proposer pubkey>:<fee recipient> the blocks are built for",
+	}
+	BuilderGenesisForkVersionFlag = cli.StringFlag{
+		Name:  "builder.genesisforkversion",
+		Usage: "Genesis fork version of the beacon chain, part of the bid signature domain",
+		Value: builder.DefaultConfig.GenesisForkVersion.String(),
+	}
+	BuilderGenesisTimeFlag = cli.Uint64Flag{
+		Name:  "builder.genesistime",
+		Usage: "Genesis time of the beacon chain, used to compute the slots",
+		Value: builder.DefaultConfig.GenesisTime,
+	}
+	BuilderSecondsPerSlotFlag = cli.Uint64Flag{
+		Name:  "builder.secondsperslot",
+		Usage: "Slot duration of the beacon chain in seconds",
+		Value: builder.DefaultConfig.SecondsPerSlot,
+	}
 	// Account settings
 	UnlockedAccountFlag = cli.StringFlag{
 		Name:  "unlock",
@@ -530,6 +609,35 @@ var (
 		Usage: "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
 		Value: ethconfig.Defaults.RPCTxFeeCap,
 	}
+	RPCBatchLimitFlag = cli.IntFlag{
+		Name:  "rpc.batchlimit",
+		Usage: "Maximum number of requests in a batch accepted by the HTTP and WS RPC endpoints (0 = no limit)",
+		Value: node.DefaultBatchItemLimit,
+	}
+	RPCResponseSizeLimitFlag = cli.IntFlag{
+		Name:  "rpc.maxresponsesize",
+		Usage: "Maximum size in bytes of responses sent by the HTTP and WS RPC endpoints (0 = no limit)",
+		Value: node.DefaultResponseSizeLimit,
+	}
+	RPCBatchConcurrencyFlag = cli.IntFlag{
+		Name:  "rpc.batchconcurrency",
+		Usage: "Number of calls in a batch executed in parallel by the HTTP and WS RPC endpoints (1 = sequential)",
+		Value: node.DefaultBatchConcurrency,
+	}
+	RPCBatchTimeoutFlag = cli.DurationFlag{
+		Name:  "rpc.batchtimeout",
+		Usage: "Maximum time spent on a batch by the HTTP and WS RPC endpoints (0 = no limit)",
+		Value: node.DefaultBatchTimeout,
+	}
+	RPCLogsLimitFlag = cli.IntFlag{
+		Name:  "rpc.logslimit",
+		Usage: "Maximum number of logs returned by eth_getLogs and eth_getFilterLogs (0 = no limit)",
+		Value: ethconfig.Defaults.RPCLogsLimit,
+	}
+	RPCAPIKeysFlag = cli.StringFlag{
+		Name:  "rpc.apikeys",
+		Usage: "JSON file listing the API keys, modules and rate limits required to access the HTTP and WS RPC endpoints",
+	}
 	// Authenticated RPC HTTP settings
 	AuthListenFlag = cli.StringFlag{
 		Name:  "authrpc.addr",
@@ -550,6 +658,18 @@ var (
 		Name:  "authrpc.jwtsecret",
 		Usage: "Path to a JWT secret to use for authenticated RPC endpoints",
 	}
+	AuthTLSCertFlag = cli.StringFlag{
+		Name:  "authrpc.tlscert",
+		Usage: "Path to a PEM encoded TLS certificate for authenticated RPC endpoints",
+	}
+	AuthTLSKeyFlag = cli.StringFlag{
+		Name:  "authrpc.tlskey",
+		Usage: "Path to a PEM encoded TLS private key for authenticated RPC endpoints",
+	}
+	AuthTLSClientCAFlag = cli.StringFlag{
+		Name:  "authrpc.tlsclientca",
+		Usage: "Path to PEM encoded CA certificates; if set, clients of authenticated RPC endpoints must present a certificate signed by one of them",
+	}
 	// Logging and debug settings
 	EthStatsURLFlag = cli.StringFlag{
 		Name:  "ethstats",
@@ -606,6 +726,18 @@ var (
 		Usage: "HTTP path path prefix on which JSON-RPC is served. Use '/' to serve on all paths.",
 		Value: "",
 	}
+	HTTPTLSCertFlag = cli.StringFlag{
+		Name:  "http.tlscert",
+		Usage: "Path to a PEM encoded TLS certificate for the HTTP-RPC and WS-RPC servers",
+	}
+	HTTPTLSKeyFlag = cli.StringFlag{
+		Name:  "http.tlskey",
+		Usage: "Path to a PEM encoded TLS private key for the HTTP-RPC and WS-RPC servers",
+	}
+	HTTPTLSClientCAFlag = cli.StringFlag{
+		Name:  "http.tlsclientca",
+		Usage: "Path to PEM encoded CA certificates; if set, HTTP-RPC and WS-RPC clients must present a certificate signed by one of them",
+	}
 	GraphQLEnabledFlag = cli.BoolFlag{
 		Name:  "graphql",
 		Usage: "Enable GraphQL on the HTTP-RPC server. Note that GraphQL can only be started if an HTTP server is started as well.",
@@ -620,6 +752,16 @@ var (
 		Usage: "Comma separated list of virtual hostnames from which to accept requests (server enforced). Accepts '*' wildcard.",
 		Value: strings.Join(node.DefaultConfig.GraphQLVirtualHosts, ","),
 	}
+	GraphQLMaxDepthFlag = cli.IntFlag{
+		Name:  "graphql.maxdepth",
+		Usage: "Maximum nesting depth of GraphQL queries (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxDepth,
+	}
+	GraphQLMaxComplexityFlag = cli.IntFlag{
+		Name:  "graphql.maxcomplexity",
+		Usage: "Maximum execution cost of GraphQL queries, in resolved fields (0 = unlimited)",
+		Value: node.DefaultConfig.GraphQLMaxComplexity,
+	}
 	WSEnabledFlag = cli.BoolFlag{
 		Name:  "ws",
 		Usage: "Enable the WS-RPC server",
@@ -1000,6 +1142,16 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 		cfg.AuthVirtualHosts = SplitAndTrim(ctx.GlobalString(AuthVirtualHostsFlag.Name))
 	}
 
+	if ctx.GlobalIsSet(AuthTLSCertFlag.Name) {
+		cfg.AuthTLS.CertFile = ctx.GlobalString(AuthTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthTLSKeyFlag.Name) {
+		cfg.AuthTLS.KeyFile = ctx.GlobalString(AuthTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(AuthTLSClientCAFlag.Name) {
+		cfg.AuthTLS.ClientCAFile = ctx.GlobalString(AuthTLSClientCAFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(HTTPCORSDomainFlag.Name) {
 		cfg.HTTPCors = SplitAndTrim(ctx.GlobalString(HTTPCORSDomainFlag.Name))
 	}
@@ -1015,6 +1167,16 @@ func setHTTP(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(HTTPPathPrefixFlag.Name) {
 		cfg.HTTPPathPrefix = ctx.GlobalString(HTTPPathPrefixFlag.Name)
 	}
+
+	if ctx.GlobalIsSet(HTTPTLSCertFlag.Name) {
+		cfg.HTTPTLS.CertFile = ctx.GlobalString(HTTPTLSCertFlag.Name)
+	}
+	if ctx.GlobalIsSet(HTTPTLSKeyFlag.Name) {
+		cfg.HTTPTLS.KeyFile = ctx.GlobalString(HTTPTLSKeyFlag.Name)
+	}
+	if ctx.GlobalIsSet(HTTPTLSClientCAFlag.Name) {
+		cfg.HTTPTLS.ClientCAFile = ctx.GlobalString(HTTPTLSClientCAFlag.Name)
+	}
 	if ctx.GlobalIsSet(AllowUnprotectedTxs.Name) {
 		cfg.AllowUnprotectedTxs = ctx.GlobalBool(AllowUnprotectedTxs.Name)
 	}
@@ -1029,6 +1191,12 @@ func setGraphQL(ctx *cli.Context, cfg *node.Config) {
 	if ctx.GlobalIsSet(GraphQLVirtualHostsFlag.Name) {
 		cfg.GraphQLVirtualHosts = SplitAndTrim(ctx.GlobalString(GraphQLVirtualHostsFlag.Name))
 	}
+	if ctx.GlobalIsSet(GraphQLMaxDepthFlag.Name) {
+		cfg.GraphQLMaxDepth = ctx.GlobalInt(GraphQLMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(GraphQLMaxComplexityFlag.Name) {
+		cfg.GraphQLMaxComplexity = ctx.GlobalInt(GraphQLMaxComplexityFlag.Name)
+	}
 }
 
 // setWS creates the WebSocket RPC listener interface string from the set
@@ -1057,6 +1225,20 @@ func setWS(ctx *cli.Context, cfg *node.Config) {
 	}
 }
 
+// setAPIKeys loads the API keys of the HTTP and WebSocket RPC endpoints from the
+// given JSON file.
+func setAPIKeys(file string, cfg *node.Config) {
+	blob, err := ioutil.ReadFile(file)
+	if err != nil {
+		Fatalf("Failed to read API keys: %v", err)
+	}
+	var keys []node.APIKey
+	if err := json.Unmarshal(blob, &keys); err != nil {
+		Fatalf("Failed to parse API keys: %v", err)
+	}
+	cfg.APIKeys = keys
+}
+
 // setIPC creates an IPC path configuration from the set command line flags,
 // returning an empty string if IPC was explicitly disabled, or the set path.
 func setIPC(ctx *cli.Context, cfg *node.Config) {
@@ -1284,6 +1466,22 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.JWTSecret = ctx.GlobalString(JWTSecretFlag.Name)
 	}
 
+	if ctx.GlobalIsSet(RPCBatchLimitFlag.Name) {
+		cfg.BatchItemLimit = ctx.GlobalInt(RPCBatchLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCResponseSizeLimitFlag.Name) {
+		cfg.ResponseSizeLimit = ctx.GlobalInt(RPCResponseSizeLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchConcurrencyFlag.Name) {
+		cfg.BatchConcurrency = ctx.GlobalInt(RPCBatchConcurrencyFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCBatchTimeoutFlag.Name) {
+		cfg.BatchTimeout = ctx.GlobalDuration(RPCBatchTimeoutFlag.Name)
+	}
+	if ctx.GlobalIsSet(RPCAPIKeysFlag.Name) {
+		setAPIKeys(ctx.GlobalString(RPCAPIKeysFlag.Name), cfg)
+	}
+
 	if ctx.GlobalIsSet(ExternalSignerFlag.Name) {
 		cfg.ExternalSigner = ctx.GlobalString(ExternalSignerFlag.Name)
 	}
@@ -1394,6 +1592,9 @@ func setTxPool(ctx *cli.Context, cfg *core.TxPoolConfig) {
 	if ctx.GlobalIsSet(TxPoolJournalFlag.Name) {
 		cfg.Journal = ctx.GlobalString(TxPoolJournalFlag.Name)
 	}
+	if ctx.GlobalIsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.GlobalString(TxPoolSnapshotFlag.Name)
+	}
 	if ctx.GlobalIsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.GlobalDuration(TxPoolRejournalFlag.Name)
 	}
@@ -1452,6 +1653,15 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		cfg.Notify = strings.Split(ctx.GlobalString(MinerNotifyFlag.Name), ",")
 	}
 	cfg.NotifyFull = ctx.GlobalBool(MinerNotifyFullFlag.Name)
+	if ctx.GlobalIsSet(MinerStratumFlag.Name) {
+		cfg.Stratum = ctx.GlobalString(MinerStratumFlag.Name)
+	}
+	if ctx.GlobalIsSet(MinerStratumDifficultyFlag.Name) {
+		cfg.StratumDifficulty = ctx.GlobalFloat64(MinerStratumDifficultyFlag.Name)
+		if cfg.StratumDifficulty <= 0 {
+			Fatalf("Invalid --%s: must be positive", MinerStratumDifficultyFlag.Name)
+		}
+	}
 	if ctx.GlobalIsSet(MinerExtraDataFlag.Name) {
 		cfg.ExtraData = []byte(ctx.GlobalString(MinerExtraDataFlag.Name))
 	}
@@ -1467,11 +1677,61 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.GlobalIsSet(MinerNoVerifyFlag.Name) {
 		cfg.Noverify = ctx.GlobalBool(MinerNoVerifyFlag.Name)
 	}
+	if ctx.GlobalIsSet(MinerOrderingFlag.Name) {
+		cfg.Ordering = ctx.GlobalString(MinerOrderingFlag.Name)
+		if _, err := miner.NewTransactionOrdering(cfg.Ordering); err != nil {
+			Fatalf("Invalid --%s: %v", MinerOrderingFlag.Name, err)
+		}
+	}
 	if ctx.GlobalIsSet(LegacyMinerGasTargetFlag.Name) {
 		log.Warn("The generic --miner.gastarget flag is deprecated and will be removed in the future!")
 	}
 }
 
+func setBuilder(ctx *cli.Context, cfg *ethconfig.Config) {
+	if !ctx.GlobalBool(BuilderEnabledFlag.Name) {
+		return
+	}
+	config := builder.DefaultConfig
+	if cfg.Builder != nil {
+		config = *cfg.Builder
+	}
+	var err error
+	if ctx.GlobalIsSet(BuilderSecretKeyFileFlag.Name) {
+		config.SecretKeyFile = ctx.GlobalString(BuilderSecretKeyFileFlag.Name)
+	}
+	if ctx.GlobalIsSet(BuilderRelayFlag.Name) {
+		config.RelayURL = ctx.GlobalString(BuilderRelayFlag.Name)
+	}
+	if ctx.GlobalIsSet(BuilderProposersFlag.Name) {
+		config.Proposers = nil
+		for _, entry := range SplitAndTrim(ctx.GlobalString(BuilderProposersFlag.Name)) {
+			parts := strings.Split(entry, ":")
+			if len(parts) != 2 || !common.IsHexAddress(parts[1]) {
+				Fatalf("Invalid builder proposer %q, want <proposer pubkey>:<fee recipient>", entry)
+			}
+			var proposer builder.Proposer
+			if err := proposer.Pubkey.UnmarshalText([]byte(parts[0])); err != nil {
+				Fatalf("Invalid builder proposer pubkey %q: %v", parts[0], err)
+			}
+			proposer.FeeRecipient = common.HexToAddress(parts[1])
+			config.Proposers = append(config.Proposers, proposer)
+		}
+	}
+	if ctx.GlobalIsSet(BuilderGenesisForkVersionFlag.Name) || config.GenesisForkVersion == nil {
+		if config.GenesisForkVersion, err = hexutil.Decode(ctx.GlobalString(BuilderGenesisForkVersionFlag.Name)); err != nil {
+			Fatalf("Invalid builder genesis fork version: %v", err)
+		}
+	}
+	if ctx.GlobalIsSet(BuilderGenesisTimeFlag.Name) {
+		config.GenesisTime = ctx.GlobalUint64(BuilderGenesisTimeFlag.Name)
+	}
+	if ctx.GlobalIsSet(BuilderSecondsPerSlotFlag.Name) {
+		config.SecondsPerSlot = ctx.GlobalUint64(BuilderSecondsPerSlotFlag.Name)
+	}
+	cfg.Builder = &config
+}
+
 func setPeerRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
 	peerRequiredBlocks := ctx.GlobalString(EthPeerRequiredBlocksFlag.Name)
 
@@ -1565,6 +1825,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setTxPool(ctx, &cfg.TxPool)
 	setEthash(ctx, cfg)
 	setMiner(ctx, &cfg.Miner)
+	setBuilder(ctx, cfg)
 	setPeerRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
 
@@ -1620,6 +1881,24 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(TxLookupLimitFlag.Name) {
 		cfg.TxLookupLimit = ctx.GlobalUint64(TxLookupLimitFlag.Name)
 	}
+	if ctx.GlobalIsSet(LogIndexFlag.Name) {
+		cfg.LogIndex = ctx.GlobalBool(LogIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressIndexFlag.Name) {
+		cfg.AddressIndex = ctx.GlobalBool(AddressIndexFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressIndexLimitFlag.Name) {
+		cfg.AddressIndexLimit = ctx.GlobalUint64(AddressIndexLimitFlag.Name)
+	}
+	if ctx.GlobalIsSet(AddressIndexInternalFlag.Name) {
+		cfg.AddressIndexInternal = ctx.GlobalBool(AddressIndexInternalFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReorgMaxDepthFlag.Name) {
+		cfg.MaxReorgDepth = ctx.GlobalUint64(ReorgMaxDepthFlag.Name)
+	}
+	if ctx.GlobalIsSet(ReorgCheckpointFlag.Name) {
+		cfg.FinalityCheckpoint = ctx.GlobalString(ReorgCheckpointFlag.Name)
+	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cfg.TrieCleanCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100
 	}
@@ -1666,6 +1945,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.GlobalIsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.GlobalFloat64(RPCGlobalTxFeeCapFlag.Name)
 	}
+	if ctx.GlobalIsSet(RPCLogsLimitFlag.Name) {
+		cfg.RPCLogsLimit = ctx.GlobalInt(RPCLogsLimitFlag.Name)
+	}
 	if ctx.GlobalIsSet(NoDiscoverFlag.Name) {
 		cfg.EthDiscoveryURLs, cfg.SnapDiscoveryURLs = []string{}, []string{}
 	} else if ctx.GlobalIsSet(DNSDiscoveryFlag.Name) {
@@ -1748,7 +2030,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 		log.Info("Using developer account", "address", developer.Address)
 
 		// Create a new developer genesis block or reuse existing one
-		cfg.Genesis = core.DeveloperGenesisBlock(uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name)), ctx.GlobalUint64(DeveloperGasLimitFlag.Name), developer.Address)
+		cfg.Genesis = core.DeveloperBeaconGenesisBlock(ctx.GlobalUint64(DeveloperGasLimitFlag.Name), developer.Address)
 		if ctx.GlobalIsSet(DataDirFlag.Name) {
 			// If datadir doesn't exist we need to open db in write-mode
 			// so leveldb can create files.
@@ -1759,7 +2041,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 			// Check if we have an already initialized chain and fall back to
 			// that if so. Otherwise we need to generate a new genesis spec.
 			chaindb := MakeChainDatabase(ctx, stack, readonly)
-			if rawdb.ReadCanonicalHash(chaindb, 0) != (common.Hash{}) {
+			if genesis := rawdb.ReadCanonicalHash(chaindb, 0); genesis != (common.Hash{}) {
+				// Developer chains used to be sealed by clique, those can't be
+				// driven by the simulated beacon.
+				if config := rawdb.ReadChainConfig(chaindb, genesis); config != nil && config.Clique != nil {
+					chaindb.Close()
+					Fatalf("Data directory %s contains a clique developer chain of an older version, remove it to create a proof-of-stake developer chain", stack.DataDir())
+				}
 				cfg.Genesis = nil // fallback to db content
 			}
 			chaindb.Close()
@@ -1818,14 +2106,55 @@ func RegisterEthService(stack *node.Node, cfg *ethconfig.Config) (ethapi.Backend
 		}
 	}
 	if backend.BlockChain().Config().TerminalTotalDifficulty != nil {
-		if err := ethcatalyst.Register(stack, backend); err != nil {
+		var listeners []ethcatalyst.ForkchoiceListener
+		if cfg.Builder != nil {
+			listeners = append(listeners, registerBuilder(stack, backend, cfg.Builder))
+		}
+		if err := ethcatalyst.Register(stack, backend, listeners...); err != nil {
 			Fatalf("Failed to register the catalyst service: %v", err)
 		}
+	} else if cfg.Builder != nil {
+		Fatalf("Block builder requires a chain with a terminal total difficulty")
 	}
 	stack.RegisterAPIs(tracers.APIs(backend.APIBackend))
 	return backend.APIBackend, backend
 }
 
+// registerBuilder adds a block builder submitting blocks to the configured relay
+// to the stack.
+func registerBuilder(stack *node.Node, backend *eth.Ethereum, cfg *builder.Config) *builder.Builder {
+	if cfg.RelayURL == "" {
+		Fatalf("Block builder requires a relay URL")
+	}
+	if len(cfg.Proposers) == 0 {
+		log.Warn("Block builder has no proposers configured")
+	}
+	b, err := builder.New(backend, builder.NewRemoteRelay(cfg.RelayURL), *cfg)
+	if err != nil {
+		Fatalf("Failed to create the block builder: %v", err)
+	}
+	stack.RegisterLifecycle(b)
+	return b
+}
+
+// RegisterSimulatedBeacon adds a simulated beacon producing the blocks of the
+// developer chain to the stack.
+func RegisterSimulatedBeacon(ctx *cli.Context, stack *node.Node, backend *eth.Ethereum) {
+	if backend == nil {
+		Fatalf("Developer mode requires a full node")
+	}
+	period := ctx.GlobalInt(DeveloperPeriodFlag.Name)
+	if period < 0 {
+		Fatalf("Invalid developer block period: %d", period)
+	}
+	simBeacon, err := ethcatalyst.NewSimulatedBeacon(uint64(period), backend)
+	if err != nil {
+		Fatalf("Failed to create the simulated beacon: %v", err)
+	}
+	ethcatalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
+	stack.RegisterLifecycle(simBeacon)
+}
+
 // RegisterEthStatsService configures the Ethereum Stats daemon and adds it to
 // the given node.
 func RegisterEthStatsService(stack *node.Node, backend ethapi.Backend, url string) {
@@ -1971,6 +2300,8 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 	var engine consensus.Engine
 	if config.Clique != nil {
 		engine = clique.New(config.Clique, chainDb)
+	} else if config.BFT != nil {
+		engine = bft.New(config.BFT, chainDb)
 	} else {
 		engine = ethash.NewFaker()
 		if !ctx.GlobalBool(FakePoWFlag.Name) {
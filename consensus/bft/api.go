@@ -0,0 +1,138 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// API is a user facing RPC API to allow controlling the validator voting and
+// inspecting the finality of the BFT consensus scheme.
+type API struct {
+	chain consensus.ChainHeaderReader
+	bft   *BFT
+}
+
+// header retrieves the header with the given number, or the current one if
+// none is requested.
+func (api *API) header(number *rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = api.chain.CurrentHeader()
+	} else {
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
+
+// GetSnapshot retrieves the validator snapshot at a given block.
+func (api *API) GetSnapshot(number *rpc.BlockNumber) (*Snapshot, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.bft.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// GetSnapshotAtHash retrieves the validator snapshot at a given block.
+func (api *API) GetSnapshotAtHash(hash common.Hash) (*Snapshot, error) {
+	header := api.chain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.bft.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
+}
+
+// GetValidators retrieves the list of validators committing the block after
+// the specified one.
+func (api *API) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.validators(), nil
+}
+
+// GetValidatorsAtHash retrieves the list of validators committing the block
+// after the specified one.
+func (api *API) GetValidatorsAtHash(hash common.Hash) ([]common.Address, error) {
+	snap, err := api.GetSnapshotAtHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return snap.validators(), nil
+}
+
+// GetCommitters verifies the committed seals of the specified block and returns
+// the validators which committed it.
+func (api *API) GetCommitters(number *rpc.BlockNumber) ([]common.Address, error) {
+	header, err := api.header(number)
+	if err != nil {
+		return nil, err
+	}
+	if header.Number.Uint64() == 0 {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.bft.snapshot(api.chain, header.Number.Uint64()-1, header.ParentHash, nil)
+	if err != nil {
+		return nil, err
+	}
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyCommittedSeals(snap, header, extra); err != nil {
+		return nil, err
+	}
+	return recoverCommitters(header, extra)
+}
+
+// Proposals returns the current proposals the node tries to uphold and vote on.
+func (api *API) Proposals() map[common.Address]bool {
+	api.bft.lock.RLock()
+	defer api.bft.lock.RUnlock()
+
+	proposals := make(map[common.Address]bool)
+	for address, auth := range api.bft.proposals {
+		proposals[address] = auth
+	}
+	return proposals
+}
+
+// Propose injects a new validator proposal that the node will attempt to push
+// through when proposing blocks.
+func (api *API) Propose(address common.Address, auth bool) {
+	api.bft.lock.Lock()
+	defer api.bft.lock.Unlock()
+
+	api.bft.proposals[address] = auth
+}
+
+// Discard drops a currently running proposal, stopping the node from casting
+// further votes (either for or against).
+func (api *API) Discard(address common.Address) {
+	api.bft.lock.Lock()
+	defer api.bft.lock.Unlock()
+
+	delete(api.bft.proposals, address)
+}
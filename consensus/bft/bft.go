@@ -0,0 +1,752 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package bft implements a byzantine fault tolerant proof-of-authority consensus
+// engine in the style of IBFT/QBFT.
+//
+// Blocks are agreed on by a set of validators in rounds of three phases: the
+// round proposer broadcasts the block (pre-prepare), the validators accepting
+// it broadcast a prepare and, after a quorum of 2F+1 prepares, a commit. A
+// block committed by a quorum of validators is final, it is never reorged. If
+// a round fails, the validators move to the next round with another proposer.
+//
+// The validator set of a block is contained in its extra-data, together with
+// the signature of the proposer and the commit signatures (committed seals)
+// proving its finality. Validators are added and removed by voting, similar
+// to clique.
+package bft
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/ethereum/go-ethereum/trie"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	checkpointInterval = 1024 // Number of blocks after which to save the vote snapshot to the database
+	inmemorySnapshots  = 128  // Number of recent vote snapshots to keep in memory
+	inmemorySignatures = 4096 // Number of recent block signatures to keep in memory
+)
+
+// BFT protocol constants.
+var (
+	defaultPeriod         = uint64(1)     // Default number of seconds between blocks
+	defaultEpoch          = uint64(30000) // Default number of blocks after which to reset the pending votes
+	defaultRequestTimeout = uint64(10000) // Default timeout of the first round in milliseconds
+
+	uncleHash = types.CalcUncleHash(nil) // Always Keccak256(RLP([])) as uncles are meaningless outside of PoW.
+
+	difficulty = big.NewInt(1) // Block difficulty, every block is final so there's no fork choice
+)
+
+// Various error messages to mark blocks invalid. These should be private to
+// prevent engine specific errors from being referenced in the remainder of the
+// codebase, inherently breaking if the engine is swapped out. Please put common
+// error types into the consensus package.
+var (
+	// errUnknownBlock is returned when the list of validators is requested for a
+	// block that is not part of the local blockchain.
+	errUnknownBlock = errors.New("unknown block")
+
+	// errInvalidNonce is returned if a block's nonce is non-zero.
+	errInvalidNonce = errors.New("non-zero nonce")
+
+	// errInvalidMixDigest is returned if a block's mix digest is non-zero.
+	errInvalidMixDigest = errors.New("non-zero mix digest")
+
+	// errInvalidUncleHash is returned if a block contains an non-empty uncle list.
+	errInvalidUncleHash = errors.New("non empty uncle hash")
+
+	// errInvalidDifficulty is returned if the difficulty of a block is not 1.
+	errInvalidDifficulty = errors.New("invalid difficulty")
+
+	// errInvalidTimestamp is returned if the timestamp of a block is lower than
+	// the previous block's timestamp + the minimum block period.
+	errInvalidTimestamp = errors.New("invalid timestamp")
+
+	// errInvalidVotingChain is returned if a validator set is attempted to be
+	// modified via out-of-range or non-contiguous headers.
+	errInvalidVotingChain = errors.New("invalid voting chain")
+
+	// errInvalidVote is returned if a block contains a vote which makes no sense
+	// for the validator set, e.g. adding an active validator.
+	errInvalidVote = errors.New("invalid validator vote")
+
+	// errMismatchingValidators is returned if a block contains a list of
+	// validators different than the one the local node calculated.
+	errMismatchingValidators = errors.New("mismatching validator list")
+
+	// errMissingValidators is returned if the genesis block contains no validators.
+	errMissingValidators = errors.New("genesis block contains no validators")
+
+	// errUnauthorizedProposer is returned if a header is signed by a non-validator.
+	errUnauthorizedProposer = errors.New("unauthorized proposer")
+
+	// errInvalidCommittedSeals is returned if a committed seal is not signed by
+	// a validator or multiple seals are signed by the same validator.
+	errInvalidCommittedSeals = errors.New("invalid committed seals")
+
+	// errInsufficientCommittedSeals is returned if a block is not committed by a
+	// quorum of the validators.
+	errInsufficientCommittedSeals = errors.New("insufficient committed seals")
+
+	// errNotStarted is returned if a block is sealed before the consensus
+	// rounds are started.
+	errNotStarted = errors.New("bft consensus not started")
+)
+
+// SignerFn hashes and signs the data to be signed by a backing account.
+type SignerFn func(signer accounts.Account, mimeType string, message []byte) ([]byte, error)
+
+// Chain is the blockchain the validators reach consensus on. Proposals are
+// fully executed on top of its head before being accepted, and blocks finalized
+// by other proposers are inserted into it.
+type Chain interface {
+	consensus.ChainReader
+
+	// CurrentBlock retrieves the current head block of the canonical chain.
+	CurrentBlock() *types.Block
+
+	// InsertChain attempts to insert the given batch of blocks into the chain.
+	InsertChain(chain types.Blocks) (int, error)
+
+	// StateAt returns a new mutable state based on a particular point in time.
+	StateAt(root common.Hash) (*state.StateDB, error)
+
+	// Processor returns the current processor.
+	Processor() core.Processor
+
+	// Validator returns the current validator.
+	Validator() core.Validator
+
+	// SubscribeChainHeadEvent registers a subscription of ChainHeadEvent.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// ecrecover extracts the address of the proposer from a signed header.
+func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
+	// If the signature's already cached, return that
+	hash := header.Hash()
+	if address, known := sigcache.Get(hash); known {
+		return address.(common.Address), nil
+	}
+	// Retrieve the signature from the header extra-data
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return common.Address{}, err
+	}
+	proposer, err := recoverAddress(sealRLP(header), extra.Seal)
+	if err != nil {
+		return common.Address{}, err
+	}
+	sigcache.Add(hash, proposer)
+	return proposer, nil
+}
+
+// BFT is the byzantine fault tolerant proof-of-authority consensus engine.
+type BFT struct {
+	config *params.BFTConfig // Consensus engine configuration parameters
+	db     ethdb.Database    // Database to store and retrieve snapshot checkpoints
+
+	recents    *lru.ARCCache // Snapshots for recent block to speed up reorgs
+	signatures *lru.ARCCache // Signatures of recent blocks to speed up mining
+
+	proposals map[common.Address]bool // Current list of proposals we are pushing
+
+	signer common.Address // Ethereum address of the signing key
+	signFn SignerFn       // Signer function to authorize hashes with
+	lock   sync.RWMutex   // Protects the signer fields and the proposals
+
+	handler   *handler   // Peers of the consensus message protocol
+	sequencer *sequencer // Consensus rounds, nil if not started
+	seqLock   sync.Mutex // Protects the sequencer
+}
+
+// New creates a BFT consensus engine. The initial validators are taken from the
+// genesis block.
+func New(config *params.BFTConfig, db ethdb.Database) *BFT {
+	// Set any missing consensus parameters to their defaults
+	conf := *config
+	if conf.Period == 0 {
+		conf.Period = defaultPeriod
+	}
+	if conf.Epoch == 0 {
+		conf.Epoch = defaultEpoch
+	}
+	if conf.RequestTimeout == 0 {
+		conf.RequestTimeout = defaultRequestTimeout
+	}
+	// Allocate the snapshot caches and create the engine
+	recents, _ := lru.NewARC(inmemorySnapshots)
+	signatures, _ := lru.NewARC(inmemorySignatures)
+
+	b := &BFT{
+		config:     &conf,
+		db:         db,
+		recents:    recents,
+		signatures: signatures,
+		proposals:  make(map[common.Address]bool),
+	}
+	b.handler = newHandler(b)
+	return b
+}
+
+// Author implements consensus.Engine, returning the address of the proposer
+// recovered from the signature in the header's extra-data section.
+func (b *BFT) Author(header *types.Header) (common.Address, error) {
+	return ecrecover(header, b.signatures)
+}
+
+// VerifyHeader checks whether a header conforms to the consensus rules. Headers
+// are always verified to be committed by a quorum of the validators.
+func (b *BFT) VerifyHeader(chain consensus.ChainHeaderReader, header *types.Header, seal bool) error {
+	return b.verifyHeader(chain, header, nil, true)
+}
+
+// VerifyHeaders is similar to VerifyHeader, but verifies a batch of headers. The
+// method returns a quit channel to abort the operations and a results channel to
+// retrieve the async verifications (the order is that of the input slice).
+func (b *BFT) VerifyHeaders(chain consensus.ChainHeaderReader, headers []*types.Header, seals []bool) (chan<- struct{}, <-chan error) {
+	abort := make(chan struct{})
+	results := make(chan error, len(headers))
+
+	go func() {
+		for i, header := range headers {
+			err := b.verifyHeader(chain, header, headers[:i], true)
+
+			select {
+			case <-abort:
+				return
+			case results <- err:
+			}
+		}
+	}()
+	return abort, results
+}
+
+// verifyHeader checks whether a header conforms to the consensus rules. The
+// caller may optionally pass in a batch of parents (ascending order) to avoid
+// looking those up from the database. Committed seals are only verified if
+// committed is set, proposals are verified without them.
+func (b *BFT) verifyHeader(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, committed bool) error {
+	if header.Number == nil {
+		return errUnknownBlock
+	}
+	number := header.Number.Uint64()
+
+	// Don't waste time checking blocks from the future
+	if header.Time > uint64(time.Now().Unix()) {
+		return consensus.ErrFutureBlock
+	}
+	// Ensure that the extra-data contains the BFT fields
+	if _, err := ExtractExtra(header); err != nil {
+		return err
+	}
+	// Votes are cast in the extra-data, the nonce and mix digest are unused
+	if header.Nonce != (types.BlockNonce{}) {
+		return errInvalidNonce
+	}
+	if header.MixDigest != (common.Hash{}) {
+		return errInvalidMixDigest
+	}
+	// Ensure that the block doesn't contain any uncles which are meaningless in PoA
+	if header.UncleHash != uncleHash {
+		return errInvalidUncleHash
+	}
+	// Ensure that the block's difficulty is meaningful
+	if number > 0 && (header.Difficulty == nil || header.Difficulty.Cmp(difficulty) != 0) {
+		return errInvalidDifficulty
+	}
+	// Verify that the gas limit is <= 2^63-1
+	if header.GasLimit > params.MaxGasLimit {
+		return fmt.Errorf("invalid gasLimit: have %v, max %v", header.GasLimit, params.MaxGasLimit)
+	}
+	// If all checks passed, validate any special fields for hard forks
+	if err := misc.VerifyForkHashes(chain.Config(), header, false); err != nil {
+		return err
+	}
+	// All basic checks passed, verify cascading fields
+	return b.verifyCascadingFields(chain, header, parents, committed)
+}
+
+// verifyCascadingFields verifies all the header fields that are not standalone,
+// rather depend on a batch of previous headers. The caller may optionally pass
+// in a batch of parents (ascending order) to avoid looking those up from the
+// database. This is useful for concurrently verifying a batch of new headers.
+func (b *BFT) verifyCascadingFields(chain consensus.ChainHeaderReader, header *types.Header, parents []*types.Header, committed bool) error {
+	// The genesis block is the always valid dead-end
+	number := header.Number.Uint64()
+	if number == 0 {
+		return nil
+	}
+	// Ensure that the block's timestamp isn't too close to its parent
+	var parent *types.Header
+	if len(parents) > 0 {
+		parent = parents[len(parents)-1]
+	} else {
+		parent = chain.GetHeader(header.ParentHash, number-1)
+	}
+	if parent == nil || parent.Number.Uint64() != number-1 || parent.Hash() != header.ParentHash {
+		return consensus.ErrUnknownAncestor
+	}
+	if parent.Time+b.config.Period > header.Time {
+		return errInvalidTimestamp
+	}
+	// Verify that the gasUsed is <= gasLimit
+	if header.GasUsed > header.GasLimit {
+		return fmt.Errorf("invalid gasUsed: have %d, gasLimit %d", header.GasUsed, header.GasLimit)
+	}
+	if !chain.Config().IsLondon(header.Number) {
+		// Verify BaseFee not present before EIP-1559 fork.
+		if header.BaseFee != nil {
+			return fmt.Errorf("invalid baseFee before fork: have %d, want <nil>", header.BaseFee)
+		}
+		if err := misc.VerifyGaslimit(parent.GasLimit, header.GasLimit); err != nil {
+			return err
+		}
+	} else if err := misc.VerifyEip1559Header(chain.Config(), parent, header); err != nil {
+		// Verify the header's EIP-1559 attributes.
+		return err
+	}
+	// Retrieve the snapshot needed to verify this header and cache it
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, parents)
+	if err != nil {
+		return err
+	}
+	// Verify the validator list and the vote against the snapshot
+	extra, _ := ExtractExtra(header)
+	validators := snap.validators()
+	if len(extra.Validators) != len(validators) {
+		return errMismatchingValidators
+	}
+	for i, validator := range validators {
+		if extra.Validators[i] != validator {
+			return errMismatchingValidators
+		}
+	}
+	if extra.Vote != nil && !snap.validVote(extra.Vote.Address, extra.Vote.Authorize) {
+		return errInvalidVote
+	}
+	// All basic checks passed, verify the seals and return
+	if err := b.verifySeal(snap, header); err != nil {
+		return err
+	}
+	if committed {
+		return verifyCommittedSeals(snap, header, extra)
+	}
+	return nil
+}
+
+// snapshot retrieves the validator snapshot at a given point in time.
+func (b *BFT) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header) (*Snapshot, error) {
+	// Search for a snapshot in memory or on disk for checkpoints
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for snap == nil {
+		// If an in-memory snapshot was found, use that
+		if s, ok := b.recents.Get(hash); ok {
+			snap = s.(*Snapshot)
+			break
+		}
+		// If an on-disk checkpoint snapshot can be found, use that
+		if number%checkpointInterval == 0 {
+			if s, err := loadSnapshot(b.config, b.signatures, b.db, hash); err == nil {
+				log.Trace("Loaded validator snapshot from disk", "number", number, "hash", hash)
+				snap = s
+				break
+			}
+		}
+		// If we're at the genesis, snapshot the initial state. Alternatively if we're
+		// at an epoch block without a parent (light client CHT), or we have piled
+		// up more headers than allowed to be reorged (chain reinit from a freezer),
+		// consider the validators of the block trusted and snapshot it. Every block
+		// contains its validators, but votes are only reset at epoch blocks.
+		if number == 0 || (number%b.config.Epoch == 0 && (len(headers) > params.FullImmutabilityThreshold || chain.GetHeaderByNumber(number-1) == nil)) {
+			checkpoint := chain.GetHeaderByNumber(number)
+			if checkpoint != nil {
+				snap, err := b.checkpointSnapshot(checkpoint)
+				if err != nil {
+					return nil, err
+				}
+				if err := snap.store(b.db); err != nil {
+					return nil, err
+				}
+				log.Info("Stored checkpoint snapshot to disk", "number", number, "hash", snap.Hash)
+				return b.applySnapshot(snap, headers)
+			}
+		}
+		// No snapshot for this header, gather the header and move backward
+		var header *types.Header
+		if len(parents) > 0 {
+			// If we have explicit parents, pick from there (enforced)
+			header = parents[len(parents)-1]
+			if header.Hash() != hash || header.Number.Uint64() != number {
+				return nil, consensus.ErrUnknownAncestor
+			}
+			parents = parents[:len(parents)-1]
+		} else {
+			// No explicit parents (or no more left), reach out to the database
+			header = chain.GetHeader(hash, number)
+			if header == nil {
+				return nil, consensus.ErrUnknownAncestor
+			}
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+	return b.applySnapshot(snap, headers)
+}
+
+// checkpointSnapshot creates a snapshot from the validators contained in the
+// given trusted header. For the genesis these are the initial validators, for
+// other blocks the validators of the block have to be updated with its vote.
+func (b *BFT) checkpointSnapshot(checkpoint *types.Header) (*Snapshot, error) {
+	extra, err := ExtractExtra(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra.Validators) == 0 {
+		return nil, errMissingValidators
+	}
+	number := checkpoint.Number.Uint64()
+	if number == 0 {
+		return newSnapshot(b.config, b.signatures, number, checkpoint.Hash(), extra.Validators), nil
+	}
+	snap := newSnapshot(b.config, b.signatures, number-1, checkpoint.ParentHash, extra.Validators)
+	return snap.apply([]*types.Header{checkpoint})
+}
+
+// applySnapshot applies the gathered headers (descending order) on top of the
+// snapshot, caching and storing the result.
+func (b *BFT) applySnapshot(snap *Snapshot, headers []*types.Header) (*Snapshot, error) {
+	// Previous snapshot found, apply any pending headers on top of it
+	for i := 0; i < len(headers)/2; i++ {
+		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+	}
+	snap, err := snap.apply(headers)
+	if err != nil {
+		return nil, err
+	}
+	b.recents.Add(snap.Hash, snap)
+
+	// If we've generated a new checkpoint snapshot, save to disk
+	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
+		if err = snap.store(b.db); err != nil {
+			return nil, err
+		}
+		log.Trace("Stored validator snapshot to disk", "number", snap.Number, "hash", snap.Hash)
+	}
+	return snap, err
+}
+
+// VerifyUncles implements consensus.Engine, always returning an error for any
+// uncles as this consensus mechanism doesn't permit uncles.
+func (b *BFT) VerifyUncles(chain consensus.ChainReader, block *types.Block) error {
+	if len(block.Uncles()) > 0 {
+		return errors.New("uncles not allowed")
+	}
+	return nil
+}
+
+// verifySeal checks whether the block is signed by one of the validators.
+func (b *BFT) verifySeal(snap *Snapshot, header *types.Header) error {
+	// Verifying the genesis block is not supported
+	if header.Number.Uint64() == 0 {
+		return errUnknownBlock
+	}
+	proposer, err := ecrecover(header, b.signatures)
+	if err != nil {
+		return err
+	}
+	if !snap.isValidator(proposer) {
+		return errUnauthorizedProposer
+	}
+	return nil
+}
+
+// verifyCommittedSeals checks whether the block is committed by a quorum of the
+// validators of the snapshot.
+func verifyCommittedSeals(snap *Snapshot, header *types.Header, extra *Extra) error {
+	committers, err := recoverCommitters(header, extra)
+	if err != nil {
+		return err
+	}
+	for _, committer := range committers {
+		if !snap.isValidator(committer) {
+			return errInvalidCommittedSeals
+		}
+	}
+	if len(committers) < snap.quorum() {
+		return errInsufficientCommittedSeals
+	}
+	return nil
+}
+
+// recoverCommitters returns the validators which signed the committed seals of
+// the block, ensuring none signed multiple seals.
+func recoverCommitters(header *types.Header, extra *Extra) ([]common.Address, error) {
+	var (
+		data       = commitData(SealHash(header))
+		committers = make([]common.Address, 0, len(extra.CommittedSeals))
+		seen       = make(map[common.Address]struct{})
+	)
+	for _, seal := range extra.CommittedSeals {
+		committer, err := recoverAddress(data, seal)
+		if err != nil {
+			return nil, errInvalidCommittedSeals
+		}
+		if _, ok := seen[committer]; ok {
+			return nil, errInvalidCommittedSeals
+		}
+		seen[committer] = struct{}{}
+		committers = append(committers, committer)
+	}
+	return committers, nil
+}
+
+// Prepare implements consensus.Engine, preparing all the consensus fields of the
+// header for running the transactions on top.
+func (b *BFT) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	header.Nonce = types.BlockNonce{}
+
+	number := header.Number.Uint64()
+	// Assemble the validator snapshot to check which votes make sense
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	extra := &Extra{Validators: snap.validators()}
+
+	// If there's pending proposals, cast a vote on a random one
+	b.lock.RLock()
+	addresses := make([]common.Address, 0, len(b.proposals))
+	for address, authorize := range b.proposals {
+		if snap.validVote(address, authorize) {
+			addresses = append(addresses, address)
+		}
+	}
+	if len(addresses) > 0 {
+		address := addresses[rand.Intn(len(addresses))]
+		extra.Vote = &Vote{Address: address, Authorize: b.proposals[address]}
+	}
+	b.lock.RUnlock()
+
+	// Set the correct difficulty
+	header.Difficulty = new(big.Int).Set(difficulty)
+
+	// Ensure the extra data has all its components
+	if len(header.Extra) > ExtraVanity {
+		header.Extra = header.Extra[:ExtraVanity]
+	}
+	if err := writeExtra(header, extra); err != nil {
+		return err
+	}
+	// Mix digest is reserved for now, set to empty
+	header.MixDigest = common.Hash{}
+
+	// Ensure the timestamp has the correct delay
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	header.Time = parent.Time + b.config.Period
+	if header.Time < uint64(time.Now().Unix()) {
+		header.Time = uint64(time.Now().Unix())
+	}
+	return nil
+}
+
+// Finalize implements consensus.Engine, ensuring no uncles are set, nor block
+// rewards given.
+func (b *BFT) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header) {
+	// No block rewards in PoA, so the state remains as is and uncles are dropped
+	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
+	header.UncleHash = types.CalcUncleHash(nil)
+}
+
+// FinalizeAndAssemble implements consensus.Engine, ensuring no uncles are set,
+// nor block rewards given, and returns the final block.
+func (b *BFT) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs []*types.Transaction, uncles []*types.Header, receipts []*types.Receipt) (*types.Block, error) {
+	// Finalize block
+	b.Finalize(chain, header, state, txs, uncles)
+
+	// Assemble and return the final block for sealing
+	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil)), nil
+}
+
+// Authorize injects a private key into the consensus engine to propose and
+// commit blocks with.
+func (b *BFT) Authorize(signer common.Address, signFn SignerFn) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.signer = signer
+	b.signFn = signFn
+}
+
+// sign signs the data with the local signing credentials.
+func (b *BFT) sign(data []byte) (common.Address, []byte, error) {
+	b.lock.RLock()
+	signer, signFn := b.signer, b.signFn
+	b.lock.RUnlock()
+
+	if signFn == nil {
+		return common.Address{}, nil, errors.New("no signer authorized")
+	}
+	sig, err := signFn(accounts.Account{Address: signer}, accounts.MimetypeBFT, data)
+	return signer, sig, err
+}
+
+// Seal implements consensus.Engine, signing the block as the proposer and
+// handing it to the consensus rounds. The block is proposed to the validators
+// once it's this node's turn and returned on the results channel once committed
+// by a quorum of them.
+func (b *BFT) Seal(chain consensus.ChainHeaderReader, block *types.Block, results chan<- *types.Block, stop <-chan struct{}) error {
+	header := block.Header()
+
+	// Sealing the genesis block is not supported
+	number := header.Number.Uint64()
+	if number == 0 {
+		return errUnknownBlock
+	}
+	seq := b.currentSequencer()
+	if seq == nil {
+		return errNotStarted
+	}
+	// Bail out if we're unauthorized to propose a block
+	snap, err := b.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	b.lock.RLock()
+	signer := b.signer
+	b.lock.RUnlock()
+	if !snap.isValidator(signer) {
+		return errUnauthorizedProposer
+	}
+	// Sign the block and hand it over once its timestamp is reached
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		return err
+	}
+	_, extra.Seal, err = b.sign(sealRLP(header))
+	if err != nil {
+		return err
+	}
+	if err := writeExtra(header, extra); err != nil {
+		return err
+	}
+	delay := time.Until(time.Unix(int64(header.Time), 0))
+	log.Trace("Waiting for slot to propose", "delay", common.PrettyDuration(delay))
+
+	go func() {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+		seq.request(&sealTask{block: block.WithSeal(header), results: results, stop: stop})
+	}()
+	return nil
+}
+
+// CalcDifficulty is the difficulty adjustment algorithm. It returns the difficulty
+// that a new block should have, which is always 1 as blocks are final.
+func (b *BFT) CalcDifficulty(chain consensus.ChainHeaderReader, time uint64, parent *types.Header) *big.Int {
+	return new(big.Int).Set(difficulty)
+}
+
+// SealHash returns the hash of a block prior to it being sealed.
+func (b *BFT) SealHash(header *types.Header) common.Hash {
+	return SealHash(header)
+}
+
+// Start starts the consensus rounds on top of the given chain, proposing and
+// committing blocks with the authorized signer. Consensus messages are only
+// processed while started.
+func (b *BFT) Start(chain Chain) error {
+	b.seqLock.Lock()
+	defer b.seqLock.Unlock()
+
+	if b.sequencer != nil {
+		return nil
+	}
+	b.sequencer = newSequencer(b, chain)
+	return nil
+}
+
+// currentSequencer returns the running consensus rounds, or nil if not started.
+func (b *BFT) currentSequencer() *sequencer {
+	b.seqLock.Lock()
+	defer b.seqLock.Unlock()
+
+	return b.sequencer
+}
+
+// Stop stops the consensus rounds.
+func (b *BFT) Stop() error {
+	b.seqLock.Lock()
+	defer b.seqLock.Unlock()
+
+	if b.sequencer != nil {
+		b.sequencer.stop()
+		b.sequencer = nil
+	}
+	return nil
+}
+
+// Close implements consensus.Engine, stopping the consensus rounds.
+func (b *BFT) Close() error {
+	return b.Stop()
+}
+
+// Protocols returns the p2p sub-protocol exchanging the consensus messages and
+// finalized blocks between the validators.
+func (b *BFT) Protocols() []p2p.Protocol {
+	return []p2p.Protocol{b.handler.protocol()}
+}
+
+// APIs implements consensus.Engine, returning the user facing RPC API to allow
+// controlling the validator voting.
+func (b *BFT) APIs(chain consensus.ChainHeaderReader) []rpc.API {
+	return []rpc.API{{
+		Namespace: "bft",
+		Version:   "1.0",
+		Service:   &API{chain: chain, bft: b},
+		Public:    false,
+	}}
+}
@@ -0,0 +1,328 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testGenesis creates the genesis of a BFT chain with the given validators.
+func testGenesis(validators []common.Address) *core.Genesis {
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = nil
+	config.BFT = &params.BFTConfig{Period: 1, Epoch: 30000, RequestTimeout: 1000}
+
+	extra, err := GenesisExtra(validators)
+	if err != nil {
+		panic(err)
+	}
+	return &core.Genesis{
+		Config:     &config,
+		ExtraData:  extra,
+		GasLimit:   params.GenesisGasLimit,
+		BaseFee:    big.NewInt(params.InitialBaseFee),
+		Difficulty: big.NewInt(1),
+	}
+}
+
+// signerFn returns a signer function signing with the given key.
+func signerFn(key *ecdsa.PrivateKey) SignerFn {
+	return func(account accounts.Account, mimeType string, data []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(data), key)
+	}
+}
+
+// tester is a BFT chain with test accounts creating its blocks.
+type tester struct {
+	t        *testing.T
+	accounts []common.Address // Test accounts, the first ones are the initial validators
+	keys     map[common.Address]*ecdsa.PrivateKey
+	engine   *BFT
+	chain    *core.BlockChain
+}
+
+// newTester creates a chain with the given number of initial validators and
+// additional non-validator accounts.
+func newTester(t *testing.T, validators, outsiders int) *tester {
+	t.Helper()
+
+	tt := &tester{t: t, keys: make(map[common.Address]*ecdsa.PrivateKey)}
+	for i := 0; i < validators+outsiders; i++ {
+		key, _ := crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		tt.accounts = append(tt.accounts, addr)
+		tt.keys[addr] = key
+	}
+	// Sort the validators and outsiders separately for readable test cases
+	sort.Sort(validatorsAscending(tt.accounts[:validators]))
+	sort.Sort(validatorsAscending(tt.accounts[validators:]))
+
+	db := rawdb.NewMemoryDatabase()
+	genesis := testGenesis(tt.accounts[:validators])
+	genesis.MustCommit(db)
+
+	tt.engine = New(genesis.Config.BFT, db)
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, tt.engine, vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	tt.chain = chain
+	t.Cleanup(chain.Stop)
+	return tt
+}
+
+// validators returns the validators committing the next block.
+func (tt *tester) validators() []common.Address {
+	head := tt.chain.CurrentHeader()
+	snap, err := tt.engine.snapshot(tt.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		tt.t.Fatalf("failed to retrieve snapshot: %v", err)
+	}
+	return snap.validators()
+}
+
+// block creates a block on top of the head, proposed by the given account with
+// the given vote and committed by the given accounts.
+func (tt *tester) block(proposer common.Address, vote *Vote, committers []common.Address) *types.Block {
+	tt.t.Helper()
+
+	parent := tt.chain.CurrentHeader()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Coinbase:   proposer,
+		BaseFee:    misc.CalcBaseFee(tt.chain.Config(), parent),
+	}
+	tt.engine.lock.Lock()
+	tt.engine.proposals = make(map[common.Address]bool)
+	if vote != nil {
+		tt.engine.proposals[vote.Address] = vote.Authorize
+	}
+	tt.engine.lock.Unlock()
+
+	if err := tt.engine.Prepare(tt.chain, header); err != nil {
+		tt.t.Fatalf("failed to prepare header: %v", err)
+	}
+	header.Time = parent.Time + 1
+
+	statedb, err := tt.chain.StateAt(parent.Root)
+	if err != nil {
+		tt.t.Fatalf("failed to retrieve state: %v", err)
+	}
+	block, err := tt.engine.FinalizeAndAssemble(tt.chain, header, statedb, nil, nil, nil)
+	if err != nil {
+		tt.t.Fatalf("failed to assemble block: %v", err)
+	}
+	header = block.Header()
+	tt.seal(header, proposer, committers)
+	return block.WithSeal(header)
+}
+
+// seal signs the header by the proposer and adds the committed seals of the
+// committers.
+func (tt *tester) seal(header *types.Header, proposer common.Address, committers []common.Address) {
+	tt.t.Helper()
+
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		tt.t.Fatalf("failed to decode extra-data: %v", err)
+	}
+	extra.Seal, _ = crypto.Sign(crypto.Keccak256(sealRLP(header)), tt.keys[proposer])
+	extra.CommittedSeals = nil
+	for _, committer := range committers {
+		seal, _ := crypto.Sign(crypto.Keccak256(commitData(SealHash(header))), tt.keys[committer])
+		extra.CommittedSeals = append(extra.CommittedSeals, seal)
+	}
+	if err := writeExtra(header, extra); err != nil {
+		tt.t.Fatalf("failed to encode extra-data: %v", err)
+	}
+}
+
+// commit creates a block committed by a quorum and inserts it into the chain.
+func (tt *tester) commit(proposer common.Address, vote *Vote) {
+	tt.t.Helper()
+
+	validators := tt.validators()
+	committers := validators[:(2*len(validators)+2)/3]
+	if _, err := tt.chain.InsertChain(types.Blocks{tt.block(proposer, vote, committers)}); err != nil {
+		tt.t.Fatalf("failed to insert block: %v", err)
+	}
+}
+
+func TestExtraEncoding(t *testing.T) {
+	validators := []common.Address{{0x02}, {0x01}, {0x03}}
+	data, err := GenesisExtra(validators)
+	if err != nil {
+		t.Fatal(err)
+	}
+	extra, err := ExtractExtra(&types.Header{Extra: data})
+	if err != nil {
+		t.Fatalf("failed to decode extra-data: %v", err)
+	}
+	want := []common.Address{{0x01}, {0x02}, {0x03}}
+	for i := range want {
+		if extra.Validators[i] != want[i] {
+			t.Fatalf("validator %d mismatch: have %x, want %x", i, extra.Validators[i], want[i])
+		}
+	}
+	if extra.Vote != nil || len(extra.Seal) != 0 || len(extra.CommittedSeals) != 0 {
+		t.Fatalf("unexpected fields in genesis extra-data: %+v", extra)
+	}
+	if _, err := ExtractExtra(&types.Header{Extra: make([]byte, ExtraVanity-1)}); err != errMissingVanity {
+		t.Fatalf("short extra-data: have %v, want %v", err, errMissingVanity)
+	}
+	if _, err := ExtractExtra(&types.Header{Extra: make([]byte, ExtraVanity+1)}); err != errInvalidExtra {
+		t.Fatalf("garbage extra-data: have %v, want %v", err, errInvalidExtra)
+	}
+}
+
+func TestSealHashExcludesSeals(t *testing.T) {
+	tt := newTester(t, 4, 0)
+	v := tt.accounts
+
+	block := tt.block(v[0], nil, v[:3])
+	other := block.Header()
+	tt.seal(other, v[1], v[1:])
+
+	if block.Hash() == other.Hash() {
+		t.Fatal("differently sealed blocks have the same hash")
+	}
+	if SealHash(block.Header()) != SealHash(other) {
+		t.Fatal("seal hash depends on the seals")
+	}
+	if author, err := tt.engine.Author(block.Header()); err != nil || author != v[0] {
+		t.Fatalf("wrong author: have %x (%v), want %x", author, err, v[0])
+	}
+}
+
+func TestVerifyCommittedSeals(t *testing.T) {
+	tests := []struct {
+		name       string
+		proposer   int   // Index of the proposing account
+		committers []int // Indexes of the committing accounts
+		err        error
+	}{
+		{name: "quorum", proposer: 0, committers: []int{0, 1, 2}},
+		{name: "all validators", proposer: 3, committers: []int{0, 1, 2, 3}},
+		{name: "non-proposer quorum", proposer: 0, committers: []int{1, 2, 3}},
+		{name: "below quorum", proposer: 0, committers: []int{0, 1}, err: errInsufficientCommittedSeals},
+		{name: "no seals", proposer: 0, committers: nil, err: errInsufficientCommittedSeals},
+		{name: "non-validator seal", proposer: 0, committers: []int{0, 1, 4}, err: errInvalidCommittedSeals},
+		{name: "duplicate seal", proposer: 0, committers: []int{0, 1, 1}, err: errInvalidCommittedSeals},
+		{name: "non-validator proposer", proposer: 4, committers: []int{0, 1, 2}, err: errUnauthorizedProposer},
+	}
+	for _, test := range tests {
+		tt := newTester(t, 4, 1)
+
+		committers := make([]common.Address, len(test.committers))
+		for i, c := range test.committers {
+			committers[i] = tt.accounts[c]
+		}
+		block := tt.block(tt.accounts[test.proposer], nil, committers)
+		if _, err := tt.chain.InsertChain(types.Blocks{block}); !errors.Is(err, test.err) {
+			t.Errorf("test %q: error mismatch: have %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestVerifyValidators(t *testing.T) {
+	tt := newTester(t, 4, 1)
+	v := tt.accounts
+
+	// A block listing a different validator set is rejected
+	block := tt.block(v[0], nil, v[:3])
+	header := block.Header()
+	extra, _ := ExtractExtra(header)
+	extra.Validators = v[:3]
+	writeExtra(header, extra)
+	tt.seal(header, v[0], v[:3])
+
+	if _, err := tt.chain.InsertChain(types.Blocks{block.WithSeal(header)}); !errors.Is(err, errMismatchingValidators) {
+		t.Fatalf("mismatching validators: have %v, want %v", err, errMismatchingValidators)
+	}
+	// A vote adding an active validator is rejected
+	block = tt.block(v[0], nil, v[:3])
+	header = block.Header()
+	extra, _ = ExtractExtra(header)
+	extra.Vote = &Vote{Address: v[1], Authorize: true}
+	writeExtra(header, extra)
+	tt.seal(header, v[0], v[:3])
+
+	if _, err := tt.chain.InsertChain(types.Blocks{block.WithSeal(header)}); !errors.Is(err, errInvalidVote) {
+		t.Fatalf("invalid vote: have %v, want %v", err, errInvalidVote)
+	}
+}
+
+func TestCommittersAPI(t *testing.T) {
+	tt := newTester(t, 4, 0)
+	v := tt.accounts
+
+	tt.commit(v[1], nil)
+	api := &API{chain: tt.chain, bft: tt.engine}
+
+	committers, err := api.GetCommitters(nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve committers: %v", err)
+	}
+	if len(committers) != 3 {
+		t.Fatalf("wrong number of committers: have %d, want 3", len(committers))
+	}
+	for i, committer := range committers {
+		if committer != v[i] {
+			t.Errorf("committer %d mismatch: have %x, want %x", i, committer, v[i])
+		}
+	}
+	validators, err := api.GetValidators(nil)
+	if err != nil || len(validators) != 4 {
+		t.Fatalf("wrong validators: %v (%v)", validators, err)
+	}
+}
+
+// Tests that only messages of validators are kept for future sequences.
+func TestBacklogValidators(t *testing.T) {
+	validators := []common.Address{{1}, {2}}
+	s := &sequencer{
+		bft:      &BFT{signer: validators[0]},
+		snap:     newSnapshot(new(params.BFTConfig), nil, 0, common.Hash{}, validators),
+		sequence: 1,
+	}
+	for i := 0; i < maxBacklog; i++ {
+		s.handleMessage(&message{Code: msgPrepare, Sequence: 2, author: common.Address{3}})
+	}
+	if len(s.backlog) != 0 {
+		t.Fatalf("kept %d messages of non-validator", len(s.backlog))
+	}
+	s.handleMessage(&message{Code: msgPrepare, Sequence: 2, author: validators[1]})
+	if len(s.backlog) != 1 {
+		t.Fatalf("kept %d messages of validator, want 1", len(s.backlog))
+	}
+}
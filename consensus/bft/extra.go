@@ -0,0 +1,140 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ExtraVanity is the fixed number of extra-data prefix bytes reserved for the
+// proposer vanity. The BFT specific fields follow the vanity, RLP encoded.
+const ExtraVanity = 32
+
+var (
+	// errMissingVanity is returned if a block's extra-data section is shorter than
+	// 32 bytes, which is required to store the proposer vanity.
+	errMissingVanity = errors.New("extra-data 32 byte vanity prefix missing")
+
+	// errInvalidExtra is returned if the BFT fields of a block's extra-data
+	// section can't be decoded.
+	errInvalidExtra = errors.New("invalid bft extra-data")
+)
+
+// Vote is a proposal to add a validator to or remove a validator from the
+// validator set, cast by the proposer of a block.
+type Vote struct {
+	Address   common.Address `json:"address"`   // Account voted on
+	Authorize bool           `json:"authorize"` // Whether to add or remove the account
+}
+
+// Extra is the BFT specific part of the header extra-data.
+type Extra struct {
+	Validators     []common.Address // Validators which have to commit the block
+	Vote           *Vote            `rlp:"nil"` // Optional vote on a validator change
+	Seal           []byte           // Signature of the proposer over the seal hash
+	CommittedSeals [][]byte         // Commit signatures of the validators which finalized the block
+}
+
+// ExtractExtra decodes the BFT specific fields from the header extra-data.
+func ExtractExtra(header *types.Header) (*Extra, error) {
+	if len(header.Extra) < ExtraVanity {
+		return nil, errMissingVanity
+	}
+	extra := new(Extra)
+	if err := rlp.DecodeBytes(header.Extra[ExtraVanity:], extra); err != nil {
+		return nil, errInvalidExtra
+	}
+	return extra, nil
+}
+
+// EncodeExtra creates the header extra-data from the vanity and the BFT specific
+// fields. The vanity is truncated or zero padded to 32 bytes.
+func EncodeExtra(vanity []byte, extra *Extra) ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, ExtraVanity, ExtraVanity+len(enc))
+	copy(data, vanity)
+	return append(data, enc...), nil
+}
+
+// GenesisExtra creates the extra-data of a genesis block with the given initial
+// validators.
+func GenesisExtra(validators []common.Address) ([]byte, error) {
+	validators = append([]common.Address(nil), validators...)
+	sort.Sort(validatorsAscending(validators))
+	return EncodeExtra(nil, &Extra{Validators: validators})
+}
+
+// writeExtra replaces the BFT specific fields in the header extra-data.
+func writeExtra(header *types.Header, extra *Extra) error {
+	data, err := EncodeExtra(header.Extra, extra)
+	if err != nil {
+		return err
+	}
+	header.Extra = data
+	return nil
+}
+
+// sealHeader returns a copy of the header without the proposer and committed
+// seals, which is the header signed by the proposer.
+func sealHeader(header *types.Header) *types.Header {
+	cpy := types.CopyHeader(header)
+	if extra, err := ExtractExtra(header); err == nil {
+		extra.Seal, extra.CommittedSeals = nil, nil
+		writeExtra(cpy, extra)
+	}
+	return cpy
+}
+
+// SealHash returns the hash of a block prior to it being sealed, i.e. the hash
+// of the header without the proposer and committed seals.
+func SealHash(header *types.Header) common.Hash {
+	return sealHeader(header).Hash()
+}
+
+// sealRLP returns the RLP encoding of the header signed by the proposer, whose
+// hash is the seal hash.
+func sealRLP(header *types.Header) []byte {
+	enc, err := rlp.EncodeToBytes(sealHeader(header))
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return enc
+}
+
+// commitData returns the data signed by validators committing to the proposal
+// with the given digest.
+func commitData(digest common.Hash) []byte {
+	return append(digest.Bytes(), byte(msgCommit))
+}
+
+// recoverAddress returns the signer of the data.
+func recoverAddress(data []byte, sig []byte) (common.Address, error) {
+	pubkey, err := crypto.SigToPub(crypto.Keccak256(data), sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pubkey), nil
+}
@@ -0,0 +1,214 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// Constants of the consensus message sub-protocol.
+const (
+	protocolName    = "bft" // Name of the sub-protocol
+	protocolVersion = 1     // Version of the sub-protocol
+	protocolLength  = 2     // Number of message codes used by the sub-protocol
+
+	consensusMsg = 0x00 // Signed consensus message of a validator
+	blockMsg     = 0x01 // Block finalized by a quorum of the validators
+
+	maxMessageSize    = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+	maxKnownMessages  = 1024             // Maximum message hashes to keep in the known list per peer
+	maxSeenMessages   = 4096             // Maximum message hashes to keep in the seen list
+	maxQueuedMessages = 256              // Maximum number of messages queued for sending to a peer
+)
+
+var errMsgTooLarge = errors.New("message too long")
+
+// peer is a remote node speaking the consensus message protocol.
+type peer struct {
+	*p2p.Peer
+	rw p2p.MsgReadWriter
+
+	known *lru.Cache        // Hashes of the messages known to the peer
+	queue chan *outboundMsg // Messages waiting to be sent to the peer
+	term  chan struct{}     // Termination channel to stop the broadcaster
+}
+
+// outboundMsg is an encoded message to send to a peer.
+type outboundMsg struct {
+	code uint64
+	data []byte
+}
+
+func newPeer(p *p2p.Peer, rw p2p.MsgReadWriter) *peer {
+	known, _ := lru.New(maxKnownMessages)
+	return &peer{
+		Peer:  p,
+		rw:    rw,
+		known: known,
+		queue: make(chan *outboundMsg, maxQueuedMessages),
+		term:  make(chan struct{}),
+	}
+}
+
+// send queues a message for sending to the peer, unless the peer already knows
+// it. The message is dropped if the peer can't keep up.
+func (p *peer) send(code uint64, hash common.Hash, data []byte) {
+	if p.known.Contains(hash) {
+		return
+	}
+	p.known.Add(hash, struct{}{})
+
+	select {
+	case p.queue <- &outboundMsg{code: code, data: data}:
+	default:
+		log.Debug("Dropping consensus message to peer", "peer", p.ID(), "code", code)
+	}
+}
+
+// broadcastLoop sends the queued messages to the peer.
+func (p *peer) broadcastLoop() {
+	for {
+		select {
+		case msg := <-p.queue:
+			if err := p2p.Send(p.rw, msg.code, rlp.RawValue(msg.data)); err != nil {
+				return
+			}
+		case <-p.term:
+			return
+		}
+	}
+}
+
+// handler gossips the consensus messages and finalized blocks between the peers.
+type handler struct {
+	bft *BFT
+
+	peers map[enode.ID]*peer
+	seen  *lru.Cache // Hashes of the messages already handled
+	lock  sync.RWMutex
+}
+
+func newHandler(bft *BFT) *handler {
+	seen, _ := lru.New(maxSeenMessages)
+	return &handler{
+		bft:   bft,
+		peers: make(map[enode.ID]*peer),
+		seen:  seen,
+	}
+}
+
+// protocol returns the p2p sub-protocol of the handler.
+func (h *handler) protocol() p2p.Protocol {
+	return p2p.Protocol{
+		Name:    protocolName,
+		Version: protocolVersion,
+		Length:  protocolLength,
+		Run:     h.runPeer,
+	}
+}
+
+// runPeer registers the peer and handles its messages until it disconnects.
+func (h *handler) runPeer(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+	peer := newPeer(p, rw)
+
+	h.lock.Lock()
+	h.peers[p.ID()] = peer
+	h.lock.Unlock()
+
+	defer func() {
+		h.lock.Lock()
+		delete(h.peers, p.ID())
+		h.lock.Unlock()
+		close(peer.term)
+	}()
+	go peer.broadcastLoop()
+
+	for {
+		if err := h.handleMsg(peer); err != nil {
+			p.Log().Debug("BFT message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg reads a message from the peer and hands it over to the consensus
+// rounds, unless it was already seen.
+func (h *handler) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer msg.Discard()
+
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	data, err := ioutil.ReadAll(msg.Payload)
+	if err != nil {
+		return err
+	}
+	hash := crypto.Keccak256Hash(data)
+	p.known.Add(hash, struct{}{})
+	if ok, _ := h.seen.ContainsOrAdd(hash, struct{}{}); ok {
+		return nil
+	}
+	switch msg.Code {
+	case consensusMsg:
+		m, err := decodeMessage(data)
+		if err != nil {
+			return fmt.Errorf("invalid consensus message: %v", err)
+		}
+		if seq := h.bft.currentSequencer(); seq != nil {
+			seq.deliverMessage(m)
+		}
+	case blockMsg:
+		block := new(types.Block)
+		if err := rlp.DecodeBytes(data, block); err != nil {
+			return fmt.Errorf("invalid block: %v", err)
+		}
+		if seq := h.bft.currentSequencer(); seq != nil {
+			seq.deliverBlock(block)
+		}
+	default:
+		return fmt.Errorf("invalid message code %d", msg.Code)
+	}
+	return nil
+}
+
+// broadcast sends a message to all peers which don't know it yet.
+func (h *handler) broadcast(code uint64, hash common.Hash, data []byte) {
+	h.seen.Add(hash, struct{}{})
+
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	for _, p := range h.peers {
+		p.send(code, hash, data)
+	}
+}
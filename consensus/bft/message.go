@@ -0,0 +1,87 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Codes of the consensus messages exchanged by the validators.
+const (
+	msgPreprepare  uint64 = iota // Proposal of a block by the round proposer
+	msgPrepare                   // Acceptance of the proposal
+	msgCommit                    // Commitment to the proposal after a quorum accepted it
+	msgRoundChange               // Request to move to a new round
+)
+
+var errInvalidMessage = errors.New("invalid consensus message")
+
+// message is a signed consensus message of a validator.
+type message struct {
+	Code          uint64
+	Sequence      uint64   // Number of the block the message is about
+	Round         uint64   // Consensus round within the sequence
+	Payload       []byte   // Proposed block for pre-prepares, prepared block for round changes, digest of the proposal for prepares and commits
+	CommittedSeal []byte   // Commit signature over the digest, only for commits
+	PreparedRound uint64   // Round the block of a pre-prepare or round change was prepared in
+	Prepares      [][]byte // Encoded prepares or commits of a quorum for the block in PreparedRound
+	Signature     []byte   // Signature of the validator sending the message
+
+	author common.Address // Validator which signed the message
+	hash   common.Hash    // Hash of the encoded message, used for deduplication
+	data   []byte         // Encoded message, relayed as is
+}
+
+// signData returns the data signed by the author of the message.
+func (m *message) signData() []byte {
+	data, err := rlp.EncodeToBytes([]interface{}{m.Code, m.Sequence, m.Round, m.Payload, m.CommittedSeal, m.PreparedRound, m.Prepares})
+	if err != nil {
+		panic("can't encode: " + err.Error())
+	}
+	return data
+}
+
+// digest returns the proposal digest of a prepare or commit message.
+func (m *message) digest() common.Hash {
+	return common.BytesToHash(m.Payload)
+}
+
+// decodeMessage decodes a consensus message and recovers its author.
+func decodeMessage(data []byte) (*message, error) {
+	msg := new(message)
+	if err := rlp.DecodeBytes(data, msg); err != nil {
+		return nil, err
+	}
+	if msg.Code > msgRoundChange {
+		return nil, errInvalidMessage
+	}
+	if (msg.Code == msgPrepare || msg.Code == msgCommit) && (len(msg.Payload) != common.HashLength || len(msg.Prepares) > 0) {
+		return nil, errInvalidMessage
+	}
+	author, err := recoverAddress(msg.signData(), msg.Signature)
+	if err != nil {
+		return nil, err
+	}
+	msg.author = author
+	msg.hash = crypto.Keccak256Hash(data)
+	msg.data = data
+	return msg, nil
+}
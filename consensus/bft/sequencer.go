@@ -0,0 +1,747 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	msgChanSize       = 1024 // Number of consensus messages waiting to be processed
+	chainHeadChanSize = 10   // Size of channel listening to ChainHeadEvent
+
+	maxFutureSequences = 10   // Number of blocks ahead of the head messages are kept for
+	maxBacklog         = 1024 // Number of messages of future rounds and blocks kept
+	maxTimeoutShift    = 10   // Maximum exponent of the round timeout backoff
+)
+
+// sealTask is a block handed to the engine for sealing by the miner.
+type sealTask struct {
+	block   *types.Block
+	results chan<- *types.Block
+	stop    <-chan struct{}
+}
+
+// stopped returns whether the miner aborted sealing the block.
+func (t *sealTask) stopped() bool {
+	select {
+	case <-t.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// roundState is the phase of a consensus round.
+type roundState uint8
+
+const (
+	stateAcceptRequest roundState = iota // Waiting for the proposal of the round
+	statePreprepared                     // Proposal accepted, waiting for a quorum of prepares
+	statePrepared                        // Proposal locked, waiting for a quorum of commits
+	stateCommitted                       // Proposal committed, waiting for the finalized block
+)
+
+func (s roundState) String() string {
+	switch s {
+	case stateAcceptRequest:
+		return "AcceptRequest"
+	case statePreprepared:
+		return "Preprepared"
+	case statePrepared:
+		return "Prepared"
+	case stateCommitted:
+		return "Committed"
+	default:
+		return "Unknown"
+	}
+}
+
+// sequencer runs the consensus rounds of the validators, one sequence of rounds
+// for every block on top of the chain head.
+//
+// In each round the round proposer sends its block (or the block the validators
+// locked on in an earlier round) in a pre-prepare message. Validators accepting
+// the proposal send a prepare. Once a quorum prepared the proposal, validators
+// lock on it and send a commit containing their committed seal. Once a quorum
+// committed, the round proposer assembles the block with the committed seals,
+// which makes it final, and distributes it. Validators which don't see the
+// proposal committed before the round timeout request a new round, which is
+// entered once a quorum requested it.
+//
+// Round changes and re-proposals of locked blocks carry the prepared certificate
+// of the block, the prepares of the quorum which prepared it. Validators seeing
+// a certificate of a later round than their lock switch to its block, so a new
+// proposer re-proposes the most recently prepared block and locked validators
+// accept it, even if the proposer itself wasn't locked.
+type sequencer struct {
+	bft   *BFT
+	chain Chain
+
+	taskCh  chan *sealTask
+	msgCh   chan *message
+	blockCh chan *types.Block
+	quit    chan struct{}
+	wg      sync.WaitGroup
+
+	// The fields below are only accessed by the loop goroutine.
+	head     *types.Header // Chain head the current sequence builds on
+	snap     *Snapshot     // Validators of the current sequence
+	sequence uint64        // Number of the block agreed on
+	round    uint64        // Current round of the sequence
+	state    roundState    // Phase of the current round
+
+	proposed       bool         // Whether the pre-prepare of the current round was sent
+	proposal       *types.Block // Block accepted in the current round
+	locked         *types.Block // Block prepared by a quorum in an earlier round of the sequence
+	lockedRound    uint64       // Round the locked block was prepared in
+	lockedPrepares [][]byte     // Prepares of the quorum which prepared the locked block
+
+	prepares     map[common.Address]*message            // Prepares of the current round
+	commits      map[common.Address]*message            // Commits of the current round
+	roundChanges map[uint64]map[common.Address]*message // Round change requests for future rounds
+	changeRound  uint64                                 // Highest round a round change was requested for
+
+	task    *sealTask  // Latest block handed over by the miner
+	backlog []*message // Messages of future rounds and sequences
+	timer   *time.Timer
+}
+
+// newSequencer creates a sequencer and starts its consensus rounds.
+func newSequencer(bft *BFT, chain Chain) *sequencer {
+	s := &sequencer{
+		bft:     bft,
+		chain:   chain,
+		taskCh:  make(chan *sealTask),
+		msgCh:   make(chan *message, msgChanSize),
+		blockCh: make(chan *types.Block, msgChanSize),
+		quit:    make(chan struct{}),
+		timer:   time.NewTimer(time.Hour),
+	}
+	s.stopTimer()
+	heads := make(chan core.ChainHeadEvent, chainHeadChanSize)
+	sub := chain.SubscribeChainHeadEvent(heads)
+
+	s.wg.Add(1)
+	go s.loop(heads, sub)
+	return s
+}
+
+// stop terminates the consensus rounds.
+func (s *sequencer) stop() {
+	close(s.quit)
+	s.wg.Wait()
+}
+
+// request hands a sealed block to propose over to the sequencer.
+func (s *sequencer) request(task *sealTask) {
+	select {
+	case s.taskCh <- task:
+	case <-s.quit:
+	}
+}
+
+// deliverMessage hands a consensus message received from the network over to
+// the sequencer. Messages are dropped if the sequencer can't keep up.
+func (s *sequencer) deliverMessage(msg *message) {
+	select {
+	case s.msgCh <- msg:
+	default:
+		log.Debug("Dropping consensus message", "sequence", msg.Sequence, "round", msg.Round, "code", msg.Code)
+	}
+}
+
+// deliverBlock hands a finalized block received from the network over to the
+// sequencer.
+func (s *sequencer) deliverBlock(block *types.Block) {
+	select {
+	case s.blockCh <- block:
+	default:
+		log.Debug("Dropping finalized block", "number", block.Number(), "hash", block.Hash())
+	}
+}
+
+func (s *sequencer) loop(heads chan core.ChainHeadEvent, sub event.Subscription) {
+	defer s.wg.Done()
+	defer sub.Unsubscribe()
+	defer s.timer.Stop()
+
+	s.startSequence(s.chain.CurrentBlock().Header())
+	for {
+		select {
+		case ev := <-heads:
+			s.startSequence(ev.Block.Header())
+
+		case task := <-s.taskCh:
+			s.task = task
+			s.propose()
+
+		case msg := <-s.msgCh:
+			s.handleMessage(msg)
+
+		case block := <-s.blockCh:
+			s.handleBlock(block)
+
+		case <-s.timer.C:
+			s.handleTimeout()
+
+		case <-sub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// signer returns the address of the local validator.
+func (s *sequencer) signer() common.Address {
+	s.bft.lock.RLock()
+	defer s.bft.lock.RUnlock()
+
+	return s.bft.signer
+}
+
+// startSequence starts agreeing on the block following the given head.
+func (s *sequencer) startSequence(head *types.Header) {
+	if s.head != nil && s.head.Hash() == head.Hash() {
+		return
+	}
+	snap, err := s.bft.snapshot(s.chain, head.Number.Uint64(), head.Hash(), nil)
+	if err != nil {
+		log.Error("Failed to retrieve validators", "number", head.Number, "hash", head.Hash(), "err", err)
+		return
+	}
+	s.head, s.snap = head, snap
+	s.sequence = head.Number.Uint64() + 1
+	s.locked, s.lockedRound, s.lockedPrepares = nil, 0, nil
+	s.roundChanges = make(map[uint64]map[common.Address]*message)
+	s.changeRound = 0
+
+	s.startRound(0)
+}
+
+// startRound starts the given round of the current sequence.
+func (s *sequencer) startRound(round uint64) {
+	s.round = round
+	s.state = stateAcceptRequest
+	s.proposed, s.proposal = false, nil
+	s.prepares = make(map[common.Address]*message)
+	s.commits = make(map[common.Address]*message)
+	for r := range s.roundChanges {
+		if r <= round {
+			delete(s.roundChanges, r)
+		}
+	}
+	// Only validators take part in the consensus rounds
+	if !s.snap.isValidator(s.signer()) {
+		s.stopTimer()
+		return
+	}
+	s.resetTimer(s.roundTimeout(round))
+	log.Debug("Started consensus round", "sequence", s.sequence, "round", round, "proposer", s.snap.proposer(round))
+
+	s.processBacklog()
+	s.propose()
+}
+
+// roundTimeout returns the time a round may take before a round change is
+// requested. The timeout doubles with every round. The first round also waits
+// for the block period to pass.
+func (s *sequencer) roundTimeout(round uint64) time.Duration {
+	shift := round
+	if shift > maxTimeoutShift {
+		shift = maxTimeoutShift
+	}
+	timeout := time.Duration(s.bft.config.RequestTimeout) * time.Millisecond << shift
+	if round == 0 {
+		if wait := time.Until(time.Unix(int64(s.head.Time+s.bft.config.Period), 0)); wait > 0 {
+			timeout += wait
+		}
+	}
+	return timeout
+}
+
+// resetTimer restarts the round timer with the given timeout.
+func (s *sequencer) resetTimer(timeout time.Duration) {
+	s.stopTimer()
+	s.timer.Reset(timeout)
+}
+
+// stopTimer stops the round timer, discarding a pending expiry.
+func (s *sequencer) stopTimer() {
+	if !s.timer.Stop() {
+		select {
+		case <-s.timer.C:
+		default:
+		}
+	}
+}
+
+// propose sends the pre-prepare of the current round if the local validator is
+// the round proposer. The block locked on in an earlier round is proposed again,
+// otherwise the latest block of the miner.
+func (s *sequencer) propose() {
+	if s.snap == nil || s.state != stateAcceptRequest || s.proposed {
+		return
+	}
+	if s.snap.proposer(s.round) != s.signer() {
+		return
+	}
+	block := s.locked
+	if block == nil {
+		if s.task == nil || s.task.stopped() || s.task.block.ParentHash() != s.head.Hash() {
+			return
+		}
+		block = s.task.block
+	}
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode proposal", "err", err)
+		return
+	}
+	s.proposed = true
+	log.Debug("Proposing block", "sequence", s.sequence, "round", s.round, "hash", SealHash(block.Header()))
+
+	msg := &message{Code: msgPreprepare, Round: s.round, Payload: data}
+	if block == s.locked {
+		msg.PreparedRound, msg.Prepares = s.lockedRound, s.lockedPrepares
+	}
+	s.broadcast(msg)
+}
+
+// broadcast signs the message, sends it to the other validators and processes
+// it locally.
+func (s *sequencer) broadcast(msg *message) {
+	msg.Sequence = s.sequence
+
+	author, sig, err := s.bft.sign(msg.signData())
+	if err != nil {
+		log.Error("Failed to sign consensus message", "err", err)
+		return
+	}
+	msg.author, msg.Signature = author, sig
+
+	if msg.data, err = rlp.EncodeToBytes(msg); err != nil {
+		log.Error("Failed to encode consensus message", "err", err)
+		return
+	}
+	msg.hash = crypto.Keccak256Hash(msg.data)
+
+	s.bft.handler.broadcast(consensusMsg, msg.hash, msg.data)
+	s.process(msg)
+}
+
+// handleMessage processes a consensus message received from the network and
+// relays it to the other validators if it's valid.
+func (s *sequencer) handleMessage(msg *message) {
+	if s.snap == nil || !s.snap.isValidator(s.signer()) {
+		return
+	}
+	// Messages of future sequences are checked against the current validators
+	// too, otherwise any peer could flood the backlog.
+	if !s.snap.isValidator(msg.author) {
+		log.Debug("Ignoring message of non-validator", "author", msg.author)
+		return
+	}
+	switch {
+	case msg.Sequence < s.sequence:
+		return
+	case msg.Sequence > s.sequence:
+		if msg.Sequence <= s.sequence+maxFutureSequences {
+			s.addBacklog(msg)
+		}
+		return
+	}
+	s.bft.handler.broadcast(consensusMsg, msg.hash, msg.data)
+	s.process(msg)
+}
+
+// process handles a valid consensus message of the current sequence.
+func (s *sequencer) process(msg *message) {
+	if msg.Code == msgRoundChange {
+		s.handleRoundChange(msg)
+		return
+	}
+	switch {
+	case msg.Round < s.round:
+		return
+	case msg.Round > s.round:
+		s.addBacklog(msg)
+		return
+	}
+	switch msg.Code {
+	case msgPreprepare:
+		s.handlePreprepare(msg)
+	case msgPrepare:
+		s.prepares[msg.author] = msg
+		s.checkPrepared()
+	case msgCommit:
+		s.handleCommit(msg)
+	}
+}
+
+// addBacklog keeps a message of a future round or sequence for later.
+func (s *sequencer) addBacklog(msg *message) {
+	if len(s.backlog) >= maxBacklog {
+		s.backlog = s.backlog[1:]
+	}
+	s.backlog = append(s.backlog, msg)
+}
+
+// processBacklog handles the kept messages which became current.
+func (s *sequencer) processBacklog() {
+	backlog := s.backlog
+	s.backlog = nil
+
+	for _, msg := range backlog {
+		s.handleMessage(msg)
+	}
+}
+
+// handlePreprepare accepts the proposal of the round proposer if it's valid.
+func (s *sequencer) handlePreprepare(msg *message) {
+	if msg.author != s.snap.proposer(s.round) || s.state != stateAcceptRequest {
+		return
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(msg.Payload, block); err != nil {
+		log.Debug("Invalid proposal encoding", "err", err)
+		return
+	}
+	if block.NumberU64() != s.sequence || block.ParentHash() != s.head.Hash() {
+		return
+	}
+	prepared, err := s.preparedBlock(msg)
+	if err != nil {
+		log.Debug("Invalid prepared certificate of proposal", "sequence", s.sequence, "round", s.round, "err", err)
+		return
+	}
+	// Once locked, only the locked block may be committed in this sequence,
+	// unless a quorum prepared another block in a later round.
+	digest := SealHash(block.Header())
+	if prepared != nil {
+		s.lock(prepared, msg.PreparedRound, msg.Prepares)
+	}
+	if s.locked != nil {
+		if digest != SealHash(s.locked.Header()) {
+			log.Debug("Ignoring proposal differing from locked block", "sequence", s.sequence, "round", s.round)
+			return
+		}
+	} else if err := s.verifyProposal(block); err != nil {
+		log.Warn("Rejected invalid proposal", "sequence", s.sequence, "round", s.round, "proposer", msg.author, "err", err)
+		return
+	}
+	s.proposal, s.state = block, statePreprepared
+	s.broadcast(&message{Code: msgPrepare, Round: s.round, Payload: digest.Bytes()})
+	s.checkPrepared()
+}
+
+// preparedBlock checks the prepared certificate of a pre-prepare or round change
+// and returns the block it certifies, or nil if the message carries none. The
+// certificate must consist of prepares or commits of the certified block by a
+// quorum of the validators, made in the same earlier round.
+func (s *sequencer) preparedBlock(msg *message) (*types.Block, error) {
+	if len(msg.Prepares) == 0 {
+		if msg.Code == msgRoundChange && len(msg.Payload) > 0 {
+			return nil, errors.New("prepared block without certificate")
+		}
+		return nil, nil
+	}
+	if msg.PreparedRound >= msg.Round {
+		return nil, errors.New("certificate not of an earlier round")
+	}
+	block := new(types.Block)
+	if err := rlp.DecodeBytes(msg.Payload, block); err != nil {
+		return nil, err
+	}
+	if block.NumberU64() != s.sequence || block.ParentHash() != s.head.Hash() {
+		return nil, errors.New("prepared block not on top of head")
+	}
+	digest := SealHash(block.Header())
+
+	prepared := make(map[common.Address]struct{})
+	for _, data := range msg.Prepares {
+		prepare, err := decodeMessage(data)
+		if err != nil {
+			return nil, err
+		}
+		if prepare.Code != msgPrepare && prepare.Code != msgCommit {
+			return nil, errInvalidMessage
+		}
+		if prepare.Sequence != s.sequence || prepare.Round != msg.PreparedRound || prepare.digest() != digest {
+			return nil, errors.New("prepare of other proposal")
+		}
+		if !s.snap.isValidator(prepare.author) {
+			return nil, errors.New("prepare of non-validator")
+		}
+		prepared[prepare.author] = struct{}{}
+	}
+	if len(prepared) < s.snap.quorum() {
+		return nil, errors.New("prepares below quorum")
+	}
+	return block, nil
+}
+
+// lock locks on a block prepared by a quorum in the given round, unless the
+// current lock is of the same or a later round.
+func (s *sequencer) lock(block *types.Block, round uint64, prepares [][]byte) {
+	if s.locked != nil && round <= s.lockedRound {
+		return
+	}
+	if s.locked != nil && SealHash(s.locked.Header()) != SealHash(block.Header()) {
+		log.Debug("Switching locked block", "sequence", s.sequence, "from", s.lockedRound, "to", round)
+	}
+	s.locked, s.lockedRound, s.lockedPrepares = block, round, prepares
+}
+
+// verifyProposal fully validates a proposed block on top of the chain head.
+func (s *sequencer) verifyProposal(block *types.Block) error {
+	if err := s.bft.verifyHeader(s.chain, block.Header(), nil, false); err != nil {
+		return err
+	}
+	if err := s.chain.Validator().ValidateBody(block); err != nil {
+		return err
+	}
+	statedb, err := s.chain.StateAt(s.head.Root)
+	if err != nil {
+		return err
+	}
+	receipts, _, usedGas, err := s.chain.Processor().Process(block, statedb, vm.Config{})
+	if err != nil {
+		return err
+	}
+	return s.chain.Validator().ValidateState(block, statedb, receipts, usedGas)
+}
+
+// agreed returns the number of validators which prepared or committed the
+// proposal with the given digest.
+func (s *sequencer) agreed(digest common.Hash) int {
+	validators := make(map[common.Address]struct{})
+	for author, msg := range s.prepares {
+		if msg.digest() == digest {
+			validators[author] = struct{}{}
+		}
+	}
+	for author, msg := range s.commits {
+		if msg.digest() == digest {
+			validators[author] = struct{}{}
+		}
+	}
+	return len(validators)
+}
+
+// checkPrepared locks on the proposal and commits to it once a quorum of the
+// validators prepared it.
+func (s *sequencer) checkPrepared() {
+	if s.state != statePreprepared {
+		return
+	}
+	digest := SealHash(s.proposal.Header())
+	if s.agreed(digest) < s.snap.quorum() {
+		return
+	}
+	var prepares [][]byte
+	for _, msgs := range []map[common.Address]*message{s.prepares, s.commits} {
+		for _, msg := range msgs {
+			if msg.digest() == digest {
+				prepares = append(prepares, msg.data)
+			}
+		}
+	}
+	s.lock(s.proposal, s.round, prepares)
+	if SealHash(s.locked.Header()) != digest {
+		return // A later round prepared another block
+	}
+	s.state = statePrepared
+
+	_, seal, err := s.bft.sign(commitData(digest))
+	if err != nil {
+		log.Error("Failed to sign committed seal", "err", err)
+		return
+	}
+	s.broadcast(&message{Code: msgCommit, Round: s.round, Payload: digest.Bytes(), CommittedSeal: seal})
+	s.checkCommitted()
+}
+
+// handleCommit records the commit of a validator if its committed seal is valid.
+func (s *sequencer) handleCommit(msg *message) {
+	validator, err := recoverAddress(commitData(msg.digest()), msg.CommittedSeal)
+	if err != nil || validator != msg.author {
+		log.Debug("Invalid committed seal", "author", msg.author, "err", err)
+		return
+	}
+	s.commits[msg.author] = msg
+	s.checkPrepared()
+	s.checkCommitted()
+}
+
+// checkCommitted finalizes the proposal once a quorum of the validators
+// committed to it. The block is assembled by the round proposer only, so that
+// every node ends up with the same set of committed seals.
+func (s *sequencer) checkCommitted() {
+	if s.state != statePrepared {
+		return
+	}
+	digest := SealHash(s.proposal.Header())
+
+	var committers []common.Address
+	for author, msg := range s.commits {
+		if msg.digest() == digest {
+			committers = append(committers, author)
+		}
+	}
+	if len(committers) < s.snap.quorum() {
+		return
+	}
+	s.state = stateCommitted
+	log.Debug("Committed proposal", "sequence", s.sequence, "round", s.round, "hash", digest)
+
+	if s.snap.proposer(s.round) != s.signer() {
+		return
+	}
+	sort.Slice(committers, func(i, j int) bool {
+		return bytes.Compare(committers[i][:], committers[j][:]) < 0
+	})
+	seals := make([][]byte, len(committers))
+	for i, committer := range committers {
+		seals[i] = s.commits[committer].CommittedSeal
+	}
+	header := s.proposal.Header()
+	extra, err := ExtractExtra(header)
+	if err != nil {
+		log.Error("Failed to decode proposal extra-data", "err", err)
+		return
+	}
+	extra.CommittedSeals = seals
+	if err := writeExtra(header, extra); err != nil {
+		log.Error("Failed to encode committed seals", "err", err)
+		return
+	}
+	s.finalize(s.proposal.WithSeal(header))
+}
+
+// finalize distributes the block committed in the current round and makes it
+// the new head, either through the miner which proposed it or directly.
+func (s *sequencer) finalize(block *types.Block) {
+	log.Info("Finalized block", "number", block.Number(), "hash", block.Hash(), "round", s.round, "txs", len(block.Transactions()))
+
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		log.Error("Failed to encode finalized block", "err", err)
+		return
+	}
+	s.bft.handler.broadcast(blockMsg, crypto.Keccak256Hash(data), data)
+
+	if task := s.task; task != nil && !task.stopped() && SealHash(task.block.Header()) == SealHash(block.Header()) {
+		select {
+		case task.results <- block:
+			return
+		default:
+			log.Warn("Sealing result is not read by miner", "sealhash", SealHash(block.Header()))
+		}
+	}
+	if _, err := s.chain.InsertChain(types.Blocks{block}); err != nil {
+		log.Error("Failed to insert finalized block", "number", block.Number(), "hash", block.Hash(), "err", err)
+	}
+}
+
+// handleBlock inserts a block finalized by another validator.
+func (s *sequencer) handleBlock(block *types.Block) {
+	if s.head == nil || block.NumberU64() != s.sequence || block.ParentHash() != s.head.Hash() {
+		return
+	}
+	if _, err := s.chain.InsertChain(types.Blocks{block}); err != nil {
+		log.Debug("Failed to insert finalized block", "number", block.Number(), "hash", block.Hash(), "err", err)
+		return
+	}
+	data, err := rlp.EncodeToBytes(block)
+	if err != nil {
+		return
+	}
+	s.bft.handler.broadcast(blockMsg, crypto.Keccak256Hash(data), data)
+}
+
+// handleRoundChange moves to a new round once a quorum of the validators
+// requested it. If F+1 validators requested a round ahead of ours, at least one
+// honest validator timed out, so the request is joined. The prepared block of
+// the request is locked on if it's more recent than the current lock.
+func (s *sequencer) handleRoundChange(msg *message) {
+	prepared, err := s.preparedBlock(msg)
+	if err != nil {
+		log.Debug("Invalid prepared certificate of round change", "author", msg.author, "err", err)
+		return
+	}
+	if prepared != nil {
+		s.lock(prepared, msg.PreparedRound, msg.Prepares)
+	}
+	if msg.Round <= s.round {
+		return
+	}
+	if s.roundChanges[msg.Round] == nil {
+		s.roundChanges[msg.Round] = make(map[common.Address]*message)
+	}
+	s.roundChanges[msg.Round][msg.author] = msg
+
+	switch count := len(s.roundChanges[msg.Round]); {
+	case count >= s.snap.quorum():
+		log.Debug("Changing consensus round", "sequence", s.sequence, "from", s.round, "to", msg.Round)
+		s.startRound(msg.Round)
+	case count > s.snap.faulty() && msg.Round > s.changeRound:
+		s.sendRoundChange(msg.Round)
+	}
+}
+
+// sendRoundChange requests moving to the given round.
+func (s *sequencer) sendRoundChange(round uint64) {
+	s.changeRound = round
+	s.resetTimer(s.roundTimeout(round))
+
+	msg := &message{Code: msgRoundChange, Round: round}
+	if s.locked != nil {
+		data, err := rlp.EncodeToBytes(s.locked)
+		if err != nil {
+			log.Error("Failed to encode locked block", "err", err)
+			return
+		}
+		msg.Payload, msg.PreparedRound, msg.Prepares = data, s.lockedRound, s.lockedPrepares
+	}
+	s.broadcast(msg)
+}
+
+// handleTimeout requests the next round if the current one didn't finalize a
+// block in time.
+func (s *sequencer) handleTimeout() {
+	round := s.round
+	if s.changeRound > round {
+		round = s.changeRound
+	}
+	log.Debug("Consensus round timed out", "sequence", s.sequence, "round", s.round, "state", s.state)
+	s.sendRoundChange(round + 1)
+}
@@ -0,0 +1,430 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// testValidator is a simulated validator node running a chain and the BFT
+// engine, sealing an empty block on top of every new head like the miner.
+type testValidator struct {
+	key    *ecdsa.PrivateKey
+	engine *BFT
+	chain  *core.BlockChain
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newTestValidator(genesis *core.Genesis, key *ecdsa.PrivateKey, stack *node.Node) (*testValidator, error) {
+	db := rawdb.NewMemoryDatabase()
+	genesis.MustCommit(db)
+
+	engine := New(genesis.Config.BFT, db)
+	chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	v := &testValidator{
+		key:    key,
+		engine: engine,
+		chain:  chain,
+		quit:   make(chan struct{}),
+	}
+	stack.RegisterProtocols(engine.Protocols())
+	stack.RegisterLifecycle(v)
+	return v, nil
+}
+
+// Start implements node.Lifecycle, starting the consensus rounds.
+func (v *testValidator) Start() error {
+	v.engine.Authorize(crypto.PubkeyToAddress(v.key.PublicKey), signerFn(v.key))
+	if err := v.engine.Start(v.chain); err != nil {
+		return err
+	}
+	v.wg.Add(1)
+	go v.mine()
+	return nil
+}
+
+// Stop implements node.Lifecycle, terminating the validator.
+func (v *testValidator) Stop() error {
+	close(v.quit)
+	v.wg.Wait()
+	v.engine.Close()
+	v.chain.Stop()
+	return nil
+}
+
+// mine hands a new block to the engine for every head and inserts the blocks
+// returned as sealed.
+func (v *testValidator) mine() {
+	defer v.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 10)
+	sub := v.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	var (
+		results = make(chan *types.Block, 1)
+		stop    chan struct{}
+	)
+	seal := func() {
+		if stop != nil {
+			close(stop)
+		}
+		stop = make(chan struct{})
+
+		block, err := v.makeBlock()
+		if err != nil {
+			log.Error("Failed to create block", "err", err)
+			return
+		}
+		if err := v.engine.Seal(v.chain, block, results, stop); err != nil {
+			log.Error("Failed to seal block", "err", err)
+		}
+	}
+	seal()
+	for {
+		select {
+		case <-heads:
+			seal()
+		case block := <-results:
+			if _, err := v.chain.InsertChain(types.Blocks{block}); err != nil {
+				log.Error("Failed to insert sealed block", "err", err)
+			}
+		case <-v.quit:
+			close(stop)
+			return
+		}
+	}
+}
+
+// makeBlock assembles an empty block on top of the chain head.
+func (v *testValidator) makeBlock() (*types.Block, error) {
+	parent := v.chain.CurrentBlock().Header()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Coinbase:   crypto.PubkeyToAddress(v.key.PublicKey),
+		BaseFee:    misc.CalcBaseFee(v.chain.Config(), parent),
+	}
+	if err := v.engine.Prepare(v.chain, header); err != nil {
+		return nil, err
+	}
+	statedb, err := v.chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	return v.engine.FinalizeAndAssemble(v.chain, header, statedb, nil, nil, nil)
+}
+
+// waitHeight waits until the chains of all validators reached the given height.
+func waitHeight(t *testing.T, validators []*testValidator, height uint64) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Minute)
+	for _, v := range validators {
+		for v.chain.CurrentBlock().NumberU64() < height {
+			if time.Now().After(deadline) {
+				t.Fatalf("validator stuck at block %d, want %d", v.chain.CurrentBlock().NumberU64(), height)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+}
+
+// checkFinalized verifies that the validators finalized the same blocks up to
+// the given height, all of them committed by a quorum.
+func checkFinalized(t *testing.T, validators []*testValidator, height uint64) {
+	t.Helper()
+
+	for n := uint64(1); n <= height; n++ {
+		want := validators[0].chain.GetBlockByNumber(n)
+		for i, v := range validators {
+			block := v.chain.GetBlockByNumber(n)
+			if block == nil || block.Hash() != want.Hash() {
+				t.Fatalf("validator %d: block %d mismatch", i, n)
+			}
+		}
+		parent := validators[0].chain.GetHeaderByHash(want.ParentHash())
+		snap, err := validators[0].engine.snapshot(validators[0].chain, parent.Number.Uint64(), parent.Hash(), nil)
+		if err != nil {
+			t.Fatalf("failed to retrieve snapshot: %v", err)
+		}
+		extra, _ := ExtractExtra(want.Header())
+		if err := verifyCommittedSeals(snap, want.Header(), extra); err != nil {
+			t.Fatalf("block %d not committed: %v", n, err)
+		}
+	}
+}
+
+func TestSimulatedNetwork(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping simulation in short mode")
+	}
+	const count = 4
+
+	configs := make([]*adapters.NodeConfig, count)
+	addrs := make([]common.Address, count)
+	for i := range configs {
+		configs[i] = adapters.RandomNodeConfig()
+		configs[i].EnableMsgEvents = false
+		addrs[i] = crypto.PubkeyToAddress(configs[i].PrivateKey.PublicKey)
+	}
+	genesis := testGenesis(addrs)
+
+	var (
+		lock       sync.Mutex
+		validators = make(map[enode.ID]*testValidator)
+	)
+	adapter := adapters.NewSimAdapter(adapters.LifecycleConstructors{
+		"bft": func(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			v, err := newTestValidator(genesis, ctx.Config.PrivateKey, stack)
+			if err != nil {
+				return nil, err
+			}
+			lock.Lock()
+			validators[ctx.Config.ID] = v
+			lock.Unlock()
+			return v, nil
+		},
+	})
+	network := simulations.NewNetwork(adapter, &simulations.NetworkConfig{DefaultService: "bft"})
+	defer network.Shutdown()
+
+	ids := make([]enode.ID, count)
+	for i, config := range configs {
+		n, err := network.NewNodeWithConfig(config)
+		if err != nil {
+			t.Fatalf("failed to create node: %v", err)
+		}
+		if err := network.Start(n.ID()); err != nil {
+			t.Fatalf("failed to start node: %v", err)
+		}
+		ids[i] = n.ID()
+	}
+	// Connect the validators in a ring, so messages have to be relayed
+	if err := network.ConnectNodesRing(ids); err != nil {
+		t.Fatalf("failed to connect nodes: %v", err)
+	}
+	lock.Lock()
+	all := make([]*testValidator, count)
+	for i, id := range ids {
+		all[i] = validators[id]
+	}
+	lock.Unlock()
+
+	// All validators finalize the same blocks
+	waitHeight(t, all, 5)
+	checkFinalized(t, all, 5)
+
+	// With F=1 the network keeps finalizing blocks with a validator down, the
+	// rounds of its turns are skipped by round changes
+	if err := network.Stop(ids[count-1]); err != nil {
+		t.Fatalf("failed to stop node: %v", err)
+	}
+	live := all[:count-1]
+
+	height := uint64(0)
+	for _, v := range live {
+		if n := v.chain.CurrentBlock().NumberU64(); n > height {
+			height = n
+		}
+	}
+	waitHeight(t, live, height+count+1)
+	checkFinalized(t, live, height+count+1)
+}
+
+// lockTester drives the sequencers of a few validators directly, delivering
+// their consensus messages selectively.
+type lockTester struct {
+	t      *testing.T
+	addrs  []common.Address
+	seqs   []*sequencer
+	taps   []*peer // Peers capturing the messages sent by the sequencers
+	blocks []*types.Block
+}
+
+func newLockTester(t *testing.T, count int) *lockTester {
+	keys := make([]*ecdsa.PrivateKey, count)
+	addrs := make([]common.Address, count)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	sort.Sort(validatorsAscending(addrs))
+	for i := range keys {
+		for j := range keys {
+			if crypto.PubkeyToAddress(keys[j].PublicKey) == addrs[i] {
+				keys[i], keys[j] = keys[j], keys[i]
+			}
+		}
+	}
+	genesis := testGenesis(addrs)
+
+	lt := &lockTester{t: t, addrs: addrs}
+	for i, key := range keys {
+		db := rawdb.NewMemoryDatabase()
+		genesis.MustCommit(db)
+		engine := New(genesis.Config.BFT, db)
+		engine.Authorize(addrs[i], signerFn(key))
+		chain, err := core.NewBlockChain(db, nil, genesis.Config, engine, vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		t.Cleanup(chain.Stop)
+
+		tap := newPeer(p2p.NewPeer(enode.ID{byte(i)}, "tap", nil), nil)
+		engine.handler.peers[tap.ID()] = tap
+
+		block, err := (&testValidator{key: key, engine: engine, chain: chain}).makeBlock()
+		if err != nil {
+			t.Fatalf("failed to create block: %v", err)
+		}
+		header := block.Header()
+		extra, _ := ExtractExtra(header)
+		if _, extra.Seal, err = engine.sign(sealRLP(header)); err != nil {
+			t.Fatalf("failed to seal block: %v", err)
+		}
+		if err := writeExtra(header, extra); err != nil {
+			t.Fatalf("failed to seal block: %v", err)
+		}
+		block = block.WithSeal(header)
+
+		s := &sequencer{bft: engine, chain: chain, timer: time.NewTimer(time.Hour)}
+		s.task = &sealTask{block: block, results: make(chan *types.Block, 1), stop: make(chan struct{})}
+		t.Cleanup(func() { s.timer.Stop() })
+
+		lt.seqs = append(lt.seqs, s)
+		lt.taps = append(lt.taps, tap)
+		lt.blocks = append(lt.blocks, block)
+	}
+	return lt
+}
+
+// sent returns the consensus messages signed and sent by a validator since the
+// last call, ignoring relayed ones.
+func (lt *lockTester) sent(i int) []*message {
+	var msgs []*message
+	for {
+		select {
+		case out := <-lt.taps[i].queue:
+			if out.code != consensusMsg {
+				continue
+			}
+			msg, err := decodeMessage(out.data)
+			if err != nil {
+				lt.t.Fatalf("invalid message: %v", err)
+			}
+			if msg.author == lt.addrs[i] {
+				msgs = append(msgs, msg)
+			}
+		default:
+			return msgs
+		}
+	}
+}
+
+// deliver exchanges the messages sent by the validators until none are left.
+// The filter decides whether a message is delivered to a validator.
+func (lt *lockTester) deliver(filter func(msg *message, to int) bool) {
+	for {
+		var delivered bool
+		for from := range lt.seqs {
+			for _, msg := range lt.sent(from) {
+				for to, s := range lt.seqs {
+					if to != from && filter(msg, to) {
+						s.handleMessage(msg)
+						delivered = true
+					}
+				}
+			}
+		}
+		if !delivered {
+			return
+		}
+	}
+}
+
+// Tests that the validators agree on a block if only some of them locked on the
+// block prepared in an earlier round, and the next proposer is not among them.
+// The proposer learns the locked block from the round changes and proposes it
+// again, instead of a block the locked validators would reject.
+func TestSplitLock(t *testing.T) {
+	lt := newLockTester(t, 4)
+	for _, s := range lt.seqs {
+		s.startSequence(s.chain.CurrentBlock().Header())
+	}
+	// Proposer of round 0 is validator 1, of round 1 validator 2. The prepares
+	// of round 0 only reach validators 0 and 1, which lock on the proposal.
+	if lt.seqs[0].snap.proposer(0) != lt.addrs[1] || lt.seqs[0].snap.proposer(1) != lt.addrs[2] {
+		t.Fatal("unexpected proposer order")
+	}
+	lt.deliver(func(msg *message, to int) bool {
+		return msg.Code == msgPreprepare || (msg.Code == msgPrepare && to <= 1)
+	})
+	for i, s := range lt.seqs {
+		if locked := s.locked != nil; locked != (i <= 1) {
+			t.Fatalf("validator %d: locked %v", i, locked)
+		}
+	}
+	// All validators time out and change to round 1, where the block locked by
+	// validators 0 and 1 is committed.
+	for _, s := range lt.seqs {
+		s.handleTimeout()
+	}
+	lt.deliver(func(msg *message, to int) bool { return msg.Round >= 1 })
+
+	want := SealHash(lt.blocks[1].Header())
+	for i, s := range lt.seqs {
+		if s.round != 1 {
+			t.Fatalf("validator %d: round %d, want 1", i, s.round)
+		}
+		if s.state != stateCommitted {
+			t.Fatalf("validator %d: state %v, want %v", i, s.state, stateCommitted)
+		}
+		if hash := SealHash(s.proposal.Header()); hash != want {
+			t.Fatalf("validator %d: committed %x, want locked block %x", i, hash, want)
+		}
+	}
+	head := lt.seqs[2].chain.CurrentBlock()
+	if head.NumberU64() != 1 || SealHash(head.Header()) != want {
+		t.Fatalf("round proposer didn't finalize the locked block: head #%d %x", head.NumberU64(), SealHash(head.Header()))
+	}
+}
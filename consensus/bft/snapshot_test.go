@@ -0,0 +1,132 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bft
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// testerVote represents a block proposed with an optional vote.
+type testerVote struct {
+	proposer int  // Index of the proposing account
+	voted    int  // Index of the account voted on, -1 for no vote
+	auth     bool // Whether the vote is to add the account
+}
+
+func TestVoting(t *testing.T) {
+	tests := []struct {
+		name       string
+		validators int          // Number of initial validators, accounts 0 to validators-1
+		votes      []testerVote // Blocks to propose
+		results    []int        // Indexes of the final validators
+	}{
+		{
+			name:       "no votes",
+			validators: 4,
+			votes:      []testerVote{{0, -1, false}, {1, -1, false}},
+			results:    []int{0, 1, 2, 3},
+		}, {
+			name:       "single validator adds another",
+			validators: 1,
+			votes:      []testerVote{{0, 1, true}},
+			results:    []int{0, 1},
+		}, {
+			name:       "majority adds validator",
+			validators: 4,
+			votes:      []testerVote{{0, 4, true}, {1, 4, true}, {2, 4, true}},
+			results:    []int{0, 1, 2, 3, 4},
+		}, {
+			name:       "half isn't a majority",
+			validators: 4,
+			votes:      []testerVote{{0, 4, true}, {1, 4, true}},
+			results:    []int{0, 1, 2, 3},
+		}, {
+			name:       "repeated votes count once",
+			validators: 4,
+			votes:      []testerVote{{0, 4, true}, {0, 4, true}, {0, 4, true}},
+			results:    []int{0, 1, 2, 3},
+		}, {
+			name:       "majority removes validator",
+			validators: 4,
+			votes:      []testerVote{{0, 3, false}, {1, 3, false}, {2, 3, false}},
+			results:    []int{0, 1, 2},
+		}, {
+			name:       "votes of removed validator are discarded",
+			validators: 4,
+			votes:      []testerVote{{3, 4, true}, {0, 3, false}, {1, 3, false}, {2, 3, false}, {0, 4, true}},
+			results:    []int{0, 1, 2},
+		}, {
+			name:       "votes on changed validator are discarded",
+			validators: 4,
+			votes:      []testerVote{{0, 4, true}, {1, 4, true}, {2, 4, true}, {3, 4, false}},
+			results:    []int{0, 1, 2, 3, 4},
+		},
+	}
+	for _, test := range tests {
+		tt := newTester(t, test.validators, 5-test.validators)
+		for i, vote := range test.votes {
+			var v *Vote
+			if vote.voted >= 0 {
+				v = &Vote{Address: tt.accounts[vote.voted], Authorize: vote.auth}
+			}
+			tt.commit(tt.accounts[vote.proposer], v)
+
+			// The next block has to list the updated validator set
+			if head := tt.chain.CurrentHeader(); head.Number.Uint64() != uint64(i+1) {
+				t.Fatalf("test %q: block %d not inserted", test.name, i+1)
+			}
+		}
+		validators := tt.validators()
+		if len(validators) != len(test.results) {
+			t.Errorf("test %q: validator count mismatch: have %d, want %d", test.name, len(validators), len(test.results))
+			continue
+		}
+		want := make([]common.Address, len(test.results))
+		for j, result := range test.results {
+			want[j] = tt.accounts[result]
+		}
+		sort.Sort(validatorsAscending(want))
+		for j := range want {
+			if validators[j] != want[j] {
+				t.Errorf("test %q: validator %d mismatch: have %x, want %x", test.name, j, validators[j], want[j])
+			}
+		}
+	}
+}
+
+func TestQuorum(t *testing.T) {
+	tests := []struct {
+		validators, quorum, faulty int
+	}{
+		{1, 1, 0}, {2, 2, 0}, {3, 2, 0}, {4, 3, 1}, {5, 4, 1}, {6, 4, 1}, {7, 5, 2}, {10, 7, 3},
+	}
+	for _, test := range tests {
+		snap := &Snapshot{Validators: make(map[common.Address]struct{})}
+		for i := 0; i < test.validators; i++ {
+			snap.Validators[common.Address{byte(i)}] = struct{}{}
+		}
+		if q := snap.quorum(); q != test.quorum {
+			t.Errorf("%d validators: quorum mismatch: have %d, want %d", test.validators, q, test.quorum)
+		}
+		if f := snap.faulty(); f != test.faulty {
+			t.Errorf("%d validators: faulty mismatch: have %d, want %d", test.validators, f, test.faulty)
+		}
+	}
+}
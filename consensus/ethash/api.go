@@ -110,3 +110,16 @@ func (api *API) SubmitHashrate(rate hexutil.Uint64, id common.Hash) bool {
 func (api *API) GetHashrate() uint64 {
 	return uint64(api.ethash.Hashrate())
 }
+
+// StratumWorkers returns the share accounting of the mining workers connected
+// to the stratum server.
+func (api *API) StratumWorkers() ([]StratumWorker, error) {
+	api.ethash.lock.Lock()
+	stratum := api.ethash.stratum
+	api.ethash.lock.Unlock()
+
+	if stratum == nil {
+		return nil, errors.New("stratum server not running")
+	}
+	return stratum.Workers(), nil
+}
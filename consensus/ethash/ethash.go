@@ -445,6 +445,7 @@ type Ethash struct {
 	update   chan struct{} // Notification channel to update mining parameters
 	hashrate metrics.Meter // Meter tracking the average hashrate
 	remote   *remoteSealer
+	stratum  *Stratum // Stratum server distributing the remote work, if running
 
 	// The fields below are hooks for testing
 	shared    *Ethash       // Shared PoW verifier to avoid cache regeneration
@@ -549,6 +550,13 @@ func NewShared() *Ethash {
 
 // Close closes the exit channel to notify all backend threads exiting.
 func (ethash *Ethash) Close() error {
+	ethash.lock.Lock()
+	stratum := ethash.stratum
+	ethash.lock.Unlock()
+
+	if stratum != nil {
+		stratum.Close()
+	}
 	return ethash.StopRemoteSealer()
 }
 
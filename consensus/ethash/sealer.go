@@ -34,6 +34,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
 )
 
 const (
@@ -198,6 +199,7 @@ type remoteSealer struct {
 	notifyCtx    context.Context
 	cancelNotify context.CancelFunc // cancels all notification requests
 	reqWG        sync.WaitGroup     // tracks notification request goroutines
+	workFeed     event.Feed         // feed of new work packages for the stratum server
 
 	ethash       *Ethash
 	noverify     bool
@@ -283,6 +285,7 @@ func (s *remoteSealer) loop() {
 			s.results = work.results
 			s.makeWork(work.block)
 			s.notifyWork()
+			s.workFeed.Send(s.currentWork)
 
 		case work := <-s.fetchWorkCh:
 			// Return current mining work to remote miner.
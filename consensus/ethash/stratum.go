@@ -0,0 +1,862 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bufio"
+	"bytes"
+	crand "crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Stratum dialects spoken by mining software, detected from the first request.
+const (
+	dialectNiceHash = iota + 1 // EthereumStratum/1.0.0 with mining.* methods and extranonces
+	dialectProxy               // ETHProxy with eth_* methods and pushed work packages
+)
+
+const (
+	// stratumVersion is the protocol version announced to NiceHash style miners.
+	stratumVersion = "EthereumStratum/1.0.0"
+
+	stratumMaxRequest     = 4096             // Maximum length of a request line
+	stratumReadTimeout    = 10 * time.Minute // Time allowed for a miner to stay silent
+	stratumWriteTimeout   = 10 * time.Second // Time allowed to write a message to a miner
+	stratumReportInterval = 5 * time.Second  // Interval to feed worker hashrates to the remote sealer
+	stratumReportExpiry   = time.Minute      // Time after which a reported hashrate is replaced by the estimation
+	stratumWorkerExpiry   = time.Hour        // Time after which a disconnected worker is dropped
+	stratumExtranonceSize = 2                // Number of nonce bytes assigned to a NiceHash connection
+)
+
+var (
+	// stratumBaseTarget is the share boundary of difficulty 1, corresponding to
+	// about 2^32 hashes per share.
+	stratumBaseTarget = new(big.Int).Lsh(big.NewInt(0xffff), 208)
+
+	// maxTarget is the highest boundary expressible in a work package.
+	maxTarget = new(big.Int).Sub(two256, common.Big1)
+
+	errStratumUnsupported    = errors.New("stratum requires a proof-of-work ethash engine")
+	errStratumRunning        = errors.New("stratum server already running")
+	errStratumUnauthorized   = errors.New("unauthorized worker")
+	errStratumDialect        = errors.New("method not supported by the stratum dialect")
+	errStratumInvalidParams  = errors.New("invalid parameters")
+	errStratumStaleShare     = errors.New("stale share")
+	errStratumDuplicateShare = errors.New("duplicate share")
+	errStratumLowDifficulty  = errors.New("low difficulty share")
+
+	stratumAcceptedMeter = metrics.NewRegisteredMeter("ethash/stratum/shares/accepted", nil)
+	stratumRejectedMeter = metrics.NewRegisteredMeter("ethash/stratum/shares/rejected", nil)
+	stratumStaleMeter    = metrics.NewRegisteredMeter("ethash/stratum/shares/stale", nil)
+	stratumBlockMeter    = metrics.NewRegisteredMeter("ethash/stratum/blocks", nil)
+)
+
+// StratumWorker is the share accounting of a mining worker connected over stratum.
+type StratumWorker struct {
+	Name              string         `json:"name"`
+	Difficulty        float64        `json:"difficulty"`
+	Connections       int            `json:"connections"`
+	Accepted          uint64         `json:"accepted"`
+	Rejected          uint64         `json:"rejected"`
+	Stale             uint64         `json:"stale"`
+	Blocks            uint64         `json:"blocks"`
+	Hashrate          hexutil.Uint64 `json:"hashrate"`          // Hashrate reported by the miner
+	EstimatedHashrate hexutil.Uint64 `json:"estimatedHashrate"` // Hashrate estimated from the accepted shares
+	LastShare         time.Time      `json:"lastShare"`
+}
+
+// stratumWorker tracks a mining worker across its connections.
+type stratumWorker struct {
+	StratumWorker
+
+	id       common.Hash   // Identifier of the worker hashrate in the remote sealer
+	hashes   metrics.Meter // Number of hashes accounted by the accepted shares
+	reported time.Time     // Time the miner last reported its hashrate
+	seen     time.Time     // Time the worker was last connected or sent a share
+}
+
+// stratumJob is a work package distributed to the workers.
+type stratumJob struct {
+	hash   common.Hash         // Seal hash of the block
+	seed   common.Hash         // Seed hash of the DAG
+	target *big.Int            // Boundary of a block solution
+	number uint64              // Number of the block
+	shares map[uint64]struct{} // Nonces already submitted, to reject duplicates
+}
+
+// newStratumJob parses a work package of the remote sealer.
+func newStratumJob(work [4]string) (*stratumJob, error) {
+	number, err := hexutil.DecodeUint64(work[3])
+	if err != nil {
+		return nil, err
+	}
+	return &stratumJob{
+		hash:   common.HexToHash(work[0]),
+		seed:   common.HexToHash(work[1]),
+		target: common.HexToHash(work[2]).Big(),
+		number: number,
+		shares: make(map[uint64]struct{}),
+	}, nil
+}
+
+// shareTarget returns the share boundary of a worker difficulty for a block
+// boundary, along with the difficulty it corresponds to. Shares are never
+// harder to find than a block solution.
+func shareTarget(difficulty float64, block *big.Int) (*big.Int, float64) {
+	target, _ := new(big.Float).Quo(new(big.Float).SetInt(stratumBaseTarget), big.NewFloat(difficulty)).Int(nil)
+	if block.Sign() > 0 && target.Cmp(block) < 0 {
+		target = new(big.Int).Set(block)
+	}
+	if target.Cmp(maxTarget) > 0 {
+		target = new(big.Int).Set(maxTarget)
+	}
+	if target.Sign() == 0 {
+		target.SetUint64(1)
+	}
+	effective, _ := new(big.Float).Quo(new(big.Float).SetInt(stratumBaseTarget), new(big.Float).SetInt(target)).Float64()
+	return target, effective
+}
+
+// stratumRequest is a request sent by a miner, in either dialect.
+type stratumRequest struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Worker string          `json:"worker"`
+}
+
+// stratumResponse is the reply to a request, or a work package pushed to an
+// ETHProxy miner.
+type stratumResponse struct {
+	ID      json.RawMessage `json:"id"`
+	Version string          `json:"jsonrpc,omitempty"`
+	Result  interface{}     `json:"result"`
+	Error   interface{}     `json:"error"`
+}
+
+// stratumNotification is a message pushed to a NiceHash style miner.
+type stratumNotification struct {
+	ID     interface{}   `json:"id"`
+	Method string        `json:"method"`
+	Params []interface{} `json:"params"`
+}
+
+// stratumError is the error of a reply to an ETHProxy miner.
+type stratumError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// stratumConn is a connection of a miner.
+type stratumConn struct {
+	conn       net.Conn
+	dialect    int
+	version    string         // JSON-RPC version of the replies, set for ETHProxy miners
+	extranonce []byte         // Nonce prefix assigned to a NiceHash connection
+	worker     *stratumWorker // Authorized worker, nil before login
+	difficulty float64        // Share difficulty requested by the worker
+	target     *big.Int       // Share boundary last sent to a NiceHash miner
+	lock       sync.Mutex     // Lock protecting the writes and the share boundary
+}
+
+// write sends a message to the miner.
+func (c *stratumConn) write(msg interface{}) error {
+	blob, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(stratumWriteTimeout))
+	_, err = c.conn.Write(append(blob, '\n'))
+	return err
+}
+
+// reply answers a request of the miner, formatting errors in its dialect.
+func (c *stratumConn) reply(id json.RawMessage, result interface{}, err error) error {
+	if len(id) == 0 {
+		id = json.RawMessage("null")
+	}
+	res := &stratumResponse{ID: id, Version: c.version, Result: result}
+	if err != nil {
+		if c.dialect == dialectNiceHash {
+			res.Error = []interface{}{nicehashErrorCode(err), err.Error(), nil}
+		} else {
+			res.Error = &stratumError{Code: -1, Message: err.Error()}
+		}
+	}
+	return c.write(res)
+}
+
+// nicehashErrorCode maps an error to the codes of the EthereumStratum/1.0.0
+// specification.
+func nicehashErrorCode(err error) int {
+	switch err {
+	case errStratumStaleShare:
+		return 21
+	case errStratumDuplicateShare:
+		return 22
+	case errStratumLowDifficulty:
+		return 23
+	case errStratumUnauthorized:
+		return 24
+	default:
+		return 20
+	}
+}
+
+// Stratum is a stratum mining server distributing the work of the remote sealer
+// to mining software and submitting the found solutions back to it. Both the
+// EthereumStratum/1.0.0 (NiceHash) and ETHProxy dialects are supported.
+type Stratum struct {
+	ethash     *Ethash
+	listener   net.Listener
+	difficulty float64 // Default share difficulty of the workers
+
+	conns      map[*stratumConn]struct{}
+	workers    map[string]*stratumWorker
+	jobs       map[common.Hash]*stratumJob
+	current    *stratumJob
+	extranonce uint16
+	lock       sync.Mutex
+
+	workCh    chan [4]string
+	workSub   event.Subscription
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// StartStratum starts a stratum server listening on the given address. The
+// difficulty is the default share difficulty of the workers, difficulty 1
+// corresponding to about 2^32 hashes per share. Workers may request another
+// one with a "d=<difficulty>" password.
+func (ethash *Ethash) StartStratum(addr string, difficulty float64) (*Stratum, error) {
+	if ethash.remote == nil || ethash.config.PowMode == ModeFake || ethash.config.PowMode == ModeFullFake {
+		return nil, errStratumUnsupported
+	}
+	if difficulty <= 0 {
+		return nil, fmt.Errorf("invalid share difficulty %v", difficulty)
+	}
+	ethash.lock.Lock()
+	running := ethash.stratum != nil
+	ethash.lock.Unlock()
+	if running {
+		return nil, errStratumRunning
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	s := &Stratum{
+		ethash:     ethash,
+		listener:   listener,
+		difficulty: difficulty,
+		conns:      make(map[*stratumConn]struct{}),
+		workers:    make(map[string]*stratumWorker),
+		jobs:       make(map[common.Hash]*stratumJob),
+		workCh:     make(chan [4]string, 16),
+		quit:       make(chan struct{}),
+	}
+	s.workSub = ethash.remote.workFeed.Subscribe(s.workCh)
+
+	// Pick up the work already being sealed, if any
+	if work, err := (&API{ethash}).GetWork(); err == nil {
+		s.setWork(work)
+	}
+	ethash.lock.Lock()
+	if ethash.stratum != nil {
+		ethash.lock.Unlock()
+		s.workSub.Unsubscribe()
+		listener.Close()
+		return nil, errStratumRunning
+	}
+	ethash.stratum = s
+	ethash.lock.Unlock()
+
+	s.wg.Add(3)
+	go s.loop()
+	go s.report()
+	go s.accept()
+
+	ethash.config.Log.Info("Stratum server started", "addr", listener.Addr(), "difficulty", difficulty)
+	return s, nil
+}
+
+// Addr returns the listening address of the server.
+func (s *Stratum) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Close terminates the server, disconnecting all miners.
+func (s *Stratum) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.quit)
+		s.workSub.Unsubscribe()
+		s.listener.Close()
+
+		s.lock.Lock()
+		for c := range s.conns {
+			c.conn.Close()
+		}
+		s.lock.Unlock()
+		s.wg.Wait()
+
+		for _, w := range s.workers {
+			w.hashes.Stop()
+		}
+		s.ethash.lock.Lock()
+		if s.ethash.stratum == s {
+			s.ethash.stratum = nil
+		}
+		s.ethash.lock.Unlock()
+		s.ethash.config.Log.Info("Stratum server stopped", "addr", s.listener.Addr())
+	})
+	return nil
+}
+
+// Workers returns the share accounting of the workers, sorted by name.
+func (s *Stratum) Workers() []StratumWorker {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	workers := make([]StratumWorker, 0, len(s.workers))
+	for _, w := range s.workers {
+		stats := w.StratumWorker
+		stats.EstimatedHashrate = hexutil.Uint64(w.hashes.Rate1())
+		workers = append(workers, stats)
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		return workers[i].Name < workers[j].Name
+	})
+	return workers
+}
+
+// loop distributes the new work packages of the remote sealer.
+func (s *Stratum) loop() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case work := <-s.workCh:
+			s.setWork(work)
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// setWork makes a work package the current job and sends it to the workers.
+func (s *Stratum) setWork(work [4]string) {
+	job, err := newStratumJob(work)
+	if err != nil {
+		s.ethash.config.Log.Warn("Invalid stratum work package", "err", err)
+		return
+	}
+	s.lock.Lock()
+	if _, ok := s.jobs[job.hash]; ok {
+		// Same work can be pushed twice when the miner threads change
+		s.lock.Unlock()
+		return
+	}
+	s.jobs[job.hash] = job
+	s.current = job
+	for hash, old := range s.jobs {
+		if old.number+staleThreshold <= job.number {
+			delete(s.jobs, hash)
+		}
+	}
+	var (
+		conns        []*stratumConn
+		difficulties []float64
+	)
+	for c := range s.conns {
+		if c.worker != nil {
+			conns = append(conns, c)
+			difficulties = append(difficulties, c.difficulty)
+		}
+	}
+	s.lock.Unlock()
+
+	for i, c := range conns {
+		if err := s.sendJob(c, job, difficulties[i]); err != nil {
+			s.ethash.config.Log.Debug("Failed to send stratum job", "addr", c.conn.RemoteAddr(), "err", err)
+			c.conn.Close()
+		}
+	}
+}
+
+// sendJob sends a job to a worker, along with its share difficulty if the
+// miner needs to be told.
+func (s *Stratum) sendJob(c *stratumConn, job *stratumJob, difficulty float64) error {
+	target, difficulty := shareTarget(difficulty, job.target)
+	if c.dialect == dialectProxy {
+		return c.write(&stratumResponse{
+			ID:      json.RawMessage("0"),
+			Version: c.version,
+			Result:  proxyWork(job, target),
+		})
+	}
+	c.lock.Lock()
+	changed := c.target == nil || c.target.Cmp(target) != 0
+	c.target = target
+	c.lock.Unlock()
+
+	if changed {
+		if err := c.write(&stratumNotification{Method: "mining.set_difficulty", Params: []interface{}{difficulty}}); err != nil {
+			return err
+		}
+	}
+	return c.write(&stratumNotification{
+		Method: "mining.notify",
+		Params: []interface{}{hex.EncodeToString(job.hash[:]), hex.EncodeToString(job.seed[:]), hex.EncodeToString(job.hash[:]), true},
+	})
+}
+
+// proxyWork returns the work package of a job sent to an ETHProxy miner.
+func proxyWork(job *stratumJob, target *big.Int) [4]string {
+	return [4]string{
+		job.hash.Hex(),
+		job.seed.Hex(),
+		common.BigToHash(target).Hex(),
+		hexutil.EncodeUint64(job.number),
+	}
+}
+
+// report feeds the hashrates of the workers to the remote sealer, so they are
+// accounted for in the hashrate of the node, and drops the workers gone for
+// long.
+func (s *Stratum) report() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(stratumReportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			type rate struct {
+				id   common.Hash
+				rate uint64
+			}
+			var rates []rate
+
+			s.lock.Lock()
+			for name, w := range s.workers {
+				if w.Connections == 0 && time.Since(w.seen) > stratumWorkerExpiry {
+					w.hashes.Stop()
+					delete(s.workers, name)
+					continue
+				}
+				if time.Since(w.reported) < stratumReportExpiry {
+					rates = append(rates, rate{w.id, uint64(w.Hashrate)})
+				} else if estimated := w.hashes.Rate1(); estimated > 0 {
+					rates = append(rates, rate{w.id, uint64(estimated)})
+				}
+			}
+			s.lock.Unlock()
+
+			for _, r := range rates {
+				s.submitHashrate(r.rate, r.id)
+			}
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// accept accepts the connections of miners.
+func (s *Stratum) accept() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			select {
+			case <-s.quit:
+			default:
+				s.ethash.config.Log.Error("Stratum server failed to accept", "err", err)
+			}
+			return
+		}
+		c := &stratumConn{conn: conn, difficulty: s.difficulty}
+
+		s.lock.Lock()
+		s.extranonce++
+		c.extranonce = make([]byte, stratumExtranonceSize)
+		binary.BigEndian.PutUint16(c.extranonce, s.extranonce)
+		s.conns[c] = struct{}{}
+		s.lock.Unlock()
+
+		s.wg.Add(1)
+		go s.serve(c)
+	}
+}
+
+// serve handles the requests of a miner until it disconnects.
+func (s *Stratum) serve(c *stratumConn) {
+	defer s.wg.Done()
+	defer s.drop(c)
+
+	s.ethash.config.Log.Debug("Stratum miner connected", "addr", c.conn.RemoteAddr())
+	reader := bufio.NewReaderSize(c.conn, stratumMaxRequest)
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(stratumReadTimeout))
+		line, prefix, err := reader.ReadLine()
+		if err != nil {
+			return
+		}
+		if prefix {
+			s.ethash.config.Log.Debug("Stratum request too long", "addr", c.conn.RemoteAddr())
+			return
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var req stratumRequest
+		if err := json.Unmarshal(line, &req); err != nil {
+			s.ethash.config.Log.Debug("Invalid stratum request", "addr", c.conn.RemoteAddr(), "err", err)
+			return
+		}
+		if err := s.handle(c, &req); err != nil {
+			s.ethash.config.Log.Debug("Stratum miner failed", "addr", c.conn.RemoteAddr(), "err", err)
+			return
+		}
+	}
+}
+
+// drop removes a disconnected miner.
+func (s *Stratum) drop(c *stratumConn) {
+	c.conn.Close()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	delete(s.conns, c)
+	if c.worker != nil {
+		c.worker.Connections--
+		c.worker.seen = time.Now()
+	}
+	s.ethash.config.Log.Debug("Stratum miner disconnected", "addr", c.conn.RemoteAddr())
+}
+
+// handle processes a request of a miner. Only failures to answer are returned,
+// rejected requests are reported to the miner.
+func (s *Stratum) handle(c *stratumConn, req *stratumRequest) error {
+	dialect := dialectProxy
+	if strings.HasPrefix(req.Method, "mining.") {
+		dialect = dialectNiceHash
+	}
+	if c.dialect == 0 {
+		c.dialect = dialect
+		if dialect == dialectProxy {
+			c.version = "2.0"
+		}
+	}
+	if c.dialect != dialect {
+		return c.reply(req.ID, nil, errStratumDialect)
+	}
+	switch req.Method {
+	case "mining.subscribe":
+		id := make([]byte, 16)
+		crand.Read(id)
+		result := []interface{}{
+			[]string{"mining.notify", hex.EncodeToString(id), stratumVersion},
+			hex.EncodeToString(c.extranonce),
+		}
+		return c.reply(req.ID, result, nil)
+
+	case "mining.extranonce.subscribe":
+		return c.reply(req.ID, true, nil)
+
+	case "mining.authorize", "eth_submitLogin":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) == 0 || params[0] == "" {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		name := params[0]
+		if req.Worker != "" {
+			name += "." + req.Worker
+		}
+		password := ""
+		if len(params) > 1 {
+			password = params[1]
+		}
+		s.authorize(c, name, password)
+		if err := c.reply(req.ID, true, nil); err != nil {
+			return err
+		}
+		// NiceHash miners wait for the work to be pushed, ETHProxy ones request it
+		s.lock.Lock()
+		job, difficulty := s.current, c.difficulty
+		s.lock.Unlock()
+		if job != nil && c.dialect == dialectNiceHash {
+			return s.sendJob(c, job, difficulty)
+		}
+		return nil
+
+	case "eth_getWork":
+		if c.worker == nil {
+			return c.reply(req.ID, nil, errStratumUnauthorized)
+		}
+		s.lock.Lock()
+		job, difficulty := s.current, c.difficulty
+		s.lock.Unlock()
+		if job == nil {
+			return c.reply(req.ID, nil, errNoMiningWork)
+		}
+		target, _ := shareTarget(difficulty, job.target)
+		return c.reply(req.ID, proxyWork(job, target), nil)
+
+	case "mining.submit":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 3 {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		hash, err := hexutil.Decode("0x" + params[1])
+		if err != nil || len(hash) != common.HashLength {
+			return c.reply(req.ID, false, errStratumStaleShare)
+		}
+		suffix, err := hexutil.Decode("0x" + strings.TrimPrefix(params[2], "0x"))
+		if err != nil || len(c.extranonce)+len(suffix) != 8 {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		nonce := binary.BigEndian.Uint64(append(common.CopyBytes(c.extranonce), suffix...))
+		if err := s.submitShare(c, common.BytesToHash(hash), nonce, nil); err != nil {
+			return c.reply(req.ID, false, err)
+		}
+		return c.reply(req.ID, true, nil)
+
+	case "eth_submitWork":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 3 {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		var (
+			nonce types.BlockNonce
+			hash  common.Hash
+			mix   common.Hash
+		)
+		if nonce.UnmarshalText([]byte(params[0])) != nil || hash.UnmarshalText([]byte(params[1])) != nil || mix.UnmarshalText([]byte(params[2])) != nil {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		if err := s.submitShare(c, hash, nonce.Uint64(), &mix); err != nil {
+			return c.reply(req.ID, false, err)
+		}
+		return c.reply(req.ID, true, nil)
+
+	case "eth_submitHashrate":
+		var params []string
+		if err := json.Unmarshal(req.Params, &params); err != nil || len(params) < 2 {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		if c.worker == nil {
+			return c.reply(req.ID, false, errStratumUnauthorized)
+		}
+		rate, err := hexutil.DecodeUint64(params[0])
+		if err != nil {
+			return c.reply(req.ID, false, errStratumInvalidParams)
+		}
+		s.lock.Lock()
+		c.worker.Hashrate = hexutil.Uint64(rate)
+		c.worker.reported = time.Now()
+		id := c.worker.id
+		s.lock.Unlock()
+
+		s.submitHashrate(rate, id)
+		return c.reply(req.ID, true, nil)
+
+	default:
+		return c.reply(req.ID, nil, fmt.Errorf("method %q not found", req.Method))
+	}
+}
+
+// authorize logs a connection in as a worker. The password may request a share
+// difficulty as "d=<difficulty>" among comma separated options.
+func (s *Stratum) authorize(c *stratumConn, name string, password string) {
+	difficulty := s.difficulty
+	for _, option := range strings.Split(password, ",") {
+		if value := strings.TrimPrefix(strings.TrimSpace(option), "d="); value != option {
+			if d, err := strconv.ParseFloat(value, 64); err == nil && d > 0 {
+				difficulty = d
+			}
+		}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c.worker != nil {
+		c.worker.Connections--
+	}
+	w := s.workers[name]
+	if w == nil {
+		w = &stratumWorker{
+			StratumWorker: StratumWorker{Name: name},
+			id:            crypto.Keccak256Hash([]byte("stratum:" + name)),
+			hashes:        metrics.NewMeterForced(),
+		}
+		s.workers[name] = w
+	}
+	w.Connections++
+	w.Difficulty = difficulty
+	w.seen = time.Now()
+
+	c.worker = w
+	c.difficulty = difficulty
+
+	s.ethash.config.Log.Debug("Stratum worker authorized", "addr", c.conn.RemoteAddr(), "worker", name, "difficulty", difficulty)
+}
+
+// submitShare verifies a share of a worker and submits it to the remote sealer
+// if it's also a block solution. The mix digest is verified if the miner sent
+// it along.
+func (s *Stratum) submitShare(c *stratumConn, hash common.Hash, nonce uint64, mix *common.Hash) error {
+	s.lock.Lock()
+	w := c.worker
+	if w == nil {
+		s.lock.Unlock()
+		return errStratumUnauthorized
+	}
+	job, difficulty := s.jobs[hash], c.difficulty
+	if job == nil {
+		w.Stale++
+		s.lock.Unlock()
+		stratumStaleMeter.Mark(1)
+		return errStratumStaleShare
+	}
+	if _, ok := job.shares[nonce]; ok {
+		w.Rejected++
+		s.lock.Unlock()
+		stratumRejectedMeter.Mark(1)
+		return errStratumDuplicateShare
+	}
+	s.lock.Unlock()
+
+	// Recompute the proof-of-work of the share and check it against the boundary
+	digest, result := s.ethash.hashimoto(job.number, hash.Bytes(), nonce)
+	target, _ := shareTarget(difficulty, job.target)
+
+	var err error
+	if mix != nil && *mix != common.BytesToHash(digest) {
+		err = errInvalidMixDigest
+	} else if new(big.Int).SetBytes(result).Cmp(target) > 0 {
+		err = errStratumLowDifficulty
+	}
+	s.lock.Lock()
+	if _, ok := job.shares[nonce]; ok && err == nil {
+		err = errStratumDuplicateShare // Submitted concurrently
+	}
+	if err != nil {
+		w.Rejected++
+		s.lock.Unlock()
+		stratumRejectedMeter.Mark(1)
+		return err
+	}
+	job.shares[nonce] = struct{}{}
+	s.lock.Unlock()
+
+	// Valid share, hand it to the remote sealer if it seals the block
+	found := new(big.Int).SetBytes(result).Cmp(job.target) <= 0 && s.submitWork(nonce, digest, hash)
+
+	s.lock.Lock()
+	w.Accepted++
+	w.LastShare = time.Now()
+	w.seen = w.LastShare
+	if found {
+		w.Blocks++
+	}
+	s.lock.Unlock()
+
+	stratumAcceptedMeter.Mark(1)
+	if hashes := new(big.Int).Div(two256, target); hashes.IsInt64() {
+		w.hashes.Mark(hashes.Int64())
+	}
+	if found {
+		stratumBlockMeter.Mark(1)
+		s.ethash.config.Log.Info("Stratum worker sealed block", "worker", w.Name, "number", job.number, "sealhash", hash)
+	}
+	return nil
+}
+
+// submitWork submits a block solution to the remote sealer, returning whether
+// it was accepted.
+func (s *Stratum) submitWork(nonce uint64, digest []byte, hash common.Hash) bool {
+	errc := make(chan error, 1)
+	select {
+	case s.ethash.remote.submitWorkCh <- &mineResult{
+		nonce:     types.EncodeNonce(nonce),
+		mixDigest: common.BytesToHash(digest),
+		hash:      hash,
+		errc:      errc,
+	}:
+	case <-s.ethash.remote.exitCh:
+		return false
+	}
+	return <-errc == nil
+}
+
+// submitHashrate feeds the hashrate of a worker to the remote sealer.
+func (s *Stratum) submitHashrate(rate uint64, id common.Hash) {
+	done := make(chan struct{})
+	select {
+	case s.ethash.remote.submitRateCh <- &hashrate{done: done, rate: rate, id: id}:
+		<-done
+	case <-s.ethash.remote.exitCh:
+	}
+}
+
+// hashimoto computes the mix digest and result of a nonce, using the full
+// dataset if already generated and the verification cache otherwise.
+func (ethash *Ethash) hashimoto(number uint64, hash []byte, nonce uint64) ([]byte, []byte) {
+	if ethash.shared != nil {
+		return ethash.shared.hashimoto(number, hash, nonce)
+	}
+	if dataset := ethash.dataset(number, true); dataset.generated() {
+		digest, result := hashimotoFull(dataset.dataset, hash, nonce)
+		runtime.KeepAlive(dataset)
+		return digest, result
+	}
+	cache := ethash.cache(number)
+
+	size := datasetSize(number)
+	if ethash.config.PowMode == ModeTest {
+		size = 32 * 1024
+	}
+	digest, result := hashimotoLight(size, cache.cache, hash, nonce)
+	runtime.KeepAlive(cache)
+	return digest, result
+}
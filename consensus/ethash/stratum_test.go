@@ -0,0 +1,313 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethash
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// stratumClient is a miner connected to a stratum server.
+type stratumClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+	id     int
+}
+
+func dialStratum(t *testing.T, s *Stratum) *stratumClient {
+	conn, err := net.Dial("tcp", s.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to dial stratum server: %v", err)
+	}
+	return &stratumClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+}
+
+// send sends a request, returning its id.
+func (c *stratumClient) send(method string, worker string, params ...interface{}) int {
+	c.id++
+	req := map[string]interface{}{"id": c.id, "method": method, "params": params}
+	if worker != "" {
+		req["worker"] = worker
+	}
+	blob, _ := json.Marshal(req)
+	if _, err := c.conn.Write(append(blob, '\n')); err != nil {
+		c.t.Fatalf("failed to send %s: %v", method, err)
+	}
+	return c.id
+}
+
+// read reads the next message sent by the server.
+func (c *stratumClient) read() map[string]json.RawMessage {
+	c.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.t.Fatalf("failed to read stratum message: %v", err)
+	}
+	var msg map[string]json.RawMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		c.t.Fatalf("invalid stratum message %s: %v", line, err)
+	}
+	return msg
+}
+
+// call sends a request and decodes the result of its reply.
+func (c *stratumClient) call(result interface{}, method string, worker string, params ...interface{}) json.RawMessage {
+	id := c.send(method, worker, params...)
+	msg := c.read()
+	if have := string(msg["id"]); have != strconv.Itoa(id) {
+		c.t.Fatalf("%s: reply id mismatch: have %s, want %d", method, have, id)
+	}
+	if result != nil {
+		if err := json.Unmarshal(msg["result"], result); err != nil {
+			c.t.Fatalf("%s: invalid result %s: %v", method, msg["result"], err)
+		}
+	}
+	return msg["error"]
+}
+
+func startTestStratum(t *testing.T, difficulty float64) (*Ethash, *Stratum) {
+	ethash := New(Config{PowMode: ModeTest, Log: testlog.Logger(t, log.LvlWarn)}, nil, false)
+	s, err := ethash.StartStratum("127.0.0.1:0", difficulty)
+	if err != nil {
+		ethash.Close()
+		t.Fatalf("failed to start stratum server: %v", err)
+	}
+	return ethash, s
+}
+
+// findNonce searches for a nonce with the given prefix whose proof-of-work is
+// below or above the boundary.
+func findNonce(ethash *Ethash, header *types.Header, prefix []byte, target *big.Int, below bool) (uint64, common.Hash) {
+	hash := ethash.SealHash(header)
+	for i := uint64(0); ; i++ {
+		nonce := i
+		if len(prefix) > 0 {
+			nonce = uint64(binary.BigEndian.Uint16(prefix))<<48 | i
+		}
+		digest, result := ethash.hashimoto(header.Number.Uint64(), hash.Bytes(), nonce)
+		if (new(big.Int).SetBytes(result).Cmp(target) <= 0) == below {
+			return nonce, common.BytesToHash(digest)
+		}
+	}
+}
+
+func TestShareTarget(t *testing.T) {
+	block := new(big.Int).Lsh(common.Big1, 200)
+
+	tests := []struct {
+		difficulty float64
+		target     *big.Int
+		effective  float64
+	}{
+		{1, stratumBaseTarget, 1},
+		{2, new(big.Int).Rsh(stratumBaseTarget, 1), 2},
+		{0.5, new(big.Int).Lsh(stratumBaseTarget, 1), 0.5},
+		{1e12, block, float64(0xffff) * (1 << 8)}, // capped at the block boundary
+		{1e-20, maxTarget, 0},                     // capped at the highest boundary
+	}
+	for i, tt := range tests {
+		target, effective := shareTarget(tt.difficulty, block)
+		if target.Cmp(tt.target) != 0 {
+			t.Errorf("test %d: target mismatch: have %x, want %x", i, target, tt.target)
+		}
+		if tt.effective != 0 && effective != tt.effective {
+			t.Errorf("test %d: difficulty mismatch: have %v, want %v", i, effective, tt.effective)
+		}
+	}
+}
+
+// Tests that NiceHash style miners get the work pushed and their shares are
+// accounted and submitted to the remote sealer.
+func TestStratumNiceHash(t *testing.T) {
+	ethash, s := startTestStratum(t, 1)
+	defer ethash.Close()
+
+	c := dialStratum(t, s)
+	defer c.conn.Close()
+
+	var subscription []interface{}
+	if err := c.call(&subscription, "mining.subscribe", "", "miner/1.0", stratumVersion); string(err) != "null" {
+		t.Fatalf("subscription failed: %s", err)
+	}
+	if len(subscription) != 2 {
+		t.Fatalf("invalid subscription: %v", subscription)
+	}
+	extranonce, _ := hex.DecodeString(subscription[1].(string))
+	if len(extranonce) != stratumExtranonceSize {
+		t.Fatalf("invalid extranonce %v", subscription[1])
+	}
+	var authorized bool
+	if c.call(&authorized, "mining.authorize", "", "0x0000000000000000000000000000000000000001.rig", "d=1"); !authorized {
+		t.Fatalf("worker not authorized")
+	}
+	// Push some work, half of the nonces sealing the block
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2)}
+	results := make(chan *types.Block, 1)
+	ethash.remote.workCh <- &sealTask{block: types.NewBlockWithHeader(header), results: results}
+
+	if msg := c.read(); string(msg["method"]) != `"mining.set_difficulty"` {
+		t.Fatalf("expected difficulty, got %v", msg)
+	}
+	msg := c.read()
+	if string(msg["method"]) != `"mining.notify"` {
+		t.Fatalf("expected notification, got %v", msg)
+	}
+	var params []interface{}
+	json.Unmarshal(msg["params"], &params)
+	job := params[0].(string)
+	if want := hex.EncodeToString(ethash.SealHash(header).Bytes()); params[2] != want {
+		t.Fatalf("header hash mismatch: have %v, want %v", params[2], want)
+	}
+	// Shares above the block boundary are rejected, the ones below seal the block
+	blockTarget := new(big.Int).Div(two256, header.Difficulty)
+	suffix := func(nonce uint64) string {
+		enc := types.EncodeNonce(nonce)
+		return hex.EncodeToString(enc[stratumExtranonceSize:])
+	}
+	low, _ := findNonce(ethash, header, extranonce, blockTarget, false)
+	if err := c.call(nil, "mining.submit", "", "rig", job, suffix(low)); errorCode(err) != 23 {
+		t.Errorf("low difficulty share not rejected: %s", err)
+	}
+	nonce, _ := findNonce(ethash, header, extranonce, blockTarget, true)
+	var accepted bool
+	if err := c.call(&accepted, "mining.submit", "", "rig", job, suffix(nonce)); !accepted {
+		t.Fatalf("share rejected: %s", err)
+	}
+	select {
+	case block := <-results:
+		if block.Nonce() != nonce {
+			t.Errorf("sealed nonce mismatch: have %x, want %x", block.Nonce(), nonce)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("block not sealed")
+	}
+	if err := c.call(nil, "mining.submit", "", "rig", job, suffix(nonce)); errorCode(err) != 22 {
+		t.Errorf("duplicate share not rejected: %s", err)
+	}
+	if err := c.call(nil, "mining.submit", "", "rig", hex.EncodeToString(make([]byte, 32)), suffix(nonce)); errorCode(err) != 21 {
+		t.Errorf("stale share not rejected: %s", err)
+	}
+	workers := s.Workers()
+	if len(workers) != 1 {
+		t.Fatalf("worker count mismatch: have %d, want 1", len(workers))
+	}
+	w := workers[0]
+	if w.Accepted != 1 || w.Rejected != 2 || w.Stale != 1 || w.Blocks != 1 || w.Connections != 1 {
+		t.Errorf("share accounting mismatch: %+v", w)
+	}
+}
+
+// Tests that ETHProxy style miners can fetch work, submit solutions and report
+// their hashrate.
+func TestStratumProxy(t *testing.T) {
+	ethash, s := startTestStratum(t, 1e-9)
+	defer ethash.Close()
+
+	c := dialStratum(t, s)
+	defer c.conn.Close()
+
+	var authorized bool
+	if c.call(&authorized, "eth_submitLogin", "rig", "0x0000000000000000000000000000000000000001", "x"); !authorized {
+		t.Fatalf("worker not authorized")
+	}
+	if err := c.call(nil, "eth_getWork", ""); string(err) == "null" {
+		t.Fatalf("work returned before sealing started")
+	}
+	header := &types.Header{Number: big.NewInt(1), Difficulty: big.NewInt(2)}
+	results := make(chan *types.Block, 1)
+	ethash.remote.workCh <- &sealTask{block: types.NewBlockWithHeader(header), results: results}
+
+	// Work is pushed to the miner, with the share boundary capped by the block one
+	var pushed [4]string
+	msg := c.read()
+	if string(msg["id"]) != "0" {
+		t.Fatalf("expected pushed work, got %v", msg)
+	}
+	json.Unmarshal(msg["result"], &pushed)
+	blockTarget := new(big.Int).Div(two256, header.Difficulty)
+	if want := ethash.SealHash(header).Hex(); pushed[0] != want {
+		t.Errorf("header hash mismatch: have %s, want %s", pushed[0], want)
+	}
+	if have := common.HexToHash(pushed[2]).Big(); have.Cmp(blockTarget) != 0 {
+		t.Errorf("share boundary mismatch: have %x, want %x", have, blockTarget)
+	}
+	var work [4]string
+	c.call(&work, "eth_getWork", "")
+	if work != pushed {
+		t.Errorf("work mismatch: have %v, want %v", work, pushed)
+	}
+	// Solutions with a wrong mix digest are rejected
+	nonce, mix := findNonce(ethash, header, nil, blockTarget, true)
+
+	var accepted bool
+	if c.call(&accepted, "eth_submitWork", "rig", types.EncodeNonce(nonce), ethash.SealHash(header), common.Hash{}); accepted {
+		t.Fatalf("share with invalid mix digest accepted")
+	}
+	if err := c.call(&accepted, "eth_submitWork", "rig", types.EncodeNonce(nonce), ethash.SealHash(header), mix); !accepted {
+		t.Fatalf("share rejected: %s", err)
+	}
+	select {
+	case block := <-results:
+		if block.MixDigest() != mix {
+			t.Errorf("sealed mix digest mismatch: have %x, want %x", block.MixDigest(), mix)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("block not sealed")
+	}
+	// Reported hashrates are accounted by the node
+	if c.call(&accepted, "eth_submitHashrate", "rig", hexutil.Uint64(1000), common.Hash{1}); !accepted {
+		t.Fatalf("hashrate rejected")
+	}
+	if rate := ethash.Hashrate(); rate < 1000 {
+		t.Errorf("hashrate mismatch: have %v, want at least 1000", rate)
+	}
+	workers := s.Workers()
+	if len(workers) != 1 {
+		t.Fatalf("worker count mismatch: have %d, want 1", len(workers))
+	}
+	w := workers[0]
+	if want := "0x0000000000000000000000000000000000000001.rig"; w.Name != want {
+		t.Errorf("worker name mismatch: have %s, want %s", w.Name, want)
+	}
+	if w.Accepted != 1 || w.Rejected != 1 || w.Blocks != 1 || w.Hashrate != 1000 {
+		t.Errorf("share accounting mismatch: %+v", w)
+	}
+}
+
+// errorCode returns the code of a NiceHash style error.
+func errorCode(blob json.RawMessage) int {
+	var err []interface{}
+	if json.Unmarshal(blob, &err) != nil || len(err) == 0 {
+		return 0
+	}
+	code, _ := err[0].(float64)
+	return int(code)
+}
@@ -130,3 +130,51 @@ func TestCalcBaseFee(t *testing.T) {
 		}
 	}
 }
+
+// TestCalcBaseFeeEIP1559Config tests the base fee calculation with custom fee
+// market parameters.
+func TestCalcBaseFeeEIP1559Config(t *testing.T) {
+	config := config()
+	config.EIP1559 = &params.EIP1559Config{
+		BaseFeeChangeDenominator: 4,
+		ElasticityMultiplier:     4,
+		MinBaseFee:               big.NewInt(990000000),
+		InitialBaseFee:           big.NewInt(2000000000),
+	}
+	tests := []struct {
+		parentNumber    int64
+		parentGasUsed   uint64
+		expectedBaseFee int64
+	}{
+		{4, 0, 2000000000},         // first 1559-block, initial base fee
+		{32, 5000000, 1000000000},  // usage == target
+		{32, 4900000, 995000000},   // usage below target
+		{32, 4500000, 990000000},   // usage below target, bounded by the minimum
+		{32, 5500000, 1025000000},  // usage above target
+		{32, 20000000, 1750000000}, // usage at the limit
+	}
+	for i, test := range tests {
+		parent := &types.Header{
+			Number:   big.NewInt(test.parentNumber),
+			GasLimit: 20000000,
+			GasUsed:  test.parentGasUsed,
+			BaseFee:  big.NewInt(params.InitialBaseFee),
+		}
+		if have, want := CalcBaseFee(config, parent), big.NewInt(test.expectedBaseFee); have.Cmp(want) != 0 {
+			t.Errorf("test %d: have %d  want %d, ", i, have, want)
+		}
+	}
+	// The gas limit of the first 1559-block is scaled by the elasticity multiplier
+	parent := &types.Header{GasLimit: 10000000, Number: big.NewInt(4)}
+	for _, gasLimit := range []uint64{20000000, 40000000} {
+		header := &types.Header{GasLimit: gasLimit, BaseFee: big.NewInt(2000000000), Number: big.NewInt(5)}
+		if err := VerifyEip1559Header(config, parent, header); (err == nil) != (gasLimit == 40000000) {
+			t.Errorf("gas limit %d: unexpected verification result: %v", gasLimit, err)
+		}
+	}
+	// The initial base fee is never below the minimum
+	config.EIP1559.InitialBaseFee = big.NewInt(1)
+	if have := CalcBaseFee(config, parent); have.Cmp(config.EIP1559.MinBaseFee) != 0 {
+		t.Errorf("initial base fee mismatch: have %d, want %d", have, config.EIP1559.MinBaseFee)
+	}
+}
@@ -0,0 +1,463 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// addressQueryWindow is the initial number of blocks read at once when
+	// searching the address index, doubled until enough entries are found.
+	addressQueryWindow = 4096
+
+	// addressIndexLogInterval is the time between two progress reports while
+	// the address index is being generated.
+	addressIndexLogInterval = 8 * time.Second
+)
+
+// ErrAddressIndexUnavailable is returned when querying transactions by address
+// from a node not maintaining the address index.
+var ErrAddressIndexUnavailable = errors.New("address index not enabled")
+
+// AddressTracer returns the addresses participating in internal calls of each
+// transaction in a block, used to extend the address index beyond the senders,
+// recipients and created contracts of transactions.
+type AddressTracer func(block *types.Block) ([][]common.Address, error)
+
+// AddressTxCursor is a position within the address index, used to continue a
+// paginated query after the last transaction returned.
+type AddressTxCursor struct {
+	Number uint64 // Number of the block containing the transaction
+	Index  uint64 // Index of the transaction within the block
+}
+
+// AddressTxQuery is a query for the transactions an address participated in.
+type AddressTxQuery struct {
+	Address common.Address
+	From    uint64           // First block to search
+	To      uint64           // Last block to search
+	After   *AddressTxCursor // Position to continue after, or nil to start at the range boundary
+	Reverse bool             // Whether to return the newest transactions first
+	Limit   int              // Maximum number of transactions to return (0 = unlimited)
+}
+
+// follows reports whether the given entry comes after the query's cursor in the
+// requested order.
+func (q *AddressTxQuery) follows(entry rawdb.AddressTxEntry) bool {
+	if q.After == nil {
+		return true
+	}
+	if q.Reverse {
+		return entry.Number < q.After.Number || (entry.Number == q.After.Number && entry.Index < q.After.Index)
+	}
+	return entry.Number > q.After.Number || (entry.Number == q.After.Number && entry.Index > q.After.Index)
+}
+
+// AddressIndexer maintains an index from addresses to the transactions they
+// participated in as sender, recipient or created contract, and optionally in
+// internal calls. Only the most recent blocks are kept indexed if a limit is set,
+// similarly to the transaction lookup index.
+type AddressIndexer struct {
+	db     ethdb.Database
+	chain  *BlockChain
+	limit  uint64        // Number of recent blocks to keep indexed (0 = entire chain)
+	tracer AddressTracer // Optional tracer to collect internal call participants
+
+	lock sync.RWMutex // Lock preventing reads of the index during updates
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewAddressIndexer creates an address indexer and starts keeping the index in
+// sync with the canonical chain in the background.
+func NewAddressIndexer(db ethdb.Database, chain *BlockChain, limit uint64, tracer AddressTracer) *AddressIndexer {
+	ai := &AddressIndexer{
+		db:     db,
+		chain:  chain,
+		limit:  limit,
+		tracer: tracer,
+		quit:   make(chan struct{}),
+	}
+	ai.wg.Add(1)
+	go ai.loop()
+	return ai
+}
+
+// Close stops the indexer, waiting for any running update to terminate.
+func (ai *AddressIndexer) Close() {
+	close(ai.quit)
+	ai.wg.Wait()
+}
+
+// loop updates the index whenever the chain head changes. Updates run in the
+// background to avoid stalling the chain event feed.
+func (ai *AddressIndexer) loop() {
+	defer ai.wg.Done()
+
+	headCh := make(chan ChainHeadEvent, 1)
+	sub := ai.chain.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	var (
+		done    = make(chan struct{}) // Non-nil if a background update is running
+		pending bool                  // Whether the head changed during the running update
+	)
+	go func() { ai.update(); done <- struct{}{} }()
+
+	for {
+		select {
+		case <-headCh:
+			if done != nil {
+				pending = true
+				continue
+			}
+			done = make(chan struct{})
+			go func() { ai.update(); done <- struct{}{} }()
+
+		case <-done:
+			done = nil
+			if pending {
+				pending, done = false, make(chan struct{})
+				go func() { ai.update(); done <- struct{}{} }()
+			}
+
+		case <-sub.Err():
+			if done != nil {
+				<-done
+			}
+			return
+
+		case <-ai.quit:
+			if done != nil {
+				log.Info("Waiting background address indexer to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// update brings the index in line with the current canonical chain, removing
+// reorged blocks, indexing new ones and pruning the ones beyond the limit.
+func (ai *AddressIndexer) update() {
+	var (
+		head          = ai.chain.CurrentBlock().NumberU64()
+		indexed       bool
+		tail, last    uint64
+		start, logged = time.Now(), time.Now()
+	)
+	if t, h := rawdb.ReadAddressIndexRange(ai.db); t != nil {
+		indexed, tail, last = true, *t, *h
+	}
+	// Remove any indexed blocks which are not canonical any more
+	for indexed {
+		hash, _ := rawdb.ReadAddressBlockHash(ai.db, last)
+		if last <= head && hash == rawdb.ReadCanonicalHash(ai.db, last) {
+			break
+		}
+		batch := ai.db.NewBatch()
+		rawdb.DeleteAddressBlock(ai.db, batch, last)
+		if last == tail {
+			rawdb.DeleteAddressIndexRange(batch)
+			indexed = false
+		} else {
+			last--
+			rawdb.WriteAddressIndexRange(batch, tail, last)
+		}
+		if !ai.write(batch) {
+			return
+		}
+	}
+	// Determine the range of blocks to keep indexed. If the index fell behind by
+	// more than the limit, drop it altogether instead of indexing stale blocks.
+	first := uint64(0)
+	if ai.limit != 0 && head+1 > ai.limit {
+		first = head + 1 - ai.limit
+	}
+	if indexed && last+1 < first {
+		if !ai.unindex(tail, last+1) {
+			return
+		}
+		batch := ai.db.NewBatch()
+		rawdb.DeleteAddressIndexRange(batch)
+		if !ai.write(batch) {
+			return
+		}
+		indexed = false
+	}
+	// Index any blocks missing below the tail, e.g. if the limit was raised
+	if indexed && first < tail {
+		if !ai.index(first, tail, func(batch ethdb.Batch, number uint64) {}) {
+			return
+		}
+		batch := ai.db.NewBatch()
+		rawdb.WriteAddressIndexRange(batch, first, last)
+		if !ai.write(batch) {
+			return
+		}
+		tail = first
+	}
+	// Index all new blocks up to the current head
+	from := first
+	if indexed {
+		from = last + 1
+	} else {
+		tail = first
+	}
+	if from <= head {
+		log.Debug("Updating address index", "from", from, "head", head)
+	}
+	progress := func(batch ethdb.Batch, number uint64) {
+		rawdb.WriteAddressIndexRange(batch, tail, number)
+		if time.Since(logged) > addressIndexLogInterval {
+			log.Info("Indexing transactions by address", "number", number, "head", head, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if !ai.index(from, head+1, progress) {
+		return
+	}
+	// Prune all blocks beyond the limit
+	if tail < first {
+		if !ai.unindex(tail, first) {
+			return
+		}
+		batch := ai.db.NewBatch()
+		rawdb.WriteAddressIndexRange(batch, first, head)
+		ai.write(batch)
+	}
+}
+
+// index adds the canonical blocks in the range [from, to) to the index. The
+// progress callback is invoked with each batch before it is written out. It
+// returns false if the indexer was closed in the meantime, or if a block could
+// not be indexed. Blocks before that one are still written out, the failed one
+// is retried by the next update.
+func (ai *AddressIndexer) index(from, to uint64, progress func(batch ethdb.Batch, number uint64)) bool {
+	batch := ai.db.NewBatch()
+	for number := from; number < to; number++ {
+		select {
+		case <-ai.quit:
+			return false
+		default:
+		}
+		block := ai.chain.GetBlockByNumber(number)
+		if block == nil {
+			log.Warn("Missing block for address index", "number", number)
+			return false
+		}
+		entries, err := ai.blockEntries(block)
+		if err != nil {
+			log.Error("Failed to trace internal calls for address index", "number", number, "hash", block.Hash(), "err", err)
+			if number > from {
+				progress(batch, number-1)
+				ai.write(batch)
+			}
+			return false
+		}
+		rawdb.WriteAddressBlock(batch, number, block.Hash(), entries)
+		if batch.ValueSize() > ethdb.IdealBatchSize || number == to-1 {
+			progress(batch, number)
+			if !ai.write(batch) {
+				return false
+			}
+			batch.Reset()
+		}
+	}
+	return true
+}
+
+// unindex removes the blocks in the range [from, to) from the index. It returns
+// false if the indexer was closed in the meantime.
+func (ai *AddressIndexer) unindex(from, to uint64) bool {
+	batch := ai.db.NewBatch()
+	for number := from; number < to; number++ {
+		select {
+		case <-ai.quit:
+			return false
+		default:
+		}
+		rawdb.DeleteAddressBlock(ai.db, batch, number)
+		if batch.ValueSize() > ethdb.IdealBatchSize || number == to-1 {
+			if !ai.write(batch) {
+				return false
+			}
+			batch.Reset()
+		}
+	}
+	return true
+}
+
+// write flushes a batch of index updates into the database.
+func (ai *AddressIndexer) write(batch ethdb.Batch) bool {
+	ai.lock.Lock()
+	defer ai.lock.Unlock()
+
+	if err := batch.Write(); err != nil {
+		log.Error("Failed to write address index", "err", err)
+		return false
+	}
+	return true
+}
+
+// blockEntries assembles the address index entries of a block. An error is
+// returned if the internal calls of the block can't be traced.
+func (ai *AddressIndexer) blockEntries(block *types.Block) ([]rawdb.AddressTxEntry, error) {
+	var (
+		signer   = types.MakeSigner(ai.chain.Config(), block.Number())
+		internal [][]common.Address
+		entries  []rawdb.AddressTxEntry
+	)
+	if ai.tracer != nil && len(block.Transactions()) > 0 {
+		var err error
+		if internal, err = ai.tracer(block); err != nil {
+			return nil, err
+		}
+	}
+	for i, tx := range block.Transactions() {
+		var (
+			index = uint64(i)
+			seen  = make(map[common.Address]int)
+		)
+		add := func(address common.Address, role byte) {
+			if pos, ok := seen[address]; ok {
+				entries[pos].Roles |= role
+				return
+			}
+			seen[address] = len(entries)
+			entries = append(entries, rawdb.AddressTxEntry{Address: address, Number: block.NumberU64(), Index: index, Roles: role})
+		}
+		from, err := types.Sender(signer, tx)
+		if err == nil {
+			add(from, rawdb.AddressTxSender)
+		}
+		if to := tx.To(); to != nil {
+			add(*to, rawdb.AddressTxRecipient)
+		} else if err == nil {
+			add(crypto.CreateAddress(from, tx.Nonce()), rawdb.AddressTxCreated)
+		}
+		if i < len(internal) {
+			for _, address := range internal[i] {
+				add(address, rawdb.AddressTxInternal)
+			}
+		}
+	}
+	return entries, nil
+}
+
+// Status returns the range of blocks currently covered by the index.
+func (ai *AddressIndexer) Status() (tail uint64, head uint64, ok bool) {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	t, h := rawdb.ReadAddressIndexRange(ai.db)
+	if t == nil {
+		return 0, 0, false
+	}
+	return *t, *h, true
+}
+
+// Internal reports whether the index includes the participants of internal calls.
+func (ai *AddressIndexer) Internal() bool {
+	return ai.tracer != nil
+}
+
+// Query retrieves the transactions an address participated in, as far as they
+// are covered by the index.
+func (ai *AddressIndexer) Query(q AddressTxQuery) ([]rawdb.AddressTxEntry, error) {
+	ai.lock.RLock()
+	defer ai.lock.RUnlock()
+
+	tail, head := rawdb.ReadAddressIndexRange(ai.db)
+	if tail == nil {
+		return nil, nil
+	}
+	from, to := q.From, q.To
+	if from < *tail {
+		from = *tail
+	}
+	if to > *head {
+		to = *head
+	}
+	if q.After != nil {
+		if q.Reverse && q.After.Number < to {
+			to = q.After.Number
+		}
+		if !q.Reverse && q.After.Number > from {
+			from = q.After.Number
+		}
+	}
+	var (
+		entries []rawdb.AddressTxEntry
+		window  = uint64(addressQueryWindow)
+	)
+	for from <= to && (q.Limit <= 0 || len(entries) < q.Limit) {
+		// Read the next window of blocks in the requested direction
+		begin, end := from, to
+		if to-from >= window {
+			if q.Reverse {
+				begin = to - window + 1
+			} else {
+				end = from + window - 1
+			}
+		}
+		batch, err := rawdb.ReadAddressTxs(ai.db, q.Address, begin, end)
+		if err != nil {
+			return nil, err
+		}
+		for i := range batch {
+			entry := batch[i]
+			if q.Reverse {
+				entry = batch[len(batch)-1-i]
+			}
+			if !q.follows(entry) {
+				continue
+			}
+			entries = append(entries, entry)
+			if q.Limit > 0 && len(entries) == q.Limit {
+				break
+			}
+		}
+		// Advance the range past the window, taking care not to wrap around
+		if q.Reverse {
+			if begin == from {
+				break
+			}
+			to = begin - 1
+		} else {
+			if end == to {
+				break
+			}
+			from = end + 1
+		}
+		window *= 2
+	}
+	return entries, nil
+}
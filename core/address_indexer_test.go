@@ -0,0 +1,225 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the address index tracks senders, recipients and created contracts,
+// supports paginated queries in both directions, follows reorgs and prunes blocks
+// beyond the configured limit.
+func TestAddressIndexer(t *testing.T) {
+	var (
+		gendb  = rawdb.NewMemoryDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		first  = common.Address{0x01}
+		second = common.Address{0x02}
+		funds  = big.NewInt(100000000000000000)
+		gspec  = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{sender: {Balance: funds}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	transfer := func(recipient common.Address) func(int, *BlockGen) {
+		return func(i int, block *BlockGen) {
+			var to *common.Address
+			if i != 1 {
+				to = &recipient
+			}
+			tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+				Nonce:    block.TxNonce(sender),
+				To:       to,
+				Value:    big.NewInt(1000),
+				Gas:      100000,
+				GasPrice: block.header.BaseFee,
+			}), signer, key)
+			if err != nil {
+				panic(err)
+			}
+			block.AddTx(tx)
+		}
+	}
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 8, transfer(first))
+	forks, _ := GenerateChain(gspec.Config, blocks[3], ethash.NewFaker(), gendb, 6, transfer(second))
+
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	waitIndexed := func(indexer *AddressIndexer, tail, head uint64) {
+		t.Helper()
+		for i := 0; i < 500; i++ {
+			if have, haveHead, ok := indexer.Status(); ok && have == tail && haveHead == head {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		have, haveHead, _ := indexer.Status()
+		t.Fatalf("index range mismatch: have [%d, %d], want [%d, %d]", have, haveHead, tail, head)
+	}
+	check := func(indexer *AddressIndexer, query AddressTxQuery, want ...uint64) {
+		t.Helper()
+		entries, err := indexer.Query(query)
+		if err != nil {
+			t.Fatalf("query failed: %v", err)
+		}
+		if len(entries) != len(want) {
+			t.Fatalf("entry count mismatch: have %d, want %d", len(entries), len(want))
+		}
+		for i, entry := range entries {
+			if entry.Number != want[i] || entry.Index != 0 {
+				t.Errorf("entry %d: position mismatch: have #%d/%d, want #%d/0", i, entry.Number, entry.Index, want[i])
+			}
+		}
+	}
+	indexer := NewAddressIndexer(db, chain, 0, nil)
+	waitIndexed(indexer, 0, 8)
+
+	// Check pagination in both directions
+	check(indexer, AddressTxQuery{Address: sender, To: 8, Limit: 3}, 1, 2, 3)
+	check(indexer, AddressTxQuery{Address: sender, To: 8, Limit: 3, After: &AddressTxCursor{Number: 3}}, 4, 5, 6)
+	check(indexer, AddressTxQuery{Address: sender, To: 8, Limit: 3, Reverse: true}, 8, 7, 6)
+	check(indexer, AddressTxQuery{Address: sender, To: 8, Reverse: true, After: &AddressTxCursor{Number: 3}}, 2, 1)
+	check(indexer, AddressTxQuery{Address: first, From: 3, To: 5}, 3, 4, 5)
+
+	// Check the roles recorded for a contract creation
+	created := crypto.CreateAddress(sender, 1)
+	entries, _ := indexer.Query(AddressTxQuery{Address: created, To: 8})
+	if len(entries) != 1 || entries[0].Number != 2 || entries[0].Roles != rawdb.AddressTxCreated {
+		t.Fatalf("contract creation mismatch: have %+v", entries)
+	}
+	entries, _ = indexer.Query(AddressTxQuery{Address: sender, From: 2, To: 2})
+	if len(entries) != 1 || entries[0].Roles != rawdb.AddressTxSender {
+		t.Fatalf("sender mismatch: have %+v", entries)
+	}
+
+	// Reorg the chain and check that the index follows
+	if _, err := chain.InsertChain(forks); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	waitIndexed(indexer, 0, 10)
+	check(indexer, AddressTxQuery{Address: first, To: 10}, 1, 3, 4)
+	check(indexer, AddressTxQuery{Address: second, To: 10}, 5, 7, 8, 9, 10)
+	indexer.Close()
+
+	// Restart the indexer with a limit and check that old blocks are pruned
+	indexer = NewAddressIndexer(db, chain, 4, nil)
+	defer indexer.Close()
+
+	waitIndexed(indexer, 7, 10)
+	check(indexer, AddressTxQuery{Address: sender, To: 10}, 7, 8, 9, 10)
+	if entries, _ := rawdb.ReadAddressTxs(db, sender, 0, 6); len(entries) != 0 {
+		t.Fatalf("pruned entries still present: %+v", entries)
+	}
+}
+
+// Tests that the address index doesn't advance past blocks whose internal calls
+// can't be traced, and that they are indexed once tracing succeeds.
+func TestAddressIndexerTraceFailure(t *testing.T) {
+	var (
+		gendb  = rawdb.NewMemoryDatabase()
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		sender = crypto.PubkeyToAddress(key.PublicKey)
+		gspec  = &Genesis{
+			Config:  params.TestChainConfig,
+			Alloc:   GenesisAlloc{sender: {Balance: big.NewInt(100000000000000000)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		genesis = gspec.MustCommit(gendb)
+		signer  = types.LatestSigner(gspec.Config)
+	)
+	blocks, _ := GenerateChain(gspec.Config, genesis, ethash.NewFaker(), gendb, 6, func(i int, block *BlockGen) {
+		tx, err := types.SignTx(types.NewTx(&types.LegacyTx{
+			Nonce:    block.TxNonce(sender),
+			To:       &common.Address{0x01},
+			Value:    big.NewInt(1000),
+			Gas:      params.TxGas,
+			GasPrice: block.header.BaseFee,
+		}), signer, key)
+		if err != nil {
+			panic(err)
+		}
+		block.AddTx(tx)
+	})
+	db := rawdb.NewMemoryDatabase()
+	gspec.MustCommit(db)
+	chain, err := NewBlockChain(db, nil, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	var failing int32 = 1
+	tracer := func(block *types.Block) ([][]common.Address, error) {
+		if block.NumberU64() == 4 && atomic.LoadInt32(&failing) == 1 {
+			return nil, errors.New("state unavailable")
+		}
+		return [][]common.Address{{common.Address{0x02}}}, nil
+	}
+	waitIndexed := func(indexer *AddressIndexer, head uint64) {
+		t.Helper()
+		for i := 0; i < 500; i++ {
+			if _, have, ok := indexer.Status(); ok && have == head {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		_, have, _ := indexer.Status()
+		t.Fatalf("index head mismatch: have %d, want %d", have, head)
+	}
+	indexer := NewAddressIndexer(db, chain, 0, tracer)
+	waitIndexed(indexer, 3)
+	indexer.Close()
+	if entries, _ := rawdb.ReadAddressTxs(db, common.Address{0x02}, 0, 6); len(entries) != 3 {
+		t.Fatalf("wrong number of internal entries: have %d, want 3", len(entries))
+	}
+	// Restart the indexer with working tracing, the rest of the chain is indexed
+	atomic.StoreInt32(&failing, 0)
+	indexer = NewAddressIndexer(db, chain, 0, tracer)
+	defer indexer.Close()
+
+	waitIndexed(indexer, 6)
+	if entries, _ := rawdb.ReadAddressTxs(db, common.Address{0x02}, 0, 6); len(entries) != 6 {
+		t.Fatalf("wrong number of internal entries: have %d, want 6", len(entries))
+	}
+}
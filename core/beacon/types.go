@@ -72,6 +72,26 @@ type executableDataMarshaling struct {
 	Transactions  []hexutil.Bytes
 }
 
+//go:generate go run github.com/fjl/gencodec -type ExecutionPayloadEnvelope -field-override executionPayloadEnvelopeMarshaling -out gen_epe.go
+
+// ExecutionPayloadEnvelope is a payload built by the execution engine together
+// with its value, the fees earned by the fee recipient.
+type ExecutionPayloadEnvelope struct {
+	ExecutionPayload *ExecutableDataV1 `json:"executionPayload" gencodec:"required"`
+	BlockValue       *big.Int          `json:"blockValue"       gencodec:"required"`
+}
+
+// JSON type overrides for ExecutionPayloadEnvelope.
+type executionPayloadEnvelopeMarshaling struct {
+	BlockValue *hexutil.Big
+}
+
+// ExecutionPayloadBodyV1 is the body of an execution payload, as retrieved by
+// engine_getPayloadBodiesByHashV1 and engine_getPayloadBodiesByRangeV1.
+type ExecutionPayloadBodyV1 struct {
+	TransactionData []hexutil.Bytes `json:"transactions"`
+}
+
 type PayloadStatusV1 struct {
 	Status          string       `json:"status"`
 	LatestValidHash *common.Hash `json:"latestValidHash"`
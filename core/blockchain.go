@@ -73,6 +73,7 @@ var (
 	blockReorgMeter         = metrics.NewRegisteredMeter("chain/reorg/executes", nil)
 	blockReorgAddMeter      = metrics.NewRegisteredMeter("chain/reorg/add", nil)
 	blockReorgDropMeter     = metrics.NewRegisteredMeter("chain/reorg/drop", nil)
+	blockReorgRefusedMeter  = metrics.NewRegisteredMeter("chain/reorg/refused", nil)
 	blockReorgInvalidatedTx = metrics.NewRegisteredMeter("chain/reorg/invalidTx", nil)
 
 	blockPrefetchExecuteTimer   = metrics.NewRegisteredTimer("chain/prefetch/executes", nil)
@@ -173,6 +174,10 @@ type BlockChain struct {
 	//  * nil: disable tx reindexer/deleter, but still index new blocks
 	txLookupLimit uint64
 
+	// Reorg protection for chains without beacon finality
+	maxReorgDepth      uint64       // Maximum number of canonical blocks a reorg may drop (0 = unlimited)
+	finalityCheckpoint atomic.Value // Trusted block after which the chain is final (*FinalityCheckpoint)
+
 	hc            *HeaderChain
 	rmLogsFeed    event.Feed
 	chainFeed     event.Feed
@@ -180,6 +185,7 @@ type BlockChain struct {
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
 	blockProcFeed event.Feed
+	badChainFeed  event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
 
@@ -265,6 +271,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, chainConfig *par
 	bc.currentBlock.Store(nilBlock)
 	bc.currentFastBlock.Store(nilBlock)
 
+	var checkpoint *FinalityCheckpoint
+	if number, hash, ok := rawdb.ReadFinalityCheckpoint(db); ok {
+		checkpoint = &FinalityCheckpoint{Number: number, Hash: hash}
+	}
+	bc.finalityCheckpoint.Store(checkpoint)
+
 	// Initialize the chain with ancient data if it isn't empty.
 	var txIndexBlock uint64
 
@@ -1999,6 +2011,18 @@ func (bc *BlockChain) reorg(oldBlock, newBlock *types.Block) error {
 			return fmt.Errorf("invalid new chain")
 		}
 	}
+	// Refuse reorgs dropping finalized blocks
+	if err := bc.checkReorg(commonBlock, oldChain, newChain); err != nil {
+		head := commonBlock
+		if len(newChain) > 0 {
+			head = newChain[0]
+		}
+		log.Warn("Refused chain reorg", "number", commonBlock.Number(), "hash", commonBlock.Hash(),
+			"drop", len(oldChain), "add", len(newChain), "head", head.Hash(), "err", err)
+		blockReorgRefusedMeter.Mark(1)
+		bc.badChainFeed.Send(BadChainEvent{Block: head, Ancestor: commonBlock, Dropped: len(oldChain), Err: err})
+		return err
+	}
 	// Ensure the user sees large reorgs
 	if len(oldChain) > 0 && len(newChain) > 0 {
 		logFn := log.Info
@@ -26,9 +26,11 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/snapshot"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // CurrentHeader retrieves the current head header of the canonical chain. The
@@ -308,6 +310,87 @@ func (bc *BlockChain) StateAt(root common.Hash) (*state.StateDB, error) {
 	return state.New(root, bc.stateCache, bc.snaps)
 }
 
+// StorageSlot is a storage slot of an account, as returned by StorageRange.
+type StorageSlot struct {
+	Hash  common.Hash  // Hash of the slot key, which orders the storage
+	Key   *common.Hash // Slot key, if its preimage is known
+	Value common.Hash
+}
+
+// StorageRange returns at most limit storage slots of an account in the state
+// with the given root, ordered by the hash of their keys and starting at the
+// slot hash start. If there are further slots, the hash of the next one is
+// returned as well. The slots are read from the snapshot if it covers the state,
+// otherwise from the storage trie.
+func (bc *BlockChain) StorageRange(root common.Hash, address common.Address, start common.Hash, limit int) ([]StorageSlot, *common.Hash, error) {
+	if bc.snaps != nil {
+		if slots, next, err := bc.snapStorageRange(root, address, start, limit); err == nil {
+			return slots, next, nil
+		}
+	}
+	statedb, err := bc.StateAt(root)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr := statedb.StorageTrie(address)
+	if tr == nil {
+		return nil, nil, nil
+	}
+	var (
+		slots []StorageSlot
+		it    = trie.NewIterator(tr.NodeIterator(start[:]))
+	)
+	for it.Next() {
+		hash := common.BytesToHash(it.Key)
+		if len(slots) >= limit {
+			return slots, &hash, nil
+		}
+		slot, err := bc.storageSlot(hash, it.Value)
+		if err != nil {
+			return nil, nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil, it.Err
+}
+
+// snapStorageRange is the snapshot backed implementation of StorageRange.
+func (bc *BlockChain) snapStorageRange(root common.Hash, address common.Address, start common.Hash, limit int) ([]StorageSlot, *common.Hash, error) {
+	it, err := bc.snaps.StorageIterator(root, crypto.Keccak256Hash(address[:]), start)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer it.Release()
+
+	var slots []StorageSlot
+	for it.Next() {
+		hash := it.Hash()
+		if len(slots) >= limit {
+			return slots, &hash, nil
+		}
+		slot, err := bc.storageSlot(hash, it.Slot())
+		if err != nil {
+			return nil, nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return slots, nil, it.Error()
+}
+
+// storageSlot decodes a storage value and looks up the preimage of its key.
+func (bc *BlockChain) storageSlot(hash common.Hash, blob []byte) (StorageSlot, error) {
+	_, content, _, err := rlp.Split(blob)
+	if err != nil {
+		return StorageSlot{}, err
+	}
+	slot := StorageSlot{Hash: hash, Value: common.BytesToHash(content)}
+	if preimage := rawdb.ReadPreimage(bc.db, hash); preimage != nil {
+		key := common.BytesToHash(preimage)
+		slot.Key = &key
+	}
+	return slot, nil
+}
+
 // Config retrieves the chain's fork configuration.
 func (bc *BlockChain) Config() *params.ChainConfig { return bc.chainConfig }
 
@@ -381,6 +464,11 @@ func (bc *BlockChain) SubscribeChainSideEvent(ch chan<- ChainSideEvent) event.Su
 	return bc.scope.Track(bc.chainSideFeed.Subscribe(ch))
 }
 
+// SubscribeBadChainEvent registers a subscription of BadChainEvent.
+func (bc *BlockChain) SubscribeBadChainEvent(ch chan<- BadChainEvent) event.Subscription {
+	return bc.scope.Track(bc.badChainFeed.Subscribe(ch))
+}
+
 // SubscribeLogsEvent registers a subscription of []*types.Log.
 func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
@@ -17,6 +17,7 @@
 package core
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -3704,3 +3705,64 @@ func TestEIP1559Transition(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// Tests that storage ranges are served from the snapshot and the storage trie
+// alike, with correct pagination.
+func TestStorageRange(t *testing.T) {
+	var (
+		addr    = common.HexToAddress("0xaaaa")
+		storage = make(map[common.Hash]common.Hash)
+	)
+	for i := 1; i <= 10; i++ {
+		storage[common.BigToHash(big.NewInt(int64(i)))] = common.BigToHash(big.NewInt(int64(i * 100)))
+	}
+	gspec := &Genesis{
+		Config: params.TestChainConfig,
+		Alloc:  GenesisAlloc{addr: {Balance: big.NewInt(1), Storage: storage}},
+	}
+	for _, snapshots := range []bool{true, false} {
+		db := rawdb.NewMemoryDatabase()
+		genesis := gspec.MustCommit(db)
+		cacheConfig := *defaultCacheConfig
+		if !snapshots {
+			cacheConfig.SnapshotLimit = 0
+		}
+		chain, err := NewBlockChain(db, &cacheConfig, gspec.Config, ethash.NewFaker(), vm.Config{}, nil, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		var (
+			slots []StorageSlot
+			start common.Hash
+		)
+		for {
+			page, next, err := chain.StorageRange(genesis.Root(), addr, start, 3)
+			if err != nil {
+				t.Fatalf("snapshots %v: failed to retrieve range: %v", snapshots, err)
+			}
+			if len(page) > 3 {
+				t.Fatalf("snapshots %v: range exceeds limit: %d", snapshots, len(page))
+			}
+			slots = append(slots, page...)
+			if next == nil {
+				break
+			}
+			start = *next
+		}
+		if len(slots) != len(storage) {
+			t.Fatalf("snapshots %v: slot count mismatch: have %d, want %d", snapshots, len(slots), len(storage))
+		}
+		for i, slot := range slots {
+			if i > 0 && bytes.Compare(slots[i-1].Hash[:], slot.Hash[:]) >= 0 {
+				t.Errorf("snapshots %v: slots out of order at %d", snapshots, i)
+			}
+			if slot.Hash != crypto.Keccak256Hash(slot.Key[:]) {
+				t.Errorf("snapshots %v: slot %d: key %x doesn't match hash %x", snapshots, i, slot.Key, slot.Hash)
+			}
+			if want := storage[*slot.Key]; slot.Value != want {
+				t.Errorf("snapshots %v: slot %x: value %x, want %x", snapshots, *slot.Key, slot.Value, want)
+			}
+		}
+		chain.Stop()
+	}
+}
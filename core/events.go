@@ -41,3 +41,12 @@ type ChainSideEvent struct {
 }
 
 type ChainHeadEvent struct{ Block *types.Block }
+
+// BadChainEvent is posted when a reorg to a chain is refused for dropping
+// finalized blocks.
+type BadChainEvent struct {
+	Block    *types.Block // Head of the refused chain
+	Ancestor *types.Block // Common ancestor with the canonical chain
+	Dropped  int          // Number of canonical blocks the reorg would have dropped
+	Err      error        // Reason of the refusal
+}
@@ -0,0 +1,135 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	// ErrReorgTooDeep is returned if a reorg would drop more canonical blocks
+	// than the maximum reorg depth.
+	ErrReorgTooDeep = errors.New("reorg exceeds maximum depth")
+
+	// ErrReorgFinalized is returned if a reorg would replace the block of the
+	// finality checkpoint.
+	ErrReorgFinalized = errors.New("reorg conflicts with finality checkpoint")
+
+	// errCheckpointRewound is returned if a finality checkpoint is set below
+	// the current one.
+	errCheckpointRewound = errors.New("finality checkpoint below current one")
+)
+
+// FinalityCheckpoint is a trusted block after which the chain is treated as
+// final: reorgs replacing it are refused.
+type FinalityCheckpoint struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
+}
+
+// SetMaxReorgDepth sets the maximum number of canonical blocks a reorg may drop,
+// zero meaning reorgs of any depth are allowed.
+func (bc *BlockChain) SetMaxReorgDepth(depth uint64) {
+	atomic.StoreUint64(&bc.maxReorgDepth, depth)
+}
+
+// MaxReorgDepth returns the maximum number of canonical blocks a reorg may drop.
+func (bc *BlockChain) MaxReorgDepth() uint64 {
+	return atomic.LoadUint64(&bc.maxReorgDepth)
+}
+
+// FinalityCheckpoint returns the trusted block after which the chain is treated
+// as final, or nil if none was set.
+func (bc *BlockChain) FinalityCheckpoint() *FinalityCheckpoint {
+	return bc.finalityCheckpoint.Load().(*FinalityCheckpoint)
+}
+
+// SetFinalityCheckpoint sets the trusted block after which the chain is treated
+// as final. Checkpoints may only move forward. The checkpoint may be ahead of
+// the local chain, or even conflict with it, in which case the reorg to the
+// checkpointed chain is allowed.
+func (bc *BlockChain) SetFinalityCheckpoint(checkpoint FinalityCheckpoint) error {
+	if !bc.chainmu.TryLock() {
+		return errChainStopped
+	}
+	defer bc.chainmu.Unlock()
+
+	if current := bc.FinalityCheckpoint(); current != nil {
+		if *current == checkpoint {
+			return nil
+		}
+		if checkpoint.Number <= current.Number {
+			return fmt.Errorf("%w: %d <= %d", errCheckpointRewound, checkpoint.Number, current.Number)
+		}
+	}
+	rawdb.WriteFinalityCheckpoint(bc.db, checkpoint.Number, checkpoint.Hash)
+	bc.finalityCheckpoint.Store(&checkpoint)
+
+	if hash := bc.GetCanonicalHash(checkpoint.Number); hash != (common.Hash{}) && hash != checkpoint.Hash {
+		log.Warn("Local chain conflicts with finality checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash, "local", hash)
+	} else {
+		log.Info("Updated finality checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash)
+	}
+	return nil
+}
+
+// ReorgFloor returns the number of the highest canonical block a reorg may not
+// drop, either for being deeper than the maximum reorg depth or for being the
+// block of the finality checkpoint. Zero means reorgs are unrestricted.
+func (bc *BlockChain) ReorgFloor() uint64 {
+	var floor uint64
+	if depth, head := bc.MaxReorgDepth(), bc.CurrentBlock().NumberU64(); depth > 0 && head > depth {
+		floor = head - depth
+	}
+	if checkpoint := bc.FinalityCheckpoint(); checkpoint != nil && checkpoint.Number > floor {
+		if bc.GetCanonicalHash(checkpoint.Number) == checkpoint.Hash {
+			floor = checkpoint.Number
+		}
+	}
+	return floor
+}
+
+// checkReorg verifies that a reorg from the old to the new chain, forking off at
+// the given common ancestor, drops no finalized blocks. The chains are ordered
+// from their heads down.
+func (bc *BlockChain) checkReorg(ancestor *types.Block, oldChain, newChain types.Blocks) error {
+	if depth := bc.MaxReorgDepth(); depth > 0 && uint64(len(oldChain)) > depth {
+		return fmt.Errorf("%w: dropping %d blocks, maximum %d", ErrReorgTooDeep, len(oldChain), depth)
+	}
+	checkpoint := bc.FinalityCheckpoint()
+	if checkpoint == nil || ancestor.NumberU64() >= checkpoint.Number {
+		return nil
+	}
+	for _, block := range oldChain {
+		if block.NumberU64() == checkpoint.Number && block.Hash() == checkpoint.Hash {
+			return fmt.Errorf("%w: dropping block %d [%x…]", ErrReorgFinalized, checkpoint.Number, checkpoint.Hash[:4])
+		}
+	}
+	for _, block := range newChain {
+		if block.NumberU64() == checkpoint.Number && block.Hash() != checkpoint.Hash {
+			return fmt.Errorf("%w: block %d [%x…] instead of [%x…]", ErrReorgFinalized, checkpoint.Number, block.Hash().Bytes()[:4], checkpoint.Hash[:4])
+		}
+	}
+	return nil
+}
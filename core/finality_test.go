@@ -0,0 +1,151 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// newFinalityTestChain creates a chain of 10 blocks, returning it along with
+// its blocks.
+func newFinalityTestChain(t *testing.T) (ethdb.Database, *BlockChain, []*types.Block) {
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&Genesis{Config: params.TestChainConfig, BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
+	blocks, _ := GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, nil)
+
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return db, chain, blocks
+}
+
+// makeFork creates a fork of n blocks on top of the parent.
+func makeFork(db ethdb.Database, parent *types.Block, n int) []*types.Block {
+	blocks, _ := GenerateChain(params.TestChainConfig, parent, ethash.NewFaker(), db, n, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x01})
+	})
+	return blocks
+}
+
+func TestReorgMaxDepth(t *testing.T) {
+	db, chain, blocks := newFinalityTestChain(t)
+	defer chain.Stop()
+
+	chain.SetMaxReorgDepth(5)
+	if floor := chain.ReorgFloor(); floor != 5 {
+		t.Errorf("reorg floor mismatch: have %d, want 5", floor)
+	}
+	events := make(chan BadChainEvent, 1)
+	sub := chain.SubscribeBadChainEvent(events)
+	defer sub.Unsubscribe()
+
+	// A heavier fork dropping 7 blocks is refused
+	fork := makeFork(db, blocks[2], 12)
+	if _, err := chain.InsertChain(fork); !errors.Is(err, ErrReorgTooDeep) {
+		t.Fatalf("deep reorg error mismatch: have %v, want %v", err, ErrReorgTooDeep)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != blocks[9].Hash() {
+		t.Fatalf("head changed by refused reorg: have %d, want %d", head.NumberU64(), 10)
+	}
+	select {
+	case ev := <-events:
+		if ev.Ancestor.Hash() != blocks[2].Hash() || ev.Dropped != 7 || !errors.Is(ev.Err, ErrReorgTooDeep) {
+			t.Errorf("bad chain event mismatch: ancestor %d, dropped %d, err %v", ev.Ancestor.NumberU64(), ev.Dropped, ev.Err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no bad chain event")
+	}
+	// A heavier fork dropping 5 blocks is allowed
+	fork = makeFork(db, blocks[4], 10)
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to reorg: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != fork[9].Hash() {
+		t.Fatalf("head mismatch after reorg: have %d, want %d", head.NumberU64(), fork[9].NumberU64())
+	}
+}
+
+func TestReorgFinalityCheckpoint(t *testing.T) {
+	db, chain, blocks := newFinalityTestChain(t)
+	defer chain.Stop()
+
+	if err := chain.SetFinalityCheckpoint(FinalityCheckpoint{Number: 6, Hash: blocks[5].Hash()}); err != nil {
+		t.Fatalf("failed to set checkpoint: %v", err)
+	}
+	if floor := chain.ReorgFloor(); floor != 6 {
+		t.Errorf("reorg floor mismatch: have %d, want 6", floor)
+	}
+	// Forks replacing the checkpoint are refused, later ones are allowed
+	if _, err := chain.InsertChain(makeFork(db, blocks[4], 10)); !errors.Is(err, ErrReorgFinalized) {
+		t.Fatalf("finalized reorg error mismatch: have %v, want %v", err, ErrReorgFinalized)
+	}
+	fork := makeFork(db, blocks[5], 10)
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to reorg: %v", err)
+	}
+	// Checkpoints only move forward
+	if err := chain.SetFinalityCheckpoint(FinalityCheckpoint{Number: 5, Hash: blocks[4].Hash()}); !errors.Is(err, errCheckpointRewound) {
+		t.Errorf("rewound checkpoint error mismatch: have %v, want %v", err, errCheckpointRewound)
+	}
+	// A checkpoint conflicting with the local chain allows reorging to its chain
+	// but not to others
+	other := makeFork(db, blocks[2], 20)
+	if err := chain.SetFinalityCheckpoint(FinalityCheckpoint{Number: 8, Hash: other[4].Hash()}); err != nil {
+		t.Fatalf("failed to set checkpoint: %v", err)
+	}
+	if floor := chain.ReorgFloor(); floor != 0 {
+		t.Errorf("reorg floor mismatch: have %d, want 0", floor)
+	}
+	wrong, _ := GenerateChain(params.TestChainConfig, blocks[2], ethash.NewFaker(), db, 20, func(i int, b *BlockGen) {
+		b.SetCoinbase(common.Address{0x02})
+	})
+	if _, err := chain.InsertChain(wrong); !errors.Is(err, ErrReorgFinalized) {
+		t.Fatalf("conflicting reorg error mismatch: have %v, want %v", err, ErrReorgFinalized)
+	}
+	if _, err := chain.InsertChain(other); err != nil {
+		t.Fatalf("failed to reorg to checkpointed chain: %v", err)
+	}
+	if head := chain.CurrentBlock(); head.Hash() != other[19].Hash() {
+		t.Fatalf("head mismatch after reorg: have %d, want %d", head.NumberU64(), other[19].NumberU64())
+	}
+	// The checkpoint persists across restarts
+	chain.Stop()
+	chain, err := NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to reopen chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if have, want := chain.FinalityCheckpoint(), (&FinalityCheckpoint{Number: 8, Hash: other[4].Hash()}); have == nil || *have != *want {
+		t.Errorf("persisted checkpoint mismatch: have %v, want %v", have, want)
+	}
+}
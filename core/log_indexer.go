@@ -0,0 +1,181 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// logIndexThrottling is the time to wait between processing two consecutive
+	// index sections. It's useful during chain upgrades to prevent disk overload.
+	logIndexThrottling = 100 * time.Millisecond
+)
+
+// errCorruptLogIndex is returned if a log index entry cannot be decoded.
+var errCorruptLogIndex = errors.New("corrupt log index entry")
+
+// LogPosition identifies a single log within the canonical chain.
+type LogPosition struct {
+	Number uint64 // Number of the block containing the log
+	Index  uint   // Index of the log within the block
+}
+
+// LogIndexAddressTerm returns the log index search term of a contract address.
+func LogIndexAddressTerm(address common.Address) []byte {
+	return append([]byte{0}, address.Bytes()...)
+}
+
+// LogIndexTopicTerm returns the log index search term of a topic at the given
+// position within the log's topic list.
+func LogIndexTopicTerm(pos int, topic common.Hash) []byte {
+	return append([]byte{byte(pos + 1)}, topic.Bytes()...)
+}
+
+// DecodeLogIndexPositions parses an encoded list of log positions, as stored in
+// the log index for a single section starting at block number base.
+func DecodeLogIndexPositions(base uint64, blob []byte) ([]LogPosition, error) {
+	var (
+		positions []LogPosition
+		number    = base
+	)
+	for len(blob) > 0 {
+		delta, n := binary.Uvarint(blob)
+		if n <= 0 {
+			return nil, errCorruptLogIndex
+		}
+		blob = blob[n:]
+
+		index, n := binary.Uvarint(blob)
+		if n <= 0 {
+			return nil, errCorruptLogIndex
+		}
+		blob = blob[n:]
+
+		number += delta
+		positions = append(positions, LogPosition{Number: number, Index: uint(index)})
+	}
+	return positions, nil
+}
+
+// logIndexTerm is the in-progress list of positions of a single search term.
+type logIndexTerm struct {
+	last uint64 // Block number of the last position added (for delta encoding)
+	blob []byte // Encoded positions accumulated so far
+}
+
+// LogIndexer implements a core.ChainIndexer, building up an exact index from
+// contract addresses and positional topics to the blocks and log positions they
+// appear at, permitting log filtering without bloom false positives.
+type LogIndexer struct {
+	size    uint64                   // section size to generate the log index for
+	db      ethdb.Database           // database instance to write index data and metadata into
+	terms   map[string]*logIndexTerm // positions of all search terms in the current section
+	section uint64                   // Section is the section number being processed currently
+	head    common.Hash              // Head is the hash of the last header processed
+}
+
+// NewLogIndexer returns a chain indexer that generates the log index for the
+// canonical chain for fast logs filtering.
+func NewLogIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &LogIndexer{
+		db:   db,
+		size: size,
+	}
+	table := rawdb.NewTable(db, string(rawdb.LogIndexIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, logIndexThrottling, "logindex")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new log index section.
+func (l *LogIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	l.terms, l.section, l.head = make(map[string]*logIndexTerm), section, common.Hash{}
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the logs of a new header
+// into the index.
+func (l *LogIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	receipts := rawdb.ReadRawReceipts(l.db, hash, number)
+	if receipts == nil && header.Bloom != (types.Bloom{}) {
+		return fmt.Errorf("missing receipts for block #%d [%x]", number, hash)
+	}
+	var index uint64
+	for _, receipt := range receipts {
+		for _, log := range receipt.Logs {
+			l.add(LogIndexAddressTerm(log.Address), number, index)
+			for i, topic := range log.Topics {
+				l.add(LogIndexTopicTerm(i, topic), number, index)
+			}
+			index++
+		}
+	}
+	l.head = hash
+	return nil
+}
+
+// add appends a new log position to the given search term.
+func (l *LogIndexer) add(term []byte, number uint64, index uint64) {
+	entry := l.terms[string(term)]
+	if entry == nil {
+		entry = &logIndexTerm{last: l.section * l.size}
+		l.terms[string(term)] = entry
+	}
+	var buf [2 * binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], number-entry.last)
+	n += binary.PutUvarint(buf[n:], index)
+
+	entry.blob = append(entry.blob, buf[:n]...)
+	entry.last = number
+}
+
+// Commit implements core.ChainIndexerBackend, finalizing the log index section
+// and writing it out into the database. Any index data of the same section left
+// over from a previous (reorged) canonical chain is removed.
+func (l *LogIndexer) Commit() error {
+	batch := l.db.NewBatch()
+	rawdb.DeleteLogIndex(l.db, batch, l.section, l.section+1)
+
+	for term, entry := range l.terms {
+		rawdb.WriteLogIndex(batch, l.section, l.head, []byte(term), entry.blob)
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+	}
+	return batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (l *LogIndexer) Prune(threshold uint64) error {
+	return nil
+}
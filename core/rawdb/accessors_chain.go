@@ -242,6 +242,39 @@ func WriteLastPivotNumber(db ethdb.KeyValueWriter, pivot uint64) {
 	}
 }
 
+// finalityCheckpoint is the database encoding of a finality checkpoint.
+type finalityCheckpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// ReadFinalityCheckpoint retrieves the number and hash of the trusted block after
+// which the chain is treated as final, if any.
+func ReadFinalityCheckpoint(db ethdb.KeyValueReader) (uint64, common.Hash, bool) {
+	data, _ := db.Get(finalityCheckpointKey)
+	if len(data) == 0 {
+		return 0, common.Hash{}, false
+	}
+	var checkpoint finalityCheckpoint
+	if err := rlp.DecodeBytes(data, &checkpoint); err != nil {
+		log.Error("Invalid finality checkpoint in database", "err", err)
+		return 0, common.Hash{}, false
+	}
+	return checkpoint.Number, checkpoint.Hash, true
+}
+
+// WriteFinalityCheckpoint stores the number and hash of the trusted block after
+// which the chain is treated as final.
+func WriteFinalityCheckpoint(db ethdb.KeyValueWriter, number uint64, hash common.Hash) {
+	enc, err := rlp.EncodeToBytes(&finalityCheckpoint{Number: number, Hash: hash})
+	if err != nil {
+		log.Crit("Failed to encode finality checkpoint", "err", err)
+	}
+	if err := db.Put(finalityCheckpointKey, enc); err != nil {
+		log.Crit("Failed to store finality checkpoint", "err", err)
+	}
+}
+
 // ReadTxIndexTail retrieves the number of oldest indexed block
 // whose transaction indices has been indexed. If the corresponding entry
 // is non-existent in database it means the indexing has been finished.
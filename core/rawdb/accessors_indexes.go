@@ -18,6 +18,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -175,3 +176,195 @@ func DeleteBloombits(db ethdb.Database, bit uint, from uint64, to uint64) {
 		log.Crit("Failed to delete bloom bits", "err", it.Error())
 	}
 }
+
+// ReadLogIndex retrieves the encoded log positions belonging to the given section,
+// section head and search term (address or positional topic).
+func ReadLogIndex(db ethdb.KeyValueReader, section uint64, head common.Hash, term []byte) ([]byte, error) {
+	return db.Get(logIndexKey(section, head, term))
+}
+
+// WriteLogIndex stores the encoded log positions belonging to the given section,
+// section head and search term.
+func WriteLogIndex(db ethdb.KeyValueWriter, section uint64, head common.Hash, term []byte, positions []byte) {
+	if err := db.Put(logIndexKey(section, head, term), positions); err != nil {
+		log.Crit("Failed to store log index", "err", err)
+	}
+}
+
+// DeleteLogIndex removes all log index entries belonging to the given section
+// range, regardless of the section head they were generated for.
+func DeleteLogIndex(db ethdb.Iteratee, writer ethdb.KeyValueWriter, from uint64, to uint64) {
+	start := make([]byte, len(logIndexPrefix)+8)
+	copy(start, logIndexPrefix)
+	binary.BigEndian.PutUint64(start[len(logIndexPrefix):], from)
+
+	end := make([]byte, len(logIndexPrefix)+8)
+	copy(end, logIndexPrefix)
+	binary.BigEndian.PutUint64(end[len(logIndexPrefix):], to)
+
+	it := db.NewIterator(nil, start)
+	defer it.Release()
+
+	for it.Next() {
+		if bytes.Compare(it.Key(), end) >= 0 {
+			break
+		}
+		if len(it.Key()) <= len(logIndexPrefix)+8+common.HashLength {
+			continue
+		}
+		if err := writer.Delete(it.Key()); err != nil {
+			log.Crit("Failed to delete log index", "err", err)
+		}
+	}
+	if it.Error() != nil {
+		log.Crit("Failed to delete log index", "err", it.Error())
+	}
+}
+
+// Roles of an address within a transaction, as recorded in the address index.
+const (
+	AddressTxSender    byte = 1 << iota // Address is the sender of the transaction
+	AddressTxRecipient                  // Address is the recipient of the transaction
+	AddressTxCreated                    // Address is the contract created by the transaction
+	AddressTxInternal                   // Address participates in an internal call
+)
+
+// AddressTxEntry is a single record of the address index, referencing a
+// transaction in which an address participated.
+type AddressTxEntry struct {
+	Address common.Address
+	Number  uint64 // Number of the block containing the transaction
+	Index   uint64 // Index of the transaction within the block
+	Roles   byte   // Bitset of AddressTx* roles of the address in the transaction
+}
+
+// addressBlock is the list of address index entries written for a block, kept
+// to allow removing them when the block is reorged out or pruned.
+type addressBlock struct {
+	Hash    common.Hash
+	Entries []AddressTxEntry
+}
+
+// ReadAddressTxs retrieves the address index entries of the given address within
+// the block range [from, to], in ascending order.
+func ReadAddressTxs(db ethdb.Iteratee, address common.Address, from uint64, to uint64) ([]AddressTxEntry, error) {
+	prefix := append(addressTxPrefix, address.Bytes()...)
+
+	it := db.NewIterator(prefix, encodeBlockNumber(from))
+	defer it.Release()
+
+	var entries []AddressTxEntry
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(prefix)+16 || len(it.Value()) != 1 {
+			continue
+		}
+		number := binary.BigEndian.Uint64(key[len(prefix):])
+		if number > to {
+			break
+		}
+		entries = append(entries, AddressTxEntry{
+			Address: address,
+			Number:  number,
+			Index:   binary.BigEndian.Uint64(key[len(prefix)+8:]),
+			Roles:   it.Value()[0],
+		})
+	}
+	if err := it.Error(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// ReadAddressBlockHash retrieves the hash of the block indexed at the given
+// number in the address index.
+func ReadAddressBlockHash(db ethdb.KeyValueReader, number uint64) (common.Hash, bool) {
+	block := readAddressBlock(db, number)
+	if block == nil {
+		return common.Hash{}, false
+	}
+	return block.Hash, true
+}
+
+func readAddressBlock(db ethdb.KeyValueReader, number uint64) *addressBlock {
+	data, _ := db.Get(addressBlockKey(number))
+	if len(data) == 0 {
+		return nil
+	}
+	block := new(addressBlock)
+	if err := rlp.DecodeBytes(data, block); err != nil {
+		log.Error("Invalid address index block", "number", number, "err", err)
+		return nil
+	}
+	return block
+}
+
+// WriteAddressBlock stores the address index entries of a block.
+func WriteAddressBlock(db ethdb.KeyValueWriter, number uint64, hash common.Hash, entries []AddressTxEntry) {
+	for _, entry := range entries {
+		if err := db.Put(addressTxKey(entry.Address, entry.Number, entry.Index), []byte{entry.Roles}); err != nil {
+			log.Crit("Failed to store address index entry", "err", err)
+		}
+	}
+	data, err := rlp.EncodeToBytes(&addressBlock{Hash: hash, Entries: entries})
+	if err != nil {
+		log.Crit("Failed to encode address index block", "err", err)
+	}
+	if err := db.Put(addressBlockKey(number), data); err != nil {
+		log.Crit("Failed to store address index block", "err", err)
+	}
+}
+
+// DeleteAddressBlock removes all address index entries of the block indexed at
+// the given number.
+func DeleteAddressBlock(db ethdb.KeyValueReader, writer ethdb.KeyValueWriter, number uint64) {
+	if block := readAddressBlock(db, number); block != nil {
+		for _, entry := range block.Entries {
+			if err := writer.Delete(addressTxKey(entry.Address, entry.Number, entry.Index)); err != nil {
+				log.Crit("Failed to delete address index entry", "err", err)
+			}
+		}
+	}
+	if err := writer.Delete(addressBlockKey(number)); err != nil {
+		log.Crit("Failed to delete address index block", "err", err)
+	}
+}
+
+// ReadAddressIndexRange retrieves the range of blocks covered by the address
+// index, or nil if nothing has been indexed yet.
+func ReadAddressIndexRange(db ethdb.KeyValueReader) (tail *uint64, head *uint64) {
+	read := func(key []byte) *uint64 {
+		data, _ := db.Get(key)
+		if len(data) != 8 {
+			return nil
+		}
+		number := binary.BigEndian.Uint64(data)
+		return &number
+	}
+	tail, head = read(addressIndexTailKey), read(addressIndexHeadKey)
+	if tail == nil || head == nil {
+		return nil, nil
+	}
+	return tail, head
+}
+
+// WriteAddressIndexRange stores the range of blocks covered by the address index.
+func WriteAddressIndexRange(db ethdb.KeyValueWriter, tail uint64, head uint64) {
+	if err := db.Put(addressIndexTailKey, encodeBlockNumber(tail)); err != nil {
+		log.Crit("Failed to store address index tail", "err", err)
+	}
+	if err := db.Put(addressIndexHeadKey, encodeBlockNumber(head)); err != nil {
+		log.Crit("Failed to store address index head", "err", err)
+	}
+}
+
+// DeleteAddressIndexRange removes the range of blocks covered by the address
+// index, marking it empty.
+func DeleteAddressIndexRange(db ethdb.KeyValueWriter) {
+	if err := db.Delete(addressIndexTailKey); err != nil {
+		log.Crit("Failed to delete address index tail", "err", err)
+	}
+	if err := db.Delete(addressIndexHeadKey); err != nil {
+		log.Crit("Failed to delete address index head", "err", err)
+	}
+}
@@ -337,6 +337,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		storageSnaps    stat
 		preimages       stat
 		bloomBits       stat
+		logIndex        stat
+		addressIndex    stat
 		beaconHeaders   stat
 		cliqueSnaps     stat
 
@@ -398,6 +400,14 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 			bloomBits.Add(size)
 		case bytes.HasPrefix(key, BloomBitsIndexPrefix):
 			bloomBits.Add(size)
+		case bytes.HasPrefix(key, logIndexPrefix) && len(key) > (len(logIndexPrefix)+8+common.HashLength):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, LogIndexIndexPrefix):
+			logIndex.Add(size)
+		case bytes.HasPrefix(key, addressTxPrefix) && len(key) == (len(addressTxPrefix)+common.AddressLength+16):
+			addressIndex.Add(size)
+		case bytes.HasPrefix(key, addressBlockPrefix) && len(key) == (len(addressBlockPrefix)+8):
+			addressIndex.Add(size)
 		case bytes.HasPrefix(key, skeletonHeaderPrefix) && len(key) == (len(skeletonHeaderPrefix)+8):
 			beaconHeaders.Add(size)
 		case bytes.HasPrefix(key, []byte("clique-")) && len(key) == 7+common.HashLength:
@@ -416,6 +426,7 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 				databaseVersionKey, headHeaderKey, headBlockKey, headFastBlockKey, lastPivotKey,
 				fastTrieProgressKey, snapshotDisabledKey, SnapshotRootKey, snapshotJournalKey,
 				snapshotGeneratorKey, snapshotRecoveryKey, txIndexTailKey, fastTxLookupLimitKey,
+				addressIndexTailKey, addressIndexHeadKey, finalityCheckpointKey,
 				uncleanShutdownKey, badBlockKey, transitionStatusKey, skeletonSyncStatusKey,
 			} {
 				if bytes.Equal(key, meta) {
@@ -457,6 +468,8 @@ func InspectDatabase(db ethdb.Database, keyPrefix, keyStart []byte) error {
 		{"Key-Value store", "Block hash->number", hashNumPairings.Size(), hashNumPairings.Count()},
 		{"Key-Value store", "Transaction index", txLookups.Size(), txLookups.Count()},
 		{"Key-Value store", "Bloombit index", bloomBits.Size(), bloomBits.Count()},
+		{"Key-Value store", "Log index", logIndex.Size(), logIndex.Count()},
+		{"Key-Value store", "Address index", addressIndex.Size(), addressIndex.Count()},
 		{"Key-Value store", "Contract codes", codes.Size(), codes.Count()},
 		{"Key-Value store", "Trie nodes", tries.Size(), tries.Count()},
 		{"Key-Value store", "Trie preimages", preimages.Size(), preimages.Count()},
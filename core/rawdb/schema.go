@@ -42,6 +42,9 @@ var (
 	// lastPivotKey tracks the last pivot block used by fast sync (to reenable on sethead).
 	lastPivotKey = []byte("LastPivot")
 
+	// finalityCheckpointKey tracks the trusted block after which the chain is final.
+	finalityCheckpointKey = []byte("FinalityCheckpoint")
+
 	// fastTrieProgressKey tracks the number of trie entries imported during fast sync.
 	fastTrieProgressKey = []byte("TrieSync")
 
@@ -69,6 +72,12 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// addressIndexTailKey tracks the oldest block indexed in the address index.
+	addressIndexTailKey = []byte("AddressIndexTail")
+
+	// addressIndexHeadKey tracks the latest block indexed in the address index.
+	addressIndexHeadKey = []byte("AddressIndexHead")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -92,6 +101,9 @@ var (
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
+	logIndexPrefix        = []byte("g") // logIndexPrefix + section (uint64 big endian) + hash + term -> log positions
+	addressTxPrefix       = []byte("x") // addressTxPrefix + address + num (uint64 big endian) + tx index (uint64 big endian) -> address roles
+	addressBlockPrefix    = []byte("X") // addressBlockPrefix + num (uint64 big endian) -> address index entries of a block
 	SnapshotAccountPrefix = []byte("a") // SnapshotAccountPrefix + account hash -> account trie value
 	SnapshotStoragePrefix = []byte("o") // SnapshotStoragePrefix + account hash + storage hash -> storage trie value
 	CodePrefix            = []byte("c") // CodePrefix + code hash -> account code
@@ -103,6 +115,7 @@ var (
 
 	// Chain index prefixes (use `i` + single byte to avoid mixing data types).
 	BloomBitsIndexPrefix = []byte("iB") // BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
+	LogIndexIndexPrefix  = []byte("iL") // LogIndexIndexPrefix is the data table of the log indexer to track its progress
 
 	preimageCounter    = metrics.NewRegisteredCounter("db/preimage/total", nil)
 	preimageHitCounter = metrics.NewRegisteredCounter("db/preimage/hits", nil)
@@ -205,6 +218,26 @@ func storageSnapshotsKey(accountHash common.Hash) []byte {
 	return append(SnapshotStoragePrefix, accountHash.Bytes()...)
 }
 
+// logIndexKey = logIndexPrefix + section (uint64 big endian) + hash + term
+func logIndexKey(section uint64, hash common.Hash, term []byte) []byte {
+	key := append(append(append(logIndexPrefix, make([]byte, 8)...), hash.Bytes()...), term...)
+	binary.BigEndian.PutUint64(key[1:], section)
+	return key
+}
+
+// addressTxKey = addressTxPrefix + address + num (uint64 big endian) + tx index (uint64 big endian)
+func addressTxKey(address common.Address, number uint64, index uint64) []byte {
+	key := append(append(addressTxPrefix, address.Bytes()...), make([]byte, 16)...)
+	binary.BigEndian.PutUint64(key[len(addressTxPrefix)+common.AddressLength:], number)
+	binary.BigEndian.PutUint64(key[len(addressTxPrefix)+common.AddressLength+8:], index)
+	return key
+}
+
+// addressBlockKey = addressBlockPrefix + num (uint64 big endian)
+func addressBlockKey(number uint64) []byte {
+	return append(addressBlockPrefix, encodeBlockNumber(number)...)
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
@@ -61,28 +61,43 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 	if _, err := os.Stat(journal.path); os.IsNotExist(err) {
 		return nil
 	}
-	// Open the journal for loading any past transactions
-	input, err := os.Open(journal.path)
-	if err != nil {
-		return err
-	}
-	defer input.Close()
-
 	// Temporarily discard any journal additions (don't double add on load)
 	journal.writer = new(devNull)
 	defer func() { journal.writer = nil }()
 
-	// Inject all transactions from the journal into the pool
+	total, dropped, err := loadTransactions(journal.path, add)
+	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
+
+	return err
+}
+
+// loadTransactions parses an RLP stream of transactions from the given file and
+// injects them in small-ish batches via the provided add method. It returns the
+// number of parsed transactions and the number of ones rejected by the pool. A
+// missing file is not considered an error.
+func loadTransactions(path string, add func([]*types.Transaction) []error) (int, int, error) {
+	// Skip the parsing if the file doesn't exist at all
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	// Open the file for loading any past transactions
+	input, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer input.Close()
+
+	// Inject all transactions from the file into the pool
 	stream := rlp.NewStream(input, 0)
 	total, dropped := 0, 0
 
 	// Create a method to load a limited batch of transactions and bump the
 	// appropriate progress counters. Then use this method to load all the
-	// journaled transactions in small-ish batches.
+	// stored transactions in small-ish batches.
 	loadBatch := func(txs types.Transactions) {
 		for _, err := range add(txs) {
 			if err != nil {
-				log.Debug("Failed to add journaled transaction", "err", err)
+				log.Debug("Failed to add stored transaction", "err", err)
 				dropped++
 			}
 		}
@@ -111,9 +126,7 @@ func (journal *txJournal) load(add func([]*types.Transaction) []error) error {
 			batch = batch[:0]
 		}
 	}
-	log.Info("Loaded local transaction journal", "transactions", total, "dropped", dropped)
-
-	return failure
+	return total, dropped, failure
 }
 
 // insert adds the specified transaction to the local disk journal.
@@ -154,7 +154,8 @@ type TxPoolConfig struct {
 	Locals    []common.Address // Addresses that should be treated by default as local
 	NoLocals  bool             // Whether local transaction handling should be disabled
 	Journal   string           // Journal of local transactions to survive node restarts
-	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
+	Rejournal time.Duration    // Time interval to regenerate the local transaction journal and pool snapshot
+	Snapshot  string           // Snapshot of all (local and remote) transactions to survive node restarts
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -248,8 +249,9 @@ type TxPool struct {
 	pendingNonces *txNoncer      // Pending state tracking virtual nonces
 	currentMaxGas uint64         // Current gas limit for transaction caps
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *txJournal  // Journal of local transaction to back up to disk
+	locals   *accountSet // Set of local transaction to exempt from eviction rules
+	journal  *txJournal  // Journal of local transaction to back up to disk
+	snapshot *txSnapshot // Snapshot of all transactions to back up to disk
 
 	pending map[common.Address]*txList   // All currently processable transactions
 	queue   map[common.Address]*txList   // Queued but non-processable transactions
@@ -322,6 +324,15 @@ func NewTxPool(config TxPoolConfig, chainconfig *params.ChainConfig, chain block
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If full pool persistence is enabled, reinject everything on top of the
+	// locals. The transactions are revalidated against the current head.
+	if config.Snapshot != "" {
+		pool.snapshot = newTxSnapshot(config.Snapshot)
+
+		if err := pool.snapshot.load(pool.AddRemotesSync); err != nil {
+			log.Warn("Failed to load transaction pool snapshot", "err", err)
+		}
+	}
 
 	// Subscribe events from blockchain and start the main event loop.
 	pool.chainHeadSub = pool.chain.SubscribeChainHeadEvent(pool.chainHeadCh)
@@ -406,6 +417,11 @@ func (pool *TxPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.snapshot != nil {
+				if err := pool.snapshot.save(pool.Content()); err != nil {
+					log.Warn("Failed to save tx pool snapshot", "err", err)
+				}
+			}
 		}
 	}
 }
@@ -422,6 +438,11 @@ func (pool *TxPool) Stop() {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.snapshot != nil {
+		if err := pool.snapshot.save(pool.Content()); err != nil {
+			log.Warn("Failed to save tx pool snapshot", "err", err)
+		}
+	}
 	log.Info("Transaction pool stopped")
 }
 
@@ -2347,6 +2347,83 @@ func testTransactionJournaling(t *testing.T, nolocals bool) {
 	pool.Stop()
 }
 
+// TestTransactionSnapshotting tests that both remote and queued transactions
+// survive a pool restart if the full pool snapshot is enabled, and that stale
+// ones are dropped upon reinjection.
+func TestTransactionSnapshotting(t *testing.T) {
+	t.Parallel()
+
+	// Create a temporary file for the snapshot
+	file, err := ioutil.TempFile("", "")
+	if err != nil {
+		t.Fatalf("failed to create temporary snapshot: %v", err)
+	}
+	snapshot := file.Name()
+	defer os.Remove(snapshot)
+
+	// Clean up the temporary file, we only need the path for now
+	file.Close()
+	os.Remove(snapshot)
+
+	// Create the original pool to inject transaction into the snapshot
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	config := testTxPoolConfig
+	config.NoLocals = true
+	config.Snapshot = snapshot
+
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+
+	// Create two remote accounts, one with executable and one with gapped transactions
+	first, _ := crypto.GenerateKey()
+	second, _ := crypto.GenerateKey()
+
+	testAddBalance(pool, crypto.PubkeyToAddress(first.PublicKey), big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(second.PublicKey), big.NewInt(1000000000))
+
+	txs := []*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), first),
+		pricedTransaction(1, 100000, big.NewInt(1), first),
+		pricedTransaction(2, 100000, big.NewInt(1), first),
+		pricedTransaction(1, 100000, big.NewInt(1), second),
+	}
+	for i, err := range pool.AddRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	pending, queued := pool.Stats()
+	if pending != 3 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 3)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	// Terminate the old pool, bump the first nonce, create a new pool and ensure
+	// only the still valid transactions survive
+	pool.Stop()
+	statedb.SetNonce(crypto.PubkeyToAddress(first.PublicKey), 1)
+	blockchain = &testBlockChain{1000000, statedb, new(event.Feed)}
+
+	pool = NewTxPool(config, params.TestChainConfig, blockchain)
+	defer pool.Stop()
+
+	pending, queued = pool.Stats()
+	if pending != 2 {
+		t.Fatalf("pending transactions mismatched: have %d, want %d", pending, 2)
+	}
+	if queued != 1 {
+		t.Fatalf("queued transactions mismatched: have %d, want %d", queued, 1)
+	}
+	if pool.Has(txs[0].Hash()) {
+		t.Fatalf("stale transaction reinjected")
+	}
+	if err := validateTxPoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestTransactionStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestTransactionStatusCheck(t *testing.T) {
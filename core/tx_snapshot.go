@@ -0,0 +1,85 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// txSnapshot is a periodically regenerated dump of the entire transaction pool,
+// both local and remote, pending and queued. Contrary to the local journal, it
+// is not appended to on every insertion, rather it is fully rewritten on each
+// rotation and on shutdown. Its goal is to allow block producers to restart
+// without losing all the transactions gathered from the network.
+type txSnapshot struct {
+	path string // Filesystem path to store the transactions at
+}
+
+// newTxSnapshot creates a new transaction pool snapshot backed by the given file.
+func newTxSnapshot(path string) *txSnapshot {
+	return &txSnapshot{
+		path: path,
+	}
+}
+
+// load parses a transaction pool snapshot from disk, injecting its contents into
+// the pool via the specified method. The transactions are revalidated by the
+// pool against the current chain head, so any stale ones are simply dropped.
+func (snap *txSnapshot) load(add func([]*types.Transaction) []error) error {
+	if _, err := os.Stat(snap.path); os.IsNotExist(err) {
+		return nil
+	}
+	total, dropped, err := loadTransactions(snap.path, add)
+	log.Info("Loaded transaction pool snapshot", "transactions", total, "dropped", dropped)
+
+	return err
+}
+
+// save regenerates the transaction pool snapshot based on the given pending and
+// queued transactions. The file is atomically replaced, so a crash midway will
+// leave the previous snapshot intact.
+func (snap *txSnapshot) save(pending, queued map[common.Address]types.Transactions) error {
+	replacement, err := os.OpenFile(snap.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	saved := 0
+	for _, set := range []map[common.Address]types.Transactions{pending, queued} {
+		for _, txs := range set {
+			for _, tx := range txs {
+				if err = rlp.Encode(replacement, tx); err != nil {
+					replacement.Close()
+					return err
+				}
+			}
+			saved += len(txs)
+		}
+	}
+	if err = replacement.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(snap.path+".new", snap.path); err != nil {
+		return err
+	}
+	log.Info("Saved transaction pool snapshot", "transactions", saved)
+	return nil
+}
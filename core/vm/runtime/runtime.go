@@ -95,7 +95,7 @@ func setDefaults(cfg *Config) {
 		}
 	}
 	if cfg.BaseFee == nil {
-		cfg.BaseFee = big.NewInt(params.InitialBaseFee)
+		cfg.BaseFee = cfg.ChainConfig.InitialBaseFee()
 	}
 }
 
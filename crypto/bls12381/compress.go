@@ -0,0 +1,166 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"errors"
+)
+
+// Flags of the zcash point serialization, stored in the three most significant
+// bits of the encoding.
+const (
+	compressionFlag = 1 << 7
+	infinityFlag    = 1 << 6
+	signFlag        = 1 << 5
+)
+
+// isLexicographicallyLargest reports whether the field element is larger than
+// its negation.
+func isLexicographicallyLargest(e *fe) bool {
+	n := new(fe)
+	neg(n, e)
+	return toBig(e).Cmp(toBig(n)) > 0
+}
+
+// isLexicographicallyLargest2 reports whether the extension field element is
+// larger than its negation, comparing the imaginary parts first.
+func isLexicographicallyLargest2(e *fe2) bool {
+	if !e[1].isZero() {
+		return isLexicographicallyLargest(&e[1])
+	}
+	return isLexicographicallyLargest(&e[0])
+}
+
+// EncodeCompressed serializes a point into 48 bytes in the compressed form of
+// the zcash serialization format.
+func (g *G1) EncodeCompressed(p *PointG1) []byte {
+	out := make([]byte, 48)
+	if g.IsZero(p) {
+		out[0] = compressionFlag | infinityFlag
+		return out
+	}
+	g.Affine(p)
+	copy(out, toBytes(&p[0]))
+	out[0] |= compressionFlag
+	if isLexicographicallyLargest(&p[1]) {
+		out[0] |= signFlag
+	}
+	return out
+}
+
+// DecodeCompressed deserializes a point from the 48 byte compressed form of
+// the zcash serialization format. The point is checked to be in the correct
+// subgroup.
+func (g *G1) DecodeCompressed(in []byte) (*PointG1, error) {
+	if len(in) != 48 {
+		return nil, errors.New("compressed g1 point should be 48 bytes")
+	}
+	if in[0]&compressionFlag == 0 {
+		return nil, errors.New("compression flag not set")
+	}
+	buf := make([]byte, 48)
+	copy(buf, in)
+	buf[0] &^= compressionFlag | infinityFlag | signFlag
+
+	if in[0]&infinityFlag != 0 {
+		if in[0]&signFlag != 0 || !new(fe).setBytes(buf).isZero() {
+			return nil, errors.New("invalid infinity encoding")
+		}
+		return g.Zero(), nil
+	}
+	x, err := fromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + b
+	y := new(fe)
+	square(y, x)
+	mul(y, y, x)
+	add(y, y, b)
+	if !sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLexicographicallyLargest(y) != (in[0]&signFlag != 0) {
+		neg(y, y)
+	}
+	p := &PointG1{*x, *y, *new(fe).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
+
+// EncodeCompressed serializes a point into 96 bytes in the compressed form of
+// the zcash serialization format.
+func (g *G2) EncodeCompressed(p *PointG2) []byte {
+	out := make([]byte, 96)
+	if g.IsZero(p) {
+		out[0] = compressionFlag | infinityFlag
+		return out
+	}
+	g.Affine(p)
+	copy(out, g.f.toBytes(&p[0]))
+	out[0] |= compressionFlag
+	if isLexicographicallyLargest2(&p[1]) {
+		out[0] |= signFlag
+	}
+	return out
+}
+
+// DecodeCompressed deserializes a point from the 96 byte compressed form of
+// the zcash serialization format. The point is checked to be in the correct
+// subgroup.
+func (g *G2) DecodeCompressed(in []byte) (*PointG2, error) {
+	if len(in) != 96 {
+		return nil, errors.New("compressed g2 point should be 96 bytes")
+	}
+	if in[0]&compressionFlag == 0 {
+		return nil, errors.New("compression flag not set")
+	}
+	buf := make([]byte, 96)
+	copy(buf, in)
+	buf[0] &^= compressionFlag | infinityFlag | signFlag
+
+	if in[0]&infinityFlag != 0 {
+		for _, v := range buf {
+			if v != 0 || in[0]&signFlag != 0 {
+				return nil, errors.New("invalid infinity encoding")
+			}
+		}
+		return g.Zero(), nil
+	}
+	x, err := g.f.fromBytes(buf)
+	if err != nil {
+		return nil, err
+	}
+	// y^2 = x^3 + b
+	y := new(fe2)
+	g.f.square(y, x)
+	g.f.mul(y, y, x)
+	g.f.add(y, y, b2)
+	if !g.f.sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if isLexicographicallyLargest2(y) != (in[0]&signFlag != 0) {
+		g.f.neg(y, y)
+	}
+	p := &PointG2{*x, *y, *new(fe2).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not in correct subgroup")
+	}
+	return p, nil
+}
@@ -0,0 +1,108 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// expandMessageXMD implements expand_message_xmd with SHA-256 as specified in
+// https://www.rfc-editor.org/rfc/rfc9380#section-5.3.1
+func expandMessageXMD(msg, dst []byte, length int) ([]byte, error) {
+	ell := (length + sha256.Size - 1) / sha256.Size
+	if ell > 255 || length > 65535 || len(dst) > 255 {
+		return nil, errors.New("invalid message expansion parameters")
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	h := sha256.New()
+	h.Write(make([]byte, sha256.BlockSize))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	h.Reset()
+	h.Write(b0)
+	h.Write([]byte{1})
+	h.Write(dstPrime)
+	bi := h.Sum(nil)
+
+	out := make([]byte, 0, ell*sha256.Size)
+	out = append(out, bi...)
+	for i := 2; i <= ell; i++ {
+		xored := make([]byte, sha256.Size)
+		for j := range xored {
+			xored[j] = b0[j] ^ bi[j]
+		}
+		h.Reset()
+		h.Write(xored)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(nil)
+		out = append(out, bi...)
+	}
+	return out[:length], nil
+}
+
+// hashToFieldFp2 implements hash_to_field for the extension field, returning
+// count elements.
+func hashToFieldFp2(msg, dst []byte, count int) ([]*fe2, error) {
+	// Each base field element is derived from 64 bytes, providing 128 bits of
+	// security on top of the 381 bit modulus.
+	const l = 64
+
+	uniform, err := expandMessageXMD(msg, dst, count*2*l)
+	if err != nil {
+		return nil, err
+	}
+	p := modulus.big()
+	elems := make([]*fe2, count)
+	for i := range elems {
+		elems[i] = new(fe2)
+		for j := 0; j < 2; j++ {
+			offset := l * (j + i*2)
+			v := new(big.Int).SetBytes(uniform[offset : offset+l])
+			e, err := fromBig(v.Mod(v, p))
+			if err != nil {
+				return nil, err
+			}
+			elems[i][j].set(e)
+		}
+	}
+	return elems, nil
+}
+
+// HashToCurve hashes the message to a G2 point, using the domain separation tag
+// dst. It implements the BLS12381G2_XMD:SHA-256_SSWU_RO_ suite as specified in
+// https://www.rfc-editor.org/rfc/rfc9380#section-8.8.2
+func (g *G2) HashToCurve(msg, dst []byte) (*PointG2, error) {
+	u, err := hashToFieldFp2(msg, dst, 2)
+	if err != nil {
+		return nil, err
+	}
+	q := g.New()
+	for _, e := range u {
+		x, y := swuMapG2(g.f, e)
+		isogenyMapG2(g.f, x, y)
+		g.Add(q, q, &PointG2{*x, *y, *new(fe2).one()})
+	}
+	g.ClearCofactor(q)
+	return g.Affine(q), nil
+}
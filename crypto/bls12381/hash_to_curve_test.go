@@ -0,0 +1,82 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestHashToCurveG2(t *testing.T) {
+	// Test vector of https://www.rfc-editor.org/rfc/rfc9380#appendix-J.10.1
+	var (
+		dst  = []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_")
+		want = common.FromHex("0x" +
+			"05cb8437535e20ecffaef7752baddf98034139c38452458baeefab379ba13dff5bf5dd71b72418717047f5b0f37da03d" +
+			"0141ebfbdca40eb85b87142e130ab689c673cf60f1a3e98d69335266f30d9b8d4ac44c1038e9dcdd5393faf5c41fb78a" +
+			"12424ac32561493f3fe3c260708a12b7c620e7be00099a974e259ddc7d1f6395c3c811cdd19f1e8dbf3e9ecfdcbab8d6" +
+			"0503921d7f6a12805e72940b963c0cf3471c7b2a524950ca195d11062ee75ec076daf2d4bc358c4b190c0c98064fdd92")
+	)
+	g := NewG2()
+	p, err := g.HashToCurve([]byte{}, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have := g.ToBytes(p); !bytes.Equal(have, want) {
+		t.Fatalf("hash mismatch:\nhave %x\nwant %x", have, want)
+	}
+	if !g.InCorrectSubgroup(p) {
+		t.Fatal("hashed point not in correct subgroup")
+	}
+}
+
+func TestCompressedEncoding(t *testing.T) {
+	g1, g2 := NewG1(), NewG2()
+	for i := 0; i < fuz; i++ {
+		p1 := g1.rand()
+		enc1 := g1.EncodeCompressed(p1)
+		dec1, err := g1.DecodeCompressed(enc1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g1.Equal(p1, dec1) {
+			t.Fatal("g1 compressed encoding round trip failed")
+		}
+		p2 := g2.rand()
+		enc2 := g2.EncodeCompressed(p2)
+		dec2, err := g2.DecodeCompressed(enc2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !g2.Equal(p2, dec2) {
+			t.Fatal("g2 compressed encoding round trip failed")
+		}
+	}
+	// Points at infinity have a dedicated encoding.
+	if p, err := g1.DecodeCompressed(g1.EncodeCompressed(g1.Zero())); err != nil || !g1.IsZero(p) {
+		t.Fatal("g1 infinity round trip failed", err)
+	}
+	if p, err := g2.DecodeCompressed(g2.EncodeCompressed(g2.Zero())); err != nil || !g2.IsZero(p) {
+		t.Fatal("g2 infinity round trip failed", err)
+	}
+	// Encodings without the compression flag are rejected.
+	if _, err := g1.DecodeCompressed(make([]byte, 48)); err == nil {
+		t.Fatal("expected error for missing compression flag")
+	}
+}
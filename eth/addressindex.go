@@ -0,0 +1,104 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+
+	// Force-load the native tracers to make the call tracer available
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+// addressTraceReexec is the number of blocks to re-execute at most to regenerate
+// a missing state when tracing blocks for the address index.
+const addressTraceReexec = uint64(128)
+
+// addressCallFrame is the subset of the call tracer's output needed to collect
+// the participants of internal calls.
+type addressCallFrame struct {
+	From  common.Address     `json:"from"`
+	To    common.Address     `json:"to"`
+	Calls []addressCallFrame `json:"calls"`
+}
+
+// traceInternalAddresses re-executes a block with the native call tracer and
+// returns the addresses participating in the internal calls of each transaction.
+// It implements core.AddressTracer.
+func (eth *Ethereum) traceInternalAddresses(block *types.Block) ([][]common.Address, error) {
+	if block.NumberU64() == 0 {
+		return nil, nil
+	}
+	parent := eth.blockchain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return nil, fmt.Errorf("parent %#x not found", block.ParentHash())
+	}
+	statedb, err := eth.StateAtBlock(parent, addressTraceReexec, nil, true, false)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		config    = eth.blockchain.Config()
+		signer    = types.MakeSigner(config, block.Number())
+		blockCtx  = core.NewEVMBlockContext(block.Header(), eth.blockchain, nil)
+		addresses = make([][]common.Address, len(block.Transactions()))
+	)
+	for i, tx := range block.Transactions() {
+		msg, err := tx.AsMessage(signer, block.BaseFee())
+		if err != nil {
+			return nil, err
+		}
+		tracer, err := tracers.New("callTracer", &tracers.Context{BlockHash: block.Hash(), TxIndex: i, TxHash: tx.Hash()})
+		if err != nil {
+			return nil, err
+		}
+		vmenv := vm.NewEVM(blockCtx, core.NewEVMTxContext(msg), statedb, config, vm.Config{Debug: true, Tracer: tracer})
+		statedb.Prepare(tx.Hash(), i)
+		if _, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.Gas())); err != nil {
+			return nil, fmt.Errorf("transaction %#x failed: %v", tx.Hash(), err)
+		}
+		statedb.Finalise(vmenv.ChainConfig().IsEIP158(block.Number()))
+
+		result, err := tracer.GetResult()
+		if err != nil {
+			return nil, err
+		}
+		var frame addressCallFrame
+		if err := json.Unmarshal(result, &frame); err != nil {
+			return nil, err
+		}
+		// The outermost frame is the transaction itself, only collect nested calls
+		var collect func(frames []addressCallFrame)
+		collect = func(frames []addressCallFrame) {
+			for _, call := range frames {
+				addresses[i] = append(addresses[i], call.From)
+				if call.To != (common.Address{}) {
+					addresses[i] = append(addresses[i], call.To)
+				}
+				collect(call.Calls)
+			}
+		}
+		collect(frame.Calls)
+	}
+	return addresses, nil
+}
@@ -36,6 +36,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/ethereum/go-ethereum/trie"
@@ -67,6 +68,47 @@ func (api *PublicEthereumAPI) Hashrate() hexutil.Uint64 {
 	return hexutil.Uint64(api.e.Miner().Hashrate())
 }
 
+// FinalityCheckpoint returns the trusted block after which the chain is treated
+// as final, or nil if none was set.
+func (api *PublicEthereumAPI) FinalityCheckpoint() *core.FinalityCheckpoint {
+	return api.e.BlockChain().FinalityCheckpoint()
+}
+
+// SendBundleArgs represents the arguments of a transaction bundle submission.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle submits an ordered bundle of signed transactions to be included
+// atomically into the block with the given number. Transactions of the bundle
+// may only revert if listed in the reverting transaction hashes. It returns the
+// hash identifying the bundle.
+func (api *PublicEthereumAPI) SendBundle(args SendBundleArgs) (common.Hash, error) {
+	if len(args.Txs) == 0 {
+		return common.Hash{}, errors.New("bundle missing transactions")
+	}
+	if head := api.e.BlockChain().CurrentHeader().Number.Uint64(); uint64(args.BlockNumber) <= head {
+		return common.Hash{}, fmt.Errorf("bundle target block %d not after head %d", args.BlockNumber, head)
+	}
+	bundle := &miner.Bundle{
+		BlockNumber:       uint64(args.BlockNumber),
+		RevertingTxHashes: args.RevertingTxHashes,
+	}
+	for i, input := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return common.Hash{}, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+		bundle.Txs = append(bundle.Txs, tx)
+	}
+	if err := api.e.Miner().AddBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return bundle.Hash(), nil
+}
+
 // PublicMinerAPI provides an API to control the miner.
 // It offers only methods that operate on data that pose no security risk when it is publicly accessible.
 type PublicMinerAPI struct {
@@ -159,6 +201,15 @@ func NewPrivateAdminAPI(eth *Ethereum) *PrivateAdminAPI {
 	return &PrivateAdminAPI{eth: eth}
 }
 
+// SetFinalityCheckpoint sets the trusted block after which the chain is treated
+// as final, refusing any reorg replacing it.
+func (api *PrivateAdminAPI) SetFinalityCheckpoint(checkpoint core.FinalityCheckpoint) (bool, error) {
+	if err := api.eth.BlockChain().SetFinalityCheckpoint(checkpoint); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // ExportChain exports the current blockchain into a local file,
 // or a range of blocks if first and last are non-nil
 func (api *PrivateAdminAPI) ExportChain(file string, first *uint64, last *uint64) (bool, error) {
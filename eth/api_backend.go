@@ -274,6 +274,25 @@ func (b *EthAPIBackend) TxPoolContentFrom(addr common.Address) (types.Transactio
 	return b.eth.TxPool().ContentFrom(addr)
 }
 
+func (b *EthAPIBackend) StorageRange(ctx context.Context, root common.Hash, address common.Address, start common.Hash, limit int) ([]core.StorageSlot, *common.Hash, error) {
+	return b.eth.blockchain.StorageRange(root, address, start, limit)
+}
+
+func (b *EthAPIBackend) TransactionsByAddress(ctx context.Context, query core.AddressTxQuery) ([]rawdb.AddressTxEntry, error) {
+	if b.eth.addressIndexer == nil {
+		return nil, core.ErrAddressIndexUnavailable
+	}
+	return b.eth.addressIndexer.Query(query)
+}
+
+func (b *EthAPIBackend) AddressIndexStatus() (uint64, uint64, bool, bool) {
+	if b.eth.addressIndexer == nil {
+		return 0, 0, false, false
+	}
+	tail, head, ok := b.eth.addressIndexer.Status()
+	return tail, head, b.eth.addressIndexer.Internal(), ok
+}
+
 func (b *EthAPIBackend) TxPool() *core.TxPool {
 	return b.eth.TxPool()
 }
@@ -331,6 +350,14 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+func (b *EthAPIBackend) LogIndexStatus() (uint64, uint64) {
+	if b.eth.logIndexer == nil {
+		return params.BloomBitsBlocks, 0
+	}
+	sections, _, _ := b.eth.logIndexer.Sections()
+	return params.BloomBitsBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
@@ -31,7 +31,9 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
@@ -84,6 +86,9 @@ type Ethereum struct {
 
 	bloomRequests     chan chan *bloombits.Retrieval // Channel receiving bloom data retrieval requests
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
+	logIndexer        *core.ChainIndexer             // Log indexer operating during block imports (nil if disabled)
+	addressIndexer    *core.AddressIndexer           // Address activity indexer following the chain head (nil if disabled)
+	finalityFeed      *finalityFeed                  // Trusted finality checkpoint feed (nil if disabled)
 	closeBloomHandler chan struct{}
 
 	APIBackend *EthAPIBackend
@@ -206,11 +211,29 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		eth.blockchain.SetHead(compat.RewindTo)
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
+	eth.blockchain.SetMaxReorgDepth(config.MaxReorgDepth)
+	if config.FinalityCheckpoint != "" {
+		eth.finalityFeed = newFinalityFeed(config.FinalityCheckpoint, eth.blockchain)
+	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.LogIndex {
+		eth.logIndexer = core.NewLogIndexer(chainDb, params.BloomBitsBlocks, params.BloomConfirms)
+		eth.logIndexer.Start(eth.blockchain)
+	}
+	if config.AddressIndex {
+		var tracer core.AddressTracer
+		if config.AddressIndexInternal {
+			tracer = eth.traceInternalAddresses
+		}
+		eth.addressIndexer = core.NewAddressIndexer(chainDb, eth.blockchain, config.AddressIndexLimit, tracer)
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
 	eth.txPool = core.NewTxPool(config.TxPool, chainConfig, eth.blockchain)
 
 	// Permit the downloader to use the trie cache allowance during fast sync
@@ -237,6 +260,16 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth.miner = miner.New(eth, &config.Miner, chainConfig, eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
+	if config.Miner.Stratum != "" {
+		pow, ok := eth.innerEngine().(*ethash.Ethash)
+		if !ok {
+			return nil, errors.New("stratum mining server requires the ethash consensus engine")
+		}
+		if _, err := pow.StartStratum(config.Miner.Stratum, config.Miner.StratumDifficulty); err != nil {
+			return nil, fmt.Errorf("failed to start stratum server: %v", err)
+		}
+	}
+
 	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil}
 	if eth.APIBackend.allowUnprotectedTxs {
 		log.Info("Unprotected transactions allowed")
@@ -322,7 +355,7 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "eth",
 			Version:   "1.0",
-			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute),
+			Service:   filters.NewPublicFilterAPI(s.APIBackend, false, 5*time.Minute, s.config.RPCLogsLimit),
 			Public:    true,
 		}, {
 			Namespace: "admin",
@@ -481,6 +514,17 @@ func (s *Ethereum) StartMining(threads int) error {
 			}
 			cli.Authorize(eb, wallet.SignData)
 		}
+		if b, ok := s.innerEngine().(*bft.BFT); ok {
+			wallet, err := s.accountManager.Find(accounts.Account{Address: eb})
+			if wallet == nil || err != nil {
+				log.Error("Etherbase account unavailable locally", "err", err)
+				return fmt.Errorf("validator missing: %v", err)
+			}
+			b.Authorize(eb, wallet.SignData)
+			if err := b.Start(s.blockchain); err != nil {
+				return err
+			}
+		}
 		// If mining is started, we can disable the transaction rejection mechanism
 		// introduced to speed sync times.
 		atomic.StoreUint32(&s.handler.acceptTxs, 1)
@@ -525,6 +569,14 @@ func (s *Ethereum) SyncMode() downloader.SyncMode {
 	return mode
 }
 
+// innerEngine returns the consensus engine wrapped by the beacon engine.
+func (s *Ethereum) innerEngine() consensus.Engine {
+	if b, ok := s.engine.(*beacon.Beacon); ok {
+		return b.InnerEngine()
+	}
+	return s.engine
+}
+
 // Protocols returns all the currently configured
 // network protocols to start.
 func (s *Ethereum) Protocols() []p2p.Protocol {
@@ -532,6 +584,13 @@ func (s *Ethereum) Protocols() []p2p.Protocol {
 	if s.config.SnapshotCache > 0 {
 		protos = append(protos, snap.MakeProtocols((*snapHandler)(s.handler), s.snapDialCandidates)...)
 	}
+	// Some consensus engines exchange messages over their own sub-protocol
+	type protocols interface {
+		Protocols() []p2p.Protocol
+	}
+	if engine, ok := s.innerEngine().(protocols); ok {
+		protos = append(protos, engine.Protocols()...)
+	}
 	return protos
 }
 
@@ -546,6 +605,11 @@ func (s *Ethereum) Start() error {
 	// Regularly update shutdown marker
 	s.shutdownTracker.Start()
 
+	// Follow the trusted finality checkpoints if requested
+	if s.finalityFeed != nil {
+		s.finalityFeed.start()
+	}
+
 	// Figure out a max peers count based on the server limits
 	maxPeers := s.p2pServer.MaxPeers
 	if s.config.LightServ > 0 {
@@ -568,7 +632,16 @@ func (s *Ethereum) Stop() error {
 	s.handler.Stop()
 
 	// Then stop everything else.
+	if s.finalityFeed != nil {
+		s.finalityFeed.stop()
+	}
 	s.bloomIndexer.Close()
+	if s.logIndexer != nil {
+		s.logIndexer.Close()
+	}
+	if s.addressIndexer != nil {
+		s.addressIndexer.Close()
+	}
 	close(s.closeBloomHandler)
 	s.txPool.Stop()
 	s.miner.Close()
@@ -18,8 +18,6 @@
 package catalyst
 
 import (
-	"crypto/sha256"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
@@ -31,25 +29,31 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 // Register adds catalyst APIs to the full node.
-func Register(stack *node.Node, backend *eth.Ethereum) error {
+// The listeners are notified about every accepted forkchoice update.
+func Register(stack *node.Node, backend *eth.Ethereum, listeners ...ForkchoiceListener) error {
 	log.Warn("Catalyst mode enabled", "protocol", "eth")
+
+	authAPI, api := NewConsensusAPI(backend), NewConsensusAPI(backend)
+	authAPI.listeners, api.listeners = listeners, listeners
+
 	stack.RegisterAPIs([]rpc.API{
 		{
 			Namespace:     "engine",
 			Version:       "1.0",
-			Service:       NewConsensusAPI(backend),
+			Service:       authAPI,
 			Public:        true,
 			Authenticated: true,
 		},
 		{
 			Namespace:     "engine",
 			Version:       "1.0",
-			Service:       NewConsensusAPI(backend),
+			Service:       api,
 			Public:        true,
 			Authenticated: false,
 		},
@@ -57,10 +61,20 @@ func Register(stack *node.Node, backend *eth.Ethereum) error {
 	return nil
 }
 
+// ForkchoiceListener is notified about the forkchoice updates accepted by the
+// consensus API, e.g. to build payloads for external proposers.
+type ForkchoiceListener interface {
+	// ForkchoiceUpdated is called after the head of the chain was updated. The
+	// payload attributes are nil if no payload building was requested. It must
+	// not block.
+	ForkchoiceUpdated(update beacon.ForkchoiceStateV1, attributes *beacon.PayloadAttributesV1)
+}
+
 type ConsensusAPI struct {
 	eth          *eth.Ethereum
-	remoteBlocks *headerQueue  // Cache of remote payloads received
-	localBlocks  *payloadQueue // Cache of local payloads generated
+	remoteBlocks *headerQueue         // Cache of remote payloads received
+	localBlocks  *payloadQueue        // Cache of local payloads generated
+	listeners    []ForkchoiceListener // Listeners notified about forkchoice updates
 }
 
 // NewConsensusAPI creates a new consensus api for the given backend.
@@ -177,22 +191,31 @@ func (api *ConsensusAPI) ForkchoiceUpdatedV1(update beacon.ForkchoiceStateV1, pa
 			return beacon.STATUS_INVALID, errors.New("safe head not canonical")
 		}
 	}
+	for _, listener := range api.listeners {
+		listener.ForkchoiceUpdated(update, payloadAttributes)
+	}
 	// If payload generation was requested, create a new block to be potentially
 	// sealed by the beacon client. The payload will be requested later, and we
 	// might replace it arbitrarily many times in between.
 	if payloadAttributes != nil {
-		log.Info("Creating new payload for sealing")
-		start := time.Now()
-
-		data, err := api.assembleBlock(update.HeadBlockHash, payloadAttributes)
+		args := &miner.BuildPayloadArgs{
+			Parent:       update.HeadBlockHash,
+			Timestamp:    payloadAttributes.Timestamp,
+			FeeRecipient: payloadAttributes.SuggestedFeeRecipient,
+			Random:       payloadAttributes.Random,
+		}
+		id := args.Id()
+		// If we already are busy generating this work, then we do not need
+		// to start a second process.
+		if api.localBlocks.has(id) {
+			return api.validForkChoiceResponse(&id), nil
+		}
+		payload, err := api.eth.Miner().BuildPayload(args)
 		if err != nil {
-			log.Error("Failed to create sealing payload", "err", err)
+			log.Error("Failed to build payload", "err", err)
 			return api.validForkChoiceResponse(nil), err // valid setHead, invalid payload
 		}
-		id := computePayloadId(update.HeadBlockHash, payloadAttributes)
-		api.localBlocks.put(id, data)
-
-		log.Info("Created payload for sealing", "id", id, "elapsed", time.Since(start))
+		api.localBlocks.put(id, payload)
 		return api.validForkChoiceResponse(&id), nil
 	}
 	return api.validForkChoiceResponse(nil), nil
@@ -233,16 +256,64 @@ func (api *ConsensusAPI) ExchangeTransitionConfigurationV1(config beacon.Transit
 	return &beacon.TransitionConfigurationV1{TerminalTotalDifficulty: (*hexutil.Big)(ttd)}, nil
 }
 
-// GetPayloadV1 returns a cached payload by id.
+// GetPayloadV1 returns a cached payload by id. The background building of the
+// payload is stopped and the most valuable version built so far is returned.
 func (api *ConsensusAPI) GetPayloadV1(payloadID beacon.PayloadID) (*beacon.ExecutableDataV1, error) {
 	log.Trace("Engine API request received", "method", "GetPayload", "id", payloadID)
-	data := api.localBlocks.get(payloadID)
+	data, err := api.getPayload(payloadID, false)
+	if err != nil {
+		return nil, err
+	}
+	return data.ExecutionPayload, nil
+}
+
+// getPayload resolves a cached payload by id. If full is set, it waits for the
+// first version of the payload filled with transactions.
+func (api *ConsensusAPI) getPayload(payloadID beacon.PayloadID, full bool) (*beacon.ExecutionPayloadEnvelope, error) {
+	data := api.localBlocks.get(payloadID, full)
 	if data == nil {
 		return nil, &beacon.UnknownPayload
 	}
+	log.Info("Delivering payload", "id", payloadID, "number", data.ExecutionPayload.Number, "hash", data.ExecutionPayload.BlockHash,
+		"txs", len(data.ExecutionPayload.Transactions), "value", data.BlockValue)
 	return data, nil
 }
 
+// GetPayloadBodiesByHashV1 returns the transactions of the blocks with the given
+// hashes. Unknown blocks are returned as null.
+func (api *ConsensusAPI) GetPayloadBodiesByHashV1(hashes []common.Hash) ([]*beacon.ExecutionPayloadBodyV1, error) {
+	if len(hashes) > maxPayloadBodies {
+		return nil, &beacon.TooLargeRequest
+	}
+	db := api.eth.ChainDb()
+	bodies := make([]*beacon.ExecutionPayloadBodyV1, len(hashes))
+	for i, hash := range hashes {
+		bodies[i] = payloadBodyByHash(db, hash)
+	}
+	return bodies, nil
+}
+
+// GetPayloadBodiesByRangeV1 returns the transactions of count canonical blocks
+// starting at the given number. Missing blocks are returned as null, but the
+// range is cut off at the current head.
+func (api *ConsensusAPI) GetPayloadBodiesByRangeV1(start, count hexutil.Uint64) ([]*beacon.ExecutionPayloadBodyV1, error) {
+	if start == 0 || count == 0 {
+		return nil, &beacon.InvalidParams
+	}
+	if count > maxPayloadBodies {
+		return nil, &beacon.TooLargeRequest
+	}
+	var (
+		db     = api.eth.ChainDb()
+		head   = api.eth.BlockChain().CurrentBlock().NumberU64()
+		bodies = make([]*beacon.ExecutionPayloadBodyV1, 0, count)
+	)
+	for number := uint64(start); number <= head && number < uint64(start)+uint64(count); number++ {
+		bodies = append(bodies, canonicalPayloadBody(db, number))
+	}
+	return bodies, nil
+}
+
 // NewPayloadV1 creates an Eth1 block, inserts it in the chain, and returns the status of the chain.
 func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableDataV1) (beacon.PayloadStatusV1, error) {
 	log.Trace("Engine API request received", "method", "ExecutePayload", "number", params.Number, "hash", params.BlockHash)
@@ -319,19 +390,6 @@ func (api *ConsensusAPI) NewPayloadV1(params beacon.ExecutableDataV1) (beacon.Pa
 	return beacon.PayloadStatusV1{Status: beacon.VALID, LatestValidHash: &hash}, nil
 }
 
-// computePayloadId computes a pseudo-random payloadid, based on the parameters.
-func computePayloadId(headBlockHash common.Hash, params *beacon.PayloadAttributesV1) beacon.PayloadID {
-	// Hash
-	hasher := sha256.New()
-	hasher.Write(headBlockHash[:])
-	binary.Write(hasher, binary.BigEndian, params.Timestamp)
-	hasher.Write(params.Random[:])
-	hasher.Write(params.SuggestedFeeRecipient[:])
-	var out beacon.PayloadID
-	copy(out[:], hasher.Sum(nil)[:8])
-	return out
-}
-
 // invalid returns a response "INVALID" with the latest valid hash set to the current head.
 func (api *ConsensusAPI) invalid(err error) beacon.PayloadStatusV1 {
 	currentHash := api.eth.BlockChain().CurrentHeader().Hash()
@@ -339,15 +397,24 @@ func (api *ConsensusAPI) invalid(err error) beacon.PayloadStatusV1 {
 	return beacon.PayloadStatusV1{Status: beacon.INVALID, LatestValidHash: &currentHash, ValidationError: &errorMsg}
 }
 
-// assembleBlock creates a new block and returns the "execution
-// data" required for beacon clients to process the new block.
+// assembleBlock creates a new block with the transactions of the pool and
+// returns the "execution data" required for beacon clients to process it.
 func (api *ConsensusAPI) assembleBlock(parentHash common.Hash, params *beacon.PayloadAttributesV1) (*beacon.ExecutableDataV1, error) {
 	log.Info("Producing block", "parentHash", parentHash)
-	block, err := api.eth.Miner().GetSealingBlock(parentHash, params.Timestamp, params.SuggestedFeeRecipient, params.Random)
+	payload, err := api.eth.Miner().BuildPayload(&miner.BuildPayloadArgs{
+		Parent:       parentHash,
+		Timestamp:    params.Timestamp,
+		FeeRecipient: params.SuggestedFeeRecipient,
+		Random:       params.Random,
+	})
 	if err != nil {
 		return nil, err
 	}
-	return beacon.BlockToExecutableData(block), nil
+	data := payload.ResolveFull()
+	if data == nil {
+		return nil, errors.New("failed to build payload")
+	}
+	return data.ExecutionPayload, nil
 }
 
 // Used in tests to add a the list of transactions from a block to the tx pool.
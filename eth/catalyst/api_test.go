@@ -17,8 +17,11 @@
 package catalyst
 
 import (
+	"bytes"
 	"fmt"
+	"io/ioutil"
 	"math/big"
+	"os"
 	"testing"
 	"time"
 
@@ -164,7 +167,12 @@ func TestEth2PrepareAndGetPayload(t *testing.T) {
 		if err != nil {
 			t.Fatalf("error preparing payload, err=%v", err)
 		}
-		payloadID := computePayloadId(fcState.HeadBlockHash, &blockParams)
+		payloadID := (&miner.BuildPayloadArgs{
+			Parent:       fcState.HeadBlockHash,
+			Timestamp:    blockParams.Timestamp,
+			FeeRecipient: blockParams.SuggestedFeeRecipient,
+			Random:       blockParams.Random,
+		}).Id()
 		execData, err := api.GetPayloadV1(payloadID)
 		if err != nil {
 			t.Fatalf("error getting payload, err=%v", err)
@@ -523,3 +531,150 @@ func TestExchangeTransitionConfig(t *testing.T) {
 		t.Fatalf("expected no error on valid config, got %v", err)
 	}
 }
+
+// checkPayloadBody checks that a payload body contains the transactions of the block.
+func checkPayloadBody(t *testing.T, body *beacon.ExecutionPayloadBodyV1, block *types.Block) {
+	t.Helper()
+
+	if body == nil {
+		t.Fatalf("missing body of block %d", block.NumberU64())
+	}
+	if len(body.TransactionData) != len(block.Transactions()) {
+		t.Fatalf("block %d: wrong number of transactions: have %d, want %d", block.NumberU64(), len(body.TransactionData), len(block.Transactions()))
+	}
+	for i, tx := range block.Transactions() {
+		enc, _ := tx.MarshalBinary()
+		if !bytes.Equal(body.TransactionData[i], enc) {
+			t.Fatalf("block %d: wrong transaction %d", block.NumberU64(), i)
+		}
+	}
+}
+
+func TestGetPayloadBodies(t *testing.T) {
+	genesis, blocks := generatePreMergeChain(10)
+	n, ethservice := startEthService(t, genesis, blocks)
+	defer n.Close()
+
+	api := NewConsensusAPI(ethservice)
+
+	// Bodies by hash, unknown blocks are null.
+	hashes := []common.Hash{blocks[3].Hash(), {0x01}, blocks[9].Hash()}
+	bodies, err := api.GetPayloadBodiesByHashV1(hashes)
+	if err != nil {
+		t.Fatalf("failed to get bodies: %v", err)
+	}
+	if len(bodies) != len(hashes) {
+		t.Fatalf("wrong number of bodies: have %d, want %d", len(bodies), len(hashes))
+	}
+	checkPayloadBody(t, bodies[0], blocks[3])
+	if bodies[1] != nil {
+		t.Fatal("expected null body for unknown block")
+	}
+	checkPayloadBody(t, bodies[2], blocks[9])
+
+	// Bodies by range, the range is cut off at the head.
+	bodies, err = api.GetPayloadBodiesByRangeV1(8, 5)
+	if err != nil {
+		t.Fatalf("failed to get bodies: %v", err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("wrong number of bodies: have %d, want 3", len(bodies))
+	}
+	for i, body := range bodies {
+		checkPayloadBody(t, body, blocks[7+i])
+	}
+	if bodies, err := api.GetPayloadBodiesByRangeV1(20, 5); err != nil || len(bodies) != 0 {
+		t.Fatalf("expected no bodies past the head, have %d (err %v)", len(bodies), err)
+	}
+	// Invalid requests.
+	if _, err := api.GetPayloadBodiesByHashV1(make([]common.Hash, maxPayloadBodies+1)); err != &beacon.TooLargeRequest {
+		t.Fatalf("wrong error for too many hashes: %v", err)
+	}
+	if _, err := api.GetPayloadBodiesByRangeV1(1, maxPayloadBodies+1); err != &beacon.TooLargeRequest {
+		t.Fatalf("wrong error for too large range: %v", err)
+	}
+	if _, err := api.GetPayloadBodiesByRangeV1(0, 1); err != &beacon.InvalidParams {
+		t.Fatalf("wrong error for zero start: %v", err)
+	}
+	if _, err := api.GetPayloadBodiesByRangeV1(1, 0); err != &beacon.InvalidParams {
+		t.Fatalf("wrong error for zero count: %v", err)
+	}
+}
+
+func TestPayloadBodiesFromFreezer(t *testing.T) {
+	_, blocks := generatePreMergeChain(4)
+
+	frdir, err := ioutil.TempDir("", "")
+	if err != nil {
+		t.Fatalf("failed to create temp freezer dir: %v", err)
+	}
+	defer os.RemoveAll(frdir)
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), frdir, "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	// Move the first blocks into the freezer, keep the others in the key-value store.
+	receipts := make([]types.Receipts, 3)
+	if _, err := rawdb.WriteAncientBlocks(db, append([]*types.Block{types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0)})}, blocks[:2]...), receipts, big.NewInt(0)); err != nil {
+		t.Fatalf("failed to write ancient blocks: %v", err)
+	}
+	for _, block := range blocks[2:] {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+	}
+	for _, block := range blocks {
+		rawdb.WriteHeaderNumber(db, block.Hash(), block.NumberU64())
+	}
+	for _, block := range blocks {
+		checkPayloadBody(t, payloadBodyByHash(db, block.Hash()), block)
+		checkPayloadBody(t, canonicalPayloadBody(db, block.NumberU64()), block)
+	}
+	if body := canonicalPayloadBody(db, 5); body != nil {
+		t.Fatal("expected no body for unknown block")
+	}
+}
+
+// forkchoiceRecorder is a ForkchoiceListener recording the updates.
+type forkchoiceRecorder struct {
+	updates    []beacon.ForkchoiceStateV1
+	attributes []*beacon.PayloadAttributesV1
+}
+
+func (r *forkchoiceRecorder) ForkchoiceUpdated(update beacon.ForkchoiceStateV1, attributes *beacon.PayloadAttributesV1) {
+	r.updates = append(r.updates, update)
+	r.attributes = append(r.attributes, attributes)
+}
+
+func TestForkchoiceListener(t *testing.T) {
+	n, ethservice := startEthService(t, core.DeveloperBeaconGenesisBlock(11_500_000, testAddr), nil)
+	defer n.Close()
+
+	var (
+		recorder = new(forkchoiceRecorder)
+		api      = NewConsensusAPI(ethservice)
+		head     = ethservice.BlockChain().CurrentBlock()
+		update   = beacon.ForkchoiceStateV1{HeadBlockHash: head.Hash()}
+		attrs    = &beacon.PayloadAttributesV1{Timestamp: head.Time() + 1}
+	)
+	api.listeners = []ForkchoiceListener{recorder}
+
+	// Rejected updates are not passed on.
+	if _, err := api.ForkchoiceUpdatedV1(beacon.ForkchoiceStateV1{HeadBlockHash: common.Hash{0x01}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.ForkchoiceUpdatedV1(update, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.ForkchoiceUpdatedV1(update, attrs); err != nil {
+		t.Fatal(err)
+	}
+	if len(recorder.updates) != 2 {
+		t.Fatalf("wrong number of updates: have %d, want 2", len(recorder.updates))
+	}
+	if recorder.updates[1] != update || recorder.attributes[0] != nil || recorder.attributes[1] != attrs {
+		t.Fatalf("wrong updates recorded: %v %v", recorder.updates, recorder.attributes)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"bytes"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// maxPayloadBodies is the maximum number of payload bodies served by a single
+// request, as defined by the engine API specification.
+const maxPayloadBodies = 1024
+
+// payloadBodyByHash retrieves the payload body of the block with the given hash
+// from the key-value store or the freezer. It returns nil if the block is unknown.
+func payloadBodyByHash(db ethdb.Reader, hash common.Hash) *beacon.ExecutionPayloadBodyV1 {
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		return nil
+	}
+	return decodePayloadBody(rawdb.ReadBodyRLP(db, hash, *number))
+}
+
+// canonicalPayloadBody retrieves the payload body of the canonical block with
+// the given number from the key-value store or the freezer. It returns nil if
+// the block is unknown.
+func canonicalPayloadBody(db ethdb.Reader, number uint64) *beacon.ExecutionPayloadBodyV1 {
+	return decodePayloadBody(rawdb.ReadCanonicalBodyRLP(db, number))
+}
+
+// decodePayloadBody converts an RLP encoded block body into a payload body,
+// keeping the transactions in their binary encoding.
+func decodePayloadBody(data rlp.RawValue) *beacon.ExecutionPayloadBodyV1 {
+	if len(data) == 0 {
+		return nil
+	}
+	body := new(types.Body)
+	if err := rlp.Decode(bytes.NewReader(data), body); err != nil {
+		log.Error("Invalid block body RLP", "err", err)
+		return nil
+	}
+	txs := make([]hexutil.Bytes, len(body.Transactions))
+	for i, tx := range body.Transactions {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			log.Error("Failed to encode transaction", "hash", tx.Hash(), "err", err)
+			return nil
+		}
+		txs[i] = enc
+	}
+	return &beacon.ExecutionPayloadBodyV1{TransactionData: txs}
+}
@@ -22,6 +22,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/beacon"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
 )
 
 // maxTrackedPayloads is the maximum number of prepared payloads the execution
@@ -38,7 +39,7 @@ const maxTrackedHeaders = 10
 // or evicted.
 type payloadQueueItem struct {
 	id      beacon.PayloadID
-	payload *beacon.ExecutableDataV1
+	payload *miner.Payload
 }
 
 // payloadQueue tracks the latest handful of constructed payloads to be retrieved
@@ -56,20 +57,40 @@ func newPayloadQueue() *payloadQueue {
 	}
 }
 
-// put inserts a new payload into the queue at the given id.
-func (q *payloadQueue) put(id beacon.PayloadID, data *beacon.ExecutableDataV1) {
+// put inserts a new payload into the queue at the given id. The building of
+// the payload evicted from the queue is stopped.
+func (q *payloadQueue) put(id beacon.PayloadID, payload *miner.Payload) {
 	q.lock.Lock()
 	defer q.lock.Unlock()
 
+	if evicted := q.payloads[len(q.payloads)-1]; evicted != nil {
+		evicted.payload.Cancel()
+	}
 	copy(q.payloads[1:], q.payloads)
 	q.payloads[0] = &payloadQueueItem{
 		id:      id,
-		payload: data,
+		payload: payload,
 	}
 }
 
 // get retrieves a previously stored payload item or nil if it does not exist.
-func (q *payloadQueue) get(id beacon.PayloadID) *beacon.ExecutableDataV1 {
+// The payload is resolved, which stops its building. If full is set, the call
+// waits for the first version filled with transactions, if it can be built.
+func (q *payloadQueue) get(id beacon.PayloadID, full bool) *beacon.ExecutionPayloadEnvelope {
+	payload := q.find(id)
+	if payload == nil {
+		return nil
+	}
+	if full {
+		if data := payload.ResolveFull(); data != nil {
+			return data
+		}
+	}
+	return payload.Resolve()
+}
+
+// find retrieves a previously stored payload or nil if it does not exist.
+func (q *payloadQueue) find(id beacon.PayloadID) *miner.Payload {
 	q.lock.RLock()
 	defer q.lock.RUnlock()
 
@@ -84,6 +105,11 @@ func (q *payloadQueue) get(id beacon.PayloadID) *beacon.ExecutableDataV1 {
 	return nil
 }
 
+// has checks if a particular payload is already tracked.
+func (q *payloadQueue) has(id beacon.PayloadID) bool {
+	return q.find(id) != nil
+}
+
 // headerQueueItem represents an hash->header tuple to store until it's retrieved
 // or evicted.
 type headerQueueItem struct {
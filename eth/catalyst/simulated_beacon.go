@@ -0,0 +1,294 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// devEpochLength is the number of blocks the finalized block lags behind the
+// head of the chain driven by the simulated beacon.
+const devEpochLength = 32
+
+// SimulatedBeacon drives the execution engine of a developer chain the way a
+// beacon client does, producing blocks through the engine API either on a fixed
+// period or whenever new transactions arrive.
+type SimulatedBeacon struct {
+	eth       *eth.Ethereum
+	engineAPI *ConsensusAPI
+	period    uint64 // Block period in seconds, 0 means sealing on demand
+
+	mu                 sync.Mutex // Lock protecting the fields below, held while sealing
+	curForkchoiceState beacon.ForkchoiceStateV1
+	feeRecipient       common.Address
+	lastBlockTime      uint64
+	timeOffset         uint64 // Seconds added to the wall clock for block timestamps
+
+	shutdownCh chan struct{}
+	wg         sync.WaitGroup
+}
+
+// NewSimulatedBeacon creates a simulated beacon driving the given node. The
+// chain must be merged from the genesis.
+func NewSimulatedBeacon(period uint64, eth *eth.Ethereum) (*SimulatedBeacon, error) {
+	chainConfig := eth.BlockChain().Config()
+	if chainConfig.TerminalTotalDifficulty == nil || chainConfig.TerminalTotalDifficulty.Sign() != 0 {
+		return nil, errors.New("simulated beacon requires a chain merged at genesis")
+	}
+	// The fee recipient defaults to the etherbase, but it can be changed later on.
+	feeRecipient, _ := eth.Etherbase()
+
+	head := eth.BlockChain().CurrentBlock()
+	return &SimulatedBeacon{
+		eth:       eth,
+		engineAPI: NewConsensusAPI(eth),
+		period:    period,
+		curForkchoiceState: beacon.ForkchoiceStateV1{
+			HeadBlockHash:      head.Hash(),
+			SafeBlockHash:      head.Hash(),
+			FinalizedBlockHash: head.Hash(),
+		},
+		feeRecipient:  feeRecipient,
+		lastBlockTime: head.Time(),
+		shutdownCh:    make(chan struct{}),
+	}, nil
+}
+
+// Start implements node.Lifecycle, starting the block production.
+func (c *SimulatedBeacon) Start() error {
+	// Mark the current head as the initial forkchoice state.
+	c.mu.Lock()
+	_, err := c.engineAPI.ForkchoiceUpdatedV1(c.curForkchoiceState, nil)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to initialize forkchoice: %v", err)
+	}
+	c.wg.Add(1)
+	if c.period == 0 {
+		go c.loopOnDemand()
+	} else {
+		go c.loop()
+	}
+	log.Info("Started simulated beacon", "period", c.period)
+	return nil
+}
+
+// Stop implements node.Lifecycle, stopping the block production.
+func (c *SimulatedBeacon) Stop() error {
+	close(c.shutdownCh)
+	c.wg.Wait()
+	return nil
+}
+
+// loop seals a block every period.
+func (c *SimulatedBeacon) loop() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(c.period) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := c.Commit(); err != nil {
+				log.Warn("Failed to seal block", "err", err)
+			}
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// loopOnDemand seals blocks as long as there are pending transactions.
+func (c *SimulatedBeacon) loopOnDemand() {
+	defer c.wg.Done()
+
+	var (
+		newTxs = make(chan core.NewTxsEvent)
+		sub    = c.eth.TxPool().SubscribeNewTxsEvent(newTxs)
+	)
+	defer sub.Unsubscribe()
+
+	for {
+		select {
+		case <-newTxs:
+			c.sealPending()
+		case <-sub.Err():
+			return
+		case <-c.shutdownCh:
+			return
+		}
+	}
+}
+
+// sealPending seals blocks until the pending transactions are included. It
+// stops early if a block can't be sealed or doesn't include any transaction,
+// e.g. because the pending ones are underpriced.
+func (c *SimulatedBeacon) sealPending() {
+	for {
+		if pending, _ := c.eth.TxPool().Stats(); pending == 0 {
+			return
+		}
+		block, err := c.Commit()
+		if err != nil {
+			log.Warn("Failed to seal block", "err", err)
+			return
+		}
+		if len(c.eth.BlockChain().GetBlockByHash(block).Transactions()) == 0 {
+			return
+		}
+		select {
+		case <-c.shutdownCh:
+			return
+		default:
+		}
+	}
+}
+
+// Commit seals a block on top of the current head and returns its hash.
+func (c *SimulatedBeacon) Commit() (common.Hash, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timestamp := uint64(time.Now().Unix()) + c.timeOffset
+	if timestamp <= c.lastBlockTime {
+		timestamp = c.lastBlockTime + 1
+	}
+	var random common.Hash
+	if _, err := rand.Read(random[:]); err != nil {
+		return common.Hash{}, err
+	}
+	fcResponse, err := c.engineAPI.ForkchoiceUpdatedV1(c.curForkchoiceState, &beacon.PayloadAttributesV1{
+		Timestamp:             timestamp,
+		Random:                random,
+		SuggestedFeeRecipient: c.feeRecipient,
+	})
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if fcResponse.PayloadStatus.Status != beacon.VALID || fcResponse.PayloadID == nil {
+		return common.Hash{}, fmt.Errorf("payload building rejected: %s", fcResponse.PayloadStatus.Status)
+	}
+	payload, err := c.engineAPI.getPayload(*fcResponse.PayloadID, true)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	status, err := c.engineAPI.NewPayloadV1(*payload.ExecutionPayload)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	if status.Status != beacon.VALID {
+		return common.Hash{}, fmt.Errorf("payload rejected: %s", status.Status)
+	}
+	// Mark the payload as the new head. The finalized block lags behind by an
+	// epoch, the safe block is the head itself.
+	finalized := c.curForkchoiceState.FinalizedBlockHash
+	if number := payload.ExecutionPayload.Number; number >= devEpochLength {
+		if hash := c.eth.BlockChain().GetCanonicalHash(number - devEpochLength); hash != (common.Hash{}) {
+			if c.eth.BlockChain().GetHeaderByHash(finalized).Number.Uint64() < number-devEpochLength {
+				finalized = hash
+			}
+		}
+	}
+	if err := c.setHead(payload.ExecutionPayload.BlockHash, finalized); err != nil {
+		return common.Hash{}, err
+	}
+	c.lastBlockTime = payload.ExecutionPayload.Timestamp
+	return payload.ExecutionPayload.BlockHash, nil
+}
+
+// setHead updates the forkchoice of the execution engine. It assumes the lock
+// is held.
+func (c *SimulatedBeacon) setHead(head, finalized common.Hash) error {
+	state := beacon.ForkchoiceStateV1{
+		HeadBlockHash:      head,
+		SafeBlockHash:      head,
+		FinalizedBlockHash: finalized,
+	}
+	resp, err := c.engineAPI.ForkchoiceUpdatedV1(state, nil)
+	if err != nil {
+		return err
+	}
+	if resp.PayloadStatus.Status != beacon.VALID {
+		return fmt.Errorf("forkchoice update rejected: %s", resp.PayloadStatus.Status)
+	}
+	c.curForkchoiceState = state
+	return nil
+}
+
+// Finalize marks the current head as finalized and returns its hash.
+func (c *SimulatedBeacon) Finalize() (common.Hash, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	head := c.curForkchoiceState.HeadBlockHash
+	if err := c.setHead(head, head); err != nil {
+		return common.Hash{}, err
+	}
+	return head, nil
+}
+
+// AdvanceTime shifts the timestamps of the following blocks into the future by
+// the given number of seconds. It returns the total time offset.
+func (c *SimulatedBeacon) AdvanceTime(seconds uint64) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.timeOffset += seconds
+	return c.timeOffset
+}
+
+// SetFeeRecipient sets the fee recipient of the following blocks.
+func (c *SimulatedBeacon) SetFeeRecipient(feeRecipient common.Address) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.feeRecipient = feeRecipient
+}
+
+// ForkchoiceState returns the current forkchoice state of the chain.
+func (c *SimulatedBeacon) ForkchoiceState() beacon.ForkchoiceStateV1 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.curForkchoiceState
+}
+
+// RegisterSimulatedBeaconAPIs registers the "dev" RPC namespace, which allows
+// controlling the simulated beacon. The namespace is not public, it needs to be
+// enabled explicitly on the HTTP and WebSocket endpoints.
+func RegisterSimulatedBeaconAPIs(stack *node.Node, sim *SimulatedBeacon) {
+	stack.RegisterAPIs([]rpc.API{
+		{
+			Namespace: "dev",
+			Version:   "1.0",
+			Service:   &simulatedBeaconAPI{sim},
+		},
+	})
+}
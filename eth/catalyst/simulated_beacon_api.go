@@ -0,0 +1,53 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/beacon"
+)
+
+// simulatedBeaconAPI is the "dev" RPC namespace controlling the simulated beacon.
+type simulatedBeaconAPI struct {
+	sim *SimulatedBeacon
+}
+
+// Commit seals a block with the pending transactions and returns its hash.
+func (api *simulatedBeaconAPI) Commit() (common.Hash, error) {
+	return api.sim.Commit()
+}
+
+// Finalize marks the current head as finalized and returns its hash.
+func (api *simulatedBeaconAPI) Finalize() (common.Hash, error) {
+	return api.sim.Finalize()
+}
+
+// AdvanceTime moves the timestamps of the following blocks the given number of
+// seconds into the future. It returns the total time offset.
+func (api *simulatedBeaconAPI) AdvanceTime(seconds uint64) uint64 {
+	return api.sim.AdvanceTime(seconds)
+}
+
+// SetFeeRecipient sets the fee recipient of the following blocks.
+func (api *simulatedBeaconAPI) SetFeeRecipient(feeRecipient common.Address) {
+	api.sim.SetFeeRecipient(feeRecipient)
+}
+
+// ForkchoiceState returns the head, safe and finalized blocks of the chain.
+func (api *simulatedBeaconAPI) ForkchoiceState() beacon.ForkchoiceStateV1 {
+	return api.sim.ForkchoiceState()
+}
@@ -0,0 +1,172 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func startSimulatedBeacon(t *testing.T, period uint64) (*node.Node, *eth.Ethereum, *SimulatedBeacon) {
+	t.Helper()
+
+	genesis := core.DeveloperBeaconGenesisBlock(11_500_000, testAddr)
+	n, ethservice := startEthService(t, genesis, nil)
+
+	sim, err := NewSimulatedBeacon(period, ethservice)
+	if err != nil {
+		n.Close()
+		t.Fatal("can't create simulated beacon:", err)
+	}
+	if err := sim.Start(); err != nil {
+		n.Close()
+		t.Fatal("can't start simulated beacon:", err)
+	}
+	return n, ethservice, sim
+}
+
+func TestSimulatedBeaconOnDemand(t *testing.T) {
+	n, ethservice, sim := startSimulatedBeacon(t, 0)
+	defer n.Close()
+	defer sim.Stop()
+
+	var (
+		signer    = types.LatestSigner(ethservice.BlockChain().Config())
+		recipient = common.HexToAddress("0xdeadbeef")
+		txs       = make(map[common.Hash]bool)
+	)
+	for i := 0; i < 5; i++ {
+		tx := types.MustSignNewTx(testKey, signer, &types.DynamicFeeTx{
+			ChainID:   ethservice.BlockChain().Config().ChainID,
+			Nonce:     uint64(i),
+			To:        &recipient,
+			Value:     big.NewInt(1000),
+			Gas:       params.TxGas,
+			GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+			GasTipCap: big.NewInt(params.GWei),
+		})
+		if err := ethservice.TxPool().AddLocal(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+		txs[tx.Hash()] = true
+	}
+	// Wait for all transactions to be included.
+	deadline := time.Now().Add(10 * time.Second)
+	for len(txs) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("transactions not included: %d left", len(txs))
+		}
+		for hash := range txs {
+			if tx, _, _, _ := rawdb.ReadTransaction(ethservice.ChainDb(), hash); tx != nil {
+				delete(txs, hash)
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	head := ethservice.BlockChain().CurrentBlock()
+	if head.NumberU64() == 0 {
+		t.Fatal("no blocks produced")
+	}
+	if head.Difficulty().Sign() != 0 {
+		t.Fatalf("produced block is not proof-of-stake, difficulty %v", head.Difficulty())
+	}
+	if head.MixDigest() == (common.Hash{}) {
+		t.Fatal("produced block has no randomness")
+	}
+	if head.Coinbase() != testAddr {
+		t.Fatalf("wrong fee recipient: have %x, want %x", head.Coinbase(), testAddr)
+	}
+	if fc := sim.ForkchoiceState(); fc.HeadBlockHash != head.Hash() || fc.SafeBlockHash != head.Hash() {
+		t.Fatalf("wrong forkchoice state: %+v", fc)
+	}
+	if !ethservice.Merger().PoSFinalized() {
+		t.Fatal("chain not marked as proof-of-stake finalized")
+	}
+}
+
+func TestSimulatedBeaconControl(t *testing.T) {
+	n, ethservice, sim := startSimulatedBeacon(t, 0)
+	defer n.Close()
+	defer sim.Stop()
+
+	api := &simulatedBeaconAPI{sim}
+	genesis := ethservice.BlockChain().Genesis()
+
+	// Blocks can be sealed without transactions.
+	first, err := api.Commit()
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	block := ethservice.BlockChain().GetBlockByHash(first)
+	if block == nil || block.NumberU64() != 1 || ethservice.BlockChain().CurrentBlock().Hash() != first {
+		t.Fatal("sealed block is not the head")
+	}
+	if fc := api.ForkchoiceState(); fc.FinalizedBlockHash != genesis.Hash() {
+		t.Fatalf("wrong finalized block: have %x, want %x", fc.FinalizedBlockHash, genesis.Hash())
+	}
+	// Advancing time moves the timestamp of the next block.
+	if offset := api.AdvanceTime(3600); offset != 3600 {
+		t.Fatalf("wrong time offset: %d", offset)
+	}
+	recipient := common.HexToAddress("0xfee")
+	api.SetFeeRecipient(recipient)
+
+	second, err := api.Commit()
+	if err != nil {
+		t.Fatalf("failed to seal block: %v", err)
+	}
+	next := ethservice.BlockChain().GetBlockByHash(second)
+	if next.Time() < block.Time()+3600 {
+		t.Fatalf("time not advanced: parent %d, block %d", block.Time(), next.Time())
+	}
+	if next.Coinbase() != recipient {
+		t.Fatalf("wrong fee recipient: have %x, want %x", next.Coinbase(), recipient)
+	}
+	// Finalizing marks the head as final.
+	final, err := api.Finalize()
+	if err != nil {
+		t.Fatalf("failed to finalize: %v", err)
+	}
+	if final != second {
+		t.Fatalf("wrong finalized block: have %x, want %x", final, second)
+	}
+	if fc := api.ForkchoiceState(); fc.FinalizedBlockHash != second {
+		t.Fatalf("finalized block not updated: %x", fc.FinalizedBlockHash)
+	}
+}
+
+func TestSimulatedBeaconRequiresMergedGenesis(t *testing.T) {
+	genesis, blocks := generatePreMergeChain(1)
+	config := *genesis.Config
+	config.TerminalTotalDifficulty = big.NewInt(1)
+	genesis.Config = &config
+	n, ethservice := startEthService(t, genesis, blocks)
+	defer n.Close()
+
+	if _, err := NewSimulatedBeacon(0, ethservice); err == nil {
+		t.Fatal("expected error for chain not merged at genesis")
+	}
+}
@@ -205,6 +205,9 @@ type BlockChain interface {
 
 	// Snapshots returns the blockchain snapshot tree to paused it during sync.
 	Snapshots() *snapshot.Tree
+
+	// ReorgFloor returns the number of the highest block a reorg may not drop.
+	ReorgFloor() uint64
 }
 
 // New creates a new downloader to fetch hashes and blocks from remote peers.
@@ -835,7 +838,7 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 
 	ancestor, err := d.findAncestorSpanSearch(p, mode, remoteHeight, localHeight, floor)
 	if err == nil {
-		return ancestor, nil
+		return d.checkFinality(p, mode, ancestor)
 	}
 	// The returned error was not nil.
 	// If the error returned does not reflect that a common ancestor was not found, return it.
@@ -849,6 +852,20 @@ func (d *Downloader) findAncestor(p *peerConnection, remoteHeader *types.Header)
 	if err != nil {
 		return 0, err
 	}
+	return d.checkFinality(p, mode, ancestor)
+}
+
+// checkFinality refuses a common ancestor below the blocks the local chain
+// treats as final, as the local chain would refuse the reorg anyway.
+func (d *Downloader) checkFinality(p *peerConnection, mode SyncMode, ancestor uint64) (uint64, error) {
+	if mode == LightSync || d.blockchain == nil {
+		return ancestor, nil
+	}
+	if final := d.blockchain.ReorgFloor(); ancestor < final {
+		p.log.Warn("Ancestor below finalized block", "number", ancestor, "finalized", final)
+		ancestorRefusedMeter.Mark(1)
+		return 0, fmt.Errorf("%w: below finalized block %d", errInvalidAncestor, final)
+	}
 	return ancestor, nil
 }
 
@@ -616,6 +616,33 @@ func testBoundedForkedSync(t *testing.T, protocol uint, mode SyncMode) {
 	}
 }
 
+// Tests that chain forks below the blocks finalized by the local chain are
+// rejected.
+func TestFinalizedForkedSync66Full(t *testing.T) { testFinalizedForkedSync(t, eth.ETH66, FullSync) }
+func TestFinalizedForkedSync66Snap(t *testing.T) { testFinalizedForkedSync(t, eth.ETH66, SnapSync) }
+
+func testFinalizedForkedSync(t *testing.T, protocol uint, mode SyncMode) {
+	tester := newTester()
+	defer tester.terminate()
+
+	chainA := testChainForkLightA.shorten(len(testChainBase.blocks) + 80)
+	chainB := testChainForkLightB.shorten(len(testChainBase.blocks) + 81)
+	tester.newPeer("fork A", protocol, chainA.blocks[1:])
+	tester.newPeer("fork B", protocol, chainB.blocks[1:])
+
+	if err := tester.sync("fork A", nil, mode); err != nil {
+		t.Fatalf("failed to synchronise blocks: %v", err)
+	}
+	assertOwnChain(t, tester, len(chainA.blocks))
+
+	// Finalize the blocks deeper than 50 and ensure the fork is rejected
+	tester.chain.SetMaxReorgDepth(50)
+	if err := tester.sync("fork B", nil, mode); !errors.Is(err, errInvalidAncestor) {
+		t.Fatalf("sync failure mismatch: have %v, want %v", err, errInvalidAncestor)
+	}
+	assertOwnChain(t, tester, len(chainA.blocks))
+}
+
 // Tests that chain forks are contained within a certain interval of the current
 // chain head for short but heavy forks too. These are a bit special because they
 // take different ancestor lookup paths.
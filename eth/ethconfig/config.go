@@ -25,9 +25,11 @@ import (
 	"runtime"
 	"time"
 
+	"github.com/ethereum/go-ethereum/builder"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/bft"
 	"github.com/ethereum/go-ethereum/consensus/clique"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -74,6 +76,7 @@ var Defaults = Config{
 	},
 	NetworkId:               1,
 	TxLookupLimit:           2350000,
+	AddressIndexLimit:       2350000,
 	LightPeers:              100,
 	UltraLightFraction:      75,
 	DatabaseCache:           512,
@@ -87,6 +90,8 @@ var Defaults = Config{
 		GasCeil:  8000000,
 		GasPrice: big.NewInt(params.GWei),
 		Recommit: 3 * time.Second,
+
+		StratumDifficulty: 1,
 	},
 	TxPool:        core.DefaultTxPoolConfig,
 	RPCGasCap:     50000000,
@@ -138,11 +143,22 @@ type Config struct {
 
 	TxLookupLimit uint64 `toml:",omitempty"` // The maximum number of blocks from head whose tx indices are reserved.
 
+	LogIndex bool `toml:",omitempty"` // Whether to maintain a dedicated address/topic log index for filtering
+
+	// Address activity index options
+	AddressIndex         bool   `toml:",omitempty"` // Whether to index transactions by participating addresses
+	AddressIndexLimit    uint64 `toml:",omitempty"` // The maximum number of blocks from head whose transactions are indexed by address (0 = entire chain)
+	AddressIndexInternal bool   `toml:",omitempty"` // Whether to trace blocks to index the participants of internal calls too
+
 	// PeerRequiredBlocks is a set of block number -> hash mappings which must be in the
 	// canonical chain of all remote peers. Setting the option makes geth verify the
 	// presence of these blocks for every new peer connection.
 	PeerRequiredBlocks map[uint64]common.Hash `toml:"-"`
 
+	// Reorg protection for chains without beacon finality
+	MaxReorgDepth      uint64 `toml:",omitempty"` // Maximum number of canonical blocks a reorg may drop (0 = unlimited)
+	FinalityCheckpoint string `toml:",omitempty"` // JSON file or RPC endpoint of a trusted node feeding finality checkpoints
+
 	// Light client options
 	LightServ          int  `toml:",omitempty"` // Maximum percentage of time allowed for serving LES requests
 	LightIngress       int  `toml:",omitempty"` // Incoming bandwidth limit for light servers
@@ -174,6 +190,9 @@ type Config struct {
 	// Mining options
 	Miner miner.Config
 
+	// Block builder options, nil if building for external proposers is disabled
+	Builder *builder.Config `toml:",omitempty"`
+
 	// Ethash options
 	Ethash ethash.Config
 
@@ -199,6 +218,10 @@ type Config struct {
 	// send-transction variants. The unit is ether.
 	RPCTxFeeCap float64
 
+	// RPCLogsLimit is the maximum number of logs returned by a log query over
+	// RPC. Zero means no limit.
+	RPCLogsLimit int `toml:",omitempty"`
+
 	// Checkpoint is a hardcoded checkpoint which can be nil.
 	Checkpoint *params.TrustedCheckpoint `toml:",omitempty"`
 
@@ -218,6 +241,8 @@ func CreateConsensusEngine(stack *node.Node, chainConfig *params.ChainConfig, co
 	var engine consensus.Engine
 	if chainConfig.Clique != nil {
 		engine = clique.New(chainConfig.Clique, db)
+	} else if chainConfig.BFT != nil {
+		engine = bft.New(chainConfig.BFT, db)
 	} else {
 		switch config.PowMode {
 		case ethash.ModeFake:
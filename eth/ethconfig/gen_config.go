@@ -6,6 +6,7 @@ import (
 	"math/big"
 	"time"
 
+	"github.com/ethereum/go-ethereum/builder"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
@@ -26,7 +27,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		NoPruning                       bool
 		NoPrefetch                      bool
 		TxLookupLimit                   uint64                 `toml:",omitempty"`
+		LogIndex                        bool                   `toml:",omitempty"`
+		AddressIndex                    bool                   `toml:",omitempty"`
+		AddressIndexLimit               uint64                 `toml:",omitempty"`
+		AddressIndexInternal            bool                   `toml:",omitempty"`
 		PeerRequiredBlocks              map[uint64]common.Hash `toml:"-"`
+		MaxReorgDepth                   uint64                 `toml:",omitempty"`
+		FinalityCheckpoint              string                 `toml:",omitempty"`
 		LightServ                       int                    `toml:",omitempty"`
 		LightIngress                    int                    `toml:",omitempty"`
 		LightEgress                     int                    `toml:",omitempty"`
@@ -49,6 +56,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		SnapshotCache                   int
 		Preimages                       bool
 		Miner                           miner.Config
+		Builder                         *builder.Config `toml:",omitempty"`
 		Ethash                          ethash.Config
 		TxPool                          core.TxPoolConfig
 		GPO                             gasprice.Config
@@ -57,6 +65,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		RPCGasCap                       uint64
 		RPCEVMTimeout                   time.Duration
 		RPCTxFeeCap                     float64
+		RPCLogsLimit                    int                            `toml:",omitempty"`
 		Checkpoint                      *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideArrowGlacier            *big.Int                       `toml:",omitempty"`
@@ -71,7 +80,13 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.NoPruning = c.NoPruning
 	enc.NoPrefetch = c.NoPrefetch
 	enc.TxLookupLimit = c.TxLookupLimit
+	enc.LogIndex = c.LogIndex
+	enc.AddressIndex = c.AddressIndex
+	enc.AddressIndexLimit = c.AddressIndexLimit
+	enc.AddressIndexInternal = c.AddressIndexInternal
 	enc.PeerRequiredBlocks = c.PeerRequiredBlocks
+	enc.MaxReorgDepth = c.MaxReorgDepth
+	enc.FinalityCheckpoint = c.FinalityCheckpoint
 	enc.LightServ = c.LightServ
 	enc.LightIngress = c.LightIngress
 	enc.LightEgress = c.LightEgress
@@ -94,6 +109,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.SnapshotCache = c.SnapshotCache
 	enc.Preimages = c.Preimages
 	enc.Miner = c.Miner
+	enc.Builder = c.Builder
 	enc.Ethash = c.Ethash
 	enc.TxPool = c.TxPool
 	enc.GPO = c.GPO
@@ -102,6 +118,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
+	enc.RPCLogsLimit = c.RPCLogsLimit
 	enc.Checkpoint = c.Checkpoint
 	enc.CheckpointOracle = c.CheckpointOracle
 	enc.OverrideArrowGlacier = c.OverrideArrowGlacier
@@ -120,7 +137,13 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		NoPruning                       *bool
 		NoPrefetch                      *bool
 		TxLookupLimit                   *uint64                `toml:",omitempty"`
+		LogIndex                        *bool                  `toml:",omitempty"`
+		AddressIndex                    *bool                  `toml:",omitempty"`
+		AddressIndexLimit               *uint64                `toml:",omitempty"`
+		AddressIndexInternal            *bool                  `toml:",omitempty"`
 		PeerRequiredBlocks              map[uint64]common.Hash `toml:"-"`
+		MaxReorgDepth                   *uint64                `toml:",omitempty"`
+		FinalityCheckpoint              *string                `toml:",omitempty"`
 		LightServ                       *int                   `toml:",omitempty"`
 		LightIngress                    *int                   `toml:",omitempty"`
 		LightEgress                     *int                   `toml:",omitempty"`
@@ -143,6 +166,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		SnapshotCache                   *int
 		Preimages                       *bool
 		Miner                           *miner.Config
+		Builder                         *builder.Config `toml:",omitempty"`
 		Ethash                          *ethash.Config
 		TxPool                          *core.TxPoolConfig
 		GPO                             *gasprice.Config
@@ -151,6 +175,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		RPCGasCap                       *uint64
 		RPCEVMTimeout                   *time.Duration
 		RPCTxFeeCap                     *float64
+		RPCLogsLimit                    *int                           `toml:",omitempty"`
 		Checkpoint                      *params.TrustedCheckpoint      `toml:",omitempty"`
 		CheckpointOracle                *params.CheckpointOracleConfig `toml:",omitempty"`
 		OverrideArrowGlacier            *big.Int                       `toml:",omitempty"`
@@ -184,9 +209,27 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxLookupLimit != nil {
 		c.TxLookupLimit = *dec.TxLookupLimit
 	}
+	if dec.LogIndex != nil {
+		c.LogIndex = *dec.LogIndex
+	}
+	if dec.AddressIndex != nil {
+		c.AddressIndex = *dec.AddressIndex
+	}
+	if dec.AddressIndexLimit != nil {
+		c.AddressIndexLimit = *dec.AddressIndexLimit
+	}
+	if dec.AddressIndexInternal != nil {
+		c.AddressIndexInternal = *dec.AddressIndexInternal
+	}
 	if dec.PeerRequiredBlocks != nil {
 		c.PeerRequiredBlocks = dec.PeerRequiredBlocks
 	}
+	if dec.MaxReorgDepth != nil {
+		c.MaxReorgDepth = *dec.MaxReorgDepth
+	}
+	if dec.FinalityCheckpoint != nil {
+		c.FinalityCheckpoint = *dec.FinalityCheckpoint
+	}
 	if dec.LightServ != nil {
 		c.LightServ = *dec.LightServ
 	}
@@ -253,6 +296,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.Miner != nil {
 		c.Miner = *dec.Miner
 	}
+	if dec.Builder != nil {
+		c.Builder = dec.Builder
+	}
 	if dec.Ethash != nil {
 		c.Ethash = *dec.Ethash
 	}
@@ -277,6 +323,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
+	if dec.RPCLogsLimit != nil {
+		c.RPCLogsLimit = *dec.RPCLogsLimit
+	}
 	if dec.Checkpoint != nil {
 		c.Checkpoint = dec.Checkpoint
 	}
@@ -30,6 +30,8 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -39,6 +41,8 @@ type filter struct {
 	typ      Type
 	deadline *time.Timer // filter is inactiv when deadline triggers
 	hashes   []common.Hash
+	fullTx   bool
+	txs      []*types.Transaction
 	crit     FilterCriteria
 	logs     []*types.Log
 	s        *Subscription // associated subscription in event system
@@ -54,15 +58,18 @@ type PublicFilterAPI struct {
 	filtersMu sync.Mutex
 	filters   map[rpc.ID]*filter
 	timeout   time.Duration
+	logLimit  int
 }
 
-// NewPublicFilterAPI returns a new PublicFilterAPI instance.
-func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration) *PublicFilterAPI {
+// NewPublicFilterAPI returns a new PublicFilterAPI instance. Log queries over a
+// block range fail if they match more than logLimit logs, zero means no limit.
+func NewPublicFilterAPI(backend Backend, lightMode bool, timeout time.Duration, logLimit int) *PublicFilterAPI {
 	api := &PublicFilterAPI{
-		backend: backend,
-		events:  NewEventSystem(backend, lightMode),
-		filters: make(map[rpc.ID]*filter),
-		timeout: timeout,
+		backend:  backend,
+		events:   NewEventSystem(backend, lightMode),
+		filters:  make(map[rpc.ID]*filter),
+		timeout:  timeout,
+		logLimit: logLimit,
 	}
 	go api.timeoutLoop(timeout)
 
@@ -99,30 +106,72 @@ func (api *PublicFilterAPI) timeoutLoop(timeout time.Duration) {
 	}
 }
 
-// NewPendingTransactionFilter creates a filter that fetches pending transaction hashes
-// as transactions enter the pending state.
+// PendingTxCriteria restricts the pending transactions delivered by a pending
+// transaction filter or subscription. A transaction matches if its sender is in
+// From (or From is empty) and its recipient is in To (or To is empty). Contract
+// creations never match a non-empty To set.
+type PendingTxCriteria struct {
+	From []common.Address `json:"from"`
+	To   []common.Address `json:"to"`
+}
+
+// filter returns the subset of txs matching the criteria. Senders are only
+// derived if there are sender constraints to check.
+func (crit *PendingTxCriteria) filter(signer types.Signer, txs []*types.Transaction) []*types.Transaction {
+	if crit == nil || (len(crit.From) == 0 && len(crit.To) == 0) {
+		return txs
+	}
+	matched := make([]*types.Transaction, 0, len(txs))
+	for _, tx := range txs {
+		if len(crit.To) > 0 && (tx.To() == nil || !includes(crit.To, *tx.To())) {
+			continue
+		}
+		if len(crit.From) > 0 {
+			from, err := types.Sender(signer, tx)
+			if err != nil || !includes(crit.From, from) {
+				continue
+			}
+		}
+		matched = append(matched, tx)
+	}
+	return matched
+}
+
+// NewPendingTransactionFilter creates a filter that fetches pending transactions
+// as they enter the pending state. If fullTx is true the filter retains the full
+// transactions, otherwise only their hashes. The optional criteria restrict the
+// transactions by sender and recipient.
 //
 // It is part of the filter package because this filter can be used through the
 // `eth_getFilterChanges` polling method that is also used for log filters.
 //
 // https://eth.wiki/json-rpc/API#eth_newpendingtransactionfilter
-func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
+func (api *PublicFilterAPI) NewPendingTransactionFilter(fullTx *bool, crit *PendingTxCriteria) rpc.ID {
 	var (
-		pendingTxs   = make(chan []common.Hash)
+		pendingTxs   = make(chan []*types.Transaction)
 		pendingTxSub = api.events.SubscribePendingTxs(pendingTxs)
+		signer       = types.LatestSigner(api.backend.ChainConfig())
 	)
 
 	api.filtersMu.Lock()
-	api.filters[pendingTxSub.ID] = &filter{typ: PendingTransactionsSubscription, deadline: time.NewTimer(api.timeout), hashes: make([]common.Hash, 0), s: pendingTxSub}
+	api.filters[pendingTxSub.ID] = &filter{typ: PendingTransactionsSubscription, fullTx: fullTx != nil && *fullTx, deadline: time.NewTimer(api.timeout), hashes: make([]common.Hash, 0), s: pendingTxSub}
 	api.filtersMu.Unlock()
 
 	go func() {
 		for {
 			select {
-			case ph := <-pendingTxs:
+			case pTx := <-pendingTxs:
+				pTx = crit.filter(signer, pTx)
+
 				api.filtersMu.Lock()
 				if f, found := api.filters[pendingTxSub.ID]; found {
-					f.hashes = append(f.hashes, ph...)
+					if f.fullTx {
+						f.txs = append(f.txs, pTx...)
+					} else {
+						for _, tx := range pTx {
+							f.hashes = append(f.hashes, tx.Hash())
+						}
+					}
 				}
 				api.filtersMu.Unlock()
 			case <-pendingTxSub.Err():
@@ -137,9 +186,11 @@ func (api *PublicFilterAPI) NewPendingTransactionFilter() rpc.ID {
 	return pendingTxSub.ID
 }
 
-// NewPendingTransactions creates a subscription that is triggered each time a transaction
-// enters the transaction pool and was signed from one of the transactions this nodes manages.
-func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// NewPendingTransactions creates a subscription that is triggered each time a
+// transaction enters the transaction pool. If fullTx is true the full tx is
+// sent to the client, otherwise the hash is sent. The optional criteria restrict
+// the transactions by sender and recipient.
+func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool, crit *PendingTxCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
@@ -148,16 +199,25 @@ func (api *PublicFilterAPI) NewPendingTransactions(ctx context.Context) (*rpc.Su
 	rpcSub := notifier.CreateSubscription()
 
 	go func() {
-		txHashes := make(chan []common.Hash, 128)
-		pendingTxSub := api.events.SubscribePendingTxs(txHashes)
+		pendingTxs := make(chan []*types.Transaction, 128)
+		pendingTxSub := api.events.SubscribePendingTxs(pendingTxs)
+		chainConfig := api.backend.ChainConfig()
+		signer := types.LatestSigner(chainConfig)
 
 		for {
 			select {
-			case hashes := <-txHashes:
+			case txs := <-pendingTxs:
+				txs = crit.filter(signer, txs)
+
 				// To keep the original behaviour, send a single tx hash in one notification.
 				// TODO(rjl493456442) Send a batch of tx hashes in one notification
-				for _, h := range hashes {
-					notifier.Notify(rpcSub.ID, h)
+				latest := api.backend.CurrentHeader()
+				for _, tx := range txs {
+					if fullTx != nil && *fullTx {
+						notifier.Notify(rpcSub.ID, ethapi.NewRPCPendingTransaction(tx, latest, chainConfig))
+					} else {
+						notifier.Notify(rpcSub.ID, tx.Hash())
+					}
 				}
 			case <-rpcSub.Err():
 				pendingTxSub.Unsubscribe()
@@ -237,7 +297,33 @@ func (api *PublicFilterAPI) NewHeads(ctx context.Context) (*rpc.Subscription, er
 	return rpcSub, nil
 }
 
+const (
+	// backfillChunkSize is the number of blocks queried at once when delivering
+	// historical logs to a subscription.
+	backfillChunkSize = 2048
+
+	// backfillReorgWindow is the number of blocks below the head at subscription
+	// time for which delivered blocks are tracked to reconcile reorgs crossing
+	// the boundary between historical and live logs.
+	backfillReorgWindow = 128
+
+	// backfillMaxPending is the maximum number of live logs buffered while the
+	// historical logs of a subscription are delivered.
+	backfillMaxPending = 10000
+)
+
+// errBackfillOverflow ends log subscriptions receiving more live logs than can be
+// buffered until their historical logs are delivered.
+var errBackfillOverflow = errors.New("too many live logs while delivering historical logs")
+
 // Logs creates a subscription that fires for all new log that match the given filter criteria.
+//
+// If the criteria contain a fromBlock not above the current head, the matching
+// historical logs are delivered first, after which the subscription switches to
+// live logs. Reorgs crossing the switch-over point are reported with removed=true
+// for previously delivered logs, while duplicate deliveries are suppressed. This
+// allows clients to resume a subscription after a disconnect without missing logs.
+// For compatibility with clients defaulting to it, a fromBlock of 0 is ignored.
 func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subscription, error) {
 	notifier, supported := rpc.NotifierFromContext(ctx)
 	if !supported {
@@ -253,12 +339,29 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	if err != nil {
 		return nil, err
 	}
+	// Check whether there are historical logs to deliver. This is done only after
+	// the live subscription was installed to avoid missing blocks in between.
+	var backfill *logBackfill
+	if crit.FromBlock != nil && crit.FromBlock.Sign() > 0 {
+		if head := api.backend.CurrentHeader(); head != nil && crit.FromBlock.Uint64() <= head.Number.Uint64() {
+			to := head.Number.Uint64()
+			if crit.ToBlock != nil && crit.ToBlock.Sign() >= 0 && crit.ToBlock.Uint64() < to {
+				to = crit.ToBlock.Uint64()
+			}
+			backfill = newLogBackfill(crit.FromBlock.Uint64(), to, head.Number.Uint64())
+		}
+	}
 
 	go func() {
-
+		if backfill != nil && !api.backfillLogs(notifier, rpcSub, logsSub, matchedLogs, crit, backfill) {
+			return
+		}
 		for {
 			select {
 			case logs := <-matchedLogs:
+				if backfill != nil {
+					logs = backfill.reconcile(logs)
+				}
 				for _, log := range logs {
 					notifier.Notify(rpcSub.ID, &log)
 				}
@@ -275,6 +378,143 @@ func (api *PublicFilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc
 	return rpcSub, nil
 }
 
+// backfillLogs delivers the historical logs of a subscription, buffering any live
+// logs arriving in the meantime and delivering them once done. It returns false
+// if the subscription was terminated during the backfill, which fails it with
+// errBackfillOverflow if more than backfillMaxPending live logs arrive.
+func (api *PublicFilterAPI) backfillLogs(notifier *rpc.Notifier, rpcSub *rpc.Subscription, logsSub *Subscription, matchedLogs chan []*types.Log, crit FilterCriteria, backfill *logBackfill) bool {
+	type result struct {
+		logs []*types.Log
+		err  error
+	}
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		results     = make(chan result)
+		pending     [][]*types.Log
+		buffered    int
+	)
+	defer cancel()
+
+	go func() {
+		defer close(results)
+		for begin := backfill.from; begin <= backfill.to; begin += backfillChunkSize {
+			end := begin + backfillChunkSize - 1
+			if end > backfill.to {
+				end = backfill.to
+			}
+			logs, err := NewRangeFilter(api.backend, int64(begin), int64(end), crit.Addresses, crit.Topics).Logs(ctx)
+			select {
+			case results <- result{logs, err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	for {
+		select {
+		case res, ok := <-results:
+			if ok && res.err != nil {
+				log.Warn("Failed to deliver historical logs", "from", backfill.from, "to", backfill.to, "err", res.err)
+			}
+			if !ok || res.err != nil {
+				for _, logs := range pending {
+					for _, log := range backfill.reconcile(logs) {
+						notifier.Notify(rpcSub.ID, &log)
+					}
+				}
+				return true
+			}
+			for _, log := range res.logs {
+				backfill.delivered(log)
+				notifier.Notify(rpcSub.ID, &log)
+			}
+		case logs := <-matchedLogs:
+			if buffered += len(logs); buffered > backfillMaxPending {
+				log.Warn("Dropping log subscription during backfill", "from", backfill.from, "to", backfill.to, "err", errBackfillOverflow)
+				notifier.Fail(rpcSub.ID, errBackfillOverflow)
+				logsSub.Unsubscribe()
+				return false
+			}
+			pending = append(pending, logs)
+
+		case <-rpcSub.Err(): // client send an unsubscribe request
+			logsSub.Unsubscribe()
+			return false
+		case <-notifier.Closed(): // connection dropped
+			logsSub.Unsubscribe()
+			return false
+		}
+	}
+}
+
+// Delivery states of a block tracked by logBackfill.
+const (
+	blockBackfilled = iota + 1 // logs delivered from the historical chain
+	blockLive                  // logs delivered from the live feed
+	blockRemoved               // logs removed after delivery
+)
+
+// logBackfill tracks the blocks whose logs were delivered by a subscription near
+// the boundary between historical and live logs. It is used to drop duplicates
+// from the live feed and to forward only those removed logs which were actually
+// delivered to the subscriber before.
+type logBackfill struct {
+	from, to uint64              // Range of blocks to deliver historical logs for
+	head     uint64              // Chain head when the subscription was installed
+	window   uint64              // Lowest block number tracked for reorgs
+	blocks   map[common.Hash]int // Delivery states of blocks within the window
+}
+
+func newLogBackfill(from, to, head uint64) *logBackfill {
+	window := uint64(0)
+	if head > backfillReorgWindow {
+		window = head - backfillReorgWindow
+	}
+	return &logBackfill{
+		from:   from,
+		to:     to,
+		head:   head,
+		window: window,
+		blocks: make(map[common.Hash]int),
+	}
+}
+
+// delivered marks the block of a historical log as delivered.
+func (b *logBackfill) delivered(log *types.Log) {
+	if log.BlockNumber >= b.window {
+		b.blocks[log.BlockHash] = blockBackfilled
+	}
+}
+
+// reconcile filters a batch of live logs, dropping the ones already delivered
+// from history and removals of logs the subscriber has never seen.
+func (b *logBackfill) reconcile(logs []*types.Log) []*types.Log {
+	filtered := logs[:0:0]
+	for _, log := range logs {
+		if log.BlockNumber > b.head || log.BlockNumber < b.window {
+			filtered = append(filtered, log)
+			continue
+		}
+		state := b.blocks[log.BlockHash]
+		if log.Removed {
+			if state == 0 {
+				continue
+			}
+			b.blocks[log.BlockHash] = blockRemoved
+		} else {
+			if state == blockBackfilled {
+				continue
+			}
+			b.blocks[log.BlockHash] = blockLive
+		}
+		filtered = append(filtered, log)
+	}
+	return filtered
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
@@ -344,6 +584,7 @@ func (api *PublicFilterAPI) GetLogs(ctx context.Context, crit FilterCriteria) ([
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, crit.Addresses, crit.Topics)
+		filter.SetLimit(api.logLimit)
 	}
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
@@ -399,6 +640,7 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 		}
 		// Construct the range filter
 		filter = NewRangeFilter(api.backend, begin, end, f.crit.Addresses, f.crit.Topics)
+		filter.SetLimit(api.logLimit)
 	}
 	// Run the filter and return all the logs
 	logs, err := filter.Logs(ctx)
@@ -411,8 +653,9 @@ func (api *PublicFilterAPI) GetFilterLogs(ctx context.Context, id rpc.ID) ([]*ty
 // GetFilterChanges returns the logs for the filter with the given id since
 // last time it was called. This can be used for polling.
 //
-// For pending transaction and block filters the result is []common.Hash.
-// (pending)Log filters return []Log.
+// For pending transaction and block filters the result is []common.Hash, unless
+// the pending transaction filter was created with fullTx, in which case it is a
+// list of RPC transactions. (pending)Log filters return []Log.
 //
 // https://eth.wiki/json-rpc/API#eth_getfilterchanges
 func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
@@ -428,7 +671,23 @@ func (api *PublicFilterAPI) GetFilterChanges(id rpc.ID) (interface{}, error) {
 		f.deadline.Reset(api.timeout)
 
 		switch f.typ {
-		case PendingTransactionsSubscription, BlocksSubscription:
+		case PendingTransactionsSubscription:
+			if f.fullTx {
+				var (
+					txs         = make([]*ethapi.RPCTransaction, 0, len(f.txs))
+					latest      = api.backend.CurrentHeader()
+					chainConfig = api.backend.ChainConfig()
+				)
+				for _, tx := range f.txs {
+					txs = append(txs, ethapi.NewRPCPendingTransaction(tx, latest, chainConfig))
+				}
+				f.txs = nil
+				return txs, nil
+			}
+			hashes := f.hashes
+			f.hashes = nil
+			return returnHashes(hashes), nil
+		case BlocksSubscription:
 			hashes := f.hashes
 			f.hashes = nil
 			return returnHashes(hashes), nil
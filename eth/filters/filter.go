@@ -19,19 +19,25 @@ package filters
 import (
 	"context"
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
 type Backend interface {
 	ChainDb() ethdb.Database
+	ChainConfig() *params.ChainConfig
+	CurrentHeader() *types.Header
 	HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error)
 	HeaderByHash(ctx context.Context, blockHash common.Hash) (*types.Header, error)
 	GetReceipts(ctx context.Context, blockHash common.Hash) (types.Receipts, error)
@@ -47,6 +53,42 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// LogIndexBackend is an optional extension of Backend, implemented by nodes that
+// maintain the dedicated address/topic log index (see core.LogIndexer). Filters
+// prefer it over the bloom bits for the range it covers.
+type LogIndexBackend interface {
+	Backend
+
+	// LogIndexStatus returns the section size and number of sections indexed.
+	LogIndexStatus() (uint64, uint64)
+}
+
+// errLogLimitReached is returned internally when a range query found more logs
+// than the filter's limit.
+var errLogLimitReached = errors.New("log limit reached")
+
+// LogLimitError is returned by range queries matching more logs than the limit of
+// the filter. All logs up to and including LastBlock fit into the limit, so the
+// query can be split and resumed from the block after it. If LastBlock precedes
+// the start of the query, the logs of the first matching block alone exceed the
+// limit, those can still be retrieved by querying the block by hash.
+type LogLimitError struct {
+	Limit     int
+	LastBlock uint64
+}
+
+func (e *LogLimitError) Error() string {
+	return fmt.Sprintf("query returned more than %d results, last block within the limit is %d", e.Limit, e.LastBlock)
+}
+
+// ErrorCode returns the JSON-RPC error code for exceeded limits.
+func (e *LogLimitError) ErrorCode() int { return -32005 }
+
+// ErrorData returns the limit and the last block within it.
+func (e *LogLimitError) ErrorData() interface{} {
+	return map[string]interface{}{"limit": e.Limit, "lastBlock": hexutil.Uint64(e.LastBlock)}
+}
+
 // Filter can be used to retrieve and filter logs.
 type Filter struct {
 	backend Backend
@@ -58,6 +100,9 @@ type Filter struct {
 	block      common.Hash // Block hash if filtering a single block
 	begin, end int64       // Range interval if filtering multiple blocks
 
+	limit int // Maximum number of logs to return from a range query, zero if unlimited
+	found int // Number of logs found so far, used to enforce the limit
+
 	matcher *bloombits.Matcher
 }
 
@@ -114,6 +159,20 @@ func newFilter(backend Backend, addresses []common.Address, topics [][]common.Ha
 	}
 }
 
+// SetLimit sets the maximum number of logs returned by a range query. Queries
+// matching more logs fail with an error reporting the last block whose logs fit
+// into the limit. Block filters are not limited.
+func (f *Filter) SetLimit(limit int) {
+	f.limit = limit
+}
+
+// exceeded accounts for newly found logs and reports whether the result limit of
+// the filter has been exceeded.
+func (f *Filter) exceeded(found int) bool {
+	f.found += found
+	return f.limit > 0 && f.found > f.limit
+}
+
 // Logs searches the blockchain for matching log entries, returning all from the
 // first block that contains matches, updating the start of the filter accordingly.
 func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
@@ -142,18 +201,45 @@ func (f *Filter) Logs(ctx context.Context) ([]*types.Log, error) {
 	if f.end == -1 {
 		end = head
 	}
-	// Gather all indexed logs, and finish with non indexed ones
+	logs, err := f.rangeLogs(ctx, end)
+	if err == errLogLimitReached {
+		// The logs of the last block pushed the result over the limit, report
+		// the preceding block as the last one fully served. The genesis block
+		// has no logs, so this can't underflow.
+		return nil, &LogLimitError{Limit: f.limit, LastBlock: logs[f.limit].BlockNumber - 1}
+	}
+	return logs, err
+}
+
+// rangeLogs gathers the logs of a range query, using the log index and the bloom
+// bits for the range they cover and finishing with the non indexed blocks.
+func (f *Filter) rangeLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
 	var (
 		logs []*types.Log
 		err  error
 	)
+	if backend, ok := f.backend.(LogIndexBackend); ok && f.constrained() {
+		size, sections := backend.LogIndexStatus()
+		if indexed := sections * size; indexed > uint64(f.begin) {
+			if indexed > end {
+				logs, err = f.logIndexedLogs(ctx, size, end)
+			} else {
+				logs, err = f.logIndexedLogs(ctx, size, indexed-1)
+			}
+			if err != nil {
+				return logs, err
+			}
+		}
+	}
 	size, sections := f.backend.BloomStatus()
 	if indexed := sections * size; indexed > uint64(f.begin) {
+		var found []*types.Log
 		if indexed > end {
-			logs, err = f.indexedLogs(ctx, end)
+			found, err = f.indexedLogs(ctx, end)
 		} else {
-			logs, err = f.indexedLogs(ctx, indexed-1)
+			found, err = f.indexedLogs(ctx, indexed-1)
 		}
+		logs = append(logs, found...)
 		if err != nil {
 			return logs, err
 		}
@@ -203,6 +289,9 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 				return logs, err
 			}
 			logs = append(logs, found...)
+			if f.exceeded(len(found)) {
+				return logs, errLogLimitReached
+			}
 
 		case <-ctx.Done():
 			return logs, ctx.Err()
@@ -210,6 +299,175 @@ func (f *Filter) indexedLogs(ctx context.Context, end uint64) ([]*types.Log, err
 	}
 }
 
+// constrained reports whether the filter restricts the logs by address or by any
+// topic position. Unconstrained filters match every log, so they cannot benefit
+// from the log index.
+func (f *Filter) constrained() bool {
+	if len(f.addresses) > 0 {
+		return true
+	}
+	for _, topics := range f.topics {
+		if len(topics) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// logIndexedLogs returns the logs matching the filter criteria based on the
+// dedicated log index. Contrary to the bloom bits, the index yields the exact log
+// positions, so only blocks with real matches are ever loaded.
+func (f *Filter) logIndexedLogs(ctx context.Context, size uint64, end uint64) ([]*types.Log, error) {
+	var logs []*types.Log
+
+	for section := uint64(f.begin) / size; section <= end/size; section++ {
+		if err := ctx.Err(); err != nil {
+			return logs, err
+		}
+		head := rawdb.ReadCanonicalHash(f.db, (section+1)*size-1)
+		positions, err := f.sectionMatches(section, size, head)
+		if err != nil {
+			return logs, err
+		}
+		for len(positions) > 0 {
+			// Gather all the positions in the next matching block
+			number, n := positions[0].Number, 1
+			for n < len(positions) && positions[n].Number == number {
+				n++
+			}
+			indices := positions[:n]
+			positions = positions[n:]
+
+			if number < uint64(f.begin) {
+				continue
+			}
+			if number > end {
+				break
+			}
+			header, err := f.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+			if header == nil || err != nil {
+				return logs, err
+			}
+			found, err := f.pickLogs(ctx, header, indices)
+			if err != nil {
+				return logs, err
+			}
+			logs = append(logs, found...)
+			if f.exceeded(len(found)) {
+				return logs, errLogLimitReached
+			}
+		}
+		f.begin = int64((section + 1) * size)
+	}
+	f.begin = int64(end) + 1
+	return logs, nil
+}
+
+// sectionMatches returns the sorted positions of all the logs within a section
+// that match the filter criteria. Alternatives within the address list or within
+// a topic position are unioned, the different criteria are intersected.
+func (f *Filter) sectionMatches(section, size uint64, head common.Hash) ([]core.LogPosition, error) {
+	var terms [][][]byte
+	if len(f.addresses) > 0 {
+		group := make([][]byte, len(f.addresses))
+		for i, address := range f.addresses {
+			group[i] = core.LogIndexAddressTerm(address)
+		}
+		terms = append(terms, group)
+	}
+	for pos, topics := range f.topics {
+		if len(topics) == 0 {
+			continue
+		}
+		group := make([][]byte, len(topics))
+		for i, topic := range topics {
+			group[i] = core.LogIndexTopicTerm(pos, topic)
+		}
+		terms = append(terms, group)
+	}
+	var matches []core.LogPosition
+	for i, group := range terms {
+		var union []core.LogPosition
+		for _, term := range group {
+			blob, _ := rawdb.ReadLogIndex(f.db, section, head, term)
+			positions, err := core.DecodeLogIndexPositions(section*size, blob)
+			if err != nil {
+				return nil, err
+			}
+			union = mergeLogPositions(union, positions)
+		}
+		if i == 0 {
+			matches = union
+		} else {
+			matches = intersectLogPositions(matches, union)
+		}
+		if len(matches) == 0 {
+			return nil, nil
+		}
+	}
+	return matches, nil
+}
+
+// pickLogs retrieves the logs at the given positions from the block belonging to
+// the given header, dropping any that do not match the criteria after all.
+func (f *Filter) pickLogs(ctx context.Context, header *types.Header, positions []core.LogPosition) ([]*types.Log, error) {
+	logsList, err := f.backend.GetLogs(ctx, header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	var unfiltered []*types.Log
+	for _, logs := range logsList {
+		unfiltered = append(unfiltered, logs...)
+	}
+	picked := make([]*types.Log, 0, len(positions))
+	for _, pos := range positions {
+		if pos.Index < uint(len(unfiltered)) {
+			picked = append(picked, unfiltered[pos.Index])
+		}
+	}
+	return filterLogs(picked, nil, nil, f.addresses, f.topics), nil
+}
+
+// mergeLogPositions returns the sorted union of two sorted position lists.
+func mergeLogPositions(a, b []core.LogPosition) []core.LogPosition {
+	merged := make([]core.LogPosition, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case lessLogPosition(a[0], b[0]):
+			merged, a = append(merged, a[0]), a[1:]
+		case lessLogPosition(b[0], a[0]):
+			merged, b = append(merged, b[0]), b[1:]
+		default:
+			merged, a, b = append(merged, a[0]), a[1:], b[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// intersectLogPositions returns the sorted intersection of two sorted position lists.
+func intersectLogPositions(a, b []core.LogPosition) []core.LogPosition {
+	var shared []core.LogPosition
+	for len(a) > 0 && len(b) > 0 {
+		switch {
+		case lessLogPosition(a[0], b[0]):
+			a = a[1:]
+		case lessLogPosition(b[0], a[0]):
+			b = b[1:]
+		default:
+			shared, a, b = append(shared, a[0]), a[1:], b[1:]
+		}
+	}
+	return shared
+}
+
+func lessLogPosition(a, b core.LogPosition) bool {
+	if a.Number != b.Number {
+		return a.Number < b.Number
+	}
+	return a.Index < b.Index
+}
+
 // unindexedLogs returns the logs matching the filter criteria based on raw block
 // iteration and bloom matching.
 func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, error) {
@@ -225,6 +483,9 @@ func (f *Filter) unindexedLogs(ctx context.Context, end uint64) ([]*types.Log, e
 			return logs, err
 		}
 		logs = append(logs, found...)
+		if f.exceeded(len(found)) {
+			return logs, errLogLimitReached
+		}
 	}
 	return logs, nil
 }
@@ -33,8 +33,10 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -58,6 +60,15 @@ func (b *testBackend) ChainDb() ethdb.Database {
 	return b.db
 }
 
+func (b *testBackend) ChainConfig() *params.ChainConfig {
+	return params.TestChainConfig
+}
+
+func (b *testBackend) CurrentHeader() *types.Header {
+	hdr, _ := b.HeaderByNumber(context.TODO(), rpc.LatestBlockNumber)
+	return hdr
+}
+
 func (b *testBackend) HeaderByNumber(ctx context.Context, blockNr rpc.BlockNumber) (*types.Header, error) {
 	var (
 		hash common.Hash
@@ -168,7 +179,7 @@ func TestBlockSubscription(t *testing.T) {
 	var (
 		db          = rawdb.NewMemoryDatabase()
 		backend     = &testBackend{db: db}
-		api         = NewPublicFilterAPI(backend, false, deadline)
+		api         = NewPublicFilterAPI(backend, false, deadline, 0)
 		genesis     = (&core.Genesis{BaseFee: big.NewInt(params.InitialBaseFee)}).MustCommit(db)
 		chain, _    = core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {})
 		chainEvents = []core.ChainEvent{}
@@ -220,7 +231,7 @@ func TestPendingTxFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
 
 		transactions = []*types.Transaction{
 			types.NewTransaction(0, common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268"), new(big.Int), 0, new(big.Int), nil),
@@ -233,7 +244,7 @@ func TestPendingTxFilter(t *testing.T) {
 		hashes []common.Hash
 	)
 
-	fid0 := api.NewPendingTransactionFilter()
+	fid0 := api.NewPendingTransactionFilter(nil, nil)
 
 	time.Sleep(1 * time.Second)
 	backend.txFeed.Send(core.NewTxsEvent{Txs: transactions})
@@ -269,13 +280,83 @@ func TestPendingTxFilter(t *testing.T) {
 	}
 }
 
+// TestPendingTxFilterFullTx tests whether pending tx filters created with fullTx
+// retrieve the full transactions, and that sender and recipient criteria are
+// applied before delivery.
+func TestPendingTxFilterFullTx(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
+		signer  = types.LatestSigner(params.TestChainConfig)
+
+		key1, _ = crypto.GenerateKey()
+		key2, _ = crypto.GenerateKey()
+		addr1   = crypto.PubkeyToAddress(key1.PublicKey)
+		to1     = common.HexToAddress("0xb794f5ea0ba39494ce83a213fffba74279579268")
+		to2     = common.HexToAddress("0x0000000000000000000000000000000000000002")
+
+		transactions = []*types.Transaction{
+			types.MustSignNewTx(key1, signer, &types.LegacyTx{Nonce: 0, To: &to1, Gas: 21000, GasPrice: big.NewInt(1)}),
+			types.MustSignNewTx(key1, signer, &types.LegacyTx{Nonce: 1, To: &to2, Gas: 21000, GasPrice: big.NewInt(1)}),
+			types.MustSignNewTx(key2, signer, &types.LegacyTx{Nonce: 0, To: &to1, Gas: 21000, GasPrice: big.NewInt(1)}),
+			types.MustSignNewTx(key1, signer, &types.LegacyTx{Nonce: 2, Gas: 53000, GasPrice: big.NewInt(1)}),
+		}
+		fullTx = true
+	)
+	tests := []struct {
+		crit *PendingTxCriteria
+		want []*types.Transaction
+	}{
+		{nil, transactions},
+		{&PendingTxCriteria{From: []common.Address{addr1}}, []*types.Transaction{transactions[0], transactions[1], transactions[3]}},
+		{&PendingTxCriteria{To: []common.Address{to1}}, []*types.Transaction{transactions[0], transactions[2]}},
+		{&PendingTxCriteria{From: []common.Address{addr1}, To: []common.Address{to1}}, []*types.Transaction{transactions[0]}},
+	}
+	fids := make([]rpc.ID, len(tests))
+	for i, tt := range tests {
+		fids[i] = api.NewPendingTransactionFilter(&fullTx, tt.crit)
+	}
+	time.Sleep(1 * time.Second)
+	backend.txFeed.Send(core.NewTxsEvent{Txs: transactions})
+
+	for i, tt := range tests {
+		var (
+			txs     []*ethapi.RPCTransaction
+			timeout = time.Now().Add(1 * time.Second)
+		)
+		for {
+			results, err := api.GetFilterChanges(fids[i])
+			if err != nil {
+				t.Fatalf("test %d: unable to retrieve transactions: %v", i, err)
+			}
+			txs = append(txs, results.([]*ethapi.RPCTransaction)...)
+			if len(txs) >= len(tt.want) || time.Now().After(timeout) {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		if len(txs) != len(tt.want) {
+			t.Errorf("test %d: invalid number of transactions, want %d, got %d", i, len(tt.want), len(txs))
+			continue
+		}
+		for j := range txs {
+			if txs[j].Hash != tt.want[j].Hash() {
+				t.Errorf("test %d: txs[%d] invalid, want %x, got %x", i, j, tt.want[j].Hash(), txs[j].Hash)
+			}
+		}
+	}
+}
+
 // TestLogFilterCreation test whether a given filter criteria makes sense.
 // If not it must return an error.
 func TestLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
 
 		testCases = []struct {
 			crit    FilterCriteria
@@ -319,7 +400,7 @@ func TestInvalidLogFilterCreation(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
 	)
 
 	// different situations where log filter creation should fail.
@@ -341,7 +422,7 @@ func TestInvalidGetLogsRequest(t *testing.T) {
 	var (
 		db        = rawdb.NewMemoryDatabase()
 		backend   = &testBackend{db: db}
-		api       = NewPublicFilterAPI(backend, false, deadline)
+		api       = NewPublicFilterAPI(backend, false, deadline, 0)
 		blockHash = common.HexToHash("0x1111111111111111111111111111111111111111111111111111111111111111")
 	)
 
@@ -366,7 +447,7 @@ func TestLogFilter(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -473,6 +554,161 @@ func TestLogFilter(t *testing.T) {
 	}
 }
 
+// TestLogSubscriptionBackfill tests that log subscriptions with a fromBlock in the
+// past first deliver the historical logs, then switch over to live logs without
+// duplicating blocks and only report removals of logs delivered before.
+func TestLogSubscriptionBackfill(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
+		addr    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		topics  = []common.Hash{common.HexToHash("0x01")}
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 10, func(i int, gen *core.BlockGen) {
+		if i == 1 || i == 4 || i == 8 {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{{Address: addr, Topics: topics}}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	logs := make(chan types.Log)
+	sub, err := client.EthSubscribe(context.Background(), logs, "logs", map[string]interface{}{
+		"address":   addr,
+		"fromBlock": "0x3",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	// Feed live logs: a duplicate of a backfilled block, a log beyond the head,
+	// the removal of a backfilled block and the removal of an unknown block.
+	historic := []types.Log{
+		{Address: addr, Topics: topics, BlockNumber: 5, BlockHash: chain[4].Hash()},
+		{Address: addr, Topics: topics, BlockNumber: 9, BlockHash: chain[8].Hash()},
+	}
+	var (
+		duplicate = historic[1]
+		fresh     = types.Log{Address: addr, Topics: topics, BlockNumber: 11, BlockHash: common.Hash{0x11}}
+		removed   = historic[1]
+		unknown   = types.Log{Address: addr, Topics: topics, BlockNumber: 9, BlockHash: common.Hash{0x09}, Removed: true}
+	)
+	removed.Removed = true
+	backend.logsFeed.Send([]*types.Log{&duplicate, &fresh})
+	backend.rmLogsFeed.Send(core.RemovedLogsEvent{Logs: []*types.Log{&unknown, &removed}})
+
+	want := []types.Log{historic[0], historic[1], fresh, removed}
+	for i := range want {
+		select {
+		case log := <-logs:
+			if log.BlockHash != want[i].BlockHash || log.BlockNumber != want[i].BlockNumber || log.Removed != want[i].Removed {
+				t.Fatalf("log %d mismatch: have #%d [%x] removed=%v, want #%d [%x] removed=%v", i,
+					log.BlockNumber, log.BlockHash, log.Removed, want[i].BlockNumber, want[i].BlockHash, want[i].Removed)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for log %d", i)
+		}
+	}
+	select {
+	case log := <-logs:
+		t.Fatalf("unexpected log: #%d [%x] removed=%v", log.BlockNumber, log.BlockHash, log.Removed)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// stalledLogsBackend is a testBackend whose log retrieval blocks until the
+// context is canceled.
+type stalledLogsBackend struct {
+	*testBackend
+}
+
+func (b stalledLogsBackend) GetLogs(ctx context.Context, hash common.Hash) ([][]*types.Log, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// TestLogSubscriptionBackfillOverflow tests that log subscriptions receiving too
+// many live logs while delivering historical logs are failed.
+func TestLogSubscriptionBackfillOverflow(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		api     = NewPublicFilterAPI(stalledLogsBackend{backend}, false, deadline, 0)
+		addr    = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		topics  = []common.Hash{common.HexToHash("0x01")}
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 2, func(i int, gen *core.BlockGen) {
+		receipt := types.NewReceipt(nil, false, 0)
+		receipt.Logs = []*types.Log{{Address: addr, Topics: topics}}
+		gen.AddUncheckedReceipt(receipt)
+		gen.AddUncheckedTx(types.NewTransaction(uint64(i), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", api); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc.DialInProc(server)
+	defer client.Close()
+
+	logs := make(chan types.Log)
+	sub, err := client.EthSubscribe(context.Background(), logs, "logs", map[string]interface{}{
+		"address":   addr,
+		"fromBlock": "0x1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	live := make([]*types.Log, backfillMaxPending+1)
+	for i := range live {
+		live[i] = &types.Log{Address: addr, Topics: topics, BlockNumber: 3, BlockHash: common.Hash{0x03}, Index: uint(i)}
+	}
+	backend.logsFeed.Send(live)
+
+	select {
+	case log := <-logs:
+		t.Fatalf("unexpected log: #%d [%x]", log.BlockNumber, log.BlockHash)
+	case err := <-sub.Err():
+		if err == nil || err.Error() != errBackfillOverflow.Error() {
+			t.Fatalf("wrong subscription error: have %v, want %v", err, errBackfillOverflow)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription not failed")
+	}
+}
+
 // TestPendingLogsSubscription tests if a subscription receives the correct pending logs that are posted to the event feed.
 func TestPendingLogsSubscription(t *testing.T) {
 	t.Parallel()
@@ -480,7 +716,7 @@ func TestPendingLogsSubscription(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, deadline)
+		api     = NewPublicFilterAPI(backend, false, deadline, 0)
 
 		firstAddr      = common.HexToAddress("0x1111111111111111111111111111111111111111")
 		secondAddr     = common.HexToAddress("0x2222222222222222222222222222222222222222")
@@ -664,7 +900,7 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	var (
 		db      = rawdb.NewMemoryDatabase()
 		backend = &testBackend{db: db}
-		api     = NewPublicFilterAPI(backend, false, timeout)
+		api     = NewPublicFilterAPI(backend, false, timeout, 0)
 		done    = make(chan struct{})
 	)
 
@@ -688,7 +924,7 @@ func TestPendingTxFilterDeadlock(t *testing.T) {
 	// timeout either in 100ms or 200ms
 	fids := make([]rpc.ID, 20)
 	for i := 0; i < len(fids); i++ {
-		fid := api.NewPendingTransactionFilter()
+		fid := api.NewPendingTransactionFilter(nil, nil)
 		fids[i] = fid
 		// Wait for at least one tx to arrive in filter
 		for {
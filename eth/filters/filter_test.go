@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -29,6 +30,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/params"
 )
 
@@ -226,4 +228,122 @@ func TestFilters(t *testing.T) {
 	if len(logs) != 0 {
 		t.Error("expected 0 log, got", len(logs))
 	}
+
+	// Check that range queries exceeding the limit report the last block within it
+	filter = NewRangeFilter(backend, 0, -1, []common.Address{addr}, nil)
+	filter.SetLimit(3)
+	_, err = filter.Logs(context.Background())
+	if limitErr, ok := err.(*LogLimitError); !ok || limitErr.LastBlock != 999 {
+		t.Errorf("expected log limit error with last block 999, got %v", err)
+	}
+	filter = NewRangeFilter(backend, 0, 999, []common.Address{addr}, nil)
+	filter.SetLimit(3)
+	if logs, err = filter.Logs(context.Background()); err != nil || len(logs) != 3 {
+		t.Errorf("expected 3 logs within limit, got %d (%v)", len(logs), err)
+	}
+}
+
+// testLogIndexBackend extends the test backend with a dedicated log index.
+type testLogIndexBackend struct {
+	*testBackend
+	indexer *core.ChainIndexer
+}
+
+func (b *testLogIndexBackend) LogIndexStatus() (uint64, uint64) {
+	sections, _, _ := b.indexer.Sections()
+	return 8, sections
+}
+
+// testIndexerChain is a minimal core.ChainIndexerChain serving a fixed head.
+type testIndexerChain struct {
+	head *types.Header
+	feed event.Feed
+}
+
+func (c *testIndexerChain) CurrentHeader() *types.Header { return c.head }
+
+func (c *testIndexerChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return c.feed.Subscribe(ch)
+}
+
+// TestLogIndexFilters tests that range filters served from the dedicated log
+// index return exactly the same logs as plain block iteration.
+func TestLogIndexFilters(t *testing.T) {
+	var (
+		db      = rawdb.NewMemoryDatabase()
+		backend = &testBackend{db: db}
+		key1, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		addr    = crypto.PubkeyToAddress(key1.PublicKey)
+
+		addrs  = []common.Address{common.HexToAddress("0x1"), common.HexToAddress("0x2"), common.HexToAddress("0x3")}
+		topics = []common.Hash{common.HexToHash("0xa"), common.HexToHash("0xb"), common.HexToHash("0xc")}
+	)
+	genesis := core.GenesisBlockForTesting(db, addr, big.NewInt(1000000))
+	chain, receipts := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, 43, func(i int, gen *core.BlockGen) {
+		if i%3 == 2 {
+			return // leave some blocks empty
+		}
+		for j := 0; j < 2; j++ {
+			receipt := types.NewReceipt(nil, false, 0)
+			receipt.Logs = []*types.Log{
+				{Address: addrs[i%3], Topics: []common.Hash{topics[j], topics[(i+j)%3]}},
+				{Address: addrs[(i+j)%3], Topics: []common.Hash{topics[i%3]}},
+			}
+			gen.AddUncheckedReceipt(receipt)
+			gen.AddUncheckedTx(types.NewTransaction(uint64(2*i+j), common.HexToAddress("0x1"), big.NewInt(1), 1, gen.BaseFee(), nil))
+		}
+	})
+	for i, block := range chain {
+		rawdb.WriteBlock(db, block)
+		rawdb.WriteCanonicalHash(db, block.Hash(), block.NumberU64())
+		rawdb.WriteHeadBlockHash(db, block.Hash())
+		rawdb.WriteReceipts(db, block.Hash(), block.NumberU64(), receipts[i])
+	}
+	// Index the chain, expecting 5 full sections of 8 blocks
+	indexer := core.NewLogIndexer(db, 8, 0)
+	defer indexer.Close()
+	indexer.Start(&testIndexerChain{head: chain[len(chain)-1].Header()})
+
+	for i := 0; ; i++ {
+		if sections, _, _ := indexer.Sections(); sections == 5 {
+			break
+		}
+		if i == 100 {
+			t.Fatalf("log index not generated in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	indexed := &testLogIndexBackend{testBackend: backend, indexer: indexer}
+
+	tests := []struct {
+		begin, end int64
+		addresses  []common.Address
+		topics     [][]common.Hash
+	}{
+		{0, -1, []common.Address{addrs[0]}, nil},
+		{0, -1, []common.Address{addrs[0], addrs[2]}, nil},
+		{3, 37, nil, [][]common.Hash{{topics[1]}}},
+		{0, -1, nil, [][]common.Hash{nil, {topics[2]}}},
+		{5, 42, []common.Address{addrs[1]}, [][]common.Hash{{topics[0], topics[1]}, {topics[2]}}},
+		{0, 20, []common.Address{addrs[2]}, [][]common.Hash{{topics[2]}, {topics[0]}}},
+		{0, -1, []common.Address{common.HexToAddress("0xdead")}, nil},
+	}
+	for i, tt := range tests {
+		want, err := NewRangeFilter(backend, tt.begin, tt.end, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to filter unindexed logs: %v", i, err)
+		}
+		have, err := NewRangeFilter(indexed, tt.begin, tt.end, tt.addresses, tt.topics).Logs(context.Background())
+		if err != nil {
+			t.Fatalf("test %d: failed to filter indexed logs: %v", i, err)
+		}
+		if len(have) != len(want) {
+			t.Fatalf("test %d: log count mismatch: have %d, want %d", i, len(have), len(want))
+		}
+		for j := range have {
+			if have[j].BlockNumber != want[j].BlockNumber || have[j].Index != want[j].Index {
+				t.Errorf("test %d: log %d mismatch: have #%d/%d, want #%d/%d", i, j, have[j].BlockNumber, have[j].Index, want[j].BlockNumber, want[j].Index)
+			}
+		}
+	}
 }
@@ -0,0 +1,147 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+const (
+	finalityFeedInterval = 10 * time.Second // Interval to poll the checkpoint source at
+	finalityFeedTimeout  = 5 * time.Second  // Timeout of a checkpoint request to a remote source
+)
+
+// finalityFeed polls a trusted source for finality checkpoints and applies them
+// to the chain. The source is either a local JSON file holding the checkpoint,
+// or the RPC endpoint of a trusted node serving eth_finalityCheckpoint.
+type finalityFeed struct {
+	source string
+	chain  *core.BlockChain
+	client *rpc.Client // Client of a remote source, dialed on demand
+
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newFinalityFeed(source string, chain *core.BlockChain) *finalityFeed {
+	return &finalityFeed{
+		source:  source,
+		chain:   chain,
+		closeCh: make(chan struct{}),
+	}
+}
+
+// start starts polling the checkpoint source.
+func (f *finalityFeed) start() {
+	f.wg.Add(1)
+	go f.loop()
+}
+
+// stop terminates polling the checkpoint source.
+func (f *finalityFeed) stop() {
+	close(f.closeCh)
+	f.wg.Wait()
+
+	if f.client != nil {
+		f.client.Close()
+	}
+}
+
+func (f *finalityFeed) loop() {
+	defer f.wg.Done()
+
+	ticker := time.NewTicker(finalityFeedInterval)
+	defer ticker.Stop()
+
+	for {
+		f.update()
+
+		select {
+		case <-ticker.C:
+		case <-f.closeCh:
+			return
+		}
+	}
+}
+
+// update fetches the checkpoint of the source and applies it if newer than the
+// current one.
+func (f *finalityFeed) update() {
+	checkpoint, err := f.fetch()
+	if err != nil {
+		log.Warn("Failed to fetch finality checkpoint", "source", f.source, "err", err)
+		return
+	}
+	if checkpoint == nil {
+		return
+	}
+	if current := f.chain.FinalityCheckpoint(); current != nil && checkpoint.Number <= current.Number {
+		return
+	}
+	if err := f.chain.SetFinalityCheckpoint(*checkpoint); err != nil {
+		log.Warn("Failed to set finality checkpoint", "number", checkpoint.Number, "hash", checkpoint.Hash, "err", err)
+	}
+}
+
+// fetch retrieves the current checkpoint of the source.
+func (f *finalityFeed) fetch() (*core.FinalityCheckpoint, error) {
+	var checkpoint *core.FinalityCheckpoint
+	if !isRemoteSource(f.source) {
+		blob, err := ioutil.ReadFile(f.source)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(blob, &checkpoint); err != nil {
+			return nil, err
+		}
+		return checkpoint, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), finalityFeedTimeout)
+	defer cancel()
+
+	if f.client == nil {
+		client, err := rpc.DialContext(ctx, f.source)
+		if err != nil {
+			return nil, err
+		}
+		f.client = client
+	}
+	if err := f.client.CallContext(ctx, &checkpoint, "eth_finalityCheckpoint"); err != nil {
+		return nil, err
+	}
+	return checkpoint, nil
+}
+
+// isRemoteSource reports whether a checkpoint source is an RPC endpoint rather
+// than a local file.
+func isRemoteSource(source string) bool {
+	for _, scheme := range []string{"http://", "https://", "ws://", "wss://"} {
+		if strings.HasPrefix(source, scheme) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// newFinalityTestChain creates a chain of the given length for the finality
+// feed tests.
+func newFinalityTestChain(t *testing.T, blocks int) *core.BlockChain {
+	db := rawdb.NewMemoryDatabase()
+	genesis := (&core.Genesis{Config: params.TestChainConfig}).MustCommit(db)
+
+	chain, err := core.NewBlockChain(db, nil, params.TestChainConfig, ethash.NewFaker(), vm.Config{}, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	bs, _ := core.GenerateChain(params.TestChainConfig, genesis, ethash.NewFaker(), db, blocks, nil)
+	if _, err := chain.InsertChain(bs); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	return chain
+}
+
+// Tests that checkpoints are read from a local file and only ever move forward.
+func TestFinalityFeedFile(t *testing.T) {
+	chain := newFinalityTestChain(t, 16)
+	defer chain.Stop()
+
+	dir, err := ioutil.TempDir("", "finality")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	source := filepath.Join(dir, "checkpoint.json")
+
+	feed := newFinalityFeed(source, chain)
+
+	// A missing file leaves the chain without checkpoint
+	feed.update()
+	if cp := chain.FinalityCheckpoint(); cp != nil {
+		t.Fatalf("checkpoint set from missing file: %v", cp)
+	}
+	write := func(number uint64) {
+		blob := []byte(fmt.Sprintf(`{"number":%d,"hash":"%s"}`, number, chain.GetHeaderByNumber(number).Hash().Hex()))
+		if err := ioutil.WriteFile(source, blob, 0644); err != nil {
+			t.Fatalf("failed to write checkpoint: %v", err)
+		}
+	}
+	write(10)
+	feed.update()
+	if cp := chain.FinalityCheckpoint(); cp == nil || cp.Number != 10 || cp.Hash != chain.GetHeaderByNumber(10).Hash() {
+		t.Fatalf("checkpoint mismatch: have %v, want #10", cp)
+	}
+	// Older checkpoints are ignored
+	write(5)
+	feed.update()
+	if cp := chain.FinalityCheckpoint(); cp == nil || cp.Number != 10 {
+		t.Fatalf("checkpoint rewound: have %v, want #10", cp)
+	}
+	if floor := chain.ReorgFloor(); floor != 10 {
+		t.Fatalf("reorg floor mismatch: have %d, want %d", floor, 10)
+	}
+}
+
+// finalityTestService serves a fixed checkpoint over RPC.
+type finalityTestService struct {
+	checkpoint *core.FinalityCheckpoint
+}
+
+func (s *finalityTestService) FinalityCheckpoint() *core.FinalityCheckpoint {
+	return s.checkpoint
+}
+
+// Tests that checkpoints are retrieved from a trusted remote node.
+func TestFinalityFeedRPC(t *testing.T) {
+	chain := newFinalityTestChain(t, 16)
+	defer chain.Stop()
+
+	service := new(finalityTestService)
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("eth", service); err != nil {
+		t.Fatalf("failed to register service: %v", err)
+	}
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+
+	feed := newFinalityFeed(httpsrv.URL, chain)
+	defer feed.stop()
+
+	// A remote node without checkpoint leaves the chain without one
+	feed.update()
+	if cp := chain.FinalityCheckpoint(); cp != nil {
+		t.Fatalf("checkpoint set from empty source: %v", cp)
+	}
+	service.checkpoint = &core.FinalityCheckpoint{Number: 12, Hash: chain.GetHeaderByNumber(12).Hash()}
+	feed.update()
+	if cp := chain.FinalityCheckpoint(); cp == nil || *cp != *service.checkpoint {
+		t.Fatalf("checkpoint mismatch: have %v, want %v", cp, service.checkpoint)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -87,3 +88,41 @@ func TestFeeHistory(t *testing.T) {
 		}
 	}
 }
+
+// Tests that the fee history follows the fee market parameters of the chain.
+func TestFeeHistoryEIP1559Config(t *testing.T) {
+	var (
+		minBaseFee     = big.NewInt(5 * params.GWei)
+		initialBaseFee = big.NewInt(10 * params.GWei)
+	)
+	backend := newTestBackendWithEIP1559(t, big.NewInt(16), &params.EIP1559Config{
+		BaseFeeChangeDenominator: 2,
+		ElasticityMultiplier:     4,
+		MinBaseFee:               minBaseFee,
+		InitialBaseFee:           initialBaseFee,
+	}, false)
+	oracle := NewOracle(backend, Config{MaxHeaderHistory: 1000, MaxBlockHistory: 1000})
+
+	first, _, baseFee, _, err := oracle.FeeHistory(context.Background(), 17, rpc.LatestBlockNumber, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve fee history: %v", err)
+	}
+	if first.Uint64() != 16 {
+		t.Fatalf("first block mismatch: have %d, want %d", first, 16)
+	}
+	if baseFee[0].Cmp(initialBaseFee) != 0 {
+		t.Fatalf("initial base fee mismatch: have %v, want %v", baseFee[0], initialBaseFee)
+	}
+	// Blocks are way below the gas target, so the base fee halves down to the minimum
+	for i := 1; i < len(baseFee); i++ {
+		if baseFee[i].Cmp(minBaseFee) < 0 {
+			t.Fatalf("base fee %d below minimum: have %v, min %v", i, baseFee[i], minBaseFee)
+		}
+	}
+	if baseFee[2].Cmp(minBaseFee) != 0 {
+		t.Fatalf("base fee decrease too slow: have %v, want %v", baseFee[2], minBaseFee)
+	}
+	if last := baseFee[len(baseFee)-1]; last.Cmp(minBaseFee) != 0 {
+		t.Fatalf("next base fee mismatch: have %v, want %v", last, minBaseFee)
+	}
+}
@@ -20,12 +20,11 @@ import (
 	"encoding/json"
 	"errors"
 	"math/big"
-	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/tracers"
 )
@@ -34,22 +33,56 @@ func init() {
 	register("callTracer", newCallTracer)
 }
 
-type callFrame struct {
-	Type    string      `json:"type"`
-	From    string      `json:"from"`
-	To      string      `json:"to,omitempty"`
-	Value   string      `json:"value,omitempty"`
-	Gas     string      `json:"gas"`
-	GasUsed string      `json:"gasUsed"`
-	Input   string      `json:"input"`
-	Output  string      `json:"output,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Calls   []callFrame `json:"calls,omitempty"`
+// CallFrame is the result of the callTracer: a call of a transaction together
+// with the calls it made.
+type CallFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to,omitempty"` // nil for failed contract creations
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     hexutil.Uint64  `json:"gas"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output,omitempty"` // nil if the call failed without returning data
+	Error   string          `json:"error,omitempty"`
+	Calls   []CallFrame     `json:"calls,omitempty"`
+}
+
+// MarshalJSON marshals the call frame. Unlike other empty fields, an empty but
+// non-nil output is not omitted.
+func (f CallFrame) MarshalJSON() ([]byte, error) {
+	type frame struct {
+		Type    string          `json:"type"`
+		From    common.Address  `json:"from"`
+		To      *common.Address `json:"to,omitempty"`
+		Value   *hexutil.Big    `json:"value,omitempty"`
+		Gas     hexutil.Uint64  `json:"gas"`
+		GasUsed hexutil.Uint64  `json:"gasUsed"`
+		Input   hexutil.Bytes   `json:"input"`
+		Output  *hexutil.Bytes  `json:"output,omitempty"`
+		Error   string          `json:"error,omitempty"`
+		Calls   []CallFrame     `json:"calls,omitempty"`
+	}
+	enc := frame{
+		Type:    f.Type,
+		From:    f.From,
+		To:      f.To,
+		Value:   f.Value,
+		Gas:     f.Gas,
+		GasUsed: f.GasUsed,
+		Input:   f.Input,
+		Error:   f.Error,
+		Calls:   f.Calls,
+	}
+	if f.Output != nil {
+		enc.Output = &f.Output
+	}
+	return json.Marshal(&enc)
 }
 
 type callTracer struct {
 	env       *vm.EVM
-	callstack []callFrame
+	callstack []CallFrame
 	interrupt uint32 // Atomic flag to signal execution interruption
 	reason    error  // Textual reason for the interruption
 }
@@ -59,19 +92,19 @@ type callTracer struct {
 func newCallTracer() tracers.Tracer {
 	// First callframe contains tx context info
 	// and is populated on start and end.
-	return &callTracer{callstack: make([]callFrame, 1)}
+	return &callTracer{callstack: make([]CallFrame, 1)}
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
 func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
 	t.env = env
-	t.callstack[0] = callFrame{
+	t.callstack[0] = CallFrame{
 		Type:  "CALL",
-		From:  addrToHex(from),
-		To:    addrToHex(to),
-		Input: bytesToHex(input),
-		Gas:   uintToHex(gas),
-		Value: bigToHex(value),
+		From:  from,
+		To:    &to,
+		Input: common.CopyBytes(input),
+		Gas:   hexutil.Uint64(gas),
+		Value: bigCopy(value),
 	}
 	if create {
 		t.callstack[0].Type = "CREATE"
@@ -80,14 +113,14 @@ func (t *callTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Ad
 
 // CaptureEnd is called after the call finishes to finalize the tracing.
 func (t *callTracer) CaptureEnd(output []byte, gasUsed uint64, _ time.Duration, err error) {
-	t.callstack[0].GasUsed = uintToHex(gasUsed)
+	t.callstack[0].GasUsed = hexutil.Uint64(gasUsed)
 	if err != nil {
 		t.callstack[0].Error = err.Error()
 		if err.Error() == "execution reverted" && len(output) > 0 {
-			t.callstack[0].Output = bytesToHex(output)
+			t.callstack[0].Output = outputCopy(output)
 		}
 	} else {
-		t.callstack[0].Output = bytesToHex(output)
+		t.callstack[0].Output = outputCopy(output)
 	}
 }
 
@@ -107,13 +140,13 @@ func (t *callTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.
 		return
 	}
 
-	call := callFrame{
+	call := CallFrame{
 		Type:  typ.String(),
-		From:  addrToHex(from),
-		To:    addrToHex(to),
-		Input: bytesToHex(input),
-		Gas:   uintToHex(gas),
-		Value: bigToHex(value),
+		From:  from,
+		To:    &to,
+		Input: common.CopyBytes(input),
+		Gas:   hexutil.Uint64(gas),
+		Value: bigCopy(value),
 	}
 	t.callstack = append(t.callstack, call)
 }
@@ -130,13 +163,13 @@ func (t *callTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
 	t.callstack = t.callstack[:size-1]
 	size -= 1
 
-	call.GasUsed = uintToHex(gasUsed)
+	call.GasUsed = hexutil.Uint64(gasUsed)
 	if err == nil {
-		call.Output = bytesToHex(output)
+		call.Output = outputCopy(output)
 	} else {
 		call.Error = err.Error()
 		if call.Type == "CREATE" || call.Type == "CREATE2" {
-			call.To = ""
+			call.To = nil
 		}
 	}
 	t.callstack[size-1].Calls = append(t.callstack[size-1].Calls, call)
@@ -169,17 +202,16 @@ func bytesToHex(s []byte) string {
 	return "0x" + common.Bytes2Hex(s)
 }
 
-func bigToHex(n *big.Int) string {
+// bigCopy returns a copy of n for the trace result.
+func bigCopy(n *big.Int) *hexutil.Big {
 	if n == nil {
-		return ""
+		return nil
 	}
-	return "0x" + n.Text(16)
-}
-
-func uintToHex(n uint64) string {
-	return "0x" + strconv.FormatUint(n, 16)
+	return (*hexutil.Big)(new(big.Int).Set(n))
 }
 
-func addrToHex(a common.Address) string {
-	return strings.ToLower(a.Hex())
+// outputCopy returns a copy of output which is not nil, so that it is always
+// included in the trace result.
+func outputCopy(output []byte) hexutil.Bytes {
+	return append(hexutil.Bytes{}, output...)
 }
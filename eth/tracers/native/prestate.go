@@ -33,17 +33,22 @@ func init() {
 	register("prestateTracer", newPrestateTracer)
 }
 
-type prestate = map[common.Address]*account
-type account struct {
-	Balance string                      `json:"balance"`
+// PrestateResult is the result of the prestateTracer: the state of the accounts
+// touched by a transaction before its execution.
+type PrestateResult map[common.Address]*PrestateAccount
+
+// PrestateAccount is the state of an account before the execution of a
+// transaction, including the storage slots accessed by the transaction.
+type PrestateAccount struct {
+	Balance *hexutil.Big                `json:"balance"`
 	Nonce   uint64                      `json:"nonce"`
-	Code    string                      `json:"code"`
+	Code    hexutil.Bytes               `json:"code"`
 	Storage map[common.Hash]common.Hash `json:"storage"`
 }
 
 type prestateTracer struct {
 	env       *vm.EVM
-	prestate  prestate
+	prestate  PrestateResult
 	create    bool
 	to        common.Address
 	gasLimit  uint64 // Amount of gas bought for the whole tx
@@ -54,7 +59,7 @@ type prestateTracer struct {
 func newPrestateTracer() tracers.Tracer {
 	// First callframe contains tx context info
 	// and is populated on start and end.
-	return &prestateTracer{prestate: prestate{}}
+	return &prestateTracer{prestate: PrestateResult{}}
 }
 
 // CaptureStart implements the EVMLogger interface to initialize the tracing operation.
@@ -67,17 +72,16 @@ func (t *prestateTracer) CaptureStart(env *vm.EVM, from common.Address, to commo
 	t.lookupAccount(to)
 
 	// The recipient balance includes the value transferred.
-	toBal := hexutil.MustDecodeBig(t.prestate[to].Balance)
-	toBal = new(big.Int).Sub(toBal, value)
-	t.prestate[to].Balance = hexutil.EncodeBig(toBal)
+	toBal := new(big.Int).Sub(t.prestate[to].Balance.ToInt(), value)
+	t.prestate[to].Balance = (*hexutil.Big)(toBal)
 
 	// The sender balance is after reducing: value and gasLimit.
 	// We need to re-add them to get the pre-tx balance.
-	fromBal := hexutil.MustDecodeBig(t.prestate[from].Balance)
+	fromBal := new(big.Int).Set(t.prestate[from].Balance.ToInt())
 	gasPrice := env.TxContext.GasPrice
 	consumedGas := new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(t.gasLimit))
 	fromBal.Add(fromBal, new(big.Int).Add(value, consumedGas))
-	t.prestate[from].Balance = hexutil.EncodeBig(fromBal)
+	t.prestate[from].Balance = (*hexutil.Big)(fromBal)
 	t.prestate[from].Nonce--
 }
 
@@ -159,10 +163,10 @@ func (t *prestateTracer) lookupAccount(addr common.Address) {
 	if _, ok := t.prestate[addr]; ok {
 		return
 	}
-	t.prestate[addr] = &account{
-		Balance: bigToHex(t.env.StateDB.GetBalance(addr)),
+	t.prestate[addr] = &PrestateAccount{
+		Balance: bigCopy(t.env.StateDB.GetBalance(addr)),
 		Nonce:   t.env.StateDB.GetNonce(addr),
-		Code:    bytesToHex(t.env.StateDB.GetCode(addr)),
+		Code:    common.CopyBytes(t.env.StateDB.GetCode(addr)),
 		Storage: make(map[common.Hash]common.Hash),
 	}
 }
@@ -0,0 +1,284 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package ethclient
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	// ErrBatchNotExecuted is returned by the results of calls whose batch has
+	// not been executed yet.
+	ErrBatchNotExecuted = errors.New("batch not executed")
+
+	errBatchExecuted = errors.New("batch already executed")
+)
+
+// Batch collects calls which are sent to the server in a single batch request.
+// The methods of Batch queue a call and return its result, which becomes
+// available once the batch has been executed. Calls fail individually, the
+// failure of one call doesn't affect the results of the others.
+//
+// A Batch is not safe for concurrent use and can be executed only once.
+type Batch struct {
+	client   *Client
+	elems    []rpc.BatchElem
+	done     []func(error)
+	executed bool
+}
+
+// NewBatch creates an empty batch of calls.
+func (ec *Client) NewBatch() *Batch {
+	return &Batch{client: ec}
+}
+
+// Add queues a call of the given RPC method, whose result is decoded into the
+// value pointed to by result. Once the batch has been executed, done is invoked
+// with the error of the call. Add allows building typed batch calls of APIs not
+// covered by Batch, like the ones of the gethclient package.
+func (b *Batch) Add(done func(err error), result interface{}, method string, args ...interface{}) {
+	b.elems = append(b.elems, rpc.BatchElem{Method: method, Args: args, Result: result})
+	b.done = append(b.done, done)
+}
+
+// Len returns the number of queued calls.
+func (b *Batch) Len() int {
+	return len(b.elems)
+}
+
+// Execute sends all queued calls to the server in a single request and fills in
+// their results. The returned error is only non-nil if the request as a whole
+// failed, in which case it is reported by all results as well.
+func (b *Batch) Execute(ctx context.Context) error {
+	if b.executed {
+		return errBatchExecuted
+	}
+	b.executed = true
+
+	var err error
+	if len(b.elems) > 0 {
+		err = b.client.c.BatchCallContext(ctx, b.elems)
+	}
+	for i, done := range b.done {
+		callErr := err
+		if callErr == nil {
+			callErr = b.elems[i].Error
+		}
+		if done != nil {
+			done(callErr)
+		}
+	}
+	return err
+}
+
+// BigResult is the result of a batched call returning an integer.
+type BigResult struct {
+	value *big.Int
+	err   error
+}
+
+// Result returns the value and the error of the call.
+func (r *BigResult) Result() (*big.Int, error) { return r.value, r.err }
+
+// Uint64Result is the result of a batched call returning a quantity.
+type Uint64Result struct {
+	value uint64
+	err   error
+}
+
+// Result returns the value and the error of the call.
+func (r *Uint64Result) Result() (uint64, error) { return r.value, r.err }
+
+// BytesResult is the result of a batched call returning binary data.
+type BytesResult struct {
+	value []byte
+	err   error
+}
+
+// Result returns the value and the error of the call.
+func (r *BytesResult) Result() ([]byte, error) { return r.value, r.err }
+
+// HeaderResult is the result of a batched header retrieval.
+type HeaderResult struct {
+	value *types.Header
+	err   error
+}
+
+// Result returns the header and the error of the call. The error is
+// ethereum.NotFound if the header doesn't exist.
+func (r *HeaderResult) Result() (*types.Header, error) { return r.value, r.err }
+
+// ReceiptResult is the result of a batched receipt retrieval.
+type ReceiptResult struct {
+	value *types.Receipt
+	err   error
+}
+
+// Result returns the receipt and the error of the call. The error is
+// ethereum.NotFound if the receipt doesn't exist.
+func (r *ReceiptResult) Result() (*types.Receipt, error) { return r.value, r.err }
+
+func (b *Batch) big(method string, args ...interface{}) *BigResult {
+	var (
+		r   = &BigResult{err: ErrBatchNotExecuted}
+		hex hexutil.Big
+	)
+	b.Add(func(err error) {
+		if r.err = err; err == nil {
+			r.value = (*big.Int)(&hex)
+		}
+	}, &hex, method, args...)
+	return r
+}
+
+func (b *Batch) uint64(method string, args ...interface{}) *Uint64Result {
+	var (
+		r   = &Uint64Result{err: ErrBatchNotExecuted}
+		hex hexutil.Uint64
+	)
+	b.Add(func(err error) {
+		if r.err = err; err == nil {
+			r.value = uint64(hex)
+		}
+	}, &hex, method, args...)
+	return r
+}
+
+func (b *Batch) bytes(method string, args ...interface{}) *BytesResult {
+	var (
+		r   = &BytesResult{err: ErrBatchNotExecuted}
+		hex hexutil.Bytes
+	)
+	b.Add(func(err error) {
+		if r.err = err; err == nil {
+			r.value = hex
+		}
+	}, &hex, method, args...)
+	return r
+}
+
+func (b *Batch) header(method string, args ...interface{}) *HeaderResult {
+	r := &HeaderResult{err: ErrBatchNotExecuted}
+	b.Add(func(err error) {
+		if r.err = err; err == nil && r.value == nil {
+			r.err = ethereum.NotFound
+		}
+	}, &r.value, method, args...)
+	return r
+}
+
+// ChainID queues the retrieval of the chain ID.
+func (b *Batch) ChainID() *BigResult {
+	return b.big("eth_chainId")
+}
+
+// BlockNumber queues the retrieval of the most recent block number.
+func (b *Batch) BlockNumber() *Uint64Result {
+	return b.uint64("eth_blockNumber")
+}
+
+// HeaderByHash queues the retrieval of the block header with the given hash.
+func (b *Batch) HeaderByHash(hash common.Hash) *HeaderResult {
+	return b.header("eth_getBlockByHash", hash, false)
+}
+
+// HeaderByNumber queues the retrieval of a block header from the current
+// canonical chain. If number is nil, the latest known header is retrieved.
+func (b *Batch) HeaderByNumber(number *big.Int) *HeaderResult {
+	return b.header("eth_getBlockByNumber", toBlockNumArg(number), false)
+}
+
+// TransactionReceipt queues the retrieval of the receipt of a transaction.
+func (b *Batch) TransactionReceipt(txHash common.Hash) *ReceiptResult {
+	r := &ReceiptResult{err: ErrBatchNotExecuted}
+	b.Add(func(err error) {
+		if r.err = err; err == nil && r.value == nil {
+			r.err = ethereum.NotFound
+		}
+	}, &r.value, "eth_getTransactionReceipt", txHash)
+	return r
+}
+
+// BalanceAt queues the retrieval of the wei balance of the given account. The
+// block number can be nil, in which case the balance is taken from the latest
+// known block.
+func (b *Batch) BalanceAt(account common.Address, blockNumber *big.Int) *BigResult {
+	return b.big("eth_getBalance", account, toBlockNumArg(blockNumber))
+}
+
+// StorageAt queues the retrieval of the value of key in the contract storage of
+// the given account. The block number can be nil, in which case the value is
+// taken from the latest known block.
+func (b *Batch) StorageAt(account common.Address, key common.Hash, blockNumber *big.Int) *BytesResult {
+	return b.bytes("eth_getStorageAt", account, key, toBlockNumArg(blockNumber))
+}
+
+// CodeAt queues the retrieval of the contract code of the given account. The
+// block number can be nil, in which case the code is taken from the latest
+// known block.
+func (b *Batch) CodeAt(account common.Address, blockNumber *big.Int) *BytesResult {
+	return b.bytes("eth_getCode", account, toBlockNumArg(blockNumber))
+}
+
+// NonceAt queues the retrieval of the account nonce of the given account. The
+// block number can be nil, in which case the nonce is taken from the latest
+// known block.
+func (b *Batch) NonceAt(account common.Address, blockNumber *big.Int) *Uint64Result {
+	return b.uint64("eth_getTransactionCount", account, toBlockNumArg(blockNumber))
+}
+
+// PendingNonceAt queues the retrieval of the account nonce of the given account
+// in the pending state.
+func (b *Batch) PendingNonceAt(account common.Address) *Uint64Result {
+	return b.uint64("eth_getTransactionCount", account, "pending")
+}
+
+// CallContract queues the execution of a message call at the given block
+// height, which can be nil to select the latest known block.
+func (b *Batch) CallContract(msg ethereum.CallMsg, blockNumber *big.Int) *BytesResult {
+	return b.bytes("eth_call", toCallArg(msg), toBlockNumArg(blockNumber))
+}
+
+// CallContractAtHash queues the execution of a message call at the block with
+// the given hash.
+func (b *Batch) CallContractAtHash(msg ethereum.CallMsg, blockHash common.Hash) *BytesResult {
+	return b.bytes("eth_call", toCallArg(msg), rpc.BlockNumberOrHashWithHash(blockHash, false))
+}
+
+// EstimateGas queues the estimation of the gas needed to execute a transaction
+// in the pending state.
+func (b *Batch) EstimateGas(msg ethereum.CallMsg) *Uint64Result {
+	return b.uint64("eth_estimateGas", toCallArg(msg))
+}
+
+// SuggestGasPrice queues the retrieval of the currently suggested gas price.
+func (b *Batch) SuggestGasPrice() *BigResult {
+	return b.big("eth_gasPrice")
+}
+
+// SuggestGasTipCap queues the retrieval of the currently suggested gas tip cap.
+func (b *Batch) SuggestGasTipCap() *BigResult {
+	return b.big("eth_maxPriorityFeePerGas")
+}
@@ -380,6 +380,34 @@ func (ec *Client) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuer
 	return ec.c.EthSubscribe(ctx, ch, "logs", arg)
 }
 
+// SubscribeFilterLogsResumable is like SubscribeFilterLogs, but the subscription
+// is re-established if the connection to the server is lost. Upon resumption, logs
+// are requested starting at the block of the last log received, so that no logs are
+// missed. Logs of that block may thus be delivered a second time.
+//
+// Only a query without BlockHash can be resumed. The server must support delivering
+// historical logs from the query's fromBlock.
+func (ec *Client) SubscribeFilterLogsResumable(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if q.BlockHash != nil {
+		return nil, fmt.Errorf("cannot resume subscription with BlockHash")
+	}
+	arg, err := toFilterArg(q)
+	if err != nil {
+		return nil, err
+	}
+	resume := func(last interface{}) []interface{} {
+		log, ok := last.(types.Log)
+		if !ok {
+			return []interface{}{"logs", arg}
+		}
+		resumed := q
+		resumed.FromBlock = new(big.Int).SetUint64(log.BlockNumber)
+		arg, _ := toFilterArg(resumed)
+		return []interface{}{"logs", arg}
+	}
+	return ec.c.SubscribeResumable(ctx, "eth", ch, resume, "logs", arg)
+}
+
 func toFilterArg(q ethereum.FilterQuery) (interface{}, error) {
 	arg := map[string]interface{}{
 		"address": q.Addresses,
@@ -294,6 +294,9 @@ func TestEthClient(t *testing.T) {
 		"TransactionSender": {
 			func(t *testing.T) { testTransactionSender(t, client) },
 		},
+		"Batch": {
+			func(t *testing.T) { testBatch(t, chain, client) },
+		},
 	}
 
 	t.Parallel()
@@ -692,3 +695,73 @@ func sendTransaction(ec *Client) error {
 	}
 	return ec.SendTransaction(context.Background(), tx)
 }
+
+func testBatch(t *testing.T, chain []*types.Block, client *rpc.Client) {
+	ec := NewClient(client)
+	batch := ec.NewBatch()
+
+	var (
+		chainID  = batch.ChainID()
+		header   = batch.HeaderByNumber(big.NewInt(1))
+		byHash   = batch.HeaderByHash(chain[2].Hash())
+		missing  = batch.HeaderByNumber(big.NewInt(1000))
+		balance  = batch.BalanceAt(testAddr, big.NewInt(0))
+		nonce    = batch.NonceAt(testAddr, big.NewInt(2))
+		code     = batch.CodeAt(testAddr, nil)
+		storage  = batch.StorageAt(testAddr, common.Hash{}, nil)
+		receipt  = batch.TransactionReceipt(testTx1.Hash())
+		noRecpt  = batch.TransactionReceipt(common.Hash{})
+		call     = batch.CallContract(ethereum.CallMsg{From: testAddr, To: &common.Address{}, Gas: 21000, Value: big.NewInt(1)}, nil)
+		badBlock = batch.BalanceAt(testAddr, big.NewInt(-2))
+	)
+	if batch.Len() != 12 {
+		t.Fatalf("wrong batch length %d", batch.Len())
+	}
+	if _, err := chainID.Result(); err != ErrBatchNotExecuted {
+		t.Fatalf("wrong error before execution: %v", err)
+	}
+	if err := batch.Execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := batch.Execute(context.Background()); err == nil {
+		t.Fatal("batch executed twice")
+	}
+
+	if id, err := chainID.Result(); err != nil || id.Cmp(params.AllEthashProtocolChanges.ChainID) != 0 {
+		t.Errorf("wrong chain ID: %v %v", id, err)
+	}
+	if h, err := header.Result(); err != nil || h.Hash() != chain[1].Hash() {
+		t.Errorf("wrong header by number: %v", err)
+	}
+	if h, err := byHash.Result(); err != nil || h.Hash() != chain[2].Hash() {
+		t.Errorf("wrong header by hash: %v", err)
+	}
+	if _, err := missing.Result(); err != ethereum.NotFound {
+		t.Errorf("wrong error for missing header: %v", err)
+	}
+	if b, err := balance.Result(); err != nil || b.Cmp(testBalance) != 0 {
+		t.Errorf("wrong balance: %v %v", b, err)
+	}
+	if n, err := nonce.Result(); err != nil || n != 2 {
+		t.Errorf("wrong nonce: %v %v", n, err)
+	}
+	if c, err := code.Result(); err != nil || len(c) != 0 {
+		t.Errorf("wrong code: %x %v", c, err)
+	}
+	if s, err := storage.Result(); err != nil || !bytes.Equal(s, make([]byte, 32)) {
+		t.Errorf("wrong storage: %x %v", s, err)
+	}
+	if r, err := receipt.Result(); err != nil || r.TxHash != testTx1.Hash() || r.BlockNumber.Uint64() != 2 {
+		t.Errorf("wrong receipt: %v", err)
+	}
+	if _, err := noRecpt.Result(); err != ethereum.NotFound {
+		t.Errorf("wrong error for missing receipt: %v", err)
+	}
+	if _, err := call.Result(); err != nil {
+		t.Errorf("call failed: %v", err)
+	}
+	// Failing calls don't affect the others
+	if _, err := badBlock.Result(); err == nil {
+		t.Error("balance at invalid block succeeded")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Batch collects calls which are sent to the server in a single batch request.
+// Besides the geth-specific calls defined here, it supports all calls of
+// ethclient.Batch. Note that CallContract is replaced by the variant taking
+// state overrides.
+type Batch struct {
+	*ethclient.Batch
+}
+
+// NewBatch creates an empty batch of calls.
+func (ec *Client) NewBatch() *Batch {
+	return &Batch{ethclient.NewClient(ec.c).NewBatch()}
+}
+
+// ProofResult is the result of a batched GetProof call.
+type ProofResult struct {
+	value *AccountResult
+	err   error
+}
+
+// Result returns the account and storage proofs and the error of the call.
+func (r *ProofResult) Result() (*AccountResult, error) { return r.value, r.err }
+
+// CallResult is the result of a batched CallContract call.
+type CallResult struct {
+	value []byte
+	err   error
+}
+
+// Result returns the return data and the error of the call.
+func (r *CallResult) Result() ([]byte, error) { return r.value, r.err }
+
+// GetProof queues the retrieval of the account and storage values of the
+// specified account including the Merkle-proof. The block number can be nil,
+// in which case the value is taken from the latest known block.
+func (b *Batch) GetProof(account common.Address, keys []string, blockNumber *big.Int) *ProofResult {
+	var (
+		r   = &ProofResult{err: ethclient.ErrBatchNotExecuted}
+		res accountResult
+	)
+	b.Add(func(err error) {
+		if r.err = err; err == nil {
+			r.value = res.toAccountResult()
+		}
+	}, &res, "eth_getProof", account, keys, toBlockNumArg(blockNumber))
+	return r
+}
+
+// CallContract queues the execution of a message call at the given block
+// height, which can be nil to select the latest known block. The state of the
+// accounts in overrides is replaced before executing the call.
+func (b *Batch) CallContract(msg ethereum.CallMsg, blockNumber *big.Int, overrides *map[common.Address]OverrideAccount) *CallResult {
+	var (
+		r   = &CallResult{err: ethclient.ErrBatchNotExecuted}
+		hex hexutil.Bytes
+	)
+	b.Add(func(err error) {
+		if r.err = err; err == nil {
+			r.value = hex
+		}
+	}, &hex, "eth_call", toCallArg(msg), toBlockNumArg(blockNumber), toOverrideMap(overrides))
+	return r
+}
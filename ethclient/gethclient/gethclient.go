@@ -19,6 +19,7 @@ package gethclient
 
 import (
 	"context"
+	"encoding/json"
 	"math/big"
 	"runtime"
 	"runtime/debug"
@@ -79,26 +80,29 @@ type StorageResult struct {
 // GetProof returns the account and storage values of the specified account including the Merkle-proof.
 // The block number can be nil, in which case the value is taken from the latest known block.
 func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []string, blockNumber *big.Int) (*AccountResult, error) {
+	var res accountResult
+	err := ec.c.CallContext(ctx, &res, "eth_getProof", account, keys, toBlockNumArg(blockNumber))
+	return res.toAccountResult(), err
+}
 
-	type storageResult struct {
-		Key   string       `json:"key"`
-		Value *hexutil.Big `json:"value"`
-		Proof []string     `json:"proof"`
-	}
+type storageResult struct {
+	Key   string       `json:"key"`
+	Value *hexutil.Big `json:"value"`
+	Proof []string     `json:"proof"`
+}
 
-	type accountResult struct {
-		Address      common.Address  `json:"address"`
-		AccountProof []string        `json:"accountProof"`
-		Balance      *hexutil.Big    `json:"balance"`
-		CodeHash     common.Hash     `json:"codeHash"`
-		Nonce        hexutil.Uint64  `json:"nonce"`
-		StorageHash  common.Hash     `json:"storageHash"`
-		StorageProof []storageResult `json:"storageProof"`
-	}
+type accountResult struct {
+	Address      common.Address  `json:"address"`
+	AccountProof []string        `json:"accountProof"`
+	Balance      *hexutil.Big    `json:"balance"`
+	CodeHash     common.Hash     `json:"codeHash"`
+	Nonce        hexutil.Uint64  `json:"nonce"`
+	StorageHash  common.Hash     `json:"storageHash"`
+	StorageProof []storageResult `json:"storageProof"`
+}
 
-	var res accountResult
-	err := ec.c.CallContext(ctx, &res, "eth_getProof", account, keys, toBlockNumArg(blockNumber))
-	// Turn hexutils back to normal datatypes
+// toAccountResult turns hexutils back to normal datatypes.
+func (res *accountResult) toAccountResult() *AccountResult {
 	storageResults := make([]StorageResult, 0, len(res.StorageProof))
 	for _, st := range res.StorageProof {
 		storageResults = append(storageResults, StorageResult{
@@ -107,18 +111,20 @@ func (ec *Client) GetProof(ctx context.Context, account common.Address, keys []s
 			Proof: st.Proof,
 		})
 	}
-	result := AccountResult{
+	return &AccountResult{
 		Address:      res.Address,
 		AccountProof: res.AccountProof,
 		Balance:      res.Balance.ToInt(),
 		Nonce:        uint64(res.Nonce),
 		CodeHash:     res.CodeHash,
 		StorageHash:  res.StorageHash,
+		StorageProof: storageResults,
 	}
-	return &result, err
 }
 
-// OverrideAccount specifies the state of an account to be overridden.
+// OverrideAccount specifies the state of an account to be overridden. The nonce
+// is always overridden, the other fields only if they are not nil. Setting Code
+// to an empty non-nil slice removes the code of the account.
 type OverrideAccount struct {
 	Nonce     uint64                      `json:"nonce"`
 	Code      []byte                      `json:"code"`
@@ -127,6 +133,29 @@ type OverrideAccount struct {
 	StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
 }
 
+// MarshalJSON encodes the override in the format of the eth_call and
+// debug_traceCall APIs.
+func (a OverrideAccount) MarshalJSON() ([]byte, error) {
+	type overrideAccount struct {
+		Nonce     hexutil.Uint64              `json:"nonce"`
+		Code      *hexutil.Bytes              `json:"code,omitempty"`
+		Balance   *hexutil.Big                `json:"balance,omitempty"`
+		State     map[common.Hash]common.Hash `json:"state,omitempty"`
+		StateDiff map[common.Hash]common.Hash `json:"stateDiff,omitempty"`
+	}
+	enc := overrideAccount{
+		Nonce:     hexutil.Uint64(a.Nonce),
+		Balance:   (*hexutil.Big)(a.Balance),
+		State:     a.State,
+		StateDiff: a.StateDiff,
+	}
+	if a.Code != nil {
+		code := hexutil.Bytes(a.Code)
+		enc.Code = &code
+	}
+	return json.Marshal(&enc)
+}
+
 // CallContract executes a message call transaction, which is directly executed in the VM
 // of the node, but never mined into the blockchain.
 //
@@ -179,6 +208,24 @@ func (ec *Client) SubscribePendingTransactions(ctx context.Context, ch chan<- co
 	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions")
 }
 
+// SubscribeFullPendingTransactions subscribes to new pending transactions, delivering
+// the full transactions instead of only their hashes.
+func (ec *Client) SubscribeFullPendingTransactions(ctx context.Context, ch chan<- *types.Transaction) (*rpc.ClientSubscription, error) {
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions", true)
+}
+
+// SubscribeFilteredPendingTransactions subscribes to new pending transactions sent
+// by any of the from accounts and to any of the to accounts, delivering the full
+// transactions. An empty list matches any address. The filtering is done by the
+// node, so non-matching transactions are never transferred.
+func (ec *Client) SubscribeFilteredPendingTransactions(ctx context.Context, from, to []common.Address, ch chan<- *types.Transaction) (*rpc.ClientSubscription, error) {
+	crit := map[string]interface{}{
+		"from": from,
+		"to":   to,
+	}
+	return ec.c.EthSubscribe(ctx, ch, "newPendingTransactions", true, crit)
+}
+
 func toBlockNumArg(number *big.Int) string {
 	if number == nil {
 		return "latest"
@@ -214,22 +261,5 @@ func toOverrideMap(overrides *map[common.Address]OverrideAccount) interface{} {
 	if overrides == nil {
 		return nil
 	}
-	type overrideAccount struct {
-		Nonce     hexutil.Uint64              `json:"nonce"`
-		Code      hexutil.Bytes               `json:"code"`
-		Balance   *hexutil.Big                `json:"balance"`
-		State     map[common.Hash]common.Hash `json:"state"`
-		StateDiff map[common.Hash]common.Hash `json:"stateDiff"`
-	}
-	result := make(map[common.Address]overrideAccount)
-	for addr, override := range *overrides {
-		result[addr] = overrideAccount{
-			Nonce:     hexutil.Uint64(override.Nonce),
-			Code:      override.Code,
-			Balance:   (*hexutil.Big)(override.Balance),
-			State:     override.State,
-			StateDiff: override.StateDiff,
-		}
-	}
-	return &result
+	return *overrides
 }
@@ -19,8 +19,11 @@ package gethclient
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"math/big"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
@@ -31,6 +34,9 @@ import (
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/eth"
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	"github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
@@ -41,11 +47,21 @@ var (
 	testKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
 	testAddr    = crypto.PubkeyToAddress(testKey.PublicKey)
 	testBalance = big.NewInt(2e15)
+
+	// traceKey sends a transaction to traceContract, which calls 0xdead.
+	traceKey, _   = crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	traceAddr     = crypto.PubkeyToAddress(traceKey.PublicKey)
+	traceContract = common.HexToAddress("0xc0de")
+	traceCode     = common.FromHex("0x6000600060006000600061dead5af100")
 )
 
 func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	// Generate test chain.
 	genesis, blocks := generateTestChain()
+	return startTestBackend(t, genesis, blocks)
+}
+
+func startTestBackend(t *testing.T, genesis *core.Genesis, blocks []*types.Block) (*node.Node, []*types.Block) {
 	// Create node
 	n, err := node.New(&node.Config{})
 	if err != nil {
@@ -58,6 +74,7 @@ func newTestBackend(t *testing.T) (*node.Node, []*types.Block) {
 	if err != nil {
 		t.Fatalf("can't create new ethereum service: %v", err)
 	}
+	n.RegisterAPIs(tracers.APIs(ethservice.APIBackend))
 	// Import the test chain.
 	if err := n.Start(); err != nil {
 		t.Fatalf("can't start test node: %v", err)
@@ -126,6 +143,9 @@ func TestGethClient(t *testing.T) {
 		}, {
 			"TestCallContract",
 			func(t *testing.T) { testCallContract(t, client) },
+		}, {
+			"TestBatch",
+			func(t *testing.T) { testBatch(t, client) },
 		},
 	}
 	t.Parallel()
@@ -255,6 +275,12 @@ func testSubscribePendingTransactions(t *testing.T, client *rpc.Client) {
 	// Subscribe to Transactions
 	ch := make(chan common.Hash)
 	ec.SubscribePendingTransactions(context.Background(), ch)
+	fullCh := make(chan *types.Transaction)
+	ec.SubscribeFullPendingTransactions(context.Background(), fullCh)
+	filteredCh := make(chan *types.Transaction)
+	ec.SubscribeFilteredPendingTransactions(context.Background(), []common.Address{testAddr}, []common.Address{{1}}, filteredCh)
+	skippedCh := make(chan *types.Transaction, 1)
+	ec.SubscribeFilteredPendingTransactions(context.Background(), nil, []common.Address{{2}}, skippedCh)
 	// Send a transaction
 	chainID, err := ethcl.ChainID(context.Background())
 	if err != nil {
@@ -281,6 +307,17 @@ func testSubscribePendingTransactions(t *testing.T, client *rpc.Client) {
 	if hash != signedTx.Hash() {
 		t.Fatalf("Invalid tx hash received, got %v, want %v", hash, signedTx.Hash())
 	}
+	if full := <-fullCh; full.Hash() != signedTx.Hash() {
+		t.Fatalf("Invalid full tx received, got %v, want %v", full.Hash(), signedTx.Hash())
+	}
+	if filtered := <-filteredCh; filtered.Hash() != signedTx.Hash() {
+		t.Fatalf("Invalid filtered tx received, got %v, want %v", filtered.Hash(), signedTx.Hash())
+	}
+	select {
+	case tx := <-skippedCh:
+		t.Fatalf("Unexpected tx delivered to non-matching filter: %v", tx.Hash())
+	case <-time.After(100 * time.Millisecond):
+	}
 }
 
 func testCallContract(t *testing.T, client *rpc.Client) {
@@ -306,3 +343,181 @@ func testCallContract(t *testing.T, client *rpc.Client) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func testBatch(t *testing.T, client *rpc.Client) {
+	ec := New(client)
+	batch := ec.NewBatch()
+	proof := batch.GetProof(testAddr, []string{}, big.NewInt(0))
+	balance := batch.BalanceAt(testAddr, big.NewInt(0))
+
+	// The call fails without the balance override, as the sender has no funds
+	msg := ethereum.CallMsg{From: common.Address{1}, To: &testAddr, Value: big.NewInt(1)}
+	failed := batch.CallContract(msg, big.NewInt(0), nil)
+	mapAcc := map[common.Address]OverrideAccount{
+		{1}: {Balance: big.NewInt(1000)},
+	}
+	call := batch.CallContract(msg, big.NewInt(0), &mapAcc)
+
+	if err := batch.Execute(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	result, err := proof.Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Address != testAddr || result.Balance.Cmp(testBalance) != 0 {
+		t.Fatalf("unexpected proof result: %v %v", result.Address, result.Balance)
+	}
+	if b, err := balance.Result(); err != nil || b.Cmp(testBalance) != 0 {
+		t.Fatalf("unexpected balance: %v %v", b, err)
+	}
+	if _, err := failed.Result(); err == nil {
+		t.Fatal("call without funds succeeded")
+	}
+	if _, err := call.Result(); err != nil {
+		t.Fatalf("call with overrides failed: %v", err)
+	}
+}
+
+// generateTraceChain creates a chain with a transaction calling traceContract.
+func generateTraceChain() (*core.Genesis, []*types.Block) {
+	db := rawdb.NewMemoryDatabase()
+	config := params.AllEthashProtocolChanges
+	genesis := &core.Genesis{
+		Config: config,
+		Alloc: core.GenesisAlloc{
+			traceAddr:     {Balance: testBalance},
+			traceContract: {Code: traceCode, Balance: big.NewInt(0)},
+		},
+	}
+	generate := func(i int, g *core.BlockGen) {
+		tx, _ := types.SignNewTx(traceKey, types.LatestSigner(config), &types.LegacyTx{
+			Nonce:    0,
+			To:       &traceContract,
+			Gas:      100000,
+			GasPrice: big.NewInt(params.InitialBaseFee),
+		})
+		g.AddTx(tx)
+	}
+	gblock := genesis.ToBlock(db)
+	blocks, _ := core.GenerateChain(config, gblock, ethash.NewFaker(), db, 1, generate)
+	return genesis, append([]*types.Block{gblock}, blocks...)
+}
+
+func TestGethClientTrace(t *testing.T) {
+	genesis, blocks := generateTraceChain()
+	backend, _ := startTestBackend(t, genesis, blocks)
+	client, err := backend.Attach()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+	defer client.Close()
+
+	var (
+		ec         = New(client)
+		ctx        = context.Background()
+		tx         = blocks[1].Transactions()[0]
+		callTracer = "callTracer"
+		callConfig = &tracers.TraceConfig{Tracer: &callTracer}
+	)
+	// Call traces
+	var frame native.CallFrame
+	if err := ec.TraceTransaction(ctx, tx.Hash(), callConfig, &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.Type != "CALL" || frame.From != traceAddr || *frame.To != traceContract {
+		t.Fatalf("wrong call frame: %+v", frame)
+	}
+	if len(frame.Calls) != 1 || *frame.Calls[0].To != common.HexToAddress("0xdead") || frame.Calls[0].Output == nil {
+		t.Fatalf("wrong inner calls: %+v", frame.Calls)
+	}
+	// Struct logs
+	var logs logger.ExecutionResult
+	if err := ec.TraceTransaction(ctx, tx.Hash(), nil, &logs); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Failed || len(logs.StructLogs) != 9 || logs.StructLogs[7].Op != "CALL" {
+		t.Fatalf("wrong struct logs: %+v", logs)
+	}
+	// Prestate
+	prestateTracer := "prestateTracer"
+	var prestate native.PrestateResult
+	if err := ec.TraceTransaction(ctx, tx.Hash(), &tracers.TraceConfig{Tracer: &prestateTracer}, &prestate); err != nil {
+		t.Fatal(err)
+	}
+	if acc := prestate[traceAddr]; acc == nil || acc.Balance.ToInt().Cmp(testBalance) != 0 || acc.Nonce != 0 {
+		t.Fatalf("wrong sender prestate: %+v", acc)
+	}
+	if acc := prestate[traceContract]; acc == nil || !bytes.Equal(acc.Code, traceCode) {
+		t.Fatalf("wrong contract prestate: %+v", acc)
+	}
+	// Block traces
+	results, err := ec.TraceBlockByNumber(ctx, big.NewInt(1), callConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	byHash, err := ec.TraceBlockByHash(ctx, blocks[1].Hash(), callConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(byHash) != 1 || !bytes.Equal(results[0].Result, byHash[0].Result) {
+		t.Fatalf("wrong block traces: %v %v", results, byHash)
+	}
+	var blockFrame native.CallFrame
+	if err := results[0].Decode(&blockFrame); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(blockFrame, frame) {
+		t.Fatalf("block trace mismatch:\nhave %+v\nwant %+v", blockFrame, frame)
+	}
+	roots, err := ec.IntermediateRoots(ctx, blocks[1].Hash(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(roots) != 1 {
+		t.Fatalf("wrong number of intermediate roots: %d", len(roots))
+	}
+	// Call traces with state overrides
+	poor := common.HexToAddress("0x1234")
+	msg := ethereum.CallMsg{From: poor, To: &traceContract, Gas: 100000, Value: big.NewInt(1)}
+	if err := ec.TraceCall(ctx, msg, nil, &TraceCallConfig{TraceConfig: *callConfig}, &frame); err == nil {
+		t.Fatal("call without funds succeeded")
+	}
+	config := &TraceCallConfig{
+		TraceConfig: *callConfig,
+		StateOverrides: map[common.Address]OverrideAccount{
+			poor: {Balance: big.NewInt(1)},
+		},
+	}
+	if err := ec.TraceCall(ctx, msg, nil, config, &frame); err != nil {
+		t.Fatal(err)
+	}
+	if frame.From != poor || frame.Value.ToInt().Cmp(big.NewInt(1)) != 0 || len(frame.Calls) != 1 {
+		t.Fatalf("wrong call frame: %+v", frame)
+	}
+}
+
+func TestOverrideAccountMarshal(t *testing.T) {
+	tests := []struct {
+		override OverrideAccount
+		want     string
+	}{
+		{OverrideAccount{}, `{"nonce":"0x0"}`},
+		{OverrideAccount{Code: []byte{}}, `{"nonce":"0x0","code":"0x"}`},
+		{OverrideAccount{Nonce: 5, Code: []byte{0x60}, Balance: big.NewInt(16)}, `{"nonce":"0x5","code":"0x60","balance":"0x10"}`},
+		{
+			OverrideAccount{StateDiff: map[common.Hash]common.Hash{{1}: {2}}},
+			`{"nonce":"0x0","stateDiff":{"0x0100000000000000000000000000000000000000000000000000000000000000":"0x0200000000000000000000000000000000000000000000000000000000000000"}}`,
+		},
+	}
+	for i, tt := range tests {
+		have, err := json.Marshal(tt.override)
+		if err != nil {
+			t.Fatalf("test %d: %v", i, err)
+		}
+		if string(have) != tt.want {
+			t.Errorf("test %d: wrong encoding\nhave: %s\nwant: %s", i, have, tt.want)
+		}
+	}
+}
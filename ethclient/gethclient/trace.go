@@ -0,0 +1,97 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gethclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+// The trace methods decode the trace result into the value pointed to by result.
+// The result types of the tracers built into geth are:
+//
+//   - the default struct logger: logger.ExecutionResult
+//   - callTracer: native.CallFrame
+//   - prestateTracer: native.PrestateResult
+//
+// The tracer and its options are selected by the trace config, which can be nil
+// to use the struct logger with default options.
+
+// TraceCallConfig is the config of TraceCall. Besides the options of the tracer,
+// it holds state overrides applied before executing the call.
+type TraceCallConfig struct {
+	tracers.TraceConfig
+	StateOverrides map[common.Address]OverrideAccount `json:"stateOverrides,omitempty"`
+}
+
+// TxTraceResult is the trace of a transaction in a block.
+type TxTraceResult struct {
+	Result json.RawMessage `json:"result,omitempty"` // Trace result produced by the tracer
+	Error  string          `json:"error,omitempty"`  // Trace failure produced by the tracer
+}
+
+// Decode decodes the trace result into the value pointed to by result. It
+// returns the trace failure if the transaction could not be traced.
+func (r *TxTraceResult) Decode(result interface{}) error {
+	if r.Error != "" {
+		return errors.New(r.Error)
+	}
+	return json.Unmarshal(r.Result, result)
+}
+
+// TraceTransaction re-executes a transaction and returns its trace in result.
+func (ec *Client) TraceTransaction(ctx context.Context, hash common.Hash, config *tracers.TraceConfig, result interface{}) error {
+	return ec.c.CallContext(ctx, result, "debug_traceTransaction", hash, config)
+}
+
+// TraceCall executes a message call on top of the given block and returns its
+// trace in result. The block number can be nil, in which case the call runs on
+// the latest known block.
+func (ec *Client) TraceCall(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int, config *TraceCallConfig, result interface{}) error {
+	return ec.c.CallContext(ctx, result, "debug_traceCall", toCallArg(msg), toBlockNumArg(blockNumber), config)
+}
+
+// TraceBlockByHash re-executes the block with the given hash and returns the
+// traces of all of its transactions.
+func (ec *Client) TraceBlockByHash(ctx context.Context, hash common.Hash, config *tracers.TraceConfig) ([]*TxTraceResult, error) {
+	var results []*TxTraceResult
+	err := ec.c.CallContext(ctx, &results, "debug_traceBlockByHash", hash, config)
+	return results, err
+}
+
+// TraceBlockByNumber re-executes a block of the canonical chain and returns the
+// traces of all of its transactions. The block number can be nil, in which case
+// the latest known block is traced.
+func (ec *Client) TraceBlockByNumber(ctx context.Context, number *big.Int, config *tracers.TraceConfig) ([]*TxTraceResult, error) {
+	var results []*TxTraceResult
+	err := ec.c.CallContext(ctx, &results, "debug_traceBlockByNumber", toBlockNumArg(number), config)
+	return results, err
+}
+
+// IntermediateRoots re-executes the block with the given hash and returns the
+// state root after each of its transactions.
+func (ec *Client) IntermediateRoots(ctx context.Context, hash common.Hash, config *tracers.TraceConfig) ([]common.Hash, error) {
+	var roots []common.Hash
+	err := ec.c.CallContext(ctx, &roots, "debug_intermediateRoots", hash, config)
+	return roots, err
+}
@@ -0,0 +1,137 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/graph-gophers/graphql-go/introspection"
+	"github.com/graph-gophers/graphql-go/trace"
+)
+
+// complexityError is returned for queries exceeding the complexity limit.
+type complexityError struct {
+	complexity, limit int
+}
+
+func (e *complexityError) Error() string {
+	return fmt.Sprintf("query complexity %d exceeds limit %d", e.complexity, e.limit)
+}
+
+// complexityTracer is a graphql-go tracer metering the execution of queries and
+// mutations. Every field resolved by the library costs one point, fields scanning
+// a range of blocks cost one point per block instead. Introspection fields are
+// free. The cost of a field is charged before its resolver runs, and execution
+// stops as soon as the total exceeds the limit. Subscriptions are not metered,
+// the library executes their events without tracing the operation. They are
+// limited by the number of operations allowed per websocket connection instead.
+type complexityTracer struct {
+	trace.NoopTracer
+	limit int
+}
+
+func newComplexityTracer(limit int) *complexityTracer {
+	return &complexityTracer{limit: limit}
+}
+
+// complexityBudget tracks the cost of a single query. It is stored in the query
+// context, which is cancelled once the limit is exceeded.
+type complexityBudget struct {
+	cost int64 // accessed atomically, fields may be resolved concurrently (first for alignment)
+
+	context.Context
+	cancel context.CancelFunc
+	limit  int64
+}
+
+type complexityBudgetKey struct{}
+
+// Err reports the exceeded limit in place of the cancellation, the library
+// returns it as the error of the query.
+func (b *complexityBudget) Err() error {
+	if atomic.LoadInt64(&b.cost) > b.limit {
+		// Complexities above the limit are reported as limit+1
+		return &complexityError{int(b.limit) + 1, int(b.limit)}
+	}
+	return b.Context.Err()
+}
+
+func (b *complexityBudget) Value(key interface{}) interface{} {
+	if key == (complexityBudgetKey{}) {
+		return b
+	}
+	return b.Context.Value(key)
+}
+
+// charge adds to the cost of the query, cancelling it if the limit is exceeded.
+func (b *complexityBudget) charge(cost int) {
+	if atomic.AddInt64(&b.cost, int64(cost)) > b.limit {
+		b.cancel()
+	}
+}
+
+// executedKey is the context key of a flag set when the library executes a query
+// or mutation. It doesn't trace subscriptions, which are executed per event.
+type executedKey struct{}
+
+func (t *complexityTracer) TraceQuery(ctx context.Context, queryString string, operationName string, variables map[string]interface{}, varTypes map[string]*introspection.Type) (context.Context, trace.TraceQueryFinishFunc) {
+	if executed, ok := ctx.Value(executedKey{}).(*bool); ok {
+		*executed = true
+	}
+	if t.limit <= 0 {
+		return t.NoopTracer.TraceQuery(ctx, queryString, operationName, variables, varTypes)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	budget := &complexityBudget{Context: ctx, cancel: cancel, limit: int64(t.limit)}
+	return budget, func(errs []*gqlerrors.QueryError) { cancel() }
+}
+
+func (t *complexityTracer) TraceField(ctx context.Context, label, typeName, fieldName string, trivial bool, args map[string]interface{}) (context.Context, trace.TraceFieldFinishFunc) {
+	if budget, ok := ctx.Value(complexityBudgetKey{}).(*complexityBudget); ok {
+		if !strings.HasPrefix(typeName, "__") && !strings.HasPrefix(fieldName, "__") {
+			budget.charge(fieldCost(typeName, fieldName, args))
+		}
+	}
+	return t.NoopTracer.TraceField(ctx, label, typeName, fieldName, trivial, args)
+}
+
+// fieldCost returns the cost of resolving a field, excluding its sub-selections.
+// Account.balanceHistory is charged per block state it may look up.
+func fieldCost(typeName, fieldName string, args map[string]interface{}) int {
+	if typeName == "Account" && fieldName == "balanceHistory" {
+		return blockRangeCost(args["from"], args["to"], maxBalanceHistoryBlocks)
+	}
+	return 1
+}
+
+// blockRangeCost returns the number of blocks in the range between the from and
+// to arguments of a field. If the end of the range is not given, or the range is
+// invalid, the maximum range of the field is assumed.
+func blockRangeCost(from, to interface{}, max int) int {
+	var f, t Long
+	if from == nil || to == nil || f.UnmarshalGraphQL(from) != nil || t.UnmarshalGraphQL(to) != nil {
+		return max
+	}
+	if t < f || t-f >= Long(max) {
+		return max
+	}
+	return int(t-f) + 1
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+const complexityTestSchema = `
+    schema {
+        query: Query
+    }
+    type Query {
+        items(count: Int!): [Item!]!
+    }
+    type Item {
+        id: Int!
+        items(count: Int!): [Item!]!
+    }
+`
+
+type complexityTestResolver struct {
+	resolved int // number of resolved items
+}
+
+type complexityTestItem struct {
+	r  *complexityTestResolver
+	id int32
+}
+
+func (r *complexityTestResolver) Items(args struct{ Count int32 }) []*complexityTestItem {
+	items := make([]*complexityTestItem, args.Count)
+	for i := range items {
+		r.resolved++
+		items[i] = &complexityTestItem{r, int32(r.resolved)}
+	}
+	return items
+}
+
+func (i *complexityTestItem) ID() int32 { return i.id }
+
+func (i *complexityTestItem) Items(args struct{ Count int32 }) []*complexityTestItem {
+	return i.r.Items(args)
+}
+
+func TestQueryComplexity(t *testing.T) {
+	tests := []struct {
+		query    string
+		resolved int  // number of items resolved before the execution stopped
+		rejected bool // whether the query exceeds the limit of 100
+	}{
+		// 1 + 10*(1 + 1 + 5*1)
+		{query: `{ items(count: 10) { id items(count: 5) { id } } }`, resolved: 60},
+		// 1 + 10*(1 + 1 + 10*1), execution stops within the ninth item
+		{query: `{ items(count: 10) { id items(count: 10) { id } } }`, resolved: 100, rejected: true},
+		// Fragments are charged for every field they select
+		{query: `{ items(count: 10) { ...f } } fragment f on Item { id items(count: 10) { id } }`, resolved: 100, rejected: true},
+		// Introspection is free
+		{query: `{ __schema { types { name fields { name type { name ofType { name } } } } } }`},
+		{query: `{ items(count: 99) { __typename } }`, resolved: 99},
+	}
+	for i, test := range tests {
+		resolver := new(complexityTestResolver)
+		s := graphql.MustParseSchema(complexityTestSchema, resolver, graphql.Tracer(newComplexityTracer(100)))
+		resp := s.Exec(context.Background(), test.query, "", nil)
+		if test.rejected {
+			if len(resp.Errors) != 1 || resp.Errors[0].Message != "query complexity 101 exceeds limit 100" {
+				t.Errorf("test %d: wrong errors %v", i, resp.Errors)
+			}
+		} else if len(resp.Errors) > 0 {
+			t.Errorf("test %d: unexpected errors %v", i, resp.Errors)
+		}
+		if resolver.resolved != test.resolved {
+			t.Errorf("test %d: resolved %d items, want %d", i, resolver.resolved, test.resolved)
+		}
+	}
+}
+
+// Tests that fields scanning block ranges are charged per block.
+func TestBlockRangeComplexity(t *testing.T) {
+	tests := []struct {
+		from, to interface{}
+		cost     int
+	}{
+		{from: int32(0), to: int32(99), cost: 100},
+		{from: int32(10), to: "10", cost: 1},
+		{from: int32(0), to: int32(199), cost: maxBalanceHistoryBlocks},
+		// Open or invalid ranges are charged at the maximum
+		{from: int32(0), cost: maxBalanceHistoryBlocks},
+		{from: int32(10), to: int32(5), cost: maxBalanceHistoryBlocks},
+		{from: int32(0), to: 1.5, cost: maxBalanceHistoryBlocks},
+	}
+	for i, test := range tests {
+		args := map[string]interface{}{"from": test.from}
+		if test.to != nil {
+			args["to"] = test.to
+		}
+		if cost := fieldCost("Account", "balanceHistory", args); cost != test.cost {
+			t.Errorf("test %d: cost %d, want %d", i, cost, test.cost)
+		}
+	}
+}
@@ -19,19 +19,23 @@ package graphql
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
+	"sync"
 
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/filters"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -71,6 +75,32 @@ func (b *Long) UnmarshalGraphQL(input interface{}) error {
 	return err
 }
 
+// JSON is an arbitrary JSON value.
+type JSON struct {
+	raw json.RawMessage
+}
+
+// ImplementsGraphQLType returns true if JSON implements the provided GraphQL type.
+func (j JSON) ImplementsGraphQLType(name string) bool { return name == "JSON" }
+
+// UnmarshalGraphQL unmarshals the provided GraphQL query data.
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	raw, err := json.Marshal(input)
+	if err != nil {
+		return err
+	}
+	j.raw = raw
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if j.raw == nil {
+		return []byte("null"), nil
+	}
+	return j.raw, nil
+}
+
 // Account represents an Ethereum account at a particular block.
 type Account struct {
 	backend       ethapi.Backend
@@ -132,6 +162,339 @@ func (a *Account) Storage(ctx context.Context, args struct{ Slot common.Hash })
 	return state.GetState(a.address, args.Slot), nil
 }
 
+// maxAccountTransactions is the maximum number of transactions returned by a
+// single Account.transactions query.
+const maxAccountTransactions = 1000
+
+func (a *Account) Transactions(ctx context.Context, args struct {
+	FromBlock  *Long
+	ToBlock    *Long
+	AfterBlock *Long
+	AfterIndex *int32
+	Limit      *int32
+	Reverse    *bool
+}) ([]*Transaction, error) {
+	query := core.AddressTxQuery{
+		Address: a.address,
+		To:      a.backend.CurrentHeader().Number.Uint64(),
+		Limit:   maxAccountTransactions,
+	}
+	if args.FromBlock != nil && *args.FromBlock >= 0 {
+		query.From = uint64(*args.FromBlock)
+	}
+	if args.ToBlock != nil && *args.ToBlock >= 0 {
+		query.To = uint64(*args.ToBlock)
+	}
+	if args.AfterBlock != nil || args.AfterIndex != nil {
+		if args.AfterBlock == nil || args.AfterIndex == nil || *args.AfterBlock < 0 || *args.AfterIndex < 0 {
+			return nil, errors.New("afterBlock and afterIndex must be specified together and be non-negative")
+		}
+		query.After = &core.AddressTxCursor{Number: uint64(*args.AfterBlock), Index: uint64(*args.AfterIndex)}
+	}
+	if args.Limit != nil {
+		if *args.Limit <= 0 || *args.Limit > maxAccountTransactions {
+			return nil, fmt.Errorf("invalid limit %d: must be between 1 and %d", *args.Limit, maxAccountTransactions)
+		}
+		query.Limit = int(*args.Limit)
+	}
+	if args.Reverse != nil {
+		query.Reverse = *args.Reverse
+	}
+	entries, err := a.backend.TransactionsByAddress(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		ret   = make([]*Transaction, 0, len(entries))
+		block *Block
+	)
+	for _, entry := range entries {
+		if block == nil || block.block.NumberU64() != entry.Number {
+			b, err := a.backend.BlockByNumber(ctx, rpc.BlockNumber(entry.Number))
+			if err != nil {
+				return nil, err
+			}
+			if b == nil {
+				return nil, fmt.Errorf("block #%d not found", entry.Number)
+			}
+			numberOrHash := rpc.BlockNumberOrHashWithHash(b.Hash(), false)
+			block = &Block{backend: a.backend, numberOrHash: &numberOrHash, hash: b.Hash(), block: b, header: b.Header()}
+		}
+		if entry.Index >= uint64(len(block.block.Transactions())) {
+			return nil, fmt.Errorf("transaction %d not found in block #%d", entry.Index, entry.Number)
+		}
+		tx := block.block.Transactions()[entry.Index]
+		ret = append(ret, &Transaction{
+			backend: a.backend,
+			hash:    tx.Hash(),
+			tx:      tx,
+			block:   block,
+			index:   entry.Index,
+		})
+	}
+	return ret, nil
+}
+
+// maxStorageRange is the maximum number of slots returned by a single
+// Account.storageRange query.
+const maxStorageRange = 1000
+
+func (a *Account) StorageRange(ctx context.Context, args struct {
+	Start *common.Hash
+	Limit *int32
+}) (*StorageRange, error) {
+	header, err := a.backend.HeaderByNumberOrHash(ctx, a.blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	var (
+		start common.Hash
+		limit = maxStorageRange
+	)
+	if args.Start != nil {
+		start = *args.Start
+	}
+	if args.Limit != nil {
+		if *args.Limit <= 0 || *args.Limit > maxStorageRange {
+			return nil, fmt.Errorf("invalid limit %d: must be between 1 and %d", *args.Limit, maxStorageRange)
+		}
+		limit = int(*args.Limit)
+	}
+	slots, next, err := a.backend.StorageRange(ctx, header.Root, a.address, start, limit)
+	if err != nil {
+		return nil, err
+	}
+	return &StorageRange{slots: slots, next: next}, nil
+}
+
+const (
+	// maxBalanceHistoryBlocks is the maximum number of block states looked up by a
+	// single Account.balanceHistory query. The query complexity analysis charges
+	// one point per block of the range, up to this limit.
+	maxBalanceHistoryBlocks = 128
+
+	// maxIndexedBalanceHistoryRange is the maximum block range of an
+	// Account.balanceHistory query served with the help of the address index.
+	// Only the headers of its blocks are read, plus the bodies of those with
+	// uncles.
+	maxIndexedBalanceHistoryRange = 8192
+)
+
+// BalanceHistory lists the balance of the account at block from and at every
+// following block where it changed.
+//
+// If the address index covers the range and includes internal calls, the state is
+// only looked up at the blocks where the balance can change: blocks containing
+// transactions the account participated in, blocks mined by the account or
+// including its uncles, and the DAO fork block. Otherwise the state of every block
+// in the range is looked up. Either way, at most maxBalanceHistoryBlocks states
+// are looked up.
+func (a *Account) BalanceHistory(ctx context.Context, args struct {
+	From Long
+	To   *Long
+}) ([]*BalanceChange, error) {
+	header, err := a.backend.HeaderByNumberOrHash(ctx, a.blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	to := header.Number.Uint64()
+	if args.To != nil {
+		if *args.To < 0 {
+			return nil, fmt.Errorf("invalid block range %d..%d", args.From, *args.To)
+		}
+		to = uint64(*args.To)
+	}
+	if args.From < 0 || uint64(args.From) > to {
+		return nil, fmt.Errorf("invalid block range %d..%d", args.From, to)
+	}
+	from := uint64(args.From)
+	numbers, err := a.balanceHistoryBlocks(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		changes []*BalanceChange
+		last    *big.Int
+	)
+	for _, number := range numbers {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		state, header, err := a.backend.StateAndHeaderByNumber(ctx, rpc.BlockNumber(number))
+		if err != nil {
+			return nil, fmt.Errorf("state of block #%d unavailable: %v", number, err)
+		}
+		if state == nil || header == nil {
+			return nil, fmt.Errorf("block #%d not found", number)
+		}
+		balance := state.GetBalance(a.address)
+		if last != nil && balance.Cmp(last) == 0 {
+			continue
+		}
+		last = balance
+		numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+		changes = append(changes, &BalanceChange{
+			block:   &Block{backend: a.backend, numberOrHash: &numberOrHash, hash: header.Hash(), header: header},
+			balance: hexutil.Big(*balance),
+		})
+	}
+	return changes, nil
+}
+
+// balanceHistoryBlocks returns the numbers of the blocks in the given range whose
+// state needs to be looked up to find the balance changes of the account.
+func (a *Account) balanceHistoryBlocks(ctx context.Context, from, to uint64) ([]uint64, error) {
+	tail, head, internal, ok := a.backend.AddressIndexStatus()
+	if !ok || !internal || from < tail || to > head {
+		if to-from >= maxBalanceHistoryBlocks {
+			return nil, fmt.Errorf("block range %d..%d exceeds maximum of %d blocks", from, to, maxBalanceHistoryBlocks)
+		}
+		numbers := make([]uint64, 0, to-from+1)
+		for number := from; number <= to; number++ {
+			numbers = append(numbers, number)
+		}
+		return numbers, nil
+	}
+	if to-from >= maxIndexedBalanceHistoryRange {
+		return nil, fmt.Errorf("block range %d..%d exceeds maximum of %d blocks", from, to, maxIndexedBalanceHistoryRange)
+	}
+	// Collect the blocks containing transactions of the account, page by page to
+	// bail out early on busy accounts.
+	var (
+		errTooManyChanges = fmt.Errorf("balance changes in too many blocks of range %d..%d, at most %d can be looked up", from, to, maxBalanceHistoryBlocks)
+
+		numbers = []uint64{from}
+		query   = core.AddressTxQuery{Address: a.address, From: from + 1, To: to, Limit: maxAccountTransactions}
+		touched = make(map[uint64]bool)
+	)
+	for {
+		entries, err := a.backend.TransactionsByAddress(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			touched[entry.Number] = true
+		}
+		if len(touched) >= maxBalanceHistoryBlocks {
+			return nil, errTooManyChanges
+		}
+		if len(entries) < query.Limit {
+			break
+		}
+		last := entries[len(entries)-1]
+		query.After = &core.AddressTxCursor{Number: last.Number, Index: last.Index}
+	}
+	// Add the blocks paying mining rewards or fees to the account, and the DAO fork
+	// block moving balances outside of transactions.
+	config := a.backend.ChainConfig()
+	for number := from + 1; number <= to; number++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if !touched[number] {
+			rewarded, err := a.rewarded(ctx, number)
+			if err != nil {
+				return nil, err
+			}
+			dao := config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Uint64() == number
+			if !rewarded && !dao {
+				continue
+			}
+		}
+		if len(numbers) == maxBalanceHistoryBlocks {
+			return nil, errTooManyChanges
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers, nil
+}
+
+// rewarded reports whether the account receives the mining reward or uncle
+// reward of the given block.
+func (a *Account) rewarded(ctx context.Context, number uint64) (bool, error) {
+	header, err := a.backend.HeaderByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return false, err
+	}
+	if header == nil {
+		return false, fmt.Errorf("block #%d not found", number)
+	}
+	if header.Coinbase == a.address {
+		return true, nil
+	}
+	if header.UncleHash == types.EmptyUncleHash {
+		return false, nil
+	}
+	block, err := a.backend.BlockByNumber(ctx, rpc.BlockNumber(number))
+	if err != nil {
+		return false, err
+	}
+	if block == nil {
+		return false, fmt.Errorf("block #%d not found", number)
+	}
+	for _, uncle := range block.Uncles() {
+		if uncle.Coinbase == a.address {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// StorageRange is a range of the storage of an account.
+type StorageRange struct {
+	slots []core.StorageSlot
+	next  *common.Hash
+}
+
+func (r *StorageRange) Slots(ctx context.Context) []*StorageSlot {
+	ret := make([]*StorageSlot, len(r.slots))
+	for i := range r.slots {
+		ret[i] = &StorageSlot{&r.slots[i]}
+	}
+	return ret
+}
+
+func (r *StorageRange) Next(ctx context.Context) *common.Hash {
+	return r.next
+}
+
+// StorageSlot is a storage slot of an account.
+type StorageSlot struct {
+	slot *core.StorageSlot
+}
+
+func (s *StorageSlot) Hash(ctx context.Context) common.Hash {
+	return s.slot.Hash
+}
+
+func (s *StorageSlot) Key(ctx context.Context) *common.Hash {
+	return s.slot.Key
+}
+
+func (s *StorageSlot) Value(ctx context.Context) common.Hash {
+	return s.slot.Value
+}
+
+// BalanceChange is the balance of an account as of a block.
+type BalanceChange struct {
+	block   *Block
+	balance hexutil.Big
+}
+
+func (c *BalanceChange) Block(ctx context.Context) *Block {
+	return c.block
+}
+
+func (c *BalanceChange) Balance(ctx context.Context) hexutil.Big {
+	return c.balance
+}
+
 // Log represents an individual log message. All arguments are mandatory.
 type Log struct {
 	backend     ethapi.Backend
@@ -185,6 +548,10 @@ type Transaction struct {
 	tx      *types.Transaction
 	block   *Block
 	index   uint64
+
+	// Transactions listed through the block are traced all at once, as they are
+	// likely to be traced together.
+	traceAll bool
 }
 
 // resolve returns the internal transaction object, fetching it if needed.
@@ -514,6 +881,50 @@ func (t *Transaction) V(ctx context.Context) (hexutil.Big, error) {
 	return hexutil.Big(*v), nil
 }
 
+// Trace executes the transaction with a tracer and returns the trace result.
+func (t *Transaction) Trace(ctx context.Context, args struct {
+	Tracer *string
+	Config *JSON
+}) (*JSON, error) {
+	tx, err := t.resolve(ctx)
+	if err != nil || tx == nil {
+		return nil, err
+	}
+	if t.block == nil {
+		return nil, nil // pending transaction
+	}
+	backend, ok := t.backend.(tracers.Backend)
+	if !ok {
+		return nil, errors.New("tracing not supported")
+	}
+	config := new(tracers.TraceConfig)
+	if args.Config != nil {
+		if err := json.Unmarshal(args.Config.raw, config); err != nil {
+			return nil, fmt.Errorf("invalid trace config: %v", err)
+		}
+	}
+	if args.Tracer != nil {
+		config.Tracer = args.Tracer
+	}
+	var (
+		api    = tracers.NewAPI(backend)
+		result interface{}
+	)
+	if t.traceAll {
+		result, err = t.block.traceTransaction(ctx, api, config, t.index)
+	} else {
+		result, err = api.TraceTransaction(ctx, t.hash, config)
+	}
+	if err != nil {
+		return nil, err
+	}
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &JSON{raw}, nil
+}
+
 type BlockType int
 
 // Block represents an Ethereum block.
@@ -526,6 +937,15 @@ type Block struct {
 	header       *types.Header
 	block        *types.Block
 	receipts     []*types.Receipt
+
+	tracesMu sync.Mutex
+	traces   map[string][]blockTrace // block traces by trace configuration
+}
+
+// blockTrace is the trace result of a transaction in a block trace.
+type blockTrace struct {
+	result interface{}
+	err    string
 }
 
 // resolve returns the internal Block object representing this block, fetching
@@ -567,6 +987,47 @@ func (b *Block) resolveHeader(ctx context.Context) (*types.Header, error) {
 	return b.header, err
 }
 
+// traceTransaction traces all transactions of the block, once per trace
+// configuration, and returns the trace result of the transaction at index.
+func (b *Block) traceTransaction(ctx context.Context, api *tracers.API, config *tracers.TraceConfig, index uint64) (interface{}, error) {
+	key, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	b.tracesMu.Lock()
+	defer b.tracesMu.Unlock()
+
+	traces, ok := b.traces[string(key)]
+	if !ok {
+		block, err := b.resolve(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, errors.New("block not found")
+		}
+		results, err := api.TraceBlockByHash(ctx, block.Hash(), config)
+		if err != nil {
+			return nil, err
+		}
+		traces = make([]blockTrace, len(results))
+		for i, res := range results {
+			traces[i] = blockTrace{result: res.Result, err: res.Error}
+		}
+		if b.traces == nil {
+			b.traces = make(map[string][]blockTrace)
+		}
+		b.traces[string(key)] = traces
+	}
+	if index >= uint64(len(traces)) {
+		return nil, fmt.Errorf("transaction %d not found in block trace", index)
+	}
+	if traces[index].err != "" {
+		return nil, errors.New(traces[index].err)
+	}
+	return traces[index].result, nil
+}
+
 // resolveReceipts returns the list of receipts for this block, fetching them
 // if necessary.
 func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
@@ -841,11 +1302,12 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	ret := make([]*Transaction, 0, len(block.Transactions()))
 	for i, tx := range block.Transactions() {
 		ret = append(ret, &Transaction{
-			backend: b.backend,
-			hash:    tx.Hash(),
-			tx:      tx,
-			block:   b,
-			index:   uint64(i),
+			backend:  b.backend,
+			hash:     tx.Hash(),
+			tx:       tx,
+			block:    b,
+			index:    uint64(i),
+			traceAll: true,
 		})
 	}
 	return &ret, nil
@@ -1103,6 +1565,9 @@ func (p *Pending) EstimateGas(ctx context.Context, args struct {
 // Resolver is the top-level object in the GraphQL hierarchy.
 type Resolver struct {
 	backend ethapi.Backend
+
+	eventsOnce sync.Once
+	events     *filters.EventSystem // created on the first subscription
 }
 
 func (r *Resolver) Block(ctx context.Context, args struct {
@@ -1354,3 +1819,124 @@ func (r *Resolver) Syncing() (*SyncState, error) {
 	// Otherwise gather the block sync stats
 	return &SyncState{progress}, nil
 }
+
+// subscriptionBuffer is the number of events buffered for a subscription. If the
+// client falls further behind, the subscription is ended.
+const subscriptionBuffer = 128
+
+// eventSystem returns the filter event system backing subscriptions.
+func (r *Resolver) eventSystem() *filters.EventSystem {
+	r.eventsOnce.Do(func() {
+		r.events = filters.NewEventSystem(r.backend, false)
+	})
+	return r.events
+}
+
+// NewHeads delivers every block that becomes the head of the chain.
+func (r *Resolver) NewHeads(ctx context.Context) (<-chan *Block, error) {
+	headers := make(chan *types.Header)
+	sub := r.eventSystem().SubscribeNewHeads(headers)
+	out := make(chan *Block, subscriptionBuffer)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case header := <-headers:
+				numberOrHash := rpc.BlockNumberOrHashWithHash(header.Hash(), false)
+				block := &Block{
+					backend:      r.backend,
+					numberOrHash: &numberOrHash,
+					hash:         header.Hash(),
+					header:       header,
+				}
+				select {
+				case out <- block:
+				default:
+					return // subscriber too slow
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// NewLogs delivers the log entries of new blocks matching the filter.
+func (r *Resolver) NewLogs(ctx context.Context, args struct{ Filter BlockFilterCriteria }) (<-chan *Log, error) {
+	var crit ethereum.FilterQuery
+	if args.Filter.Addresses != nil {
+		crit.Addresses = *args.Filter.Addresses
+	}
+	if args.Filter.Topics != nil {
+		crit.Topics = *args.Filter.Topics
+	}
+	logs := make(chan []*types.Log)
+	sub, err := r.eventSystem().SubscribeLogs(crit, logs)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan *Log, subscriptionBuffer)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case logs := <-logs:
+				for _, log := range logs {
+					if log.Removed {
+						continue
+					}
+					select {
+					case out <- &Log{
+						backend:     r.backend,
+						transaction: &Transaction{backend: r.backend, hash: log.TxHash},
+						log:         log,
+					}:
+					default:
+						return // subscriber too slow
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// NewPendingTransactions delivers transactions entering the pool.
+func (r *Resolver) NewPendingTransactions(ctx context.Context) (<-chan *Transaction, error) {
+	txs := make(chan []*types.Transaction)
+	sub := r.eventSystem().SubscribePendingTxs(txs)
+	out := make(chan *Transaction, subscriptionBuffer)
+	go func() {
+		defer close(out)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case txs := <-txs:
+				for _, tx := range txs {
+					select {
+					case out <- &Transaction{backend: r.backend, hash: tx.Hash(), tx: tx}:
+					default:
+						return // subscriber too slow
+					}
+				}
+			case <-sub.Err():
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
@@ -17,6 +17,7 @@
 package graphql
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"math/big"
@@ -26,6 +27,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -35,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/gorilla/websocket"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -198,6 +201,211 @@ func TestGraphQLBlockSerializationEIP2718(t *testing.T) {
 	}
 }
 
+func TestGraphQLStateAndTraces(t *testing.T) {
+	stack := createNode(t, true, true)
+	defer stack.Close()
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	for i, tt := range []struct {
+		body string
+		want string
+		code int
+	}{
+		{
+			body: `{"query": "{block{account(address:\"0x0000000000000000000000000000000000000dad\"){storageRange{slots{hash key value} next}}}}"}`,
+			want: `{"data":{"block":{"account":{"storageRange":{"slots":[{"hash":"0x405787fa12a823e0f2b7631cc41b3ba8828b3321ca811111fa75cd3aa3bb5ace","key":"0x0000000000000000000000000000000000000000000000000000000000000002","value":"0x000000000000000000000000000000000000000000000000000000000000beef"},{"hash":"0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6","key":"0x0000000000000000000000000000000000000000000000000000000000000001","value":"0x0000000000000000000000000000000000000000000000000000000000001337"}],"next":null}}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block{account(address:\"0x0000000000000000000000000000000000000dad\"){storageRange(limit:1){slots{value} next}}}}"}`,
+			want: `{"data":{"block":{"account":{"storageRange":{"slots":[{"value":"0x000000000000000000000000000000000000000000000000000000000000beef"}],"next":"0xb10e2d527612073b26eecdfd717e6a320cf44b4afac2b0732d9fcbe2b7fa0cf6"}}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block{account(address:\"0x71562b71999873db5b286df957af199ec94617f7\"){balanceHistory(from:0){block{number} balance}}}}"}`,
+			want: `{"data":{"block":{"account":{"balanceHistory":[{"block":{"number":0},"balance":"0x38d7ea4c68000"},{"block":{"number":1},"balance":"0x35bbcf1de776a"}]}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block{account(address:\"0x0000000000000000000000000000000000000dad\"){balanceHistory(from:1){block{number} balance}}}}"}`,
+			want: `{"data":{"block":{"account":{"balanceHistory":[{"block":{"number":1},"balance":"0x96"}]}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block{account(address:\"0x0000000000000000000000000000000000000dad\"){balanceHistory(from:2){balance}}}}"}`,
+			want: `{"errors":[{"message":"invalid block range 2..1","path":["block","account","balanceHistory"]}],"data":{"block":null}}`,
+			code: 400,
+		},
+		{
+			body: `{"query": "{block{transactions{trace(tracer:\"callTracer\")}}}"}`,
+			want: `{"data":{"block":{"transactions":[{"trace":{"type":"CALL","from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000000dad","value":"0x64","gas":"0x7148","gasUsed":"0x106c","input":"0x","output":"0x"}},{"trace":{"type":"CALL","from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000000dad","value":"0x32","gas":"0x125c","gasUsed":"0x106c","input":"0x","output":"0x"}}]}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{transaction(hash:\"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b\"){trace(tracer:\"callTracer\")}}"}`,
+			want: `{"data":{"transaction":{"trace":{"type":"CALL","from":"0x71562b71999873db5b286df957af199ec94617f7","to":"0x0000000000000000000000000000000000000dad","value":"0x64","gas":"0x7148","gasUsed":"0x106c","input":"0x","output":"0x"}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{transaction(hash:\"0xd864c9d7d37fade6b70164740540c06dd58bb9c3f6b46101908d6339db6a6a7b\"){trace(config:{disableStack:true,disableStorage:true})}}"}`,
+			want: `{"data":{"transaction":{"trace":{"gas":25204,"failed":false,"returnValue":"","structLogs":[{"pc":0,"op":"PC","gas":29000,"gasCost":2,"depth":1},{"pc":1,"op":"PC","gas":28998,"gasCost":2,"depth":1},{"pc":2,"op":"SLOAD","gas":28996,"gasCost":2100,"depth":1},{"pc":3,"op":"SLOAD","gas":26896,"gasCost":2100,"depth":1},{"pc":4,"op":"STOP","gas":24796,"gasCost":0,"depth":1}]}}}}`,
+			code: 200,
+		},
+	} {
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		if have := string(bodyBytes); have != tt.want {
+			t.Errorf("testcase %d %s,\nhave:\n%v\nwant:\n%v", i, tt.body, have, tt.want)
+		}
+		if tt.code != resp.StatusCode {
+			t.Errorf("testcase %d %s,\nwrong statuscode, have: %v, want: %v", i, tt.body, resp.StatusCode, tt.code)
+		}
+	}
+}
+
+// Tests that balance histories spanning more blocks than can be looked up are
+// served using the address index, visiting only blocks where the balance changes.
+func TestGraphQLBalanceHistoryIndexed(t *testing.T) {
+	stack := createNode(t, false, false)
+	defer stack.Close()
+
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address  = crypto.PubkeyToAddress(key.PublicKey)
+		beef     = common.HexToAddress("0x000000000000000000000000000000000000beef")
+		selfdest = common.HexToAddress("0x0000000000000000000000000000000000005d5d")
+	)
+	ethConf := &ethconfig.Config{
+		Genesis: &core.Genesis{
+			Config:     params.AllEthashProtocolChanges,
+			GasLimit:   11500000,
+			Difficulty: big.NewInt(1048576),
+			Alloc: core.GenesisAlloc{
+				address: {Balance: big.NewInt(1000000000000000000)},
+				// The address 0x5d5d self-destructs, sending its balance to 0xbeef
+				selfdest: {
+					Code:    append(append([]byte{byte(vm.PUSH20)}, beef.Bytes()...), byte(vm.SELFDESTRUCT)),
+					Balance: big.NewInt(1000),
+				},
+			},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		},
+		Ethash: ethash.Config{
+			PowMode: ethash.ModeFake,
+		},
+		NetworkId:            1337,
+		NoPruning:            true,
+		TrieCleanCache:       5,
+		TrieDirtyCache:       5,
+		TrieTimeout:          60 * time.Minute,
+		AddressIndex:         true,
+		AddressIndexInternal: true,
+	}
+	ethBackend, err := eth.New(stack, ethConf)
+	if err != nil {
+		t.Fatalf("could not create eth backend: %v", err)
+	}
+	signer := types.LatestSigner(ethConf.Genesis.Config)
+	chain, _ := core.GenerateChain(params.AllEthashProtocolChanges, ethBackend.BlockChain().Genesis(),
+		ethash.NewFaker(), ethBackend.ChainDb(), 300, func(i int, b *core.BlockGen) {
+			var to *common.Address
+			switch i {
+			case 9, 199:
+				to = &beef
+			case 249:
+				to = &selfdest
+			}
+			if i == 149 {
+				b.SetCoinbase(beef)
+			} else {
+				b.SetCoinbase(common.Address{1})
+			}
+			if to != nil {
+				tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+					Nonce:    b.TxNonce(address),
+					To:       to,
+					Value:    big.NewInt(1),
+					Gas:      50000,
+					GasPrice: b.BaseFee(),
+				})
+				b.AddTx(tx)
+			}
+		})
+	if _, err := ethBackend.BlockChain().InsertChain(chain); err != nil {
+		t.Fatalf("could not import blocks: %v", err)
+	}
+	if err := New(stack, ethBackend.APIBackend, []string{}, []string{}); err != nil {
+		t.Fatalf("could not create graphql service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if _, head, _, ok := ethBackend.APIBackend.AddressIndexStatus(); ok && head == 300 {
+			break
+		}
+		if time.Since(start) > 10*time.Second {
+			t.Fatal("address index not generated")
+		}
+	}
+	// Compute the expected history by scanning the state of every block
+	type change struct {
+		Block   struct{ Number uint64 }
+		Balance hexutil.Big
+	}
+	var want []change
+	for number := uint64(0); number <= 300; number++ {
+		state, err := ethBackend.BlockChain().StateAt(ethBackend.BlockChain().GetHeaderByNumber(number).Root)
+		if err != nil {
+			t.Fatalf("state of block #%d unavailable: %v", number, err)
+		}
+		balance := state.GetBalance(beef)
+		if len(want) > 0 && want[len(want)-1].Balance.ToInt().Cmp(balance) == 0 {
+			continue
+		}
+		var c change
+		c.Block.Number, c.Balance = number, hexutil.Big(*balance)
+		want = append(want, c)
+	}
+	if len(want) != 5 {
+		t.Fatalf("expected 5 balance changes, have %d", len(want))
+	}
+	body := `{"query": "{block{account(address:\"0x000000000000000000000000000000000000beef\"){balanceHistory(from:0){block{number} balance}}}}"}`
+	resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("could not post: %v", err)
+	}
+	defer resp.Body.Close()
+	var result struct {
+		Data struct {
+			Block struct {
+				Account struct {
+					BalanceHistory []change
+				}
+			}
+		}
+		Errors []interface{}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		t.Fatalf("could not decode response: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("query failed: %v", result.Errors)
+	}
+	have, _ := json.Marshal(result.Data.Block.Account.BalanceHistory)
+	if exp, _ := json.Marshal(want); string(have) != string(exp) {
+		t.Errorf("balance history mismatch:\nhave: %s\nwant: %s", have, exp)
+	}
+}
+
 // Tests that a graphQL request is not handled successfully when graphql is not enabled on the specified endpoint
 func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	stack := createNode(t, false, false)
@@ -214,12 +422,215 @@ func TestGraphQLHTTPOnSamePort_GQLRequest_Unsuccessful(t *testing.T) {
 	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
 
+// Tests that queries exceeding the depth or complexity limits are rejected.
+func TestGraphQLQueryLimits(t *testing.T) {
+	stack, err := node.New(&node.Config{
+		HTTPHost:             "127.0.0.1",
+		HTTPPort:             0,
+		GraphQLMaxDepth:      3,
+		GraphQLMaxComplexity: 20,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	for i, tt := range []struct {
+		body string
+		want string
+		code int
+	}{
+		{
+			body: `{"query": "{block{number parent{number}}}"}`,
+			want: `{"data":{"block":{"number":10,"parent":{"number":9}}}}`,
+			code: 200,
+		},
+		{
+			body: `{"query": "{block{parent{parent{parent{number}}}}}"}`,
+			want: `{"errors":[{"message":"Field \"parent\" has depth 4 that exceeds max depth 3","locations":[{"line":1,"column":22}]}]}`,
+			code: 400,
+		},
+		{
+			body: `{"query": "{blocks(from:0){number hash}}"}`,
+			want: `{"errors":[{"message":"query complexity 21 exceeds limit 20"}]}`,
+			code: 400,
+		},
+	} {
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		bodyBytes, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		if have := string(bodyBytes); have != tt.want {
+			t.Errorf("testcase %d %s,\nhave:\n%v\nwant:\n%v", i, tt.body, have, tt.want)
+		}
+		if tt.code != resp.StatusCode {
+			t.Errorf("testcase %d %s,\nwrong statuscode, have: %v, want: %v", i, tt.body, resp.StatusCode, tt.code)
+		}
+	}
+}
+
+// Tests that new heads are delivered to subscriptions over websocket.
+func TestGraphQLSubscription(t *testing.T) {
+	stack := createNode(t, false, false)
+	defer stack.Close()
+	ethBackend := createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	conn := dialGraphQLWebsocket(t, stack)
+	defer conn.Close()
+
+	var msg wsMessage
+	if err := conn.WriteJSON(wsMessage{Type: "ping"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "pong" {
+		t.Fatalf("no pong: %v %v", msg.Type, err)
+	}
+	subscribe := wsMessage{ID: "1", Type: "subscribe", Payload: []byte(`{"query":"subscription { newHeads { number } }"}`)}
+	if err := conn.WriteJSON(subscribe); err != nil {
+		t.Fatal(err)
+	}
+
+	// Import blocks until the subscription delivers one, it may not be installed yet.
+	conn.SetReadDeadline(time.Time{})
+	received := make(chan wsMessage, 1)
+	go func() {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err == nil {
+			received <- msg
+		}
+	}()
+	chain := ethBackend.BlockChain()
+	blocks, _ := core.GenerateChain(params.AllEthashProtocolChanges, chain.CurrentBlock(),
+		ethash.NewFaker(), ethBackend.ChainDb(), 20, func(i int, gen *core.BlockGen) {})
+	for _, block := range blocks {
+		if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+			t.Fatalf("could not import block: %v", err)
+		}
+		select {
+		case msg := <-received:
+			if msg.ID != "1" || msg.Type != "next" {
+				t.Fatalf("wrong message: id %s type %s payload %s", msg.ID, msg.Type, msg.Payload)
+			}
+			var payload struct {
+				Data struct {
+					NewHeads struct{ Number uint64 }
+				}
+			}
+			if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if n := payload.Data.NewHeads.Number; n == 0 || n > block.NumberU64() {
+				t.Fatalf("wrong head number %d, last imported %d", n, block.NumberU64())
+			}
+			return
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+	t.Fatal("no new head delivered")
+}
+
+// Tests that queries over websocket are metered, and that the number of operations
+// running on a connection is limited.
+func TestGraphQLWebsocketLimits(t *testing.T) {
+	stack, err := node.New(&node.Config{
+		HTTPHost:             "127.0.0.1",
+		HTTPPort:             0,
+		GraphQLMaxComplexity: 20,
+	})
+	if err != nil {
+		t.Fatalf("could not create node: %v", err)
+	}
+	defer stack.Close()
+	createGQLService(t, stack)
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+	conn := dialGraphQLWebsocket(t, stack)
+	defer conn.Close()
+
+	var msg wsMessage
+	query := wsMessage{ID: "query", Type: "subscribe", Payload: []byte(`{"query":"{block{number}}"}`)}
+	if err := conn.WriteJSON(query); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []wsMessage{
+		{ID: "query", Type: "next", Payload: []byte(`{"data":{"block":{"number":10}}}`)},
+		{ID: "query", Type: "complete"},
+	} {
+		var msg wsMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.ID != want.ID || msg.Type != want.Type || string(msg.Payload) != string(want.Payload) {
+			t.Fatalf("wrong message: id %s type %s payload %s", msg.ID, msg.Type, msg.Payload)
+		}
+	}
+	query = wsMessage{ID: "query", Type: "subscribe", Payload: []byte(`{"query":"{blocks(from:0){number hash}}"}`)}
+	if err := conn.WriteJSON(query); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"message":"query complexity 21 exceeds limit 20"}]`; msg.ID != "query" || msg.Type != "error" || string(msg.Payload) != want {
+		t.Fatalf("wrong message: id %s type %s payload %s", msg.ID, msg.Type, msg.Payload)
+	}
+	for i := 0; i <= wsMaxOperations; i++ {
+		subscribe := wsMessage{ID: fmt.Sprint(i), Type: "subscribe", Payload: []byte(`{"query":"subscription { newHeads { number } }"}`)}
+		if err := conn.WriteJSON(subscribe); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf(`[{"message":"%v"}]`, errTooManyOperations); msg.ID != fmt.Sprint(wsMaxOperations) || msg.Type != "error" || string(msg.Payload) != want {
+		t.Fatalf("wrong message: id %s type %s payload %s", msg.ID, msg.Type, msg.Payload)
+	}
+}
+
+// dialGraphQLWebsocket connects to the GraphQL websocket endpoint of the node and
+// initialises the connection.
+func dialGraphQLWebsocket(t *testing.T, stack *node.Node) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(stack.HTTPEndpoint(), "http") + "/graphql"
+	dialer := websocket.Dialer{Subprotocols: []string{transportWSProtocol}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("could not dial: %v", err)
+	}
+	if conn.Subprotocol() != transportWSProtocol {
+		t.Fatalf("wrong subprotocol %q", conn.Subprotocol())
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	var msg wsMessage
+	if err := conn.WriteJSON(wsMessage{Type: "connection_init"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.ReadJSON(&msg); err != nil || msg.Type != "connection_ack" {
+		t.Fatalf("no connection ack: %v %v", msg.Type, err)
+	}
+	return conn
+}
+
 func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	stack, err := node.New(&node.Config{
-		HTTPHost: "127.0.0.1",
-		HTTPPort: 0,
-		WSHost:   "127.0.0.1",
-		WSPort:   0,
+		HTTPHost:             "127.0.0.1",
+		HTTPPort:             0,
+		WSHost:               "127.0.0.1",
+		WSPort:               0,
+		GraphQLMaxDepth:      node.DefaultGraphQLMaxDepth,
+		GraphQLMaxComplexity: node.DefaultGraphQLMaxComplexity,
 	})
 	if err != nil {
 		t.Fatalf("could not create node: %v", err)
@@ -235,7 +646,7 @@ func createNode(t *testing.T, gqlEnabled bool, txEnabled bool) *node.Node {
 	return stack
 }
 
-func createGQLService(t *testing.T, stack *node.Node) {
+func createGQLService(t *testing.T, stack *node.Node) *eth.Ethereum {
 	// create backend
 	ethConf := &ethconfig.Config{
 		Genesis: &core.Genesis{
@@ -270,6 +681,7 @@ func createGQLService(t *testing.T, stack *node.Node) {
 	if err != nil {
 		t.Fatalf("could not create graphql service: %v", err)
 	}
+	return ethBackend
 }
 
 func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
@@ -294,6 +706,10 @@ func createGQLServiceWithTransactions(t *testing.T, stack *node.Node) {
 						byte(vm.SLOAD),
 						byte(vm.SLOAD),
 					},
+					Storage: map[common.Hash]common.Hash{
+						common.HexToHash("0x01"): common.HexToHash("0x1337"),
+						common.HexToHash("0x02"): common.HexToHash("0xbeef"),
+					},
 					Nonce:   0,
 					Balance: big.NewInt(0),
 				},
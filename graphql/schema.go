@@ -30,10 +30,13 @@ const schema string = `
     scalar BigInt
     # Long is a 64 bit unsigned integer.
     scalar Long
+    # JSON is an arbitrary JSON value.
+    scalar JSON
 
     schema {
         query: Query
         mutation: Mutation
+        subscription: Subscription
     }
 
     # Account is an Ethereum account at a particular block.
@@ -52,6 +55,51 @@ const schema string = `
         # Storage provides access to the storage of a contract account, indexed
         # by its 32 byte slot identifier.
         storage(slot: Bytes32!): Bytes32!
+        # Transactions lists the transactions this account participated in as
+        # sender, recipient, created contract or within internal calls, as far
+        # as covered by the node's address index. The results can be paginated
+        # by passing the block number and index of the last transaction seen
+        # as afterBlock and afterIndex. At most 1000 transactions are returned.
+        transactions(fromBlock: Long, toBlock: Long, afterBlock: Long, afterIndex: Int, limit: Int, reverse: Boolean): [Transaction!]!
+        # StorageRange lists the storage slots of a contract account, ordered by
+        # the hash of their keys and starting at the slot hash start. At most
+        # limit slots are returned, 1000 by default and at most.
+        storageRange(start: Bytes32, limit: Int): StorageRange!
+        # BalanceHistory lists the balance of the account at block from and at
+        # every following block up to block to, which defaults to the account's
+        # block, where the balance changed. The state of at most 128 blocks is
+        # looked up and must be available. If the node's address index covers
+        # the range and includes internal calls, only blocks where the balance
+        # can change are looked up and the range may span up to 8192 blocks,
+        # otherwise every block of the range is looked up.
+        balanceHistory(from: Long!, to: Long): [BalanceChange!]!
+    }
+
+    # StorageRange is a range of the storage of an account.
+    type StorageRange {
+        # Slots are the storage slots in the range.
+        slots: [StorageSlot!]!
+        # Next is the hash of the key of the slot following the range, if there
+        # are more slots. It can be passed as start to query the next range.
+        next: Bytes32
+    }
+
+    # StorageSlot is a storage slot of an account.
+    type StorageSlot {
+        # Hash is the hash of the slot key.
+        hash: Bytes32!
+        # Key is the slot key, if its preimage is known to the node.
+        key: Bytes32
+        # Value is the value stored in the slot.
+        value: Bytes32!
+    }
+
+    # BalanceChange is the balance of an account as of a block.
+    type BalanceChange {
+        # Block is the block as of which the account had the balance.
+        block: Block!
+        # Balance is the balance of the account, in wei.
+        balance: BigInt!
     }
 
     # Log is an Ethereum event log.
@@ -140,6 +188,12 @@ const schema string = `
         #Envelope transaction support
         type: Int
         accessList: [AccessTuple!]
+        # Trace executes the transaction with the given tracer and returns the
+        # trace result. Without tracer, the opcode level struct logger is used.
+        # Config holds the trace options of debug_traceTransaction, such as
+        # timeout, reexec or disableStorage. If the transaction has not yet been
+        # mined, this field will be null.
+        trace(tracer: String, config: JSON): JSON
     }
 
     # BlockFilterCriteria encapsulates log filter criteria for a filter applied
@@ -347,4 +401,16 @@ const schema string = `
         # SendRawTransaction sends an RLP-encoded transaction to the network.
         sendRawTransaction(data: Bytes!): Bytes32!
     }
+
+    # Subscription delivers events as they happen. Subscriptions are served
+    # over websocket connections using the graphql-ws protocol.
+    type Subscription {
+        # NewHeads delivers every block that becomes the head of the chain.
+        newHeads: Block!
+        # NewLogs delivers the log entries of new blocks matching the filter.
+        # Logs removed by chain reorganisations are not delivered.
+        newLogs(filter: BlockFilterCriteria!): Log!
+        # NewPendingTransactions delivers transactions entering the pool.
+        newPendingTransactions: Transaction!
+    }
 `
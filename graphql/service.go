@@ -22,19 +22,29 @@ import (
 
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/node"
+	"github.com/gorilla/websocket"
 	"github.com/graph-gophers/graphql-go"
 )
 
+// request is a GraphQL request, sent as body of HTTP requests or as payload of
+// websocket subscribe messages.
+type request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
 type handler struct {
-	Schema *graphql.Schema
+	Schema   *graphql.Schema
+	upgrader websocket.Upgrader
 }
 
 func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	var params struct {
-		Query         string                 `json:"query"`
-		OperationName string                 `json:"operationName"`
-		Variables     map[string]interface{} `json:"variables"`
+	if isWebsocket(r) {
+		h.serveWebsocket(w, r)
+		return
 	}
+	var params request
 	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -66,14 +76,22 @@ func New(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error
 
 // newHandler returns a new `http.Handler` that will answer GraphQL queries.
 // It additionally exports an interactive query browser on the / endpoint.
+// Subscriptions are served to websocket connections on the same endpoint.
 func newHandler(stack *node.Node, backend ethapi.Backend, cors, vhosts []string) error {
-	q := Resolver{backend}
+	q := Resolver{backend: backend}
 
-	s, err := graphql.ParseSchema(schema, &q)
+	opts := []graphql.SchemaOpt{graphql.Tracer(newComplexityTracer(stack.Config().GraphQLMaxComplexity))}
+	if depth := stack.Config().GraphQLMaxDepth; depth > 0 {
+		opts = append(opts, graphql.MaxDepth(depth))
+	}
+	s, err := graphql.ParseSchema(schema, &q, opts...)
 	if err != nil {
 		return err
 	}
-	h := handler{Schema: s}
+	h := handler{
+		Schema:   s,
+		upgrader: newUpgrader(cors),
+	}
 	handler := node.NewHTTPHandlerStack(h, cors, vhosts, nil)
 
 	stack.RegisterHandler("GraphQL UI", "/graphql/ui", GraphiQL{})
@@ -0,0 +1,351 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+)
+
+// Websocket subprotocols for GraphQL over websocket. Both are supported, the first
+// one is preferred.
+const (
+	transportWSProtocol = "graphql-transport-ws" // protocol of the graphql-ws library
+	legacyWSProtocol    = "graphql-ws"           // protocol of subscriptions-transport-ws
+)
+
+const (
+	wsInitTimeout       = 10 * time.Second
+	wsWriteTimeout      = 10 * time.Second
+	wsKeepAliveInterval = 30 * time.Second
+	wsReadLimit         = 1024 * 1024
+	wsMaxOperations     = 100 // Maximum number of operations running on a connection
+)
+
+var errTooManyOperations = errors.New("too many operations running on connection")
+
+// Close codes defined by the graphql-transport-ws protocol.
+const (
+	wsCloseInvalidMessage      = 4400
+	wsCloseUnauthorized        = 4401
+	wsCloseInitTimeout         = 4408
+	wsCloseSubscriberExists    = 4409
+	wsCloseTooManyInitRequests = 4429
+	wsCloseBadSubprotocol      = 4406
+)
+
+// wsMessage is the envelope of all messages of both protocols.
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConn serves GraphQL operations over a websocket connection.
+type wsConn struct {
+	handler handler
+	conn    *websocket.Conn
+	legacy  bool // whether the connection uses the legacy protocol
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	writeMu sync.Mutex
+	opsMu   sync.Mutex
+	ops     map[string]context.CancelFunc // running operations by ID
+}
+
+// isWebsocket checks the header of an http request for a websocket upgrade request.
+func isWebsocket(r *http.Request) bool {
+	return strings.ToLower(r.Header.Get("Upgrade")) == "websocket" &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// newUpgrader creates the websocket upgrader of the GraphQL endpoint. Connections
+// from browsers are accepted from the CORS domains, or from the same origin if no
+// domains are configured.
+func newUpgrader(cors []string) websocket.Upgrader {
+	return websocket.Upgrader{
+		Subprotocols: []string{transportWSProtocol, legacyWSProtocol},
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				return true
+			}
+			for _, allowed := range cors {
+				if allowed == "*" || strings.EqualFold(allowed, origin) {
+					return true
+				}
+			}
+			if len(cors) == 0 {
+				u, err := url.Parse(origin)
+				return err == nil && strings.EqualFold(u.Host, r.Host)
+			}
+			return false
+		},
+	}
+}
+
+// serveWebsocket handles a websocket connection until it is closed.
+func (h handler) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Debug("GraphQL websocket upgrade failed", "err", err)
+		return
+	}
+	c := &wsConn{
+		handler: h,
+		conn:    conn,
+		legacy:  conn.Subprotocol() == legacyWSProtocol,
+		ops:     make(map[string]context.CancelFunc),
+	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	defer c.close()
+
+	if conn.Subprotocol() == "" {
+		c.closeWith(wsCloseBadSubprotocol, "Subprotocol not acceptable")
+		return
+	}
+	c.serve()
+}
+
+// serve reads and handles messages until the connection fails or is closed.
+func (c *wsConn) serve() {
+	c.conn.SetReadLimit(wsReadLimit)
+
+	// The client must initialise the connection first.
+	c.conn.SetReadDeadline(time.Now().Add(wsInitTimeout))
+	var msg wsMessage
+	if err := c.conn.ReadJSON(&msg); err != nil {
+		c.closeWith(wsCloseInitTimeout, "Connection initialisation timeout")
+		return
+	}
+	if msg.Type != "connection_init" {
+		c.closeWith(wsCloseUnauthorized, "Unauthorized")
+		return
+	}
+	c.conn.SetReadDeadline(time.Time{})
+	c.write(&wsMessage{Type: "connection_ack"})
+	if c.legacy {
+		c.wg.Add(1)
+		go c.keepAlive()
+	}
+
+	for {
+		msg = wsMessage{}
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "subscribe", "start":
+			if !c.start(&msg) {
+				return
+			}
+		case "complete", "stop":
+			c.stop(msg.ID)
+		case "ping":
+			c.write(&wsMessage{Type: "pong", Payload: msg.Payload})
+		case "pong":
+		case "connection_terminate":
+			return
+		case "connection_init":
+			if !c.legacy {
+				c.closeWith(wsCloseTooManyInitRequests, "Too many initialisation requests")
+				return
+			}
+		default:
+			c.closeWith(wsCloseInvalidMessage, fmt.Sprintf("Invalid message type %q", msg.Type))
+			return
+		}
+	}
+}
+
+// start launches the operation requested by msg. It returns false if the
+// connection must be closed because of a protocol violation.
+func (c *wsConn) start(msg *wsMessage) bool {
+	var req request
+	if msg.ID == "" || json.Unmarshal(msg.Payload, &req) != nil {
+		c.closeWith(wsCloseInvalidMessage, "Invalid subscribe message")
+		return false
+	}
+	c.opsMu.Lock()
+	if _, exists := c.ops[msg.ID]; exists {
+		c.opsMu.Unlock()
+		c.closeWith(wsCloseSubscriberExists, fmt.Sprintf("Subscriber for %s already exists", msg.ID))
+		return false
+	}
+	if len(c.ops) >= wsMaxOperations {
+		c.opsMu.Unlock()
+		c.sendError(msg.ID, errTooManyOperations)
+		return true
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	c.ops[msg.ID] = cancel
+	c.opsMu.Unlock()
+
+	c.wg.Add(1)
+	go c.run(ctx, msg.ID, &req)
+	return true
+}
+
+// run executes an operation, sending its results to the client.
+func (c *wsConn) run(ctx context.Context, id string, req *request) {
+	defer c.wg.Done()
+
+	// Queries and mutations are executed like HTTP requests, which meters their
+	// complexity. Subscriptions aren't executed by Exec and are started instead.
+	var (
+		responses <-chan interface{}
+		executed  bool
+	)
+	resp := c.handler.Schema.Exec(context.WithValue(ctx, executedKey{}, &executed), req.Query, req.OperationName, req.Variables)
+	if executed {
+		result := make(chan interface{}, 1)
+		result <- resp
+		close(result)
+		responses = result
+	} else {
+		var err error
+		responses, err = c.handler.Schema.Subscribe(ctx, req.Query, req.OperationName, req.Variables)
+		if err != nil {
+			if c.finish(id) {
+				c.sendError(id, err)
+			}
+			return
+		}
+	}
+	dataType := "next"
+	if c.legacy {
+		dataType = "data"
+	}
+	first := true
+	for resp := range responses {
+		resp := resp.(*graphql.Response)
+		// Errors preventing the execution are reported as error message.
+		if first && resp.Data == nil && len(resp.Errors) > 0 {
+			if c.finish(id) {
+				c.sendErrors(id, resp.Errors)
+			}
+			return
+		}
+		first = false
+		payload, err := json.Marshal(resp)
+		if err != nil {
+			continue
+		}
+		c.write(&wsMessage{ID: id, Type: dataType, Payload: payload})
+	}
+	// Don't confirm the completion of operations stopped by the client.
+	if c.finish(id) {
+		c.write(&wsMessage{ID: id, Type: "complete"})
+	}
+}
+
+// stop cancels a running operation.
+func (c *wsConn) stop(id string) {
+	c.opsMu.Lock()
+	defer c.opsMu.Unlock()
+
+	if cancel, ok := c.ops[id]; ok {
+		cancel()
+		delete(c.ops, id)
+	}
+}
+
+// finish removes an operation which ended by itself. It returns false if the
+// operation was stopped by the client in the meantime.
+func (c *wsConn) finish(id string) bool {
+	c.opsMu.Lock()
+	defer c.opsMu.Unlock()
+
+	cancel, ok := c.ops[id]
+	if ok {
+		cancel()
+		delete(c.ops, id)
+	}
+	return ok
+}
+
+// keepAlive periodically sends keep-alive messages of the legacy protocol.
+func (c *wsConn) keepAlive() {
+	defer c.wg.Done()
+
+	c.write(&wsMessage{Type: "ka"})
+	ticker := time.NewTicker(wsKeepAliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.write(&wsMessage{Type: "ka"})
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *wsConn) sendError(id string, err error) {
+	c.sendErrors(id, []*gqlerrors.QueryError{gqlerrors.Errorf("%s", err)})
+}
+
+func (c *wsConn) sendErrors(id string, errs []*gqlerrors.QueryError) {
+	var payload interface{} = errs
+	if c.legacy && len(errs) > 0 {
+		// The legacy protocol sends a single error object.
+		payload = errs[0]
+	}
+	enc, _ := json.Marshal(payload)
+	c.write(&wsMessage{ID: id, Type: "error", Payload: enc})
+}
+
+func (c *wsConn) write(msg *wsMessage) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	if err := c.conn.WriteJSON(msg); err != nil {
+		log.Debug("Failed to write GraphQL websocket message", "err", err)
+		c.cancel()
+	}
+}
+
+func (c *wsConn) closeWith(code int, reason string) {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	msg := websocket.FormatCloseMessage(code, reason)
+	c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(wsWriteTimeout))
+}
+
+// close stops all operations and closes the connection.
+func (c *wsConn) close() {
+	c.cancel()
+	c.wg.Wait()
+	c.conn.Close()
+}
@@ -171,7 +171,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -179,7 +179,7 @@ func (s *PublicTxPoolAPI) Content() map[string]map[string]map[string]*RPCTransac
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 		}
 		content["queued"][account.Hex()] = dump
 	}
@@ -195,14 +195,14 @@ func (s *PublicTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string
 	// Build the pending transactions
 	dump := make(map[string]*RPCTransaction, len(pending))
 	for _, tx := range pending {
-		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+		dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 	}
 	content["pending"] = dump
 
 	// Build the queued transactions
 	dump = make(map[string]*RPCTransaction, len(queue))
 	for _, tx := range queue {
-		dump[fmt.Sprintf("%d", tx.Nonce())] = newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
+		dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig())
 	}
 	content["queued"] = dump
 
@@ -1279,8 +1279,8 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 	return result
 }
 
-// newRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
-func newRPCPendingTransaction(tx *types.Transaction, current *types.Header, config *params.ChainConfig) *RPCTransaction {
+// NewRPCPendingTransaction returns a pending transaction that will serialize to the RPC representation
+func NewRPCPendingTransaction(tx *types.Transaction, current *types.Header, config *params.ChainConfig) *RPCTransaction {
 	var baseFee *big.Int
 	blockNumber := uint64(0)
 	if current != nil {
@@ -1503,6 +1503,109 @@ func (s *PublicTransactionPoolAPI) GetTransactionCount(ctx context.Context, addr
 	return (*hexutil.Uint64)(&nonce), state.Error()
 }
 
+const (
+	// defaultAddressTxsLimit is the number of transactions returned by
+	// eth_getTransactionsByAddress if no limit is requested.
+	defaultAddressTxsLimit = 100
+
+	// maxAddressTxsLimit is the maximum number of transactions returned by a
+	// single eth_getTransactionsByAddress call.
+	maxAddressTxsLimit = 1000
+)
+
+// AddressTxCursor is the position of a transaction within the chain, used to
+// continue a paginated eth_getTransactionsByAddress query.
+type AddressTxCursor struct {
+	BlockNumber      hexutil.Uint64 `json:"blockNumber"`
+	TransactionIndex hexutil.Uint64 `json:"transactionIndex"`
+}
+
+// TransactionsByAddressArgs represents the arguments of a transactions by
+// address query.
+type TransactionsByAddressArgs struct {
+	FromBlock *rpc.BlockNumber `json:"fromBlock"`
+	ToBlock   *rpc.BlockNumber `json:"toBlock"`
+	After     *AddressTxCursor `json:"after"`
+	Limit     *hexutil.Uint64  `json:"limit"`
+	Reverse   bool             `json:"reverse"`
+}
+
+// TransactionsByAddressResult is a page of transactions an address participated
+// in. If more transactions are available, Next is the cursor to continue at.
+type TransactionsByAddressResult struct {
+	Transactions []*RPCTransaction `json:"transactions"`
+	Next         *AddressTxCursor  `json:"next"`
+}
+
+// GetTransactionsByAddress returns the transactions the given address participated
+// in as sender, recipient, created contract or, if the node indexes them, within
+// internal calls. Only blocks covered by the node's address index are searched.
+func (s *PublicTransactionPoolAPI) GetTransactionsByAddress(ctx context.Context, address common.Address, args *TransactionsByAddressArgs) (*TransactionsByAddressResult, error) {
+	if args == nil {
+		args = new(TransactionsByAddressArgs)
+	}
+	head := s.b.CurrentHeader().Number.Uint64()
+	resolve := func(number *rpc.BlockNumber, fallback uint64) uint64 {
+		switch {
+		case number == nil:
+			return fallback
+		case *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber:
+			return head
+		case *number == rpc.EarliestBlockNumber:
+			return 0
+		}
+		return uint64(*number)
+	}
+	query := core.AddressTxQuery{
+		Address: address,
+		From:    resolve(args.FromBlock, 0),
+		To:      resolve(args.ToBlock, head),
+		Reverse: args.Reverse,
+		Limit:   defaultAddressTxsLimit,
+	}
+	if query.From > query.To {
+		return nil, fmt.Errorf("invalid block range: fromBlock %d exceeds toBlock %d", query.From, query.To)
+	}
+	if args.Limit != nil {
+		if *args.Limit == 0 || *args.Limit > maxAddressTxsLimit {
+			return nil, fmt.Errorf("invalid limit %d: must be between 1 and %d", *args.Limit, maxAddressTxsLimit)
+		}
+		query.Limit = int(*args.Limit)
+	}
+	if args.After != nil {
+		query.After = &core.AddressTxCursor{Number: uint64(args.After.BlockNumber), Index: uint64(args.After.TransactionIndex)}
+	}
+	entries, err := s.b.TransactionsByAddress(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	// Resolve the transactions, loading each block only once
+	var (
+		result = &TransactionsByAddressResult{Transactions: make([]*RPCTransaction, 0, len(entries))}
+		block  *types.Block
+	)
+	for _, entry := range entries {
+		if block == nil || block.NumberU64() != entry.Number {
+			if block, err = s.b.BlockByNumber(ctx, rpc.BlockNumber(entry.Number)); err != nil {
+				return nil, err
+			}
+			if block == nil {
+				return nil, fmt.Errorf("block #%d not found", entry.Number)
+			}
+		}
+		tx := newRPCTransactionFromBlockIndex(block, entry.Index, s.b.ChainConfig())
+		if tx == nil {
+			return nil, fmt.Errorf("transaction %d not found in block #%d", entry.Index, entry.Number)
+		}
+		result.Transactions = append(result.Transactions, tx)
+	}
+	if len(entries) == query.Limit {
+		last := entries[len(entries)-1]
+		result.Next = &AddressTxCursor{BlockNumber: hexutil.Uint64(last.Number), TransactionIndex: hexutil.Uint64(last.Index)}
+	}
+	return result, nil
+}
+
 // GetTransactionByHash returns the transaction for the given hash
 func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, hash common.Hash) (*RPCTransaction, error) {
 	// Try to return an already finalized transaction
@@ -1519,7 +1622,7 @@ func (s *PublicTransactionPoolAPI) GetTransactionByHash(ctx context.Context, has
 	}
 	// No finalized transaction, try to retrieve it from the pool
 	if tx := s.b.GetPoolTransaction(hash); tx != nil {
-		return newRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
+		return NewRPCPendingTransaction(tx, s.b.CurrentHeader(), s.b.ChainConfig()), nil
 	}
 
 	// Transaction unknown, return as such
@@ -1787,7 +1890,7 @@ func (s *PublicTransactionPoolAPI) PendingTransactions() ([]*RPCTransaction, err
 	for _, tx := range pending {
 		from, _ := types.Sender(s.signer, tx)
 		if _, exists := accounts[from]; exists {
-			transactions = append(transactions, newRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()))
+			transactions = append(transactions, NewRPCPendingTransaction(tx, curHeader, s.b.ChainConfig()))
 		}
 	}
 	return transactions, nil
@@ -113,6 +113,11 @@ web3._extend({
 			call: 'ethash_submitHashrate',
 			params: 2,
 		}),
+		new web3._extend.Method({
+			name: 'stratumWorkers',
+			call: 'ethash_stratumWorkers',
+			params: 0
+		}),
 	]
 });
 `
@@ -189,6 +194,11 @@ web3._extend({
 			name: 'stopWS',
 			call: 'admin_stopWS'
 		}),
+		new web3._extend.Method({
+			name: 'setFinalityCheckpoint',
+			call: 'admin_setFinalityCheckpoint',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -522,6 +532,12 @@ web3._extend({
 			params: 1,
 			inputFormatter: [web3._extend.formatters.inputTransactionFormatter]
 		}),
+		new web3._extend.Method({
+			name: 'getTransactionsByAddress',
+			call: 'eth_getTransactionsByAddress',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'getHeaderByNumber',
 			call: 'eth_getHeaderByNumber',
@@ -600,6 +616,10 @@ web3._extend({
 			getter: 'eth_maxPriorityFeePerGas',
 			outputFormatter: web3._extend.utils.toBigNumber
 		}),
+		new web3._extend.Property({
+			name: 'finalityCheckpoint',
+			getter: 'eth_finalityCheckpoint'
+		}),
 	]
 });
 `
@@ -227,6 +227,18 @@ func (b *LesApiBackend) TxPoolContentFrom(addr common.Address) (types.Transactio
 	return b.eth.txPool.ContentFrom(addr)
 }
 
+func (b *LesApiBackend) StorageRange(ctx context.Context, root common.Hash, address common.Address, start common.Hash, limit int) ([]core.StorageSlot, *common.Hash, error) {
+	return nil, nil, errors.New("storage ranges are not available in light mode")
+}
+
+func (b *LesApiBackend) TransactionsByAddress(ctx context.Context, query core.AddressTxQuery) ([]rawdb.AddressTxEntry, error) {
+	return nil, core.ErrAddressIndexUnavailable
+}
+
+func (b *LesApiBackend) AddressIndexStatus() (uint64, uint64, bool, bool) {
+	return 0, 0, false, false
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
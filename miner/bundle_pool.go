@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	// maxBundles is the maximum number of bundles kept in the pool.
+	maxBundles = 4096
+
+	// maxBundlesPerBlock is the maximum number of bundles kept for a single
+	// target block.
+	maxBundlesPerBlock = 256
+
+	// maxBundleFutureBlocks is the maximum number of blocks past the chain head
+	// a bundle may target.
+	maxBundleFutureBlocks = 64
+
+	// maxBundleTxs is the maximum number of transactions in a bundle.
+	maxBundleTxs = 256
+)
+
+var (
+	errEmptyBundle       = errors.New("bundle contains no transactions")
+	errBundleTooLarge    = errors.New("bundle contains too many transactions")
+	errBundleKnown       = errors.New("bundle already known")
+	errBundlePoolFull    = errors.New("bundle pool is full")
+	errBundleBlockFull   = errors.New("too many bundles for target block")
+	errBundleBlockPassed = errors.New("bundle target block already passed")
+	errBundleTooFar      = errors.New("bundle target block too far in the future")
+	errBundleReverted    = errors.New("bundle transaction reverted")
+)
+
+// Bundle is an ordered list of transactions which is included into the block
+// with the given number atomically: either all of its transactions are included
+// in order, or none of them.
+type Bundle struct {
+	Txs         types.Transactions // Transactions of the bundle, in inclusion order
+	BlockNumber uint64             // Number of the block the bundle targets
+
+	// RevertingTxHashes are the transactions which are allowed to revert. The
+	// bundle is discarded if any other transaction reverts.
+	RevertingTxHashes []common.Hash
+}
+
+// Hash returns the identifier of the bundle, the hash of its transaction hashes.
+func (b *Bundle) Hash() common.Hash {
+	hashes := make([]byte, 0, len(b.Txs)*common.HashLength)
+	for _, tx := range b.Txs {
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	return crypto.Keccak256Hash(hashes)
+}
+
+// canRevert reports whether the transaction with the given hash may revert.
+func (b *Bundle) canRevert(hash common.Hash) bool {
+	for _, h := range b.RevertingTxHashes {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// BundlePool keeps the bundles submitted to the miner until their target block
+// has been built.
+type BundlePool struct {
+	mu      sync.RWMutex
+	bundles map[uint64][]*Bundle     // Bundles grouped by target block number
+	known   map[common.Hash]struct{} // Hashes of all bundles in the pool
+	oldest  uint64                   // Lowest block number bundles are accepted for
+}
+
+// NewBundlePool creates an empty bundle pool.
+func NewBundlePool() *BundlePool {
+	return &BundlePool{
+		bundles: make(map[uint64][]*Bundle),
+		known:   make(map[common.Hash]struct{}),
+	}
+}
+
+// Add inserts a bundle into the pool, given the number of the current chain
+// head. Bundles targeting the head or earlier blocks are dropped.
+func (p *BundlePool) Add(bundle *Bundle, head uint64) error {
+	if len(bundle.Txs) == 0 {
+		return errEmptyBundle
+	}
+	if len(bundle.Txs) > maxBundleTxs {
+		return errBundleTooLarge
+	}
+	hash := bundle.Hash()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.truncate(head + 1)
+	if bundle.BlockNumber < p.oldest {
+		return errBundleBlockPassed
+	}
+	if bundle.BlockNumber > head+maxBundleFutureBlocks {
+		return errBundleTooFar
+	}
+	if _, ok := p.known[hash]; ok {
+		return errBundleKnown
+	}
+	if len(p.known) >= maxBundles {
+		return errBundlePoolFull
+	}
+	if len(p.bundles[bundle.BlockNumber]) >= maxBundlesPerBlock {
+		return errBundleBlockFull
+	}
+	p.bundles[bundle.BlockNumber] = append(p.bundles[bundle.BlockNumber], bundle)
+	p.known[hash] = struct{}{}
+	return nil
+}
+
+// Bundles returns the bundles targeting the block with the given number, in the
+// order they were submitted. Bundles targeting earlier blocks are dropped.
+func (p *BundlePool) Bundles(number uint64) []*Bundle {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.truncate(number)
+	return append([]*Bundle(nil), p.bundles[number]...)
+}
+
+// truncate drops the bundles targeting blocks before the given number. The
+// method assumes the lock is held.
+func (p *BundlePool) truncate(number uint64) {
+	if number <= p.oldest {
+		return
+	}
+	for n, bundles := range p.bundles {
+		if n < number {
+			for _, bundle := range bundles {
+				delete(p.known, bundle.Hash())
+			}
+			delete(p.bundles, n)
+		}
+	}
+	p.oldest = number
+}
+
+// Len returns the number of bundles in the pool.
+func (p *BundlePool) Len() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return len(p.known)
+}
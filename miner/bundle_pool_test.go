@@ -0,0 +1,103 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestBundlePool(t *testing.T) {
+	pool := NewBundlePool()
+
+	var (
+		first  = &Bundle{Txs: types.Transactions{pendingTxs[0]}, BlockNumber: 2}
+		second = &Bundle{Txs: types.Transactions{pendingTxs[0], newTxs[0]}, BlockNumber: 2}
+		later  = &Bundle{Txs: types.Transactions{newTxs[0]}, BlockNumber: 3}
+	)
+	if err := pool.Add(&Bundle{BlockNumber: 2}, 1); !errors.Is(err, errEmptyBundle) {
+		t.Fatalf("empty bundle: have %v, want %v", err, errEmptyBundle)
+	}
+	for _, bundle := range []*Bundle{first, second, later} {
+		if err := pool.Add(bundle, 1); err != nil {
+			t.Fatalf("failed to add bundle: %v", err)
+		}
+	}
+	if err := pool.Add(&Bundle{Txs: first.Txs, BlockNumber: 2}, 1); !errors.Is(err, errBundleKnown) {
+		t.Fatalf("duplicate bundle: have %v, want %v", err, errBundleKnown)
+	}
+	if n := pool.Len(); n != 3 {
+		t.Fatalf("wrong pool size: have %d, want 3", n)
+	}
+	// Bundles are returned per target block in submission order.
+	bundles := pool.Bundles(2)
+	if len(bundles) != 2 || bundles[0] != first || bundles[1] != second {
+		t.Fatalf("wrong bundles for block 2: %v", bundles)
+	}
+	// Retrieving a later block drops the bundles of the earlier ones.
+	if bundles := pool.Bundles(3); len(bundles) != 1 || bundles[0] != later {
+		t.Fatalf("wrong bundles for block 3: %v", bundles)
+	}
+	if n := pool.Len(); n != 1 {
+		t.Fatalf("wrong pool size after pruning: have %d, want 1", n)
+	}
+	if err := pool.Add(&Bundle{Txs: newTxs, BlockNumber: 2}, 1); !errors.Is(err, errBundleBlockPassed) {
+		t.Fatalf("passed bundle: have %v, want %v", err, errBundleBlockPassed)
+	}
+	// Adding a bundle drops the ones targeting the chain head or earlier blocks.
+	if err := pool.Add(&Bundle{Txs: pendingTxs, BlockNumber: 4}, 3); err != nil {
+		t.Fatalf("failed to add bundle: %v", err)
+	}
+	if n := pool.Len(); n != 1 {
+		t.Fatalf("wrong pool size after head update: have %d, want 1", n)
+	}
+	// Bundles targeting blocks too far ahead of the chain head are rejected.
+	if err := pool.Add(&Bundle{Txs: newTxs, BlockNumber: 4 + maxBundleFutureBlocks}, 3); !errors.Is(err, errBundleTooFar) {
+		t.Fatalf("far future bundle: have %v, want %v", err, errBundleTooFar)
+	}
+}
+
+// Tests that the number of bundles in the pool is capped, both per target block
+// and in total.
+func TestBundlePoolLimits(t *testing.T) {
+	pool := NewBundlePool()
+
+	bundle := func(i int, number uint64) *Bundle {
+		tx := types.NewTransaction(uint64(i), testUserAddress, big.NewInt(0), params.TxGas, big.NewInt(1), nil)
+		return &Bundle{Txs: types.Transactions{tx}, BlockNumber: number}
+	}
+	for i := 0; i < maxBundlesPerBlock; i++ {
+		if err := pool.Add(bundle(i, 1), 0); err != nil {
+			t.Fatalf("bundle %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.Add(bundle(maxBundlesPerBlock, 1), 0); !errors.Is(err, errBundleBlockFull) {
+		t.Fatalf("block overflow: have %v, want %v", err, errBundleBlockFull)
+	}
+	for i := maxBundlesPerBlock; i < maxBundles; i++ {
+		if err := pool.Add(bundle(i, uint64(2+i/maxBundlesPerBlock)), 0); err != nil {
+			t.Fatalf("bundle %d: failed to add: %v", i, err)
+		}
+	}
+	if err := pool.Add(bundle(maxBundles, maxBundleFutureBlocks), 0); !errors.Is(err, errBundlePoolFull) {
+		t.Fatalf("pool overflow: have %v, want %v", err, errBundlePoolFull)
+	}
+}
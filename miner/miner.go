@@ -54,6 +54,10 @@ type Config struct {
 	GasPrice   *big.Int       // Minimum gas price for mining a transaction
 	Recommit   time.Duration  // The time interval for miner to re-create mining work.
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
+	Ordering   string         `toml:",omitempty"` // Transaction ordering strategy: "price" (default), "fifo" or "roundrobin"
+
+	Stratum           string  `toml:",omitempty"` // Listening address of the stratum mining server (only useful in ethash)
+	StratumDifficulty float64 // Default share difficulty of stratum workers, 1 being about 2^32 hashes
 }
 
 // Miner creates blocks and searches for proof-of-work values.
@@ -183,6 +187,18 @@ func (miner *Miner) SetExtra(extra []byte) error {
 	return nil
 }
 
+// AddBundle adds a transaction bundle to be included into the block it targets,
+// ahead of the regular transactions.
+func (miner *Miner) AddBundle(bundle *Bundle) error {
+	return miner.worker.bundles.Add(bundle, miner.worker.chain.CurrentBlock().NumberU64())
+}
+
+// SetTransactionOrdering sets the strategy ordering the transactions of sealed
+// blocks, replacing the one selected by the configuration.
+func (miner *Miner) SetTransactionOrdering(ordering TransactionOrdering) {
+	miner.worker.setOrdering(ordering)
+}
+
 // SetRecommitInterval sets the interval for sealing work resubmitting.
 func (miner *Miner) SetRecommitInterval(interval time.Duration) {
 	miner.worker.setRecommitInterval(interval)
@@ -238,7 +254,13 @@ func (miner *Miner) DisablePreseal() {
 // GetSealingBlock retrieves a sealing block based on the given parameters.
 // The returned block is not sealed but all other fields should be filled.
 func (miner *Miner) GetSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash) (*types.Block, error) {
-	return miner.worker.getSealingBlock(parent, timestamp, coinbase, random)
+	block, _, err := miner.worker.getSealingBlock(parent, timestamp, coinbase, random, false)
+	return block, err
+}
+
+// BuildPayload builds the payload according to the provided parameters.
+func (miner *Miner) BuildPayload(args *BuildPayloadArgs) (*Payload, error) {
+	return miner.worker.buildPayload(args)
 }
 
 // SubscribePendingLogs starts delivering logs from pending transactions
@@ -0,0 +1,286 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Names of the built-in transaction ordering strategies.
+const (
+	OrderingPrice      = "price"      // Local transactions first, then by price and nonce
+	OrderingFIFO       = "fifo"       // By arrival time, first come first served
+	OrderingRoundRobin = "roundrobin" // One transaction per sender in turn
+)
+
+// TransactionSet is a set of pending transactions which are returned in the
+// order they should be included into a block. The transactions of an account
+// are always returned in nonce order.
+type TransactionSet interface {
+	// Peek returns the next transaction to include, or nil if the set is exhausted.
+	Peek() *types.Transaction
+
+	// Shift replaces the next transaction with the following one of the same account.
+	Shift()
+
+	// Pop removes the next transaction, *not* replacing it with the following one
+	// of the same account. It's used when a transaction can't be executed, hence
+	// all subsequent ones of the account are discarded.
+	Pop()
+}
+
+// TransactionOrdering is a strategy deciding the order in which the pending
+// transactions are included into the blocks built by the worker.
+type TransactionOrdering interface {
+	// Order creates the ordered set of the pending transactions. The pending
+	// transactions are grouped by account and sorted by nonce. Locals contains
+	// the accounts of local transactions. Transactions unable to pay the base
+	// fee must not be included.
+	Order(signer types.Signer, pending map[common.Address]types.Transactions, locals map[common.Address]bool, baseFee *big.Int) TransactionSet
+}
+
+// NewTransactionOrdering returns the built-in ordering strategy with the given
+// name. The empty name selects the default price ordering.
+func NewTransactionOrdering(name string) (TransactionOrdering, error) {
+	switch name {
+	case "", OrderingPrice:
+		return PriceOrdering{}, nil
+	case OrderingFIFO:
+		return FIFOOrdering{}, nil
+	case OrderingRoundRobin:
+		return RoundRobinOrdering{}, nil
+	default:
+		return nil, fmt.Errorf("unknown transaction ordering %q", name)
+	}
+}
+
+// PriceOrdering includes local transactions first, followed by the remote ones.
+// Both groups are sorted by effective miner tip, with the transactions of each
+// account in nonce order.
+type PriceOrdering struct{}
+
+// Order implements TransactionOrdering.
+func (PriceOrdering) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals map[common.Address]bool, baseFee *big.Int) TransactionSet {
+	localTxs, remoteTxs := make(map[common.Address]types.Transactions), make(map[common.Address]types.Transactions)
+	for account, txs := range pending {
+		if locals[account] {
+			localTxs[account] = txs
+		} else {
+			remoteTxs[account] = txs
+		}
+	}
+	return &chainedTransactions{sets: []TransactionSet{
+		types.NewTransactionsByPriceAndNonce(signer, localTxs, baseFee),
+		types.NewTransactionsByPriceAndNonce(signer, remoteTxs, baseFee),
+	}}
+}
+
+// chainedTransactions returns the transactions of multiple sets, one set
+// after the other.
+type chainedTransactions struct {
+	sets []TransactionSet
+}
+
+// Peek implements TransactionSet.
+func (c *chainedTransactions) Peek() *types.Transaction {
+	for len(c.sets) > 0 {
+		if tx := c.sets[0].Peek(); tx != nil {
+			return tx
+		}
+		c.sets = c.sets[1:]
+	}
+	return nil
+}
+
+// Shift implements TransactionSet.
+func (c *chainedTransactions) Shift() {
+	if c.Peek() != nil {
+		c.sets[0].Shift()
+	}
+}
+
+// Pop implements TransactionSet.
+func (c *chainedTransactions) Pop() {
+	if c.Peek() != nil {
+		c.sets[0].Pop()
+	}
+}
+
+// FIFOOrdering includes transactions in the order they arrived at the node,
+// regardless of their price and origin. Transactions of an account are still
+// included in nonce order, so a transaction waits for its predecessors.
+type FIFOOrdering struct{}
+
+// Order implements TransactionOrdering.
+func (FIFOOrdering) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals map[common.Address]bool, baseFee *big.Int) TransactionSet {
+	set := &transactionsByTime{
+		txs:     make(map[common.Address]types.Transactions),
+		signer:  signer,
+		baseFee: baseFee,
+	}
+	for account, txs := range validAccounts(signer, pending, baseFee) {
+		set.heads = append(set.heads, txs[0])
+		set.txs[account] = txs[1:]
+	}
+	heap.Init(&set.heads)
+	return set
+}
+
+// txsByTime implements heap.Interface, sorting transactions by arrival time.
+type txsByTime []*types.Transaction
+
+func (s txsByTime) Len() int { return len(s) }
+func (s txsByTime) Less(i, j int) bool {
+	if s[i].Time().Equal(s[j].Time()) {
+		hi, hj := s[i].Hash(), s[j].Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	}
+	return s[i].Time().Before(s[j].Time())
+}
+func (s txsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*s = old[0 : n-1]
+	return x
+}
+
+// transactionsByTime is the transaction set of FIFOOrdering.
+type transactionsByTime struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions, without the heads
+	heads   txsByTime                             // Next transaction of each account, sorted by arrival time
+	signer  types.Signer
+	baseFee *big.Int
+}
+
+// Peek implements TransactionSet.
+func (t *transactionsByTime) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0]
+}
+
+// Shift implements TransactionSet.
+func (t *transactionsByTime) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0])
+	if txs := t.txs[acc]; len(txs) > 0 && paysBaseFee(txs[0], t.baseFee) {
+		t.heads[0], t.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&t.heads, 0)
+		return
+	}
+	heap.Pop(&t.heads)
+}
+
+// Pop implements TransactionSet.
+func (t *transactionsByTime) Pop() {
+	heap.Pop(&t.heads)
+}
+
+// RoundRobinOrdering includes one transaction per account in turn, so that no
+// sender can crowd out the others. The accounts take turns in the arrival order
+// of their first pending transaction.
+type RoundRobinOrdering struct{}
+
+// Order implements TransactionOrdering.
+func (RoundRobinOrdering) Order(signer types.Signer, pending map[common.Address]types.Transactions, locals map[common.Address]bool, baseFee *big.Int) TransactionSet {
+	set := &transactionsRoundRobin{
+		txs:     validAccounts(signer, pending, baseFee),
+		baseFee: baseFee,
+	}
+	for account := range set.txs {
+		set.queue = append(set.queue, account)
+	}
+	sort.Slice(set.queue, func(i, j int) bool {
+		ti, tj := set.txs[set.queue[i]][0].Time(), set.txs[set.queue[j]][0].Time()
+		if ti.Equal(tj) {
+			return bytes.Compare(set.queue[i][:], set.queue[j][:]) < 0
+		}
+		return ti.Before(tj)
+	})
+	return set
+}
+
+// transactionsRoundRobin is the transaction set of RoundRobinOrdering.
+type transactionsRoundRobin struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of remaining transactions
+	queue   []common.Address                      // Accounts in the order of their turns
+	baseFee *big.Int
+}
+
+// Peek implements TransactionSet.
+func (t *transactionsRoundRobin) Peek() *types.Transaction {
+	if len(t.queue) == 0 {
+		return nil
+	}
+	return t.txs[t.queue[0]][0]
+}
+
+// Shift implements TransactionSet. The account of the next transaction moves to
+// the end of the queue.
+func (t *transactionsRoundRobin) Shift() {
+	acc := t.queue[0]
+	t.queue = t.queue[1:]
+	if txs := t.txs[acc][1:]; len(txs) > 0 && paysBaseFee(txs[0], t.baseFee) {
+		t.txs[acc] = txs
+		t.queue = append(t.queue, acc)
+		return
+	}
+	delete(t.txs, acc)
+}
+
+// Pop implements TransactionSet.
+func (t *transactionsRoundRobin) Pop() {
+	delete(t.txs, t.queue[0])
+	t.queue = t.queue[1:]
+}
+
+// validAccounts filters out the accounts whose first transaction isn't signed by
+// the account or can't pay the base fee.
+func validAccounts(signer types.Signer, pending map[common.Address]types.Transactions, baseFee *big.Int) map[common.Address]types.Transactions {
+	valid := make(map[common.Address]types.Transactions, len(pending))
+	for account, txs := range pending {
+		if len(txs) == 0 {
+			continue
+		}
+		if from, _ := types.Sender(signer, txs[0]); from != account || !paysBaseFee(txs[0], baseFee) {
+			continue
+		}
+		valid[account] = txs
+	}
+	return valid
+}
+
+// paysBaseFee reports whether the fee cap of the transaction covers the base fee.
+func paysBaseFee(tx *types.Transaction, baseFee *big.Int) bool {
+	_, err := tx.EffectiveGasTip(baseFee)
+	return err == nil
+}
@@ -0,0 +1,220 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// orderingTestTx is a transaction to be created for an ordering test.
+type orderingTestTx struct {
+	sender int   // Index of the signing key
+	tip    int64 // Gas tip cap in wei
+	feeCap int64 // Gas fee cap in wei
+}
+
+// makeOrderingTxs creates the given transactions in order of arrival, assigning
+// nonces per sender, and groups them by account.
+func makeOrderingTxs(t *testing.T, signer types.Signer, keys []*ecdsa.PrivateKey, specs []orderingTestTx) (map[common.Address]types.Transactions, []*types.Transaction) {
+	t.Helper()
+
+	var (
+		pending = make(map[common.Address]types.Transactions)
+		created []*types.Transaction
+		nonces  = make(map[int]uint64)
+	)
+	for _, spec := range specs {
+		tx := types.MustSignNewTx(keys[spec.sender], signer, &types.DynamicFeeTx{
+			ChainID:   params.TestChainConfig.ChainID,
+			Nonce:     nonces[spec.sender],
+			To:        &common.Address{},
+			Gas:       params.TxGas,
+			GasTipCap: big.NewInt(spec.tip),
+			GasFeeCap: big.NewInt(spec.feeCap),
+		})
+		nonces[spec.sender]++
+
+		addr := crypto.PubkeyToAddress(keys[spec.sender].PublicKey)
+		pending[addr] = append(pending[addr], tx)
+		created = append(created, tx)
+
+		// Make sure the arrival times of the transactions differ.
+		time.Sleep(time.Millisecond)
+	}
+	return pending, created
+}
+
+// drainTransactions returns all transactions of the set, shifting after each.
+func drainTransactions(set TransactionSet) []*types.Transaction {
+	var txs []*types.Transaction
+	for tx := set.Peek(); tx != nil; tx = set.Peek() {
+		txs = append(txs, tx)
+		set.Shift()
+	}
+	return txs
+}
+
+func checkTransactionOrder(t *testing.T, have, want []*types.Transaction) {
+	t.Helper()
+
+	if len(have) != len(want) {
+		t.Fatalf("transaction count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range have {
+		if have[i].Hash() != want[i].Hash() {
+			t.Errorf("transaction %d mismatch: have %x, want %x", i, have[i].Hash(), want[i].Hash())
+		}
+	}
+}
+
+func newOrderingTestKeys(t *testing.T, n int) []*ecdsa.PrivateKey {
+	keys := make([]*ecdsa.PrivateKey, n)
+	for i := range keys {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[i] = key
+	}
+	return keys
+}
+
+func TestNewTransactionOrdering(t *testing.T) {
+	tests := []struct {
+		name string
+		want TransactionOrdering
+	}{
+		{"", PriceOrdering{}},
+		{OrderingPrice, PriceOrdering{}},
+		{OrderingFIFO, FIFOOrdering{}},
+		{OrderingRoundRobin, RoundRobinOrdering{}},
+	}
+	for _, test := range tests {
+		have, err := NewTransactionOrdering(test.name)
+		if err != nil {
+			t.Fatalf("ordering %q: %v", test.name, err)
+		}
+		if have != test.want {
+			t.Errorf("ordering %q: have %T, want %T", test.name, have, test.want)
+		}
+	}
+	if _, err := NewTransactionOrdering("random"); err == nil {
+		t.Error("expected error for unknown ordering")
+	}
+}
+
+func TestPriceOrdering(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		keys   = newOrderingTestKeys(t, 3)
+	)
+	pending, txs := makeOrderingTxs(t, signer, keys, []orderingTestTx{
+		{sender: 0, tip: 1, feeCap: 100},
+		{sender: 1, tip: 5, feeCap: 100},
+		{sender: 2, tip: 3, feeCap: 100},
+		{sender: 1, tip: 5, feeCap: 100},
+	})
+	// Sender 0 is local, hence goes first despite the lowest tip.
+	locals := map[common.Address]bool{crypto.PubkeyToAddress(keys[0].PublicKey): true}
+	have := drainTransactions(PriceOrdering{}.Order(signer, pending, locals, big.NewInt(10)))
+	checkTransactionOrder(t, have, []*types.Transaction{txs[0], txs[1], txs[3], txs[2]})
+}
+
+func TestFIFOOrdering(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		keys   = newOrderingTestKeys(t, 3)
+	)
+	pending, txs := makeOrderingTxs(t, signer, keys, []orderingTestTx{
+		{sender: 0, tip: 1, feeCap: 100},
+		{sender: 1, tip: 5, feeCap: 100},
+		{sender: 0, tip: 9, feeCap: 100},
+		{sender: 2, tip: 3, feeCap: 5}, // Can't pay the base fee
+		{sender: 1, tip: 1, feeCap: 100},
+	})
+	// Locality and price are ignored, transactions are ordered by arrival only.
+	locals := map[common.Address]bool{crypto.PubkeyToAddress(keys[1].PublicKey): true}
+	have := drainTransactions(FIFOOrdering{}.Order(signer, pending, locals, big.NewInt(10)))
+	checkTransactionOrder(t, have, []*types.Transaction{txs[0], txs[1], txs[2], txs[4]})
+}
+
+func TestFIFOOrderingNonceOrder(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		keys   = newOrderingTestKeys(t, 2)
+	)
+	// A transaction arriving before its predecessor still waits for it.
+	early := types.MustSignNewTx(keys[0], signer, &types.DynamicFeeTx{
+		ChainID:   params.TestChainConfig.ChainID,
+		Nonce:     1,
+		To:        &common.Address{},
+		Gas:       params.TxGas,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(100),
+	})
+	time.Sleep(time.Millisecond)
+
+	pending, txs := makeOrderingTxs(t, signer, keys, []orderingTestTx{
+		{sender: 1, tip: 1, feeCap: 100},
+		{sender: 0, tip: 1, feeCap: 100},
+		{sender: 1, tip: 1, feeCap: 100},
+	})
+	addr := crypto.PubkeyToAddress(keys[0].PublicKey)
+	pending[addr] = append(pending[addr], early)
+
+	set := FIFOOrdering{}.Order(signer, pending, nil, big.NewInt(10))
+	checkTransactionOrder(t, drainTransactions(set), []*types.Transaction{txs[0], txs[1], early, txs[2]})
+
+	// Popping a transaction discards the remaining ones of the account.
+	set = FIFOOrdering{}.Order(signer, pending, nil, big.NewInt(10))
+	set.Shift()
+	set.Pop()
+	checkTransactionOrder(t, drainTransactions(set), []*types.Transaction{txs[2]})
+}
+
+func TestRoundRobinOrdering(t *testing.T) {
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		keys   = newOrderingTestKeys(t, 3)
+	)
+	pending, txs := makeOrderingTxs(t, signer, keys, []orderingTestTx{
+		{sender: 0, tip: 1, feeCap: 100},
+		{sender: 0, tip: 1, feeCap: 100},
+		{sender: 0, tip: 1, feeCap: 100},
+		{sender: 1, tip: 9, feeCap: 100},
+		{sender: 2, tip: 5, feeCap: 100},
+		{sender: 1, tip: 9, feeCap: 100},
+		{sender: 2, tip: 5, feeCap: 5}, // Can't pay the base fee
+	})
+	// Senders take turns in the arrival order of their first transaction.
+	have := drainTransactions(RoundRobinOrdering{}.Order(signer, pending, nil, big.NewInt(10)))
+	checkTransactionOrder(t, have, []*types.Transaction{txs[0], txs[3], txs[4], txs[1], txs[5], txs[2]})
+
+	// Popping a transaction discards the remaining ones of the account.
+	set := RoundRobinOrdering{}.Order(signer, pending, nil, big.NewInt(10))
+	set.Shift()
+	set.Pop()
+	checkTransactionOrder(t, drainTransactions(set), []*types.Transaction{txs[4], txs[1], txs[2]})
+}
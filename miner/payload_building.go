@@ -0,0 +1,238 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// payloadBuildTimeout is the maximum time a payload is rebuilt for, after which
+// the latest version is kept until it's retrieved. It corresponds to the length
+// of a beacon chain slot.
+const payloadBuildTimeout = 12 * time.Second
+
+// BuildPayloadArgs contains the provided parameters for building payload.
+// Check engine-api specification for more details.
+// https://github.com/ethereum/execution-apis/blob/main/src/engine/specification.md#payloadattributesv1
+type BuildPayloadArgs struct {
+	Parent       common.Hash    // The parent block to build payload on top
+	Timestamp    uint64         // The provided timestamp of generated payload
+	FeeRecipient common.Address // The provided recipient address for collecting transaction fee
+	Random       common.Hash    // The provided randomness value
+}
+
+// Id computes an 8-byte identifier by hashing the components of the payload arguments.
+func (args *BuildPayloadArgs) Id() beacon.PayloadID {
+	hasher := sha256.New()
+	hasher.Write(args.Parent[:])
+	binary.Write(hasher, binary.BigEndian, args.Timestamp)
+	hasher.Write(args.Random[:])
+	hasher.Write(args.FeeRecipient[:])
+	var out beacon.PayloadID
+	copy(out[:], hasher.Sum(nil)[:8])
+	return out
+}
+
+// Payload wraps the built payload(block waiting for sealing). According to the
+// engine-api specification, EL should build the initial version of the payload
+// which has an empty transaction set and then keep update it in order to maximize
+// the revenue. Therefore, the empty-block here is always available and full-block
+// will be set/updated afterwards.
+type Payload struct {
+	id       beacon.PayloadID
+	empty    *types.Block
+	full     *types.Block
+	fullFees *big.Int
+	stop     chan struct{}
+	lock     sync.Mutex
+	cond     *sync.Cond
+}
+
+// newPayload initializes the payload object.
+func newPayload(empty *types.Block, id beacon.PayloadID) *Payload {
+	payload := &Payload{
+		id:    id,
+		empty: empty,
+		stop:  make(chan struct{}),
+	}
+	log.Info("Starting work on payload", "id", payload.id)
+	payload.cond = sync.NewCond(&payload.lock)
+	return payload
+}
+
+// update updates the full-block with latest built version, if it's more
+// valuable than the current one.
+func (payload *Payload) update(block *types.Block, fees *big.Int, elapsed time.Duration) {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	select {
+	case <-payload.stop:
+		return // reject stale update
+	default:
+	}
+	// Ensure the newly provided full block has a higher transaction fee.
+	// In post-merge stage, there is no uncle reward anymore and transaction
+	// fee(apart from the mev revenue) is the only indicator for comparison.
+	if payload.full == nil || fees.Cmp(payload.fullFees) > 0 {
+		payload.full = block
+		payload.fullFees = fees
+
+		log.Info("Updated payload", "id", payload.id, "number", block.NumberU64(), "hash", block.Hash(),
+			"txs", len(block.Transactions()), "gas", block.GasUsed(), "fees", weiToEther(fees),
+			"root", block.Root(), "elapsed", common.PrettyDuration(elapsed))
+	}
+	payload.cond.Broadcast() // fire signal for notifying full block
+}
+
+// Resolve returns the latest built version of payload and terminates the
+// background building. The full block is returned if it's available,
+// otherwise the empty one.
+func (payload *Payload) Resolve() *beacon.ExecutionPayloadEnvelope {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	payload.stopLocked()
+	if payload.full != nil {
+		return envelope(payload.full, payload.fullFees)
+	}
+	return envelope(payload.empty, new(big.Int))
+}
+
+// ResolveEmpty is basically identical to Resolve, but it expects empty block only.
+// It's only used in tests.
+func (payload *Payload) ResolveEmpty() *beacon.ExecutionPayloadEnvelope {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	return envelope(payload.empty, new(big.Int))
+}
+
+// ResolveFull is basically identical to Resolve, but it expects full block only.
+// It waits until the first full block is built, and returns nil if the building
+// terminated before.
+func (payload *Payload) ResolveFull() *beacon.ExecutionPayloadEnvelope {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	for payload.full == nil {
+		select {
+		case <-payload.stop:
+			return nil
+		default:
+		}
+		payload.cond.Wait()
+	}
+	payload.stopLocked()
+	return envelope(payload.full, payload.fullFees)
+}
+
+// Cancel terminates the background building of the payload. The versions built
+// so far remain available.
+func (payload *Payload) Cancel() {
+	payload.lock.Lock()
+	defer payload.lock.Unlock()
+
+	payload.stopLocked()
+}
+
+// stopLocked terminates the background building and wakes up the waiters of
+// the full block. It assumes the lock is held.
+func (payload *Payload) stopLocked() {
+	select {
+	case <-payload.stop:
+	default:
+		close(payload.stop)
+		payload.cond.Broadcast()
+	}
+}
+
+// envelope wraps the given block and its value into an execution payload envelope.
+func envelope(block *types.Block, fees *big.Int) *beacon.ExecutionPayloadEnvelope {
+	return &beacon.ExecutionPayloadEnvelope{
+		ExecutionPayload: beacon.BlockToExecutableData(block),
+		BlockValue:       new(big.Int).Set(fees),
+	}
+}
+
+// buildPayload builds the payload according to the provided parameters.
+func (w *worker) buildPayload(args *BuildPayloadArgs) (*Payload, error) {
+	// Build the initial version with no transaction included. It should be fast
+	// enough to run. The empty payload can at least make sure there is something
+	// to deliver for not missing slot.
+	empty, _, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, true)
+	if err != nil {
+		return nil, err
+	}
+	// Construct a payload object for return.
+	payload := newPayload(empty, args.Id())
+
+	w.mu.RLock()
+	recommit := w.recommit
+	w.mu.RUnlock()
+	if recommit < minRecommitInterval {
+		recommit = minRecommitInterval
+	}
+	// Spin up a routine for updating the payload in background. This strategy
+	// can maximum the revenue for including transactions with highest fee.
+	go func() {
+		// Setup the timer for re-building the payload. The initial clock is kept
+		// for triggering process immediately.
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+
+		// Setup the timer for terminating the process once the length of a slot
+		// (12s in the Mainnet configuration) has passed since the building started.
+		endTimer := time.NewTimer(payloadBuildTimeout)
+		defer endTimer.Stop()
+
+		// Wake up the waiters of the full block once the building ended.
+		defer payload.Cancel()
+
+		for {
+			select {
+			case <-timer.C:
+				start := time.Now()
+				block, fees, err := w.getSealingBlock(args.Parent, args.Timestamp, args.FeeRecipient, args.Random, false)
+				if err == nil {
+					payload.update(block, fees, time.Since(start))
+				} else {
+					log.Debug("Failed to update payload", "id", payload.id, "err", err)
+				}
+				timer.Reset(recommit)
+			case <-payload.stop:
+				log.Info("Stopping work on payload", "id", payload.id, "reason", "stopped")
+				return
+			case <-endTimer.C:
+				log.Info("Stopping work on payload", "id", payload.id, "reason", "timeout")
+				return
+			case <-w.exitCh:
+				return
+			}
+		}
+	}()
+	return payload, nil
+}
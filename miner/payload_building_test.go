@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/beacon"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestBuildPayload(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		recipient = common.HexToAddress("0xdeadbeef")
+	)
+	w, b := newTestWorker(t, params.TestChainConfig, ethash.NewFaker(), db, 0)
+	defer w.close()
+
+	timestamp := uint64(time.Now().Unix())
+	args := &BuildPayloadArgs{
+		Parent:       b.chain.CurrentBlock().Hash(),
+		Timestamp:    timestamp,
+		Random:       common.Hash{},
+		FeeRecipient: recipient,
+	}
+	payload, err := w.buildPayload(args)
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	verify := func(outer *beacon.ExecutionPayloadEnvelope, txs int) {
+		payload := outer.ExecutionPayload
+		if payload.ParentHash != b.chain.CurrentBlock().Hash() {
+			t.Fatal("Unexpect parent hash")
+		}
+		if payload.Random != (common.Hash{}) {
+			t.Fatal("Unexpect random value")
+		}
+		if payload.Timestamp != timestamp {
+			t.Fatal("Unexpect timestamp")
+		}
+		if payload.FeeRecipient != recipient {
+			t.Fatal("Unexpect fee recipient")
+		}
+		if len(payload.Transactions) != txs {
+			t.Fatalf("Unexpect transaction set: have %d, want %d", len(payload.Transactions), txs)
+		}
+	}
+	empty := payload.ResolveEmpty()
+	verify(empty, 0)
+	if empty.BlockValue.Sign() != 0 {
+		t.Fatalf("Unexpected empty payload value: %v", empty.BlockValue)
+	}
+	full := payload.ResolveFull()
+	verify(full, len(pendingTxs))
+	if full.BlockValue.Sign() <= 0 {
+		t.Fatalf("Unexpected full payload value: %v", full.BlockValue)
+	}
+	// Ensure resolve can be called multiple times and the
+	// result should be unchanged
+	dataOne := payload.Resolve()
+	dataTwo := payload.Resolve()
+	if !reflect.DeepEqual(dataOne, dataTwo) {
+		t.Fatal("Unexpected payload data")
+	}
+}
+
+func TestBuildPayloadImprovement(t *testing.T) {
+	var (
+		db        = rawdb.NewMemoryDatabase()
+		engine    = ethash.NewFaker()
+		recipient = common.HexToAddress("0xdeadbeef")
+	)
+	// Start with an empty transaction pool.
+	b := newTestWorkerBackend(t, params.TestChainConfig, engine, db, 0)
+	w := newWorker(testConfig, params.TestChainConfig, engine, b, new(event.TypeMux), nil, false)
+	defer w.close()
+
+	payload, err := w.buildPayload(&BuildPayloadArgs{
+		Parent:       b.chain.CurrentBlock().Hash(),
+		Timestamp:    uint64(time.Now().Unix()),
+		FeeRecipient: recipient,
+	})
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	// waitFull waits until the full block contains the given number of transactions.
+	waitFull := func(txs int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			payload.lock.Lock()
+			full := payload.full
+			payload.lock.Unlock()
+
+			if full != nil && len(full.Transactions()) == txs {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("Payload not built with %d transactions", txs)
+			}
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	waitFull(0)
+
+	// Transactions arriving during the slot are picked up by a later rebuild.
+	b.txPool.AddLocals(pendingTxs)
+	waitFull(len(pendingTxs))
+
+	res := payload.Resolve()
+	if len(res.ExecutionPayload.Transactions) != len(pendingTxs) || res.BlockValue.Sign() <= 0 {
+		t.Fatalf("Unexpected resolved payload: %d txs, value %v", len(res.ExecutionPayload.Transactions), res.BlockValue)
+	}
+	// No more updates are accepted once the payload is resolved.
+	payload.update(types.NewBlockWithHeader(&types.Header{Number: big.NewInt(1)}), big.NewInt(params.Ether), 0)
+	if again := payload.Resolve(); !reflect.DeepEqual(again, res) {
+		t.Fatal("Payload updated after resolution")
+	}
+}
+
+func TestPayloadCancel(t *testing.T) {
+	var (
+		db     = rawdb.NewMemoryDatabase()
+		engine = ethash.NewFaker()
+	)
+	b := newTestWorkerBackend(t, params.TestChainConfig, engine, db, 0)
+	w := newWorker(testConfig, params.TestChainConfig, engine, b, new(event.TypeMux), nil, false)
+	defer w.close()
+
+	payload, err := w.buildPayload(&BuildPayloadArgs{
+		Parent:    b.chain.CurrentBlock().Hash(),
+		Timestamp: uint64(time.Now().Unix()),
+	})
+	if err != nil {
+		t.Fatalf("Failed to build payload %v", err)
+	}
+	payload.Cancel()
+
+	// The empty version remains available after cancellation.
+	if res := payload.Resolve(); res.ExecutionPayload.BlockHash == (common.Hash{}) {
+		t.Fatal("Missing payload after cancellation")
+	}
+	select {
+	case <-payload.stop:
+	default:
+		t.Fatal("Payload building not stopped")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -162,11 +163,17 @@ type newWorkReq struct {
 	timestamp int64
 }
 
+// newPayloadResult represents a result struct corresponds to payload generation.
+type newPayloadResult struct {
+	err   error
+	block *types.Block
+	fees  *big.Int
+}
+
 // getWorkReq represents a request for getting a new sealing work with provided parameters.
 type getWorkReq struct {
 	params *generateParams
-	err    error
-	result chan *types.Block
+	result chan *newPayloadResult // non-blocking channel
 }
 
 // intervalAdjust represents a resubmitting interval adjustment.
@@ -212,10 +219,13 @@ type worker struct {
 	localUncles  map[common.Hash]*types.Block // A set of side blocks generated locally as the possible uncle blocks.
 	remoteUncles map[common.Hash]*types.Block // A set of side blocks as the possible uncle blocks.
 	unconfirmed  *unconfirmedBlocks           // A set of locally mined blocks pending canonicalness confirmations.
+	bundles      *BundlePool                  // Bundles to be included ahead of the pending transactions.
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra, recommit and ordering fields
 	coinbase common.Address
 	extra    []byte
+	recommit time.Duration       // The interval for rebuilding payloads with newly arrived transactions
+	ordering TransactionOrdering // The strategy ordering the transactions of sealing blocks
 
 	pendingMu    sync.RWMutex
 	pendingTasks map[common.Hash]*task
@@ -254,6 +264,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		eth:                eth,
 		mux:                mux,
 		chain:              eth.BlockChain(),
+		bundles:            NewBundlePool(),
 		isLocalBlock:       isLocalBlock,
 		localUncles:        make(map[common.Hash]*types.Block),
 		remoteUncles:       make(map[common.Hash]*types.Block),
@@ -283,6 +294,14 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		log.Warn("Sanitizing miner recommit interval", "provided", recommit, "updated", minRecommitInterval)
 		recommit = minRecommitInterval
 	}
+	worker.recommit = recommit
+
+	ordering, err := NewTransactionOrdering(worker.config.Ordering)
+	if err != nil {
+		log.Warn("Falling back to default transaction ordering", "err", err)
+		ordering = PriceOrdering{}
+	}
+	worker.ordering = ordering
 
 	worker.wg.Add(4)
 	go worker.mainLoop()
@@ -317,8 +336,19 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setOrdering sets the strategy ordering the transactions of sealing blocks.
+func (w *worker) setOrdering(ordering TransactionOrdering) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ordering = ordering
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
+	w.mu.Lock()
+	w.recommit = interval
+	w.mu.Unlock()
+
 	select {
 	case w.resubmitIntervalCh <- interval:
 	case <-w.exitCh:
@@ -529,12 +559,11 @@ func (w *worker) mainLoop() {
 			w.commitWork(req.interrupt, req.noempty, req.timestamp)
 
 		case req := <-w.getWorkCh:
-			block, err := w.generateWork(req.params)
-			if err != nil {
-				req.err = err
-				req.result <- nil
-			} else {
-				req.result <- block
+			block, fees, err := w.generateWork(req.params)
+			req.result <- &newPayloadResult{
+				err:   err,
+				block: block,
+				fees:  fees,
 			}
 
 		case ev := <-w.chainSideCh:
@@ -841,7 +870,7 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *int32) bool {
+func (w *worker) commitTransactions(env *environment, txs TransactionSet, interrupt *int32) bool {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -963,6 +992,7 @@ type generateParams struct {
 	random     common.Hash    // The randomness generated by beacon chain, empty before the merge
 	noUncle    bool           // Flag whether the uncle block inclusion is allowed
 	noExtra    bool           // Flag whether the extra field assignment is allowed
+	noTxs      bool           // Flag whether an empty block without any transaction is expected
 }
 
 // prepareWork constructs the sealing task according to the given parameters,
@@ -1009,7 +1039,7 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	if w.chainConfig.IsLondon(header.Number) {
 		header.BaseFee = misc.CalcBaseFee(w.chainConfig, parent.Header())
 		if !w.chainConfig.IsLondon(parent.Number()) {
-			parentGasLimit := parent.GasLimit() * params.ElasticityMultiplier
+			parentGasLimit := parent.GasLimit() * w.chainConfig.ElasticityMultiplier()
 			header.GasLimit = core.CalcGasLimit(parentGasLimit, w.config.GasCeil)
 		}
 	}
@@ -1047,44 +1077,159 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	return env, nil
 }
 
-// fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
-func (w *worker) fillTransactions(interrupt *int32, env *environment) {
-	// Split the pending transactions into locals and remotes
-	// Fill the block with all available pending transactions.
-	pending := w.eth.TxPool().Pending(true)
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
+// simulatedBundle is a bundle which was executed on top of the pending state.
+type simulatedBundle struct {
+	bundle   *Bundle
+	gasUsed  uint64
+	payment  *big.Int // Amount paid to the coinbase by the bundle, including tips
+	gasPrice *big.Int // Effective gas price of the bundle, the payment per gas used
+}
+
+// simulateBundle executes the bundle on a copy of the sealing state, returning
+// the payment it makes to the coinbase. An error is returned if any transaction
+// of the bundle fails or reverts without being allowed to.
+func (w *worker) simulateBundle(env *environment, bundle *Bundle) (*simulatedBundle, error) {
+	var (
+		statedb = env.state.Copy()
+		gasPool = new(core.GasPool).AddGas(env.gasPool.Gas())
+		before  = statedb.GetBalance(env.coinbase)
+		gasUsed uint64
+	)
+	for i, tx := range bundle.Txs {
+		statedb.Prepare(tx.Hash(), env.tcount+i)
+
+		receipt, err := core.ApplyTransaction(w.chainConfig, w.chain, &env.coinbase, gasPool, statedb, env.header, tx, &gasUsed, *w.chain.GetVMConfig())
+		if err != nil {
+			return nil, fmt.Errorf("transaction %x failed: %w", tx.Hash(), err)
+		}
+		if receipt.Status == types.ReceiptStatusFailed && !bundle.canRevert(tx.Hash()) {
+			return nil, fmt.Errorf("%w: %x", errBundleReverted, tx.Hash())
 		}
 	}
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
-		if w.commitTransactions(env, txs, interrupt) {
-			return
+	payment := new(big.Int).Sub(statedb.GetBalance(env.coinbase), before)
+	return &simulatedBundle{
+		bundle:   bundle,
+		gasUsed:  gasUsed,
+		payment:  payment,
+		gasPrice: new(big.Int).Div(payment, new(big.Int).SetUint64(gasUsed)),
+	}, nil
+}
+
+// commitBundle applies all transactions of the bundle to the sealing block. If
+// any of them fails or reverts without being allowed to, the whole bundle is
+// discarded.
+//
+// The bundle is applied to a copy of the sealing state, which is swapped in only
+// if the whole bundle succeeds. Reverting to a snapshot is not an option, since
+// the state is finalised after every transaction.
+func (w *worker) commitBundle(env *environment, bundle *Bundle) error {
+	work := &environment{
+		signer:   env.signer,
+		state:    env.state.Copy(),
+		tcount:   env.tcount,
+		coinbase: env.coinbase,
+		header:   types.CopyHeader(env.header),
+		txs:      make([]*types.Transaction, len(env.txs), len(env.txs)+len(bundle.Txs)),
+		receipts: make([]*types.Receipt, len(env.receipts), len(env.receipts)+len(bundle.Txs)),
+	}
+	gasPool := *env.gasPool
+	work.gasPool = &gasPool
+	copy(work.txs, env.txs)
+	copy(work.receipts, env.receipts)
+
+	for _, tx := range bundle.Txs {
+		work.state.Prepare(tx.Hash(), work.tcount)
+		if _, err := w.commitTransaction(work, tx); err != nil {
+			return fmt.Errorf("transaction %x failed: %w", tx.Hash(), err)
+		}
+		work.tcount++
+		if work.receipts[len(work.receipts)-1].Status == types.ReceiptStatusFailed && !bundle.canRevert(tx.Hash()) {
+			return fmt.Errorf("%w: %x", errBundleReverted, tx.Hash())
 		}
 	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
-		if w.commitTransactions(env, txs, interrupt) {
+	// The whole bundle succeeded, swap in the new state. The copy only holds an
+	// inactive trie prefetcher, so restart it on the new state.
+	env.state.StopPrefetcher()
+	env.state = work.state
+	env.state.StartPrefetcher("miner")
+
+	*env.gasPool = *work.gasPool
+	env.header.GasUsed = work.header.GasUsed
+	env.tcount = work.tcount
+	env.txs, env.receipts = work.txs, work.receipts
+	return nil
+}
+
+// commitBundles simulates the bundles targeting the sealing block and commits
+// them in the order of their effective gas price. Bundles failing either the
+// simulation or the inclusion are discarded.
+func (w *worker) commitBundles(env *environment, bundles []*Bundle, interrupt *int32) {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	simulated := make([]*simulatedBundle, 0, len(bundles))
+	for _, bundle := range bundles {
+		sim, err := w.simulateBundle(env, bundle)
+		if err != nil {
+			log.Debug("Discarding bundle", "hash", bundle.Hash(), "err", err)
+			continue
+		}
+		simulated = append(simulated, sim)
+	}
+	sort.SliceStable(simulated, func(i, j int) bool {
+		return simulated[i].gasPrice.Cmp(simulated[j].gasPrice) > 0
+	})
+	for _, sim := range simulated {
+		if interrupt != nil && atomic.LoadInt32(interrupt) != commitInterruptNone {
 			return
 		}
+		if err := w.commitBundle(env, sim.bundle); err != nil {
+			log.Debug("Discarding bundle", "hash", sim.bundle.Hash(), "err", err)
+			continue
+		}
+		log.Debug("Included bundle", "hash", sim.bundle.Hash(), "txs", len(sim.bundle.Txs), "gas", sim.gasUsed, "payment", sim.payment)
 	}
 }
 
-// generateWork generates a sealing block based on the given parameters.
-func (w *worker) generateWork(params *generateParams) (*types.Block, error) {
+// fillTransactions retrieves the pending bundles and transactions and fills them
+// into the given sealing block. The bundles are included first, the transactions
+// follow in the order decided by the configured ordering strategy.
+func (w *worker) fillTransactions(interrupt *int32, env *environment) {
+	if bundles := w.bundles.Bundles(env.header.Number.Uint64()); len(bundles) > 0 {
+		w.commitBundles(env, bundles, interrupt)
+	}
+	pending := w.eth.TxPool().Pending(true)
+	if len(pending) == 0 {
+		return
+	}
+	locals := make(map[common.Address]bool)
+	for _, account := range w.eth.TxPool().Locals() {
+		locals[account] = true
+	}
+	w.mu.RLock()
+	ordering := w.ordering
+	w.mu.RUnlock()
+
+	w.commitTransactions(env, ordering.Order(env.signer, pending, locals, env.header.BaseFee), interrupt)
+}
+
+// generateWork generates a sealing block based on the given parameters. Besides
+// the block, the fees paid to the fee recipient by its transactions are returned.
+func (w *worker) generateWork(params *generateParams) (*types.Block, *big.Int, error) {
 	work, err := w.prepareWork(params)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer work.discard()
 
-	w.fillTransactions(nil, work)
-	return w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	if !params.noTxs {
+		w.fillTransactions(nil, work)
+	}
+	block, err := w.engine.FinalizeAndAssemble(w.chain, work.header, work.state, work.txs, work.unclelist(), work.receipts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return block, blockFees(block, work.receipts), nil
 }
 
 // commitWork generates several new sealing tasks based on the parent block
@@ -1163,7 +1308,9 @@ func (w *worker) commit(env *environment, interval func(), update bool, start ti
 }
 
 // getSealingBlock generates the sealing block based on the given parameters.
-func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash) (*types.Block, error) {
+// If noTxs is set, the block is left empty. The fees earned by the fee recipient
+// are returned along with the block.
+func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase common.Address, random common.Hash, noTxs bool) (*types.Block, *big.Int, error) {
 	req := &getWorkReq{
 		params: &generateParams{
 			timestamp:  timestamp,
@@ -1173,18 +1320,19 @@ func (w *worker) getSealingBlock(parent common.Hash, timestamp uint64, coinbase
 			random:     random,
 			noUncle:    true,
 			noExtra:    true,
+			noTxs:      noTxs,
 		},
-		result: make(chan *types.Block, 1),
+		result: make(chan *newPayloadResult, 1),
 	}
 	select {
 	case w.getWorkCh <- req:
-		block := <-req.result
-		if block == nil {
-			return nil, req.err
+		result := <-req.result
+		if result.err != nil {
+			return nil, nil, result.err
 		}
-		return block, nil
+		return result.block, result.fees, nil
 	case <-w.exitCh:
-		return nil, errors.New("miner closed")
+		return nil, nil, errors.New("miner closed")
 	}
 }
 
@@ -1213,12 +1361,22 @@ func (w *worker) postSideBlock(event core.ChainSideEvent) {
 	}
 }
 
-// totalFees computes total consumed miner fees in ETH. Block transactions and receipts have to have the same order.
-func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
+// blockFees computes total consumed miner fees in wei. Block transactions and receipts have to have the same order.
+func blockFees(block *types.Block, receipts []*types.Receipt) *big.Int {
 	feesWei := new(big.Int)
 	for i, tx := range block.Transactions() {
 		minerFee, _ := tx.EffectiveGasTip(block.BaseFee())
 		feesWei.Add(feesWei, new(big.Int).Mul(new(big.Int).SetUint64(receipts[i].GasUsed), minerFee))
 	}
-	return new(big.Float).Quo(new(big.Float).SetInt(feesWei), new(big.Float).SetInt(big.NewInt(params.Ether)))
+	return feesWei
+}
+
+// totalFees computes total consumed miner fees in ETH. Block transactions and receipts have to have the same order.
+func totalFees(block *types.Block, receipts []*types.Receipt) *big.Float {
+	return weiToEther(blockFees(block, receipts))
+}
+
+// weiToEther converts a wei amount to ether.
+func weiToEther(wei *big.Int) *big.Float {
+	return new(big.Float).Quo(new(big.Float).SetInt(wei), new(big.Float).SetInt(big.NewInt(params.Ether)))
 }
@@ -638,7 +638,7 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 
 	// This API should work even when the automatic sealing is not enabled
 	for _, c := range cases {
-		block, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random)
+		block, _, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -654,7 +654,7 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 	// This API should work even when the automatic sealing is enabled
 	w.start()
 	for _, c := range cases {
-		block, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random)
+		block, _, err := w.getSealingBlock(c.parent, timestamp, c.coinbase, c.random, false)
 		if c.expectErr {
 			if err == nil {
 				t.Error("Expect error but get nil")
@@ -667,3 +667,123 @@ func testGetSealingWork(t *testing.T, chainConfig *params.ChainConfig, engine co
 		}
 	}
 }
+
+func TestCommitBundles(t *testing.T) {
+	var (
+		signer   = types.LatestSigner(ethashChainConfig)
+		coinbase = common.HexToAddress("0xc0ffee")
+	)
+	makeTx := func(nonce uint64, gasPrice int64, data []byte) *types.Transaction {
+		tx := &types.LegacyTx{
+			Nonce:    nonce,
+			Gas:      100000,
+			GasPrice: big.NewInt(gasPrice * params.InitialBaseFee),
+			Data:     data,
+		}
+		if data == nil {
+			tx.To = &testUserAddress
+		}
+		return types.MustSignNewTx(testBankKey, signer, tx)
+	}
+	// Contract creation with init code reverting the execution.
+	revertCode := common.FromHex("0x60006000fd")
+
+	tests := []struct {
+		bundles []*Bundle
+		want    func(bundles []*Bundle) types.Transactions
+	}{
+		// Bundles are included ahead of the pending transactions, the one paying
+		// more to the coinbase first. The conflicting one is discarded.
+		{
+			bundles: []*Bundle{
+				{Txs: types.Transactions{makeTx(0, 2, nil)}, BlockNumber: 1},
+				{Txs: types.Transactions{makeTx(0, 10, nil), makeTx(1, 10, nil)}, BlockNumber: 1},
+				{Txs: types.Transactions{makeTx(2, 20, nil)}, BlockNumber: 2},
+			},
+			want: func(bundles []*Bundle) types.Transactions { return bundles[1].Txs },
+		},
+		// Bundles with reverting transactions are discarded.
+		{
+			bundles: []*Bundle{
+				{Txs: types.Transactions{makeTx(0, 10, nil), makeTx(1, 10, revertCode)}, BlockNumber: 1},
+			},
+			want: func(bundles []*Bundle) types.Transactions { return pendingTxs },
+		},
+		// Unless the transactions are allowed to revert.
+		{
+			bundles: []*Bundle{
+				{Txs: types.Transactions{makeTx(0, 10, nil), makeTx(1, 10, revertCode)}, BlockNumber: 1},
+			},
+			want: func(bundles []*Bundle) types.Transactions {
+				bundles[0].RevertingTxHashes = []common.Hash{bundles[0].Txs[1].Hash()}
+				return bundles[0].Txs
+			},
+		},
+	}
+	for i, test := range tests {
+		engine := ethash.NewFaker()
+		w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+
+		want := test.want(test.bundles)
+		for _, bundle := range test.bundles {
+			if err := w.bundles.Add(bundle, 0); err != nil {
+				t.Fatalf("test %d: failed to add bundle: %v", i, err)
+			}
+		}
+		block, _, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), coinbase, common.Hash{}, false)
+		if err != nil {
+			t.Fatalf("test %d: failed to build block: %v", i, err)
+		}
+		if len(block.Transactions()) != len(want) {
+			t.Fatalf("test %d: transaction count mismatch: have %d, want %d", i, len(block.Transactions()), len(want))
+		}
+		for j, tx := range block.Transactions() {
+			if tx.Hash() != want[j].Hash() {
+				t.Errorf("test %d: transaction %d mismatch: have %x, want %x", i, j, tx.Hash(), want[j].Hash())
+			}
+		}
+		w.close()
+		engine.Close()
+	}
+	// Bundles passing the simulation may still fail at inclusion time, after some
+	// of their transactions were already applied. These must be discarded as a
+	// whole, leaving the sealing block untouched.
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	for i, bundle := range []*Bundle{
+		{Txs: types.Transactions{makeTx(0, 10, nil), makeTx(1, 10, revertCode)}, BlockNumber: 1},
+		{Txs: types.Transactions{makeTx(0, 10, nil), makeTx(2, 10, nil)}, BlockNumber: 1},
+	} {
+		env, err := w.prepareWork(&generateParams{
+			timestamp:  uint64(time.Now().Unix()),
+			forceTime:  true,
+			parentHash: b.chain.Genesis().Hash(),
+			coinbase:   coinbase,
+			noUncle:    true,
+			noExtra:    true,
+		})
+		if err != nil {
+			t.Fatalf("test %d: failed to prepare work: %v", i, err)
+		}
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+		gas := env.gasPool.Gas()
+
+		if err := w.commitBundle(env, bundle); err == nil {
+			t.Fatalf("test %d: failing bundle committed", i)
+		}
+		if len(env.txs) != 0 || len(env.receipts) != 0 || env.tcount != 0 {
+			t.Errorf("test %d: transactions left behind: txs %d, receipts %d, tcount %d", i, len(env.txs), len(env.receipts), env.tcount)
+		}
+		if env.header.GasUsed != 0 || env.gasPool.Gas() != gas {
+			t.Errorf("test %d: gas left behind: used %d, pool %d, want %d", i, env.header.GasUsed, env.gasPool.Gas(), gas)
+		}
+		if nonce := env.state.GetNonce(testBankAddress); nonce != 0 {
+			t.Errorf("test %d: state left behind: nonce %d", i, nonce)
+		}
+		env.discard()
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// apiKeyHeader is the HTTP header carrying the API key of a request. Clients not
+// able to set custom headers may append the key to the request path instead.
+const apiKeyHeader = "X-API-Key"
+
+// DefaultRPCMethodCosts is the default number of rate limit tokens consumed by
+// calls which are considerably more expensive to serve than an average call.
+// Methods not listed here cost a single token.
+var DefaultRPCMethodCosts = map[string]float64{
+	"eth_getLogs":          10,
+	"eth_getFilterLogs":    10,
+	"eth_call":             2,
+	"eth_estimateGas":      2,
+	"eth_createAccessList": 5,
+	"debug_trace*":         50,
+	"debug_storageRangeAt": 10,
+}
+
+// APIKey grants access to a set of RPC modules on the HTTP and WebSocket
+// endpoints, subject to per-method rate limits.
+type APIKey struct {
+	Key     string   // Secret presented by the client
+	Modules []string // API modules accessible with the key
+
+	// Limits maps method patterns to the token bucket charged for matching calls.
+	// A pattern is either a full method name or a prefix followed by '*', e.g.
+	// "debug_trace*" or "*". A call is only admitted if every matching bucket
+	// holds enough tokens to cover its cost.
+	Limits map[string]RateLimit `toml:",omitempty"`
+}
+
+// RateLimit configures a token bucket.
+type RateLimit struct {
+	Rate  float64 // Tokens added to the bucket per second
+	Burst float64 // Maximum number of tokens held by the bucket
+}
+
+// matchMethod reports whether the method name matches the given pattern.
+func matchMethod(pattern, method string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(method, pattern[:len(pattern)-1])
+	}
+	return pattern == method
+}
+
+// methodCost returns the number of tokens consumed by a call to the given method.
+// Exact method names take precedence over patterns, longer patterns over shorter
+// ones.
+func methodCost(costs map[string]float64, method string) float64 {
+	if cost, ok := costs[method]; ok {
+		return cost
+	}
+	var (
+		cost    = 1.0
+		longest = -1
+	)
+	for pattern, c := range costs {
+		if len(pattern) > longest && strings.HasSuffix(pattern, "*") && matchMethod(pattern, method) {
+			cost, longest = c, len(pattern)
+		}
+	}
+	return cost
+}
+
+// tokenBucket is a rate limiter refilled continuously at a fixed rate.
+type tokenBucket struct {
+	pattern string
+	limit   RateLimit
+	tokens  float64
+	updated mclock.AbsTime
+}
+
+// refill tops up the bucket with the tokens accumulated since the last update.
+func (b *tokenBucket) refill(now mclock.AbsTime) {
+	elapsed := time.Duration(now - b.updated).Seconds()
+	b.tokens = math.Min(b.limit.Burst, b.tokens+elapsed*b.limit.Rate)
+	b.updated = now
+}
+
+// apiKeyLimiter enforces the rate limits of a single API key. It implements
+// rpc.CallLimiter.
+type apiKeyLimiter struct {
+	clock   mclock.Clock
+	costs   map[string]float64
+	lock    sync.Mutex
+	buckets []*tokenBucket
+}
+
+func newAPIKeyLimiter(clock mclock.Clock, limits map[string]RateLimit, costs map[string]float64) *apiKeyLimiter {
+	l := &apiKeyLimiter{clock: clock, costs: costs}
+	now := clock.Now()
+	for pattern, limit := range limits {
+		l.buckets = append(l.buckets, &tokenBucket{pattern: pattern, limit: limit, tokens: limit.Burst, updated: now})
+	}
+	sort.Slice(l.buckets, func(i, j int) bool { return l.buckets[i].pattern < l.buckets[j].pattern })
+	return l
+}
+
+// AllowCall charges the cost of the method to all matching buckets, or rejects
+// the call if any of them lacks the required tokens.
+func (l *apiKeyLimiter) AllowCall(method string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	var (
+		now     = l.clock.Now()
+		cost    = methodCost(l.costs, method)
+		matched []*tokenBucket
+		wait    time.Duration
+	)
+	for _, b := range l.buckets {
+		if !matchMethod(b.pattern, method) {
+			continue
+		}
+		b.refill(now)
+		// Calls more expensive than the whole bucket are admitted on a full bucket,
+		// otherwise they could never be served.
+		need := math.Min(cost, b.limit.Burst)
+		if b.tokens < need {
+			retry := time.Duration(math.MaxInt64)
+			if b.limit.Rate > 0 {
+				retry = time.Duration((need - b.tokens) / b.limit.Rate * float64(time.Second))
+			}
+			if retry > wait {
+				wait = retry
+			}
+		}
+		matched = append(matched, b)
+	}
+	if wait > 0 {
+		return &rpc.LimitExceededError{Method: method, RetryAfter: wait}
+	}
+	for _, b := range matched {
+		b.tokens -= math.Min(cost, b.limit.Burst)
+	}
+	return nil
+}
+
+// validateAPIKeys checks the API key configuration for obvious mistakes.
+func validateAPIKeys(keys []APIKey) error {
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		if key.Key == "" {
+			return fmt.Errorf("API key %d is empty", i)
+		}
+		if strings.ContainsAny(key.Key, "/?#") {
+			return fmt.Errorf("API key %d contains URL meta-characters", i)
+		}
+		if seen[key.Key] {
+			return fmt.Errorf("API key %d is duplicated", i)
+		}
+		seen[key.Key] = true
+
+		for pattern, limit := range key.Limits {
+			if limit.Rate < 0 || limit.Burst <= 0 {
+				return fmt.Errorf("API key %d has invalid limit for %q: rate %v, burst %v", i, pattern, limit.Rate, limit.Burst)
+			}
+		}
+	}
+	return nil
+}
+
+// apiKeyHandler routes requests to the RPC server of the API key they carry.
+type apiKeyHandler struct {
+	prefix   string
+	handlers map[string]http.Handler
+}
+
+// newAPIKeyLimiters creates the rate limiters of the API keys. They are created
+// once per node and shared by the HTTP and WebSocket endpoints, so the limits of
+// a key apply to its calls over both transports together.
+func newAPIKeyLimiters(keys []APIKey, costs map[string]float64) (map[string]*apiKeyLimiter, error) {
+	if err := validateAPIKeys(keys); err != nil {
+		return nil, err
+	}
+	if costs == nil {
+		costs = DefaultRPCMethodCosts
+	}
+	limiters := make(map[string]*apiKeyLimiter, len(keys))
+	for _, key := range keys {
+		limiters[key.Key] = newAPIKeyLimiter(mclock.System{}, key.Limits, costs)
+	}
+	return limiters, nil
+}
+
+// newAPIKeyServers creates a dedicated RPC server for every API key, exposing
+// the modules granted to the key and enforcing its rate limits.
+func newAPIKeyServers(apis []rpc.API, keys []APIKey, limiters map[string]*apiKeyLimiter) (map[string]*rpc.Server, error) {
+	servers := make(map[string]*rpc.Server, len(keys))
+	for _, key := range keys {
+		srv := rpc.NewServer()
+		if err := RegisterApis(apis, key.Modules, srv, false); err != nil {
+			for _, srv := range servers {
+				srv.Stop()
+			}
+			return nil, err
+		}
+		srv.SetCallLimiter(limiters[key.Key])
+		servers[key.Key] = srv
+	}
+	return servers, nil
+}
+
+// apiKeyFromPath extracts the API key from a request path of the form
+// <prefix>/<key>. It returns false if the path doesn't have that form.
+func apiKeyFromPath(path, prefix string) (string, bool) {
+	if !strings.HasPrefix(path, prefix) {
+		return "", false
+	}
+	rest := strings.TrimPrefix(path[len(prefix):], "/")
+	if rest == "" || strings.Contains(rest, "/") {
+		return "", false
+	}
+	return rest, true
+}
+
+// checkKeyPath reports whether the request targets the handler mounted on the
+// given prefix, with the API key optionally appended to the path.
+func checkKeyPath(r *http.Request, prefix string) bool {
+	if checkPath(r, prefix) {
+		return true
+	}
+	_, ok := apiKeyFromPath(r.URL.Path, prefix)
+	return ok
+}
+
+func (h *apiKeyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get(apiKeyHeader)
+	if key == "" {
+		key, _ = apiKeyFromPath(r.URL.Path, h.prefix)
+	}
+	handler, ok := h.handlers[key]
+	if !ok {
+		http.Error(w, "missing or invalid API key", http.StatusUnauthorized)
+		return
+	}
+	handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,167 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+type apiKeyTestService struct{}
+
+func (s *apiKeyTestService) Ping() string { return "pong" }
+
+func (s *apiKeyTestService) GetLogs() string { return "logs" }
+
+// Tests that the token buckets of an API key charge the method costs to all
+// matching buckets and refill over time.
+func TestAPIKeyLimiter(t *testing.T) {
+	var (
+		clock   = new(mclock.Simulated)
+		limiter = newAPIKeyLimiter(clock, map[string]RateLimit{
+			"*":            {Rate: 10, Burst: 20},
+			"test_getLogs": {Rate: 1, Burst: 10},
+		}, map[string]float64{"test_get*": 5, "test_getLogs": 10})
+	)
+	// The expensive method drains its own bucket in one go
+	if err := limiter.AllowCall("test_getLogs"); err != nil {
+		t.Fatalf("first expensive call rejected: %v", err)
+	}
+	err := limiter.AllowCall("test_getLogs")
+	var limitErr *rpc.LimitExceededError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("second expensive call not rejected: %v", err)
+	}
+	if limitErr.RetryAfter != 10*time.Second {
+		t.Errorf("wrong retry delay: have %v, want %v", limitErr.RetryAfter, 10*time.Second)
+	}
+	// Cheap methods only consume the remaining global quota, rejected calls are
+	// free of charge
+	for i := 0; i < 10; i++ {
+		if err := limiter.AllowCall("test_ping"); err != nil {
+			t.Fatalf("cheap call %d rejected: %v", i, err)
+		}
+	}
+	if err := limiter.AllowCall("test_ping"); err == nil {
+		t.Fatal("call exceeding global quota admitted")
+	}
+	// Pattern costs apply to methods without an exact entry
+	clock.Run(500 * time.Millisecond)
+	if err := limiter.AllowCall("test_getBalance"); err != nil {
+		t.Fatalf("refilled call rejected: %v", err)
+	}
+	if err := limiter.AllowCall("test_ping"); err == nil {
+		t.Fatal("call exceeding refilled quota admitted")
+	}
+	clock.Run(10 * time.Second)
+	if err := limiter.AllowCall("test_getLogs"); err != nil {
+		t.Fatalf("expensive call rejected after refill: %v", err)
+	}
+}
+
+// Tests that the HTTP endpoint requires a valid API key, only exposes the modules
+// granted to the key and reports exhausted quotas as JSON-RPC errors. The quota
+// of a key is shared with the WebSocket endpoint.
+func TestAPIKeyHTTP(t *testing.T) {
+	var (
+		apis = []rpc.API{{Namespace: "test", Service: new(apiKeyTestService)}}
+		keys = []APIKey{
+			{Key: "limited", Modules: []string{"test"}, Limits: map[string]RateLimit{"test_getLogs": {Burst: 10}}},
+			{Key: "nomodules"},
+		}
+		srv = newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	)
+	limiters, err := newAPIKeyLimiters(keys, map[string]float64{"test_getLogs": 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.enableRPC(apis, httpConfig{apiKeys: keys, apiKeyLimiters: limiters}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.enableWS(apis, wsConfig{Origins: []string{"*"}, apiKeys: keys, apiKeyLimiters: limiters}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.setListenAddr("localhost", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.stop()
+
+	url := "http://" + srv.listenAddr()
+	if resp := rpcRequest(t, url); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request without key: wrong status %d", resp.StatusCode)
+	}
+	if resp := rpcRequest(t, url, apiKeyHeader, "invalid"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("request with invalid key: wrong status %d", resp.StatusCode)
+	}
+	if resp := rpcRequest(t, url+"/limited"); resp.StatusCode != http.StatusOK {
+		t.Errorf("request with key in path: wrong status %d", resp.StatusCode)
+	}
+	// Check the modules granted to the keys
+	client, err := rpc.DialHTTP(url + "/nomodules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res string
+	if err := client.Call(&res, "test_ping"); err == nil {
+		t.Error("call to module not granted to the key succeeded")
+	}
+	client.Close()
+
+	client, err = rpc.DialHTTP(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.SetHeader(apiKeyHeader, "limited")
+
+	if err := client.Call(&res, "test_ping"); err != nil || res != "pong" {
+		t.Fatalf("call failed: %q %v", res, err)
+	}
+	// Exhaust the quota of the expensive method
+	if err := client.Call(&res, "test_getLogs"); err != nil {
+		t.Fatalf("first expensive call failed: %v", err)
+	}
+	err = client.Call(&res, "test_getLogs")
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("wrong error for exhausted quota: %v", err)
+	}
+	if err := client.Call(&res, "test_ping"); err != nil {
+		t.Fatalf("unlimited call failed: %v", err)
+	}
+	// The quota exhausted over HTTP also applies to WebSocket
+	wsClient, err := rpc.DialWebsocket(context.Background(), "ws://"+srv.listenAddr()+"/limited", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wsClient.Close()
+
+	err = wsClient.Call(&res, "test_getLogs")
+	if rpcErr, ok := err.(rpc.Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("wrong error for quota exhausted over HTTP: %v", err)
+	}
+}
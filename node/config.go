@@ -25,6 +25,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -139,6 +140,10 @@ type Config struct {
 	// HTTPPathPrefix specifies a path prefix on which http-rpc is to be served.
 	HTTPPathPrefix string `toml:",omitempty"`
 
+	// HTTPTLS configures TLS on the HTTP and websocket RPC endpoints. If a client
+	// CA file is set, clients must present a certificate signed by one of its CAs.
+	HTTPTLS TLSConfig `toml:",omitempty"`
+
 	// AuthAddr is the listening address on which authenticated APIs are provided.
 	AuthAddr string `toml:",omitempty"`
 
@@ -149,6 +154,10 @@ type Config struct {
 	// for the authenticated api. This is by default {'localhost'}.
 	AuthVirtualHosts []string `toml:",omitempty"`
 
+	// AuthTLS configures TLS on the authenticated API endpoint. If a client CA file
+	// is set, clients must present a certificate signed by one of its CAs.
+	AuthTLS TLSConfig `toml:",omitempty"`
+
 	// WSHost is the host interface on which to start the websocket RPC server. If
 	// this field is empty, no websocket API endpoint will be started.
 	WSHost string
@@ -178,6 +187,35 @@ type Config struct {
 	// private APIs to untrusted users is a major security risk.
 	WSExposeAll bool `toml:",omitempty"`
 
+	// BatchItemLimit is the maximum number of requests in a batch accepted by the
+	// HTTP and WebSocket RPC endpoints. Zero means no limit.
+	BatchItemLimit int `toml:",omitempty"`
+
+	// ResponseSizeLimit is the maximum size in bytes of responses sent by the HTTP
+	// and WebSocket RPC endpoints. For batches, it limits the size of all responses
+	// together. Zero means no limit.
+	ResponseSizeLimit int `toml:",omitempty"`
+
+	// BatchConcurrency is the number of calls in a batch executed in parallel by
+	// the HTTP and WebSocket RPC endpoints. Values below two process batches
+	// sequentially.
+	BatchConcurrency int `toml:",omitempty"`
+
+	// BatchTimeout is the maximum time the HTTP and WebSocket RPC endpoints spend
+	// on a batch. Calls not finished in time are canceled and answered with an
+	// error. Zero means no limit.
+	BatchTimeout time.Duration `toml:",omitempty"`
+
+	// APIKeys restricts the HTTP and WebSocket RPC endpoints to clients presenting
+	// one of the configured keys, either in the X-API-Key header or appended to the
+	// request path. Every key has its own module list and per-method rate limits,
+	// which replace HTTPModules and WSModules.
+	APIKeys []APIKey `toml:",omitempty"`
+
+	// RPCMethodCosts is the number of rate limit tokens consumed by calls to the
+	// given method patterns. If nil, DefaultRPCMethodCosts is used.
+	RPCMethodCosts map[string]float64 `toml:",omitempty"`
+
 	// GraphQLCors is the Cross-Origin Resource Sharing header to send to requesting
 	// clients. Please be aware that CORS is a browser enforced security, it's fully
 	// useless for custom HTTP clients.
@@ -192,6 +230,14 @@ type Config struct {
 	// Requests using ip address directly are not affected
 	GraphQLVirtualHosts []string `toml:",omitempty"`
 
+	// GraphQLMaxDepth is the maximum nesting depth of GraphQL queries. Zero means
+	// no limit.
+	GraphQLMaxDepth int `toml:",omitempty"`
+
+	// GraphQLMaxComplexity is the maximum cost of executing GraphQL queries, see the
+	// graphql package for how it is computed. Zero means no limit.
+	GraphQLMaxComplexity int `toml:",omitempty"`
+
 	// Logger is a custom logger to use with the p2p.Server.
 	Logger log.Logger `toml:",omitempty"`
 
@@ -21,6 +21,7 @@ import (
 	"os/user"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/nat"
@@ -46,19 +47,35 @@ var (
 	DefaultAuthModules = []string{"eth", "engine"}
 )
 
+const (
+	DefaultBatchItemLimit    = 0                // Default maximum number of requests in a batch
+	DefaultResponseSizeLimit = 0                // Default maximum size of RPC responses in bytes
+	DefaultBatchConcurrency  = 1                // Default number of batch calls executed in parallel
+	DefaultBatchTimeout      = time.Duration(0) // Default maximum time spent on an RPC batch
+
+	DefaultGraphQLMaxDepth      = 20    // Default maximum nesting depth of GraphQL queries
+	DefaultGraphQLMaxComplexity = 10000 // Default maximum execution cost of GraphQL queries
+)
+
 // DefaultConfig contains reasonable default settings.
 var DefaultConfig = Config{
-	DataDir:             DefaultDataDir(),
-	HTTPPort:            DefaultHTTPPort,
-	AuthAddr:            DefaultAuthHost,
-	AuthPort:            DefaultAuthPort,
-	AuthVirtualHosts:    DefaultAuthVhosts,
-	HTTPModules:         []string{"net", "web3"},
-	HTTPVirtualHosts:    []string{"localhost"},
-	HTTPTimeouts:        rpc.DefaultHTTPTimeouts,
-	WSPort:              DefaultWSPort,
-	WSModules:           []string{"net", "web3"},
-	GraphQLVirtualHosts: []string{"localhost"},
+	DataDir:              DefaultDataDir(),
+	HTTPPort:             DefaultHTTPPort,
+	AuthAddr:             DefaultAuthHost,
+	AuthPort:             DefaultAuthPort,
+	AuthVirtualHosts:     DefaultAuthVhosts,
+	HTTPModules:          []string{"net", "web3"},
+	HTTPVirtualHosts:     []string{"localhost"},
+	HTTPTimeouts:         rpc.DefaultHTTPTimeouts,
+	WSPort:               DefaultWSPort,
+	WSModules:            []string{"net", "web3"},
+	GraphQLVirtualHosts:  []string{"localhost"},
+	GraphQLMaxDepth:      DefaultGraphQLMaxDepth,
+	GraphQLMaxComplexity: DefaultGraphQLMaxComplexity,
+	BatchItemLimit:       DefaultBatchItemLimit,
+	ResponseSizeLimit:    DefaultResponseSizeLimit,
+	BatchConcurrency:     DefaultBatchConcurrency,
+	BatchTimeout:         DefaultBatchTimeout,
 	P2P: p2p.Config{
 		ListenAddr: ":30303",
 		MaxPeers:   50,
@@ -155,6 +155,8 @@ func New(conf *Config) (*Node, error) {
 	node.httpAuth = newHTTPServer(node.log, conf.HTTPTimeouts)
 	node.ws = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
 	node.wsAuth = newHTTPServer(node.log, rpc.DefaultHTTPTimeouts)
+	node.http.tls, node.ws.tls = conf.HTTPTLS, conf.HTTPTLS
+	node.httpAuth.tls, node.wsAuth.tls = conf.AuthTLS, conf.AuthTLS
 	node.ipc = newIPCServer(node.log, conf.IPCEndpoint())
 
 	return node, nil
@@ -342,6 +344,17 @@ func (n *Node) closeDataDir() {
 	}
 }
 
+// rpcEndpointConfig returns the limits configured for the HTTP and WebSocket
+// RPC endpoints.
+func (n *Node) rpcEndpointConfig() rpcEndpointConfig {
+	return rpcEndpointConfig{
+		batchItemLimit:    n.config.BatchItemLimit,
+		responseSizeLimit: n.config.ResponseSizeLimit,
+		batchConcurrency:  n.config.BatchConcurrency,
+		batchTimeout:      n.config.BatchTimeout,
+	}
+}
+
 // obtainJWTSecret loads the jwt-secret, either from the provided config,
 // or from the default location. If neither of those are present, it generates
 // a new secret and stores to the default location.
@@ -394,6 +407,11 @@ func (n *Node) startRPC() error {
 		servers   []*httpServer
 		open, all = n.GetAPIs()
 	)
+	// The rate limits of API keys apply across the HTTP and WebSocket endpoints.
+	limiters, err := newAPIKeyLimiters(n.config.APIKeys, n.config.RPCMethodCosts)
+	if err != nil {
+		return err
+	}
 
 	initHttp := func(server *httpServer, apis []rpc.API, port int) error {
 		if err := server.setListenAddr(n.config.HTTPHost, port); err != nil {
@@ -404,6 +422,9 @@ func (n *Node) startRPC() error {
 			Vhosts:             n.config.HTTPVirtualHosts,
 			Modules:            n.config.HTTPModules,
 			prefix:             n.config.HTTPPathPrefix,
+			apiKeys:            n.config.APIKeys,
+			apiKeyLimiters:     limiters,
+			rpcEndpointConfig:  n.rpcEndpointConfig(),
 		}); err != nil {
 			return err
 		}
@@ -417,9 +438,12 @@ func (n *Node) startRPC() error {
 			return err
 		}
 		if err := server.enableWS(n.rpcAPIs, wsConfig{
-			Modules: n.config.WSModules,
-			Origins: n.config.WSOrigins,
-			prefix:  n.config.WSPathPrefix,
+			Modules:           n.config.WSModules,
+			Origins:           n.config.WSOrigins,
+			prefix:            n.config.WSPathPrefix,
+			apiKeys:           n.config.APIKeys,
+			apiKeyLimiters:    limiters,
+			rpcEndpointConfig: n.rpcEndpointConfig(),
 		}); err != nil {
 			return err
 		}
@@ -656,15 +680,15 @@ func (n *Node) IPCEndpoint() string {
 // HTTPEndpoint returns the URL of the HTTP server. Note that this URL does not
 // contain the JSON-RPC path prefix set by HTTPPathPrefix.
 func (n *Node) HTTPEndpoint() string {
-	return "http://" + n.http.listenAddr()
+	return n.http.scheme("http") + "://" + n.http.listenAddr()
 }
 
 // WSEndpoint returns the current JSON-RPC over WebSocket endpoint.
 func (n *Node) WSEndpoint() string {
 	if n.http.wsAllowed() {
-		return "ws://" + n.http.listenAddr() + n.http.wsConfig.prefix
+		return n.http.scheme("ws") + "://" + n.http.listenAddr() + n.http.wsConfig.prefix
 	}
-	return "ws://" + n.ws.listenAddr() + n.ws.wsConfig.prefix
+	return n.ws.scheme("ws") + "://" + n.ws.listenAddr() + n.ws.wsConfig.prefix
 }
 
 // EventMux retrieves the event multiplexer used by all the network services in
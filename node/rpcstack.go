@@ -19,6 +19,7 @@ package node
 import (
 	"compress/gzip"
 	"context"
+	"crypto/tls"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -28,6 +29,7 @@ import (
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -39,21 +41,52 @@ type httpConfig struct {
 	Modules            []string
 	CorsAllowedOrigins []string
 	Vhosts             []string
-	prefix             string // path prefix on which to mount http handler
-	jwtSecret          []byte // optional JWT secret
+	prefix             string                    // path prefix on which to mount http handler
+	jwtSecret          []byte                    // optional JWT secret
+	apiKeys            []APIKey                  // optional API keys required for access
+	apiKeyLimiters     map[string]*apiKeyLimiter // rate limiters of the API keys, shared across transports
+	rpcEndpointConfig
 }
 
 // wsConfig is the JSON-RPC/Websocket configuration
 type wsConfig struct {
-	Origins   []string
-	Modules   []string
-	prefix    string // path prefix on which to mount ws handler
-	jwtSecret []byte // optional JWT secret
+	Origins        []string
+	Modules        []string
+	prefix         string                    // path prefix on which to mount ws handler
+	jwtSecret      []byte                    // optional JWT secret
+	apiKeys        []APIKey                  // optional API keys required for access
+	apiKeyLimiters map[string]*apiKeyLimiter // rate limiters of the API keys, shared across transports
+	rpcEndpointConfig
+}
+
+// rpcEndpointConfig holds the limits applied by the RPC servers of an endpoint.
+type rpcEndpointConfig struct {
+	batchItemLimit    int           // maximum number of requests in a batch
+	responseSizeLimit int           // maximum size of responses in bytes
+	batchConcurrency  int           // number of batch calls executed in parallel
+	batchTimeout      time.Duration // maximum time to process a batch
+}
+
+// apply configures the limits on an RPC server.
+func (c rpcEndpointConfig) apply(srv *rpc.Server) {
+	srv.SetBatchLimits(c.batchItemLimit, c.responseSizeLimit)
+	srv.SetBatchConcurrency(c.batchConcurrency, c.batchTimeout)
 }
 
 type rpcHandler struct {
 	http.Handler
-	server *rpc.Server
+	server *rpc.Server            // nil if access requires an API key
+	keyed  map[string]*rpc.Server // servers of the configured API keys
+}
+
+// stop shuts down all RPC servers behind the handler.
+func (h *rpcHandler) stop() {
+	if h.server != nil {
+		h.server.Stop()
+	}
+	for _, srv := range h.keyed {
+		srv.Stop()
+	}
 }
 
 type httpServer struct {
@@ -64,6 +97,7 @@ type httpServer struct {
 	mu       sync.Mutex
 	server   *http.Server
 	listener net.Listener // non-nil when server is running
+	tls      TLSConfig    // optional TLS configuration of the listener
 
 	// HTTP RPC handler things.
 
@@ -105,6 +139,18 @@ func (h *httpServer) setListenAddr(host string, port int) error {
 	return nil
 }
 
+// scheme returns the URL scheme of the server for the given protocol, either
+// "http" or "ws", accounting for TLS.
+func (h *httpServer) scheme(protocol string) string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.tls.Enabled() {
+		return protocol + "s"
+	}
+	return protocol
+}
+
 // listenAddr returns the listening address of the server.
 func (h *httpServer) listenAddr() string {
 	h.mu.Lock()
@@ -135,6 +181,15 @@ func (h *httpServer) start() error {
 	}
 
 	// Start the server.
+	var reloader *certReloader
+	if h.tls.Enabled() {
+		var err error
+		if reloader, err = newCertReloader(h.tls, h.log); err != nil {
+			h.disableRPC()
+			h.disableWS()
+			return err
+		}
+	}
 	listener, err := net.Listen("tcp", h.endpoint)
 	if err != nil {
 		// If the server fails to start, we need to clear out the RPC and WS
@@ -143,11 +198,18 @@ func (h *httpServer) start() error {
 		h.disableWS()
 		return err
 	}
+	if reloader != nil {
+		listener = tls.NewListener(listener, reloader.serverConfig())
+	}
 	h.listener = listener
 	go h.server.Serve(listener)
 
 	if h.wsAllowed() {
-		url := fmt.Sprintf("ws://%v", listener.Addr())
+		scheme := "ws"
+		if reloader != nil {
+			scheme = "wss"
+		}
+		url := fmt.Sprintf("%s://%v", scheme, listener.Addr())
 		if h.wsConfig.prefix != "" {
 			url += h.wsConfig.prefix
 		}
@@ -159,7 +221,7 @@ func (h *httpServer) start() error {
 	}
 	// Log http endpoint.
 	h.log.Info("HTTP server started",
-		"endpoint", listener.Addr(), "auth", (h.httpConfig.jwtSecret != nil),
+		"endpoint", listener.Addr(), "auth", (h.httpConfig.jwtSecret != nil), "tls", reloader != nil,
 		"prefix", h.httpConfig.prefix,
 		"cors", strings.Join(h.httpConfig.CorsAllowedOrigins, ","),
 		"vhosts", strings.Join(h.httpConfig.Vhosts, ","),
@@ -186,8 +248,13 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// check if ws request and serve if ws enabled
 	ws := h.wsHandler.Load().(*rpcHandler)
 	if ws != nil && isWebsocket(r) {
-		if checkPath(r, h.wsConfig.prefix) {
+		if checkPath(r, h.wsConfig.prefix) || (ws.keyed != nil && checkKeyPath(r, h.wsConfig.prefix)) {
 			ws.ServeHTTP(w, r)
+			return
+		}
+		// Handlers registered via Node.RegisterHandler may accept websockets too.
+		if muxHandler, pattern := h.mux.Handler(r); pattern != "" {
+			muxHandler.ServeHTTP(w, r)
 		}
 		return
 	}
@@ -204,7 +271,7 @@ func (h *httpServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if checkPath(r, h.httpConfig.prefix) {
+		if checkPath(r, h.httpConfig.prefix) || (rpc.keyed != nil && checkKeyPath(r, h.httpConfig.prefix)) {
 			rpc.ServeHTTP(w, r)
 			return
 		}
@@ -256,11 +323,11 @@ func (h *httpServer) doStop() {
 	wsHandler := h.wsHandler.Load().(*rpcHandler)
 	if httpHandler != nil {
 		h.httpHandler.Store((*rpcHandler)(nil))
-		httpHandler.server.Stop()
+		httpHandler.stop()
 	}
 	if wsHandler != nil {
 		h.wsHandler.Store((*rpcHandler)(nil))
-		wsHandler.server.Stop()
+		wsHandler.stop()
 	}
 	h.server.Shutdown(context.Background())
 	h.listener.Close()
@@ -280,8 +347,27 @@ func (h *httpServer) enableRPC(apis []rpc.API, config httpConfig) error {
 		return fmt.Errorf("JSON-RPC over HTTP is already enabled")
 	}
 
-	// Create RPC server and handler.
+	// Create RPC server and handler. If API keys are configured, every key gets
+	// its own server exposing only the modules granted to it.
+	if len(config.apiKeys) > 0 {
+		servers, err := newAPIKeyServers(apis, config.apiKeys, config.apiKeyLimiters)
+		if err != nil {
+			return err
+		}
+		router := &apiKeyHandler{prefix: config.prefix, handlers: make(map[string]http.Handler)}
+		for key, srv := range servers {
+			config.rpcEndpointConfig.apply(srv)
+			router.handlers[key] = srv
+		}
+		h.httpConfig = config
+		h.httpHandler.Store(&rpcHandler{
+			Handler: NewHTTPHandlerStack(router, config.CorsAllowedOrigins, config.Vhosts, config.jwtSecret),
+			keyed:   servers,
+		})
+		return nil
+	}
 	srv := rpc.NewServer()
+	config.rpcEndpointConfig.apply(srv)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -298,7 +384,7 @@ func (h *httpServer) disableRPC() bool {
 	handler := h.httpHandler.Load().(*rpcHandler)
 	if handler != nil {
 		h.httpHandler.Store((*rpcHandler)(nil))
-		handler.server.Stop()
+		handler.stop()
 	}
 	return handler != nil
 }
@@ -311,8 +397,27 @@ func (h *httpServer) enableWS(apis []rpc.API, config wsConfig) error {
 	if h.wsAllowed() {
 		return fmt.Errorf("JSON-RPC over WebSocket is already enabled")
 	}
-	// Create RPC server and handler.
+	// Create RPC server and handler. If API keys are configured, every key gets
+	// its own server exposing only the modules granted to it.
+	if len(config.apiKeys) > 0 {
+		servers, err := newAPIKeyServers(apis, config.apiKeys, config.apiKeyLimiters)
+		if err != nil {
+			return err
+		}
+		router := &apiKeyHandler{prefix: config.prefix, handlers: make(map[string]http.Handler)}
+		for key, srv := range servers {
+			config.rpcEndpointConfig.apply(srv)
+			router.handlers[key] = srv.WebsocketHandler(config.Origins)
+		}
+		h.wsConfig = config
+		h.wsHandler.Store(&rpcHandler{
+			Handler: NewWSHandlerStack(router, config.jwtSecret),
+			keyed:   servers,
+		})
+		return nil
+	}
 	srv := rpc.NewServer()
+	config.rpcEndpointConfig.apply(srv)
 	if err := RegisterApis(apis, config.Modules, srv, false); err != nil {
 		return err
 	}
@@ -341,7 +446,7 @@ func (h *httpServer) disableWS() bool {
 	ws := h.wsHandler.Load().(*rpcHandler)
 	if ws != nil {
 		h.wsHandler.Store((*rpcHandler)(nil))
-		ws.server.Stop()
+		ws.stop()
 	}
 	return ws != nil
 }
@@ -465,7 +570,7 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 
 func newGzipHandler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || isWebsocket(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -0,0 +1,176 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// tlsReloadInterval is the minimum time between two checks of the certificate
+// files for changes.
+const tlsReloadInterval = time.Second
+
+// TLSConfig configures TLS on an RPC endpoint.
+type TLSConfig struct {
+	CertFile     string `toml:",omitempty"` // PEM encoded certificate chain of the server
+	KeyFile      string `toml:",omitempty"` // PEM encoded private key of the server
+	ClientCAFile string `toml:",omitempty"` // PEM encoded CAs to verify client certificates, enables mutual TLS
+}
+
+// Enabled reports whether TLS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// certReloader provides the certificate and client CAs of a TLS endpoint. The
+// files are checked for changes during handshakes, so renewed certificates are
+// picked up without restarting the server.
+type certReloader struct {
+	config TLSConfig
+	log    log.Logger
+
+	lock      sync.Mutex
+	checked   time.Time   // last time the files were checked for changes
+	modTimes  []time.Time // modification times of the loaded files
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// newCertReloader loads the certificate and client CAs of the given config.
+func newCertReloader(config TLSConfig, log log.Logger) (*certReloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("TLS requires both a certificate and a key file")
+	}
+	r := &certReloader{config: config, log: log}
+	modTimes, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(modTimes); err != nil {
+		return nil, err
+	}
+	r.checked = time.Now()
+	return r, nil
+}
+
+// files returns the paths of all files the configuration refers to.
+func (r *certReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+// stat returns the modification times of the configured files.
+func (r *certReloader) stat() ([]time.Time, error) {
+	var times []time.Time
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		times = append(times, info.ModTime())
+	}
+	return times, nil
+}
+
+// load reads the configured files. The caller must hold the lock, unless the
+// reloader is not yet in use.
+func (r *certReloader) load(modTimes []time.Time) error {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %v", err)
+	}
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		blob, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to load TLS client CAs: %v", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(blob) {
+			return fmt.Errorf("no certificates found in %s", r.config.ClientCAFile)
+		}
+	}
+	r.cert, r.clientCAs, r.modTimes = &cert, clientCAs, modTimes
+	return nil
+}
+
+// current returns the certificate and client CAs, reloading them if any of the
+// files changed. Failed reloads are logged and the previous files stay in use.
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if now := time.Now(); now.Sub(r.checked) >= tlsReloadInterval {
+		r.checked = now
+		modTimes, err := r.stat()
+		if err != nil {
+			r.log.Warn("Failed to check TLS certificate files", "err", err)
+		} else if changed(r.modTimes, modTimes) {
+			if err := r.load(modTimes); err != nil {
+				r.log.Warn("Failed to reload TLS certificate", "err", err)
+			} else {
+				r.log.Info("Reloaded TLS certificate", "cert", r.config.CertFile)
+			}
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+// changed reports whether any of the modification times differ.
+func changed(old, new []time.Time) bool {
+	if len(old) != len(new) {
+		return true
+	}
+	for i := range old {
+		if !old[i].Equal(new[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// serverConfig returns the TLS configuration of the endpoint. The certificate and
+// client CAs are resolved for every connection.
+func (r *certReloader) serverConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			cert, clientCAs := r.current()
+			config := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*cert},
+			}
+			if clientCAs != nil {
+				config.ClientCAs = clientCAs
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package node
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/internal/testlog"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// testCert is a certificate along with its key.
+type testCert struct {
+	cert *x509.Certificate
+	der  []byte
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate signed by the given parent. If the parent is
+// nil, a self-signed CA certificate is created.
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+	}
+	signer, signerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, der: der, key: key}
+}
+
+// write stores the certificate and key as PEM files, returning their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	keyDer, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+// Tests that HTTP and WebSocket endpoints are served over TLS and that client
+// certificates are verified if a client CA is configured.
+func TestTLSEndpoint(t *testing.T) {
+	var (
+		dir                   = t.TempDir()
+		ca                    = newTestCert(t, "ca", nil)
+		caFile, _             = ca.write(t, dir, "ca")
+		serverCert, serverKey = newTestCert(t, "server", ca).write(t, dir, "server")
+		clientCert, clientKey = newTestCert(t, "client", ca).write(t, dir, "client")
+		rogueCert, rogueKey   = newTestCert(t, "rogue", newTestCert(t, "rogue-ca", nil)).write(t, dir, "rogue")
+	)
+	srv := newHTTPServer(testlog.Logger(t, log.LvlDebug), rpc.DefaultHTTPTimeouts)
+	srv.tls = TLSConfig{CertFile: serverCert, KeyFile: serverKey, ClientCAFile: caFile}
+	if err := srv.enableRPC(nil, httpConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.enableWS(nil, wsConfig{Origins: []string{"*"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.setListenAddr("localhost", 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.stop()
+
+	tests := []struct {
+		certFile, keyFile string
+		ok                bool
+	}{
+		{certFile: clientCert, keyFile: clientKey, ok: true},
+		{certFile: rogueCert, keyFile: rogueKey, ok: false},
+		{ok: false},
+	}
+	for _, test := range tests {
+		config, err := rpc.NewTLSClientConfig(caFile, test.certFile, test.keyFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, scheme := range []string{srv.scheme("http"), srv.scheme("ws")} {
+			url := scheme + "://" + srv.listenAddr()
+			client, err := rpc.DialOptions(context.Background(), url, rpc.WithTLSConfig(config))
+			if err == nil {
+				var res map[string]string
+				err = client.Call(&res, "rpc_modules")
+				client.Close()
+			}
+			if test.ok && err != nil {
+				t.Errorf("%s with certificate %q: call failed: %v", url, test.certFile, err)
+			}
+			if !test.ok && err == nil {
+				t.Errorf("%s with certificate %q: call succeeded", url, test.certFile)
+			}
+		}
+	}
+}
+
+// Tests that changed certificate files are picked up and that failed reloads
+// keep the previous certificate in use.
+func TestCertReloader(t *testing.T) {
+	var (
+		dir               = t.TempDir()
+		ca                = newTestCert(t, "ca", nil)
+		first             = newTestCert(t, "first", ca)
+		second            = newTestCert(t, "second", ca)
+		certFile, keyFile = first.write(t, dir, "server")
+	)
+	r, err := newCertReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile}, testlog.Logger(t, log.LvlDebug))
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := func() string {
+		cert, _ := r.current()
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.Subject.CommonName
+	}
+	// touch bumps the modification times and expires the check interval.
+	touch := func(offset time.Duration) {
+		for _, file := range []string{certFile, keyFile} {
+			mtime := time.Now().Add(offset)
+			if err := os.Chtimes(file, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.checked = time.Time{}
+	}
+	if have := name(); have != "first" {
+		t.Fatalf("wrong initial certificate %q", have)
+	}
+	// Replace the certificate
+	second.write(t, dir, "server")
+	touch(time.Minute)
+	if have := name(); have != "second" {
+		t.Fatalf("certificate not reloaded, have %q", have)
+	}
+	// Break the key, the previous certificate must stay in use
+	if err := ioutil.WriteFile(keyFile, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	touch(2 * time.Minute)
+	if have := name(); have != "second" {
+		t.Fatalf("certificate changed after failed reload, have %q", have)
+	}
+}
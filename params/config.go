@@ -258,16 +258,16 @@ var (
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	AllEthashProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil, nil}
 
 	// AllCliqueProtocolChanges contains every protocol change (EIPs) introduced
 	// and accepted by the Ethereum core developers into the Clique consensus.
 	//
 	// This configuration is intentionally not using keyed fields to force anyone
 	// adding flags to the config to also have to set these fields.
-	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}}
+	AllCliqueProtocolChanges = &ChainConfig{big.NewInt(1337), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, nil, nil, &CliqueConfig{Period: 0, Epoch: 30000}, nil}
 
-	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, new(EthashConfig), nil}
+	TestChainConfig = &ChainConfig{big.NewInt(1), big.NewInt(0), nil, false, big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, new(EthashConfig), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int), false)
 )
 
@@ -353,9 +353,13 @@ type ChainConfig struct {
 	// the network that triggers the consensus upgrade.
 	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
 
+	// EIP1559 overrides the fee market parameters of the chain (nil = mainnet parameters)
+	EIP1559 *EIP1559Config `json:"eip1559,omitempty"`
+
 	// Various consensus engines
 	Ethash *EthashConfig `json:"ethash,omitempty"`
 	Clique *CliqueConfig `json:"clique,omitempty"`
+	BFT    *BFTConfig    `json:"bft,omitempty"`
 }
 
 // EthashConfig is the consensus engine configs for proof-of-work based sealing.
@@ -377,6 +381,28 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// BFTConfig is the consensus engine configs for byzantine fault tolerant
+// proof-of-authority based sealing with immediate finality.
+type BFTConfig struct {
+	Period         uint64 `json:"period"`         // Number of seconds between blocks to enforce
+	Epoch          uint64 `json:"epoch"`          // Epoch length to reset pending votes
+	RequestTimeout uint64 `json:"requestTimeout"` // Timeout of the first consensus round in milliseconds
+}
+
+// String implements the stringer interface, returning the consensus engine details.
+func (c *BFTConfig) String() string {
+	return "bft"
+}
+
+// EIP1559Config is the fee market parameters of a chain. Zero fields fall back
+// to the mainnet parameters.
+type EIP1559Config struct {
+	BaseFeeChangeDenominator uint64   `json:"baseFeeChangeDenominator,omitempty"` // Bounds the amount the base fee can change between blocks
+	ElasticityMultiplier     uint64   `json:"elasticityMultiplier,omitempty"`     // Bounds the maximum gas limit an EIP-1559 block may have
+	MinBaseFee               *big.Int `json:"minBaseFee,omitempty"`               // Lower bound of the base fee (nil = no bound)
+	InitialBaseFee           *big.Int `json:"initialBaseFee,omitempty"`           // Base fee of the first EIP-1559 block (nil = mainnet value)
+}
+
 // String implements the fmt.Stringer interface.
 func (c *ChainConfig) String() string {
 	var engine interface{}
@@ -385,6 +411,8 @@ func (c *ChainConfig) String() string {
 		engine = c.Ethash
 	case c.Clique != nil:
 		engine = c.Clique
+	case c.BFT != nil:
+		engine = c.BFT
 	default:
 		engine = "unknown"
 	}
@@ -410,6 +438,43 @@ func (c *ChainConfig) String() string {
 	)
 }
 
+// BaseFeeChangeDenominator bounds the amount the base fee can change between blocks.
+func (c *ChainConfig) BaseFeeChangeDenominator() uint64 {
+	if c.EIP1559 != nil && c.EIP1559.BaseFeeChangeDenominator != 0 {
+		return c.EIP1559.BaseFeeChangeDenominator
+	}
+	return BaseFeeChangeDenominator
+}
+
+// ElasticityMultiplier bounds the maximum gas limit an EIP-1559 block may have.
+func (c *ChainConfig) ElasticityMultiplier() uint64 {
+	if c.EIP1559 != nil && c.EIP1559.ElasticityMultiplier != 0 {
+		return c.EIP1559.ElasticityMultiplier
+	}
+	return ElasticityMultiplier
+}
+
+// MinBaseFee returns the lower bound of the base fee, or nil if unbounded.
+func (c *ChainConfig) MinBaseFee() *big.Int {
+	if c.EIP1559 != nil && c.EIP1559.MinBaseFee != nil {
+		return new(big.Int).Set(c.EIP1559.MinBaseFee)
+	}
+	return nil
+}
+
+// InitialBaseFee returns the base fee of the first EIP-1559 block, never below
+// the minimum base fee.
+func (c *ChainConfig) InitialBaseFee() *big.Int {
+	baseFee := new(big.Int).SetUint64(InitialBaseFee)
+	if c.EIP1559 != nil && c.EIP1559.InitialBaseFee != nil {
+		baseFee.Set(c.EIP1559.InitialBaseFee)
+	}
+	if min := c.MinBaseFee(); min != nil && baseFee.Cmp(min) < 0 {
+		return min
+	}
+	return baseFee
+}
+
 // IsHomestead returns whether num is either equal to the homestead block or greater.
 func (c *ChainConfig) IsHomestead(num *big.Int) bool {
 	return isForked(c.HomesteadBlock, num)
@@ -602,9 +667,20 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.MergeForkBlock, newcfg.MergeForkBlock, head) {
 		return newCompatError("Merge Start fork block", c.MergeForkBlock, newcfg.MergeForkBlock)
 	}
+	if c.IsLondon(head) && !c.eip1559Equal(newcfg) {
+		return newCompatError("EIP-1559 parameters", c.LondonBlock, newcfg.LondonBlock)
+	}
 	return nil
 }
 
+// eip1559Equal returns whether two configs derive the same base fees.
+func (c *ChainConfig) eip1559Equal(newcfg *ChainConfig) bool {
+	return c.BaseFeeChangeDenominator() == newcfg.BaseFeeChangeDenominator() &&
+		c.ElasticityMultiplier() == newcfg.ElasticityMultiplier() &&
+		configNumEqual(c.MinBaseFee(), newcfg.MinBaseFee()) &&
+		configNumEqual(c.InitialBaseFee(), newcfg.InitialBaseFee())
+}
+
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
 // block s2 because head is already past the fork.
 func isForkIncompatible(s1, s2, head *big.Int) bool {
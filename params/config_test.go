@@ -87,6 +87,29 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored:  &ChainConfig{LondonBlock: big.NewInt(30)},
+			new:     &ChainConfig{LondonBlock: big.NewInt(30), EIP1559: &EIP1559Config{ElasticityMultiplier: 4}},
+			head:    20,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{LondonBlock: big.NewInt(30)},
+			new:     &ChainConfig{LondonBlock: big.NewInt(30), EIP1559: &EIP1559Config{BaseFeeChangeDenominator: BaseFeeChangeDenominator}},
+			head:    40,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{LondonBlock: big.NewInt(30)},
+			new:    &ChainConfig{LondonBlock: big.NewInt(30), EIP1559: &EIP1559Config{MinBaseFee: big.NewInt(1)}},
+			head:   40,
+			wantErr: &ConfigCompatError{
+				What:         "EIP-1559 parameters",
+				StoredConfig: big.NewInt(30),
+				NewConfig:    big.NewInt(30),
+				RewindTo:     29,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -21,7 +21,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/url"
 	"reflect"
 	"strconv"
 	"sync/atomic"
@@ -73,9 +72,11 @@ type BatchElem struct {
 
 // Client represents a connection to an RPC server.
 type Client struct {
-	idgen    func() ID // for subscriptions
-	isHTTP   bool      // connection type: http, ws or ipc
-	services *serviceRegistry
+	idgen         func() ID // for subscriptions
+	isHTTP        bool      // connection type: http, ws or ipc
+	services      *serviceRegistry
+	handlerConfig handlerConfig // limits applied when serving requests
+	pool          *failoverPool // routes all calls if the client was created by DialFailover
 
 	idCounter uint32
 
@@ -112,7 +113,7 @@ func (c *Client) newClientConn(conn ServerCodec) *clientConn {
 	ctx := context.Background()
 	ctx = context.WithValue(ctx, clientContextKey{}, c)
 	ctx = context.WithValue(ctx, peerInfoContextKey{}, conn.peerInfo())
-	handler := newHandler(ctx, conn, c.idgen, c.services)
+	handler := newHandler(ctx, conn, c.idgen, c.services, c.handlerConfig)
 	return &clientConn{conn, handler}
 }
 
@@ -168,22 +169,7 @@ func Dial(rawurl string) (*Client, error) {
 // The context is used to cancel or time out the initial connection establishment. It does
 // not affect subsequent interactions with the client.
 func DialContext(ctx context.Context, rawurl string) (*Client, error) {
-	u, err := url.Parse(rawurl)
-	if err != nil {
-		return nil, err
-	}
-	switch u.Scheme {
-	case "http", "https":
-		return DialHTTP(rawurl)
-	case "ws", "wss":
-		return DialWebsocket(ctx, rawurl, "")
-	case "stdio":
-		return DialStdIO(ctx)
-	case "":
-		return DialIPC(ctx, rawurl)
-	default:
-		return nil, fmt.Errorf("no known transport for URL scheme %q", u.Scheme)
-	}
+	return DialOptions(ctx, rawurl)
 }
 
 // ClientFromContext retrieves the client from the context, if any. This can be used to perform
@@ -198,27 +184,28 @@ func newClient(initctx context.Context, connect reconnectFunc) (*Client, error)
 	if err != nil {
 		return nil, err
 	}
-	c := initClient(conn, randomIDGenerator(), new(serviceRegistry))
+	c := initClient(conn, randomIDGenerator(), new(serviceRegistry), handlerConfig{})
 	c.reconnectFunc = connect
 	return c, nil
 }
 
-func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry) *Client {
+func initClient(conn ServerCodec, idgen func() ID, services *serviceRegistry, cfg handlerConfig) *Client {
 	_, isHTTP := conn.(*httpConn)
 	c := &Client{
-		isHTTP:      isHTTP,
-		idgen:       idgen,
-		services:    services,
-		writeConn:   conn,
-		close:       make(chan struct{}),
-		closing:     make(chan struct{}),
-		didClose:    make(chan struct{}),
-		reconnected: make(chan ServerCodec),
-		readOp:      make(chan readOp),
-		readErr:     make(chan error),
-		reqInit:     make(chan *requestOp),
-		reqSent:     make(chan error, 1),
-		reqTimeout:  make(chan *requestOp),
+		isHTTP:        isHTTP,
+		idgen:         idgen,
+		services:      services,
+		handlerConfig: cfg,
+		writeConn:     conn,
+		close:         make(chan struct{}),
+		closing:       make(chan struct{}),
+		didClose:      make(chan struct{}),
+		reconnected:   make(chan ServerCodec),
+		readOp:        make(chan readOp),
+		readErr:       make(chan error),
+		reqInit:       make(chan *requestOp),
+		reqSent:       make(chan error, 1),
+		reqTimeout:    make(chan *requestOp),
 	}
 	if !isHTTP {
 		go c.dispatch(conn)
@@ -251,6 +238,10 @@ func (c *Client) SupportedModules() (map[string]string, error) {
 
 // Close closes the client, aborting any in-flight requests.
 func (c *Client) Close() {
+	if c.pool != nil {
+		c.pool.close()
+		return
+	}
 	if c.isHTTP {
 		return
 	}
@@ -265,6 +256,10 @@ func (c *Client) Close() {
 // This method only works for clients using HTTP, it doesn't have
 // any effect for clients using another transport.
 func (c *Client) SetHeader(key, value string) {
+	if c.pool != nil {
+		c.pool.setHeader(key, value)
+		return
+	}
 	if !c.isHTTP {
 		return
 	}
@@ -293,6 +288,9 @@ func (c *Client) CallContext(ctx context.Context, result interface{}, method str
 	if result != nil && reflect.TypeOf(result).Kind() != reflect.Ptr {
 		return fmt.Errorf("call result parameter must be pointer or nil interface: %v", result)
 	}
+	if c.pool != nil {
+		return c.pool.call(ctx, result, method, args...)
+	}
 	msg, err := c.newMessage(method, args...)
 	if err != nil {
 		return err
@@ -343,6 +341,9 @@ func (c *Client) BatchCall(b []BatchElem) error {
 //
 // Note that batch calls may not be executed atomically on the server side.
 func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
+	if c.pool != nil {
+		return c.pool.batchCall(ctx, b)
+	}
 	var (
 		msgs = make([]*jsonrpcMessage, len(b))
 		byID = make(map[string]int, len(b))
@@ -394,6 +395,9 @@ func (c *Client) BatchCallContext(ctx context.Context, b []BatchElem) error {
 
 // Notify sends a notification, i.e. a method call that doesn't expect a response.
 func (c *Client) Notify(ctx context.Context, method string, args ...interface{}) error {
+	if c.pool != nil {
+		return c.pool.notify(ctx, method, args...)
+	}
 	op := new(requestOp)
 	msg, err := c.newMessage(method, args...)
 	if err != nil {
@@ -439,29 +443,72 @@ func (c *Client) Subscribe(ctx context.Context, namespace string, channel interf
 	if chanVal.IsNil() {
 		panic("channel given to Subscribe must not be nil")
 	}
+	if c.pool != nil {
+		return c.pool.subscribe(ctx, namespace, chanVal, nil, args...)
+	}
 	if c.isHTTP {
 		return nil, ErrNotificationsUnsupported
 	}
+	sub := newClientSubscription(c, namespace, chanVal)
+	if err := c.subscribe(ctx, sub, args...); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
 
-	msg, err := c.newMessage(namespace+subscribeMethodSuffix, args...)
-	if err != nil {
+// SubscribeResumable is like Subscribe, but the subscription survives the loss of
+// the underlying connection. Instead of failing, the subscription is re-established
+// after the client's automatic reconnect by calling "<namespace>_subscribe" again,
+// with the arguments returned by resume. The resume function is given the last
+// notification value received on the subscription, or nil if there was none, and
+// can use it to continue where the previous subscription left off.
+//
+// The subscription only fails if re-establishing it does not succeed within a few
+// attempts. Notifications sent by the server while disconnected are lost, unless
+// the server supports replaying them based on the resume arguments.
+func (c *Client) SubscribeResumable(ctx context.Context, namespace string, channel interface{}, resume func(last interface{}) []interface{}, args ...interface{}) (*ClientSubscription, error) {
+	if resume == nil {
+		panic("resume function given to SubscribeResumable must not be nil")
+	}
+	// Check type of channel first.
+	chanVal := reflect.ValueOf(channel)
+	if chanVal.Kind() != reflect.Chan || chanVal.Type().ChanDir()&reflect.SendDir == 0 {
+		panic(fmt.Sprintf("channel argument of SubscribeResumable has type %T, need writable channel", channel))
+	}
+	if chanVal.IsNil() {
+		panic("channel given to SubscribeResumable must not be nil")
+	}
+	if c.pool != nil {
+		return c.pool.subscribe(ctx, namespace, chanVal, resume, args...)
+	}
+	if c.isHTTP {
+		return nil, ErrNotificationsUnsupported
+	}
+	sub := newClientSubscription(c, namespace, chanVal)
+	sub.resume = resume
+	if err := c.subscribe(ctx, sub, args...); err != nil {
 		return nil, err
 	}
+	return sub, nil
+}
+
+// subscribe sends the subscription request of sub and waits for the response.
+// The arrival and validity of the response is signaled on sub.quit.
+func (c *Client) subscribe(ctx context.Context, sub *ClientSubscription, args ...interface{}) error {
+	msg, err := c.newMessage(sub.namespace+subscribeMethodSuffix, args...)
+	if err != nil {
+		return err
+	}
 	op := &requestOp{
 		ids:  []json.RawMessage{msg.ID},
 		resp: make(chan *jsonrpcMessage),
-		sub:  newClientSubscription(c, namespace, chanVal),
+		sub:  sub,
 	}
-
-	// Send the subscription request.
-	// The arrival and validity of the response is signaled on sub.quit.
 	if err := c.send(ctx, op, msg); err != nil {
-		return nil, err
-	}
-	if _, err := op.wait(ctx, c); err != nil {
-		return nil, err
+		return err
 	}
-	return op.sub, nil
+	_, err = op.wait(ctx, c)
+	return err
 }
 
 func (c *Client) newMessage(method string, paramsIn ...interface{}) (*jsonrpcMessage, error) {
@@ -0,0 +1,193 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/websocket"
+)
+
+// ClientOption is a configuration option for the RPC client.
+type ClientOption interface {
+	applyOption(*clientConfig)
+}
+
+type clientConfig struct {
+	httpClient *http.Client
+	httpHeader http.Header
+	wsDialer   *websocket.Dialer
+	tlsConfig  *tls.Config
+}
+
+type optionFunc func(*clientConfig)
+
+func (fn optionFunc) applyOption(cfg *clientConfig) {
+	fn(cfg)
+}
+
+// WithHTTPClient configures the http.Client used by HTTP connections.
+func WithHTTPClient(c *http.Client) ClientOption {
+	return optionFunc(func(cfg *clientConfig) {
+		cfg.httpClient = c
+	})
+}
+
+// WithWebsocketDialer configures the websocket.Dialer used by WebSocket connections.
+func WithWebsocketDialer(dialer websocket.Dialer) ClientOption {
+	return optionFunc(func(cfg *clientConfig) {
+		cfg.wsDialer = &dialer
+	})
+}
+
+// WithHeader adds an HTTP header to all requests, including the WebSocket handshake.
+func WithHeader(key, value string) ClientOption {
+	return optionFunc(func(cfg *clientConfig) {
+		if cfg.httpHeader == nil {
+			cfg.httpHeader = make(http.Header)
+		}
+		cfg.httpHeader.Add(key, value)
+	})
+}
+
+// WithTLSConfig configures the TLS settings of HTTPS and secure WebSocket
+// connections, e.g. to verify the server against a private CA or to present a
+// client certificate. The configuration takes precedence over the TLS settings
+// of a dialer or HTTP client passed as option.
+func WithTLSConfig(config *tls.Config) ClientOption {
+	return optionFunc(func(cfg *clientConfig) {
+		cfg.tlsConfig = config
+	})
+}
+
+// NewTLSClientConfig creates a TLS configuration for connecting to a server
+// that uses certificates of a private CA and/or requires client certificates.
+// All arguments are paths to PEM encoded files and may be empty: if caFile is
+// empty, the system roots are used, and no client certificate is presented
+// unless both certFile and keyFile are given.
+func NewTLSClientConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		blob, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(blob) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("client certificate requires both a certificate and a key file")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// DialOptions creates a new RPC client for the given URL. You can configure the
+// client using the options.
+//
+// The context is used to cancel or time out the initial connection establishment. It does
+// not affect subsequent interactions with the client.
+func DialOptions(ctx context.Context, rawurl string, options ...ClientOption) (*Client, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	cfg := new(clientConfig)
+	for _, opt := range options {
+		opt.applyOption(cfg)
+	}
+
+	var client *Client
+	switch u.Scheme {
+	case "http", "https":
+		client, err = DialHTTPWithClient(rawurl, cfg.httpClientWithTLS())
+		if err == nil {
+			for key, values := range cfg.httpHeader {
+				for _, value := range values {
+					client.SetHeader(key, value)
+				}
+			}
+		}
+	case "ws", "wss":
+		client, err = dialWebsocket(ctx, rawurl, "", cfg.wsDialerWithTLS(), cfg.httpHeader)
+	case "stdio":
+		client, err = DialStdIO(ctx)
+	case "":
+		client, err = DialIPC(ctx, rawurl)
+	default:
+		return nil, fmt.Errorf("no known transport for URL scheme %q", u.Scheme)
+	}
+	return client, err
+}
+
+// httpClientWithTLS returns the HTTP client to use, with the TLS configuration
+// applied to its transport. A client passed as option is never modified.
+func (cfg *clientConfig) httpClientWithTLS() *http.Client {
+	client := cfg.httpClient
+	if client == nil {
+		client = new(http.Client)
+	}
+	if cfg.tlsConfig == nil {
+		return client
+	}
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		// Custom round trippers are responsible for their own TLS settings.
+		return client
+	}
+	transport.TLSClientConfig = cfg.tlsConfig
+	copy := *client
+	copy.Transport = transport
+	return &copy
+}
+
+// wsDialerWithTLS returns the WebSocket dialer to use, with the TLS configuration
+// applied.
+func (cfg *clientConfig) wsDialerWithTLS() websocket.Dialer {
+	dialer := websocket.Dialer{
+		ReadBufferSize:  wsReadBuffer,
+		WriteBufferSize: wsWriteBuffer,
+		WriteBufferPool: wsBufferPool,
+	}
+	if cfg.wsDialer != nil {
+		dialer = *cfg.wsDialer
+	}
+	if cfg.tlsConfig != nil {
+		dialer.TLSClientConfig = cfg.tlsConfig
+	}
+	return dialer
+}
@@ -18,6 +18,8 @@ package rpc
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"math/rand"
@@ -311,6 +313,140 @@ func TestClientSubscribe(t *testing.T) {
 	}
 }
 
+// This test checks that subscriptions failed by the server end with the error sent
+// by the server.
+func TestClientSubscribeFailure(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	client := DialInProc(server)
+	defer client.Close()
+
+	nc := make(chan int)
+	count := 3
+	sub, err := client.Subscribe(context.Background(), "nftest", nc, "failingSubscription", count, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	for i := 0; i < count; i++ {
+		if val := <-nc; val != i {
+			t.Fatalf("value mismatch: got %d, want %d", val, i)
+		}
+	}
+	select {
+	case v := <-nc:
+		t.Fatal("received value after failure:", v)
+	case err := <-sub.Err():
+		rpcErr, ok := err.(Error)
+		if !ok || rpcErr.ErrorCode() != -32000 || err.Error() != "subscription failed" {
+			t.Fatalf("wrong error: %v", err)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("subscription not closed within 1s after failure")
+	}
+}
+
+// This test checks that resumable subscriptions are re-established after the
+// connection drops, continuing from the last received value.
+func TestClientSubscribeResumable(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	httpsrv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer httpsrv.Close()
+
+	client, err := DialWebsocket(context.Background(), "ws:"+strings.TrimPrefix(httpsrv.URL, "http:"), "")
+	if err != nil {
+		t.Fatal("can't dial", err)
+	}
+	defer client.Close()
+
+	var (
+		nc      = make(chan int)
+		count   = 5
+		resumes = make(chan interface{}, 1)
+	)
+	resume := func(last interface{}) []interface{} {
+		resumes <- last
+		return []interface{}{"someSubscription", count, last.(int) + 1}
+	}
+	sub, err := client.SubscribeResumable(context.Background(), "nftest", nc, resume, "someSubscription", count, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	defer sub.Unsubscribe()
+
+	for i := 0; i < count; i++ {
+		if val := <-nc; val != i {
+			t.Fatalf("value mismatch: got %d, want %d", val, i)
+		}
+	}
+	// Drop the connection and ensure the subscription continues where it left off.
+	client.writeConn.(ServerCodec).close()
+
+	select {
+	case last := <-resumes:
+		if last != count-1 {
+			t.Fatalf("resumed with wrong last value: got %v, want %d", last, count-1)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed instead of resuming: %v", err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not resumed within 5s")
+	}
+	for i := count; i < 2*count; i++ {
+		select {
+		case val := <-nc:
+			if val != i {
+				t.Fatalf("value mismatch after resume: got %d, want %d", val, i)
+			}
+		case err := <-sub.Err():
+			t.Fatalf("subscription failed after resuming: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no notification within 5s after resume")
+		}
+	}
+}
+
+// Tests that closing the client ends a resumable subscription waiting to retry
+// its resubscription.
+func TestClientSubscribeResumableClose(t *testing.T) {
+	server := newTestServer()
+	httpsrv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+
+	client, err := DialWebsocket(context.Background(), "ws:"+strings.TrimPrefix(httpsrv.URL, "http:"), "")
+	if err != nil {
+		t.Fatal("can't dial", err)
+	}
+	resume := func(last interface{}) []interface{} {
+		return []interface{}{"someSubscription", 1, 0}
+	}
+	nc := make(chan int)
+	sub, err := client.SubscribeResumable(context.Background(), "nftest", nc, resume, "someSubscription", 1, 0)
+	if err != nil {
+		t.Fatal("can't subscribe:", err)
+	}
+	<-nc
+
+	// Shut down the server, resubscribing fails until the client is closed.
+	httpsrv.Close()
+	server.Stop()
+	client.writeConn.(ServerCodec).close()
+	time.Sleep(3 * resubscribeBackoff)
+
+	start := time.Now()
+	client.Close()
+	select {
+	case err := <-sub.Err():
+		if err != nil {
+			t.Fatalf("wrong error after close: %v", err)
+		}
+	case <-time.After(resubscribeBackoff):
+		t.Fatal("subscription not ended after close")
+	}
+	if elapsed := time.Since(start); elapsed > resubscribeBackoff {
+		t.Fatalf("subscription ended %v after close", elapsed)
+	}
+}
+
 // In this test, the connection drops while Subscribe is waiting for a response.
 func TestClientSubscribeClose(t *testing.T) {
 	server := newTestServer()
@@ -554,6 +690,57 @@ func TestClientSetHeader(t *testing.T) {
 	}
 }
 
+// Tests that DialOptions applies the TLS configuration and headers to HTTP and
+// WebSocket connections.
+func TestDialOptionsTLS(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Stop()
+	var headers = make(chan string, 1)
+	handler := func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case headers <- r.Header.Get("test"):
+			default:
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+	httpsrv := httptest.NewTLSServer(handler(srv))
+	defer httpsrv.Close()
+	wssrv := httptest.NewTLSServer(handler(srv.WebsocketHandler([]string{"*"})))
+	defer wssrv.Close()
+
+	for _, url := range []string{httpsrv.URL, "wss" + strings.TrimPrefix(wssrv.URL, "https")} {
+		// The test certificate isn't trusted by default
+		if client, err := Dial(url); err == nil {
+			_, err = client.SupportedModules()
+			client.Close()
+			if err == nil {
+				t.Fatalf("%s: call with untrusted certificate succeeded", url)
+			}
+		}
+		roots := x509.NewCertPool()
+		roots.AddCert(httpsrv.Certificate())
+		roots.AddCert(wssrv.Certificate())
+
+		select {
+		case <-headers: // drain the header of the failed attempt, if any
+		default:
+		}
+		client, err := DialOptions(context.Background(), url, WithTLSConfig(&tls.Config{RootCAs: roots}), WithHeader("test", "ok"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := client.SupportedModules(); err != nil {
+			t.Fatalf("%s: call failed: %v", url, err)
+		}
+		client.Close()
+		if header := <-headers; header != "ok" {
+			t.Fatalf("%s: wrong header %q", url, header)
+		}
+	}
+}
+
 func TestClientHTTP(t *testing.T) {
 	server := newTestServer()
 	defer server.Stop()
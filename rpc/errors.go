@@ -16,7 +16,10 @@
 
 package rpc
 
-import "fmt"
+import (
+	"fmt"
+	"time"
+)
 
 // HTTPError is returned by client operations when the HTTP status code of the
 // response is not a 2xx status.
@@ -103,6 +106,20 @@ func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
 
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32003 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response too large (limit %d bytes)", e.limit)
+}
+
+type batchTimeoutError struct{}
+
+func (e *batchTimeoutError) ErrorCode() int { return -32002 }
+
+func (e *batchTimeoutError) Error() string { return "batch timed out" }
+
 type CustomError struct {
 	Code            int
 	ValidationError string
@@ -111,3 +128,20 @@ type CustomError struct {
 func (e *CustomError) ErrorCode() int { return e.Code }
 
 func (e *CustomError) Error() string { return e.ValidationError }
+
+// LimitExceededError is returned by a CallLimiter when a call is rejected because
+// the caller ran out of request quota.
+type LimitExceededError struct {
+	Method     string        // method that was rejected
+	RetryAfter time.Duration // time until enough quota is available again
+}
+
+func (e *LimitExceededError) ErrorCode() int { return -32005 }
+
+func (e *LimitExceededError) Error() string {
+	return fmt.Sprintf("rate limit exceeded for %s, retry in %v", e.Method, e.RetryAfter)
+}
+
+func (e *LimitExceededError) ErrorData() interface{} {
+	return map[string]interface{}{"method": e.Method, "retryAfter": e.RetryAfter.Seconds()}
+}
@@ -0,0 +1,542 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	defaultCheckInterval = 5 * time.Second
+	defaultCheckTimeout  = 2 * time.Second
+
+	// fastLatencyFactor defines the set of endpoints calls are balanced across:
+	// all healthy endpoints at most this many times slower than the fastest one.
+	fastLatencyFactor = 2
+)
+
+// ErrNoHealthyEndpoint is returned by failover clients when no endpoint is
+// available to serve a call.
+var ErrNoHealthyEndpoint = errors.New("no healthy RPC endpoint")
+
+// nonIdempotentMethods are not retried on another endpoint after a connection
+// failure, because the first endpoint might have executed them already.
+var nonIdempotentMethods = []string{
+	"eth_sendTransaction",
+	"eth_sendRawTransaction",
+	"eth_submitWork",
+	"eth_submitHashrate",
+	"personal_",
+	"admin_",
+	"miner_",
+}
+
+// statefulMethods refer to state kept by the endpoint, such as installed filters.
+// They are always sent to the same endpoint while it is healthy.
+var statefulMethods = []string{
+	"eth_newFilter",
+	"eth_newBlockFilter",
+	"eth_newPendingTransactionFilter",
+	"eth_getFilterChanges",
+	"eth_getFilterLogs",
+	"eth_uninstallFilter",
+}
+
+// FailoverConfig configures a client distributing calls across several endpoints.
+type FailoverConfig struct {
+	// Endpoints are the URLs of the servers, using any transport supported by
+	// DialOptions. Subscriptions are only sent to non-HTTP endpoints.
+	Endpoints []string
+
+	// CheckInterval is the time between two health checks of the endpoints.
+	CheckInterval time.Duration
+
+	// CheckTimeout is the time an endpoint has to answer a health check.
+	CheckTimeout time.Duration
+
+	// MaxHeadLag is the number of blocks an endpoint may trail behind the highest
+	// head seen on any endpoint and still receive calls. Endpoints supporting
+	// subscriptions report their heads through a newHeads subscription, the heads
+	// of HTTP endpoints are sampled by the health checks.
+	MaxHeadLag uint64
+
+	// Retries is the number of other endpoints an idempotent call is retried on
+	// after a connection failure. Zero tries all endpoints, a negative value
+	// disables retries.
+	Retries int
+}
+
+// failoverEndpoint is a server used by a failover client.
+type failoverEndpoint struct {
+	url string
+
+	// These fields are protected by failoverPool.mu.
+	client  *Client             // nil until the endpoint was dialed successfully
+	heads   *ClientSubscription // newHeads subscription, nil if not running
+	healthy bool                // whether the last health check succeeded
+	head    uint64              // latest block number reported by the endpoint
+	latency time.Duration       // smoothed response time of health checks
+}
+
+// failoverPool routes the calls of a client created by DialFailover.
+type failoverPool struct {
+	config  FailoverConfig
+	options []ClientOption
+
+	mu        sync.Mutex
+	endpoints []*failoverEndpoint
+	highest   uint64            // highest head seen on any endpoint
+	sticky    *failoverEndpoint // endpoint serving stateful methods
+	headers   http.Header       // headers set through Client.SetHeader
+	next      uint32            // round-robin counter
+
+	quit      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// DialFailover creates a client that distributes calls across several endpoints.
+// The returned client can be used like any other, e.g. with ethclient.NewClient.
+//
+// Endpoints are health-checked periodically by requesting their head block number
+// and measuring the response time. Between checks, the heads of endpoints which
+// support subscriptions are tracked through newHeads notifications. Before every
+// call, endpoints trailing the highest head seen so far by more than MaxHeadLag
+// blocks are skipped, and the call is balanced across the fastest of the others.
+// If a call fails because the endpoint can't be reached, idempotent calls are
+// retried on the next endpoint. Filter methods stick to a single endpoint because
+// filters are kept by the server.
+//
+// Subscriptions are pinned to the endpoint they were created on. When its connection
+// fails, the subscription is re-established on another endpoint, as described for
+// SubscribeResumable.
+//
+// The context is used to cancel or time out the initial connection establishment
+// and health check.
+func DialFailover(ctx context.Context, config FailoverConfig, options ...ClientOption) (*Client, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("no endpoints configured")
+	}
+	if config.CheckInterval == 0 {
+		config.CheckInterval = defaultCheckInterval
+	}
+	if config.CheckTimeout == 0 {
+		config.CheckTimeout = defaultCheckTimeout
+	}
+	if config.Retries == 0 {
+		config.Retries = len(config.Endpoints) - 1
+	}
+	p := &failoverPool{
+		config:  config,
+		options: options,
+		headers: make(http.Header),
+		quit:    make(chan struct{}),
+	}
+	for _, url := range config.Endpoints {
+		p.endpoints = append(p.endpoints, &failoverEndpoint{url: url})
+	}
+	p.check(ctx)
+
+	var dialed bool
+	for _, ep := range p.endpoints {
+		dialed = dialed || ep.client != nil
+	}
+	if !dialed {
+		return nil, ErrNoHealthyEndpoint
+	}
+	p.wg.Add(1)
+	go p.loop()
+
+	return &Client{services: new(serviceRegistry), pool: p}, nil
+}
+
+// loop runs the periodic health checks.
+func (p *failoverPool) loop() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(p.config.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.check(context.Background())
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// check dials all endpoints that aren't connected yet and updates the head and
+// latency of all endpoints. Head subscriptions are started on endpoints that
+// support them.
+func (p *failoverPool) check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, ep := range p.endpoints {
+		wg.Add(1)
+		go func(ep *failoverEndpoint) {
+			defer wg.Done()
+			p.checkEndpoint(ctx, ep)
+		}(ep)
+	}
+	wg.Wait()
+}
+
+func (p *failoverPool) checkEndpoint(ctx context.Context, ep *failoverEndpoint) {
+	ctx, cancel := context.WithTimeout(ctx, p.config.CheckTimeout)
+	defer cancel()
+
+	p.mu.Lock()
+	client := ep.client
+	p.mu.Unlock()
+
+	if client == nil {
+		var err error
+		if client, err = DialOptions(ctx, ep.url, p.options...); err != nil {
+			log.Debug("Failed to dial RPC endpoint", "url", ep.url, "err", err)
+			return
+		}
+		p.mu.Lock()
+		for key, values := range p.headers {
+			for _, value := range values {
+				client.SetHeader(key, value)
+			}
+		}
+		ep.client = client
+		p.mu.Unlock()
+	}
+	var (
+		head  hexutil.Uint64
+		start = time.Now()
+		err   = client.CallContext(ctx, &head, "eth_blockNumber")
+		took  = time.Since(start)
+	)
+	p.mu.Lock()
+	if err != nil {
+		if ep.healthy {
+			log.Warn("RPC endpoint failed health check", "url", ep.url, "err", err)
+		}
+		ep.healthy = false
+		p.mu.Unlock()
+		return
+	}
+	if !ep.healthy {
+		log.Debug("RPC endpoint healthy", "url", ep.url, "head", uint64(head), "latency", took)
+	}
+	ep.healthy = true
+	p.setHead(ep, uint64(head))
+	if ep.latency == 0 {
+		ep.latency = took
+	} else {
+		ep.latency = (ep.latency*3 + took) / 4
+	}
+	watch := ep.heads == nil && !client.isHTTP
+	p.mu.Unlock()
+
+	if watch {
+		p.watchHeads(ctx, ep, client)
+	}
+}
+
+// watchHeadsBuffer is the number of head notifications buffered per endpoint.
+const watchHeadsBuffer = 16
+
+// failoverHead is the part of newHeads notifications used by the failover client.
+type failoverHead struct {
+	Number hexutil.Uint64 `json:"number"`
+}
+
+// watchHeads subscribes to the heads of an endpoint, updating its head until the
+// subscription fails. A new subscription is made by the next health check then.
+func (p *failoverPool) watchHeads(ctx context.Context, ep *failoverEndpoint, client *Client) {
+	heads := make(chan *failoverHead, watchHeadsBuffer)
+	sub, err := client.EthSubscribe(ctx, heads, "newHeads")
+	if err != nil {
+		log.Debug("Failed to subscribe to RPC endpoint heads", "url", ep.url, "err", err)
+		return
+	}
+	p.mu.Lock()
+	ep.heads = sub
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case head := <-heads:
+				p.mu.Lock()
+				p.setHead(ep, uint64(head.Number))
+				p.mu.Unlock()
+			case err := <-sub.Err():
+				log.Debug("RPC endpoint head subscription failed", "url", ep.url, "err", err)
+				p.mu.Lock()
+				ep.heads = nil
+				p.mu.Unlock()
+				return
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+}
+
+// setHead updates the head of an endpoint. It is called with p.mu held.
+func (p *failoverPool) setHead(ep *failoverEndpoint, head uint64) {
+	ep.head = head
+	if head > p.highest {
+		p.highest = head
+	}
+}
+
+// markFailed takes an endpoint out of rotation until its next successful health
+// check.
+func (p *failoverPool) markFailed(client *Client, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, ep := range p.endpoints {
+		if ep.client == client && ep.healthy {
+			log.Warn("RPC endpoint failed", "url", ep.url, "err", err)
+			ep.healthy = false
+		}
+	}
+}
+
+// candidates returns the clients of all endpoints eligible for a call, in the
+// order they should be tried.
+func (p *failoverPool) candidates(stateful, subscription bool) []*Client {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var eps []*failoverEndpoint
+	for _, ep := range p.endpoints {
+		switch {
+		case ep.client == nil || !ep.healthy:
+		case ep.head+p.config.MaxHeadLag < p.highest:
+		case subscription && ep.client.isHTTP:
+		default:
+			eps = append(eps, ep)
+		}
+	}
+	if len(eps) == 0 {
+		return nil
+	}
+	sort.SliceStable(eps, func(i, j int) bool { return eps[i].latency < eps[j].latency })
+
+	if stateful {
+		// Keep using the same endpoint while it is eligible.
+		for _, ep := range eps {
+			if ep == p.sticky {
+				return []*Client{ep.client}
+			}
+		}
+		p.sticky = eps[0]
+		return []*Client{eps[0].client}
+	}
+	// Rotate the fast endpoints to spread the load, keeping the slow ones as
+	// fallback.
+	fast := 1
+	for fast < len(eps) && eps[fast].latency <= eps[0].latency*fastLatencyFactor {
+		fast++
+	}
+	clients := make([]*Client, len(eps))
+	offset := int(atomic.AddUint32(&p.next, 1)) % fast
+	for i, ep := range eps {
+		if i < fast {
+			clients[i] = eps[(i+offset)%fast].client
+		} else {
+			clients[i] = ep.client
+		}
+	}
+	return clients
+}
+
+// do runs fn on the eligible endpoints until one of them doesn't fail with a
+// connection error. Retries are only allowed for idempotent calls.
+func (p *failoverPool) do(ctx context.Context, stateful, idempotent bool, fn func(*Client) error) error {
+	clients := p.candidates(stateful, false)
+	if len(clients) == 0 {
+		return ErrNoHealthyEndpoint
+	}
+	attempts := 1
+	if idempotent && p.config.Retries > 0 {
+		attempts += p.config.Retries
+	}
+	var err error
+	for i := 0; i < len(clients) && i < attempts; i++ {
+		if err = fn(clients[i]); err == nil || ctx.Err() != nil || !isEndpointFailure(err) {
+			return err
+		}
+		p.markFailed(clients[i], err)
+	}
+	return err
+}
+
+func (p *failoverPool) call(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	return p.do(ctx, isStateful(method), isIdempotent(method), func(c *Client) error {
+		return c.CallContext(ctx, result, method, args...)
+	})
+}
+
+func (p *failoverPool) batchCall(ctx context.Context, b []BatchElem) error {
+	stateful, idempotent := false, true
+	for _, elem := range b {
+		stateful = stateful || isStateful(elem.Method)
+		idempotent = idempotent && isIdempotent(elem.Method)
+	}
+	return p.do(ctx, stateful, idempotent, func(c *Client) error {
+		for i := range b {
+			b[i].Error = nil
+		}
+		return c.BatchCallContext(ctx, b)
+	})
+}
+
+func (p *failoverPool) notify(ctx context.Context, method string, args ...interface{}) error {
+	return p.do(ctx, isStateful(method), false, func(c *Client) error {
+		return c.Notify(ctx, method, args...)
+	})
+}
+
+// subscribe creates a subscription on the first eligible endpoint. If resume is
+// nil, the subscription is re-established with the original arguments.
+func (p *failoverPool) subscribe(ctx context.Context, namespace string, channel reflect.Value, resume func(interface{}) []interface{}, args ...interface{}) (*ClientSubscription, error) {
+	clients := p.candidates(false, true)
+	if len(clients) == 0 {
+		if p.allHTTP() {
+			return nil, ErrNotificationsUnsupported
+		}
+		return nil, ErrNoHealthyEndpoint
+	}
+	if resume == nil {
+		resume = func(interface{}) []interface{} { return args }
+	}
+	var err error
+	for _, c := range clients {
+		sub := newClientSubscription(c, namespace, channel)
+		sub.resume, sub.reroute = resume, p.reroute
+		if err = c.subscribe(ctx, sub, args...); err == nil {
+			return sub, nil
+		}
+		if ctx.Err() != nil || !isEndpointFailure(err) {
+			return nil, err
+		}
+		p.markFailed(c, err)
+	}
+	return nil, err
+}
+
+// reroute is called when the connection of a subscription fails. It returns the
+// client of the endpoint the subscription should move to.
+func (p *failoverPool) reroute(failed *Client, err error) *Client {
+	p.markFailed(failed, err)
+	if clients := p.candidates(false, true); len(clients) > 0 {
+		return clients[0]
+	}
+	// Nothing else available, let the client reconnect.
+	return failed
+}
+
+func (p *failoverPool) allHTTP() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for _, ep := range p.endpoints {
+		if ep.client == nil || !ep.client.isHTTP {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *failoverPool) setHeader(key, value string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.headers.Set(key, value)
+	for _, ep := range p.endpoints {
+		if ep.client != nil {
+			ep.client.SetHeader(key, value)
+		}
+	}
+}
+
+func (p *failoverPool) close() {
+	p.closeOnce.Do(func() {
+		close(p.quit)
+		p.wg.Wait()
+
+		p.mu.Lock()
+		var clients []*Client
+		for _, ep := range p.endpoints {
+			if ep.client != nil {
+				clients = append(clients, ep.client)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, c := range clients {
+			c.Close()
+		}
+	})
+}
+
+// isEndpointFailure reports whether an error returned by a client call indicates
+// that the endpoint couldn't serve the call, rather than an answer of the server.
+func isEndpointFailure(err error) bool {
+	switch err := err.(type) {
+	case Error, *json.UnmarshalTypeError, *json.SyntaxError:
+		return false
+	case HTTPError:
+		return err.StatusCode >= 500 || err.StatusCode == http.StatusTooManyRequests
+	}
+	return err != ErrNoResult && err != ErrClientQuit
+}
+
+func isIdempotent(method string) bool {
+	return !matchesAny(method, nonIdempotentMethods) && !isStateful(method)
+}
+
+func isStateful(method string) bool {
+	return matchesAny(method, statefulMethods)
+}
+
+// matchesAny reports whether the method equals one of the names, or starts with
+// one of them if the name ends in an underscore.
+func matchesAny(method string, names []string) bool {
+	for _, name := range names {
+		if method == name || (strings.HasSuffix(name, "_") && strings.HasPrefix(method, name)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// failoverTestService reports its head and the index of its endpoint. Heads sent
+// to the heads channel are announced to newHeads subscriptions.
+type failoverTestService struct {
+	index int
+	head  uint64 // accessed atomically
+	heads chan uint64
+}
+
+func (s *failoverTestService) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(atomic.LoadUint64(&s.head))
+}
+
+func (s *failoverTestService) Endpoint() int { return s.index }
+
+func (s *failoverTestService) NewHeads(ctx context.Context) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	sub := notifier.CreateSubscription()
+	go func() {
+		for {
+			select {
+			case head := <-s.heads:
+				atomic.StoreUint64(&s.head, head)
+				notifier.Notify(sub.ID, &failoverHead{Number: hexutil.Uint64(head)})
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+type failoverTestEndpoint struct {
+	server  *Server
+	httpsrv *httptest.Server
+	service *failoverTestService
+}
+
+func (e *failoverTestEndpoint) stop() {
+	e.httpsrv.Close()
+	e.server.Stop()
+}
+
+func newFailoverTestEndpoints(t *testing.T, ws bool, heads ...uint64) ([]*failoverTestEndpoint, []string) {
+	var (
+		endpoints []*failoverTestEndpoint
+		urls      []string
+	)
+	for i, head := range heads {
+		server := NewServer()
+		service := &failoverTestService{index: i, head: head, heads: make(chan uint64)}
+		if err := server.RegisterName("eth", service); err != nil {
+			t.Fatal(err)
+		}
+		if err := server.RegisterName("nftest", new(notificationTestService)); err != nil {
+			t.Fatal(err)
+		}
+		var httpsrv *httptest.Server
+		if ws {
+			httpsrv = httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+			urls = append(urls, "ws"+strings.TrimPrefix(httpsrv.URL, "http"))
+		} else {
+			httpsrv = httptest.NewServer(server)
+			urls = append(urls, httpsrv.URL)
+		}
+		endpoints = append(endpoints, &failoverTestEndpoint{server, httpsrv, service})
+	}
+	return endpoints, urls
+}
+
+// Tests that calls are only routed to healthy endpoints at the highest head and
+// are retried on other endpoints after connection failures.
+func TestFailoverRouting(t *testing.T) {
+	endpoints, urls := newFailoverTestEndpoints(t, false, 10, 10, 8)
+	defer func() {
+		for _, e := range endpoints {
+			e.stop()
+		}
+	}()
+	client, err := DialFailover(context.Background(), FailoverConfig{Endpoints: urls, CheckInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	served := func() map[int]int {
+		counts := make(map[int]int)
+		for i := 0; i < 20; i++ {
+			var index int
+			if err := client.Call(&index, "eth_endpoint"); err != nil {
+				t.Fatalf("call %d failed: %v", i, err)
+			}
+			counts[index]++
+		}
+		return counts
+	}
+	if counts := served(); counts[2] > 0 {
+		t.Fatalf("lagging endpoint served calls: %v", counts)
+	}
+	// Kill one endpoint, calls must be retried on the other one
+	endpoints[0].stop()
+	if counts := served(); counts[1] != 20 {
+		t.Fatalf("calls not failed over: %v", counts)
+	}
+	// Batches fail over as well
+	batch := []BatchElem{{Method: "eth_endpoint", Result: new(int)}, {Method: "eth_blockNumber", Result: new(hexutil.Uint64)}}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if batch[0].Error != nil || *batch[0].Result.(*int) != 1 {
+		t.Fatalf("wrong batch result: %v %v", batch[0].Error, *batch[0].Result.(*int))
+	}
+	// Only the lagging endpoint is left, which must not be used
+	endpoints[1].stop()
+	var index int
+	if err := client.Call(&index, "eth_endpoint"); err == nil {
+		t.Fatalf("call served by endpoint %d", index)
+	}
+	if err := client.Call(&index, "eth_endpoint"); err != ErrNoHealthyEndpoint {
+		t.Fatalf("wrong error with no healthy endpoints: %v", err)
+	}
+	// Server errors don't take endpoints out of rotation
+	endpoints, urls = newFailoverTestEndpoints(t, false, 1)
+	defer endpoints[0].stop()
+	client2, err := DialFailover(context.Background(), FailoverConfig{Endpoints: urls, CheckInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client2.Close()
+	if err := client2.Call(nil, "eth_unknown"); err == nil || err == ErrNoHealthyEndpoint {
+		t.Fatalf("wrong error for unknown method: %v", err)
+	}
+	if err := client2.Call(&index, "eth_endpoint"); err != nil {
+		t.Fatalf("call failed after server error: %v", err)
+	}
+}
+
+// Tests that calls are not routed to endpoints falling behind between health
+// checks, if their heads are announced through subscriptions.
+func TestFailoverHeadTracking(t *testing.T) {
+	endpoints, urls := newFailoverTestEndpoints(t, true, 10, 10)
+	defer func() {
+		for _, e := range endpoints {
+			e.stop()
+		}
+	}()
+	client, err := DialFailover(context.Background(), FailoverConfig{Endpoints: urls, CheckInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// setHead announces a new head on an endpoint and waits for the client to
+	// process it.
+	setHead := func(index int, head uint64) {
+		endpoints[index].service.heads <- head
+		for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+			client.pool.mu.Lock()
+			seen := client.pool.endpoints[index].head
+			client.pool.mu.Unlock()
+			if seen == head {
+				return
+			}
+			if time.Since(start) > 5*time.Second {
+				t.Fatalf("head %d of endpoint %d not tracked", head, index)
+			}
+		}
+	}
+	served := func() map[int]int {
+		counts := make(map[int]int)
+		for i := 0; i < 20; i++ {
+			var index int
+			if err := client.Call(&index, "eth_endpoint"); err != nil {
+				t.Fatalf("call %d failed: %v", i, err)
+			}
+			counts[index]++
+		}
+		return counts
+	}
+	setHead(0, 11)
+	if counts := served(); counts[0] != 20 {
+		t.Fatalf("lagging endpoint served calls: %v", counts)
+	}
+	setHead(1, 12)
+	if counts := served(); counts[1] != 20 {
+		t.Fatalf("lagging endpoint served calls: %v", counts)
+	}
+}
+
+func TestFailoverMethodClasses(t *testing.T) {
+	tests := []struct {
+		method               string
+		idempotent, stateful bool
+	}{
+		{"eth_call", true, false},
+		{"eth_getBlockByNumber", true, false},
+		{"eth_sendRawTransaction", false, false},
+		{"personal_unlockAccount", false, false},
+		{"eth_newFilter", false, true},
+		{"eth_getFilterChanges", false, true},
+	}
+	for _, test := range tests {
+		if have := isIdempotent(test.method); have != test.idempotent {
+			t.Errorf("%s: idempotent %v, want %v", test.method, have, test.idempotent)
+		}
+		if have := isStateful(test.method); have != test.stateful {
+			t.Errorf("%s: stateful %v, want %v", test.method, have, test.stateful)
+		}
+	}
+}
+
+// Tests that subscriptions move to another endpoint when their connection fails.
+func TestFailoverSubscription(t *testing.T) {
+	endpoints, urls := newFailoverTestEndpoints(t, true, 10, 10)
+	defer func() {
+		for _, e := range endpoints {
+			e.stop()
+		}
+	}()
+	client, err := DialFailover(context.Background(), FailoverConfig{Endpoints: urls, CheckInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ch := make(chan int)
+	sub, err := client.Subscribe(context.Background(), "nftest", ch, "someSubscription", 3, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+
+	receive := func() {
+		for i := 0; i < 3; i++ {
+			select {
+			case v := <-ch:
+				if v != i {
+					t.Fatalf("wrong value %d, want %d", v, i)
+				}
+			case err := <-sub.Err():
+				t.Fatalf("subscription failed: %v", err)
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for notification")
+			}
+		}
+	}
+	receive()
+
+	// Find and kill the endpoint of the subscription
+	pinned := -1
+	client.pool.mu.Lock()
+	for i, ep := range client.pool.endpoints {
+		if ep.client == sub.client {
+			pinned = i
+		}
+	}
+	client.pool.mu.Unlock()
+	if pinned < 0 {
+		t.Fatal("subscription not pinned to an endpoint")
+	}
+	endpoints[pinned].stop()
+
+	// The subscription must resume on the other endpoint
+	receive()
+	if sub.client == client.pool.endpoints[pinned].client {
+		t.Fatal("subscription didn't move")
+	}
+}
@@ -19,6 +19,7 @@ package rpc
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -61,17 +62,27 @@ type handler struct {
 	conn           jsonWriter                     // where responses will be sent
 	log            log.Logger
 	allowSubscribe bool
+	cfg            handlerConfig
 
 	subLock    sync.Mutex
 	serverSubs map[ID]*Subscription
 }
 
+// handlerConfig holds the limits a handler applies when serving requests.
+type handlerConfig struct {
+	limiter           *callLimiterRef // consulted before executing calls, if set
+	batchItemLimit    int             // maximum number of requests in a batch, zero means unlimited
+	responseSizeLimit int             // maximum size of responses in bytes, zero means unlimited
+	batchConcurrency  int             // number of batch calls executed in parallel, at most one if zero
+	batchTimeout      time.Duration   // maximum time to process a batch, zero means unlimited
+}
+
 type callProc struct {
 	ctx       context.Context
 	notifiers []*Notifier
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, cfg handlerConfig) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	h := &handler{
 		reg:            reg,
@@ -84,6 +95,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 		allowSubscribe: true,
 		serverSubs:     make(map[ID]*Subscription),
 		log:            log.Root(),
+		cfg:            cfg,
 	}
 	if conn.remoteAddr() != "" {
 		h.log = h.log.New("conn", conn.remoteAddr())
@@ -101,6 +113,23 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	// Reject batches exceeding the item limit, answering every call with an error:
+	if limit := h.cfg.batchItemLimit; limit > 0 && len(msgs) > limit {
+		h.startCallProc(func(cp *callProc) {
+			err := &invalidRequestError{fmt.Sprintf("batch too large (%d>%d)", len(msgs), limit)}
+			answers := make([]*jsonrpcMessage, 0, len(msgs))
+			for _, msg := range msgs {
+				if msg.hasValidID() {
+					answers = append(answers, msg.errorResponse(err))
+				}
+			}
+			if len(answers) == 0 {
+				answers = append(answers, errorMessage(err))
+			}
+			h.conn.writeJSON(cp.ctx, answers)
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -114,11 +143,11 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	}
 	// Process calls on a goroutine because they may block indefinitely:
 	h.startCallProc(func(cp *callProc) {
-		answers := make([]*jsonrpcMessage, 0, len(msgs))
-		for _, msg := range calls {
-			if answer := h.handleCallMsg(cp, msg); answer != nil {
-				answers = append(answers, answer)
-			}
+		var answers []*jsonrpcMessage
+		if h.cfg.batchConcurrency > 1 || h.cfg.batchTimeout > 0 {
+			answers = h.runBatchConcurrently(cp, calls)
+		} else {
+			answers = h.runBatchSequentially(cp, calls)
 		}
 		h.addSubscriptions(cp.notifiers)
 		if len(answers) > 0 {
@@ -130,6 +159,146 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// runBatchSequentially executes the calls of a batch one after the other and
+// returns the answers in batch order.
+func (h *handler) runBatchSequentially(cp *callProc, calls []*jsonrpcMessage) []*jsonrpcMessage {
+	var (
+		answers = make([]*jsonrpcMessage, 0, len(calls))
+		limit   = h.cfg.responseSizeLimit
+		size    int
+	)
+	for _, msg := range calls {
+		// Once the response size limit is reached, the remaining calls are
+		// not executed but answered with an error.
+		if limit > 0 && size > limit {
+			if msg.isCall() {
+				answers = append(answers, msg.errorResponse(&responseTooLargeError{limit}))
+			}
+			continue
+		}
+		if answer := h.handleCallMsg(cp, msg); answer != nil {
+			size += answer.size()
+			if limit > 0 && size > limit {
+				answer = msg.errorResponse(&responseTooLargeError{limit})
+			}
+			answers = append(answers, answer)
+		}
+	}
+	return answers
+}
+
+// runBatchConcurrently executes the calls of a batch on a bounded number of worker
+// goroutines and returns the answers in batch order. Subscription calls are not
+// handed to the workers, they are processed in order on the calling goroutine to
+// keep the notifiers of the batch in a single place.
+//
+// If the batch timeout expires, calls that haven't finished by then are answered
+// with an error. Their context is canceled, their eventual results are discarded.
+func (h *handler) runBatchConcurrently(cp *callProc, calls []*jsonrpcMessage) []*jsonrpcMessage {
+	ctx, cancel := context.WithCancel(cp.ctx)
+	if h.cfg.batchTimeout > 0 {
+		ctx, cancel = context.WithTimeout(cp.ctx, h.cfg.batchTimeout)
+	}
+	defer cancel()
+
+	var (
+		lock     sync.Mutex
+		results  = make([]*jsonrpcMessage, len(calls))
+		finished = make([]bool, len(calls))
+		expired  bool
+	)
+	deliver := func(i int, answer *jsonrpcMessage) {
+		lock.Lock()
+		defer lock.Unlock()
+		if !expired {
+			results[i], finished[i] = answer, true
+		}
+	}
+	// Queue up the plain calls and start the workers on them
+	queue := make(chan int, len(calls))
+	for i, msg := range calls {
+		if !msg.isSubscribe() && !msg.isUnsubscribe() {
+			queue <- i
+		}
+	}
+	close(queue)
+
+	workers := h.cfg.batchConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(queue) {
+		workers = len(queue)
+	}
+	var pending sync.WaitGroup
+	pending.Add(workers)
+	for w := 0; w < workers; w++ {
+		h.callWG.Add(1)
+		go func() {
+			defer h.callWG.Done()
+			defer pending.Done()
+
+			wcp := &callProc{ctx: ctx}
+			for i := range queue {
+				if ctx.Err() != nil {
+					return
+				}
+				answer := h.handleCallMsg(wcp, calls[i])
+				// Calls returning because of the timeout are answered with
+				// the timeout error.
+				if ctx.Err() != nil {
+					return
+				}
+				deliver(i, answer)
+			}
+		}()
+	}
+	// Process the subscription calls while the workers are busy
+	for i, msg := range calls {
+		if msg.isSubscribe() || msg.isUnsubscribe() {
+			deliver(i, h.handleCallMsg(cp, msg))
+		}
+	}
+	done := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+	// Assemble the answers, replacing the missing ones and those exceeding the
+	// response size limit with errors.
+	lock.Lock()
+	defer lock.Unlock()
+	expired = true
+
+	var (
+		answers = make([]*jsonrpcMessage, 0, len(calls))
+		limit   = h.cfg.responseSizeLimit
+		size    int
+	)
+	for i, msg := range calls {
+		answer := results[i]
+		if !finished[i] {
+			if !msg.isCall() {
+				continue
+			}
+			answer = msg.errorResponse(&batchTimeoutError{})
+		}
+		if answer == nil {
+			continue
+		}
+		size += answer.size()
+		if limit > 0 && size > limit {
+			answer = msg.errorResponse(&responseTooLargeError{limit})
+		}
+		answers = append(answers, answer)
+	}
+	return answers
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(msg *jsonrpcMessage) {
 	if ok := h.handleImmediate(msg); ok {
@@ -254,8 +423,13 @@ func (h *handler) handleSubscriptionResult(msg *jsonrpcMessage) {
 		h.log.Debug("Dropping invalid subscription message")
 		return
 	}
-	if h.clientSubs[result.ID] != nil {
-		h.clientSubs[result.ID].deliver(result.Result)
+	if sub := h.clientSubs[result.ID]; sub != nil {
+		if result.Error != nil {
+			delete(h.clientSubs, result.ID)
+			sub.close(result.Error)
+			return
+		}
+		sub.deliver(result.Result)
 	}
 }
 
@@ -280,9 +454,13 @@ func (h *handler) handleResponse(msg *jsonrpcMessage) {
 		op.err = msg.Error
 		return
 	}
-	if op.err = json.Unmarshal(msg.Result, &op.sub.subid); op.err == nil {
-		go op.sub.run()
-		h.clientSubs[op.sub.subid] = op.sub
+	var subid string
+	if op.err = json.Unmarshal(msg.Result, &subid); op.err == nil {
+		// Resumed subscriptions already have their forwarding loop running.
+		if op.sub.established(subid) {
+			go op.sub.run()
+		}
+		h.clientSubs[subid] = op.sub
 	}
 }
 
@@ -317,6 +495,11 @@ func (h *handler) handleCallMsg(ctx *callProc, msg *jsonrpcMessage) *jsonrpcMess
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage) *jsonrpcMessage {
+	if limiter := h.cfg.limiter.get(); limiter != nil && !msg.isUnsubscribe() {
+		if err := limiter.AllowCall(msg.Method); err != nil {
+			return msg.errorResponse(err)
+		}
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg)
 	}
@@ -390,7 +573,11 @@ func (h *handler) runMethod(ctx context.Context, msg *jsonrpcMessage, callb *cal
 	if err != nil {
 		return msg.errorResponse(err)
 	}
-	return msg.response(result)
+	answer := msg.response(result, h.cfg.responseSizeLimit)
+	if limit := h.cfg.responseSizeLimit; limit > 0 && answer.size() > limit {
+		return msg.errorResponse(&responseTooLargeError{limit})
+	}
+	return answer
 }
 
 // unsubscribe is the callback function for all *_unsubscribe calls.
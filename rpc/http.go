@@ -111,6 +111,10 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 
 // DialHTTPWithClient creates a new RPC client that connects to an RPC server over HTTP
 // using the provided HTTP Client.
+//
+// To connect to servers using a private CA or requiring client certificates, set
+// the TLSClientConfig of the client's transport, e.g. to a configuration created
+// by NewTLSClientConfig, or use DialOptions with WithTLSConfig.
 func DialHTTPWithClient(endpoint string, client *http.Client) (*Client, error) {
 	// Sanity check URL so we don't end up with a client that will fail every request.
 	_, err := url.Parse(endpoint)
@@ -44,6 +44,7 @@ var null = json.RawMessage("null")
 type subscriptionResult struct {
 	ID     string          `json:"subscription"`
 	Result json.RawMessage `json:"result,omitempty"`
+	Error  *jsonError      `json:"error,omitempty"` // set in the final notification of a failed subscription
 }
 
 // A value of this type can a JSON-RPC request, notification, successful response or
@@ -55,6 +56,8 @@ type jsonrpcMessage struct {
 	Params  json.RawMessage `json:"params,omitempty"`
 	Error   *jsonError      `json:"error,omitempty"`
 	Result  json.RawMessage `json:"result,omitempty"`
+
+	stream *streamedResult // result encoded while writing, set instead of Result
 }
 
 func (msg *jsonrpcMessage) isNotification() bool {
@@ -97,13 +100,27 @@ func (msg *jsonrpcMessage) errorResponse(err error) *jsonrpcMessage {
 	return resp
 }
 
-func (msg *jsonrpcMessage) response(result interface{}) *jsonrpcMessage {
-	enc, err := json.Marshal(result)
+func (msg *jsonrpcMessage) response(result interface{}, limit int) *jsonrpcMessage {
+	enc, stream, err := encodeResult(result, limit)
 	if err != nil {
 		// TODO: wrap with 'internal server error'
 		return msg.errorResponse(err)
 	}
-	return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: enc}
+	return &jsonrpcMessage{Version: vsn, ID: msg.ID, Result: enc, stream: stream}
+}
+
+// size returns the approximate encoded size of the message's result or error.
+// Streamed results only count their buffered part, they only occur without a
+// response size limit.
+func (msg *jsonrpcMessage) size() int {
+	switch {
+	case msg.stream != nil:
+		return len(msg.stream.head)
+	case msg.Error != nil:
+		return len(msg.Error.Message)
+	default:
+		return len(msg.Result)
+	}
 }
 
 func errorMessage(err error) *jsonrpcMessage {
@@ -165,11 +182,12 @@ type ConnRemoteAddr interface {
 // support for parsing arguments and serializing (result) objects.
 type jsonCodec struct {
 	remote  string
-	closer  sync.Once                 // close closed channel once
-	closeCh chan interface{}          // closed on Close
-	decode  func(v interface{}) error // decoder to allow multiple transports
-	encMu   sync.Mutex                // guards the encoder
-	encode  func(v interface{}) error // encoder to allow multiple transports
+	closer  sync.Once                      // close closed channel once
+	closeCh chan interface{}               // closed on Close
+	decode  func(v interface{}) error      // decoder to allow multiple transports
+	encMu   sync.Mutex                     // guards the encoder
+	encode  func(v interface{}) error      // encoder to allow multiple transports
+	stream  func() (io.WriteCloser, error) // opens a writer for streamed responses, optional
 	conn    deadlineCloser
 }
 
@@ -195,7 +213,9 @@ func NewCodec(conn Conn) ServerCodec {
 	enc := json.NewEncoder(conn)
 	dec := json.NewDecoder(conn)
 	dec.UseNumber()
-	return NewFuncCodec(conn, enc.Encode, dec.Decode)
+	codec := NewFuncCodec(conn, enc.Encode, dec.Decode).(*jsonCodec)
+	codec.stream = func() (io.WriteCloser, error) { return nopWriteCloser{conn}, nil }
+	return codec
 }
 
 func (c *jsonCodec) peerInfo() PeerInfo {
@@ -234,9 +254,32 @@ func (c *jsonCodec) writeJSON(ctx context.Context, v interface{}) error {
 		deadline = time.Now().Add(defaultWriteTimeout)
 	}
 	c.conn.SetWriteDeadline(deadline)
+	if hasStreamedResult(v) {
+		return c.writeStreamed(v)
+	}
 	return c.encode(v)
 }
 
+// writeStreamed writes messages containing streamed results. If the transport can't
+// stream, the results are encoded in memory and sent through the regular encoder.
+func (c *jsonCodec) writeStreamed(v interface{}) error {
+	if c.stream == nil {
+		if err := materializeResults(v); err != nil {
+			return err
+		}
+		return c.encode(v)
+	}
+	w, err := c.stream()
+	if err != nil {
+		return err
+	}
+	if err := writeMessages(w, v); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
 func (c *jsonCodec) close() {
 	c.closer.Do(func() {
 		close(c.closeCh)
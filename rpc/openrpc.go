@@ -0,0 +1,380 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// openRPCVersion is the version of the OpenRPC specification the service
+// discovery document conforms to.
+const openRPCVersion = "1.2.6"
+
+// OpenRPCDocument is an OpenRPC service description, as returned by rpc_discover.
+// See https://spec.open-rpc.org for the specification.
+type OpenRPCDocument struct {
+	OpenRPC    string            `json:"openrpc"`
+	Info       OpenRPCInfo       `json:"info"`
+	Methods    []*OpenRPCMethod  `json:"methods"`
+	Components OpenRPCComponents `json:"components"`
+}
+
+// OpenRPCInfo contains the metadata of an OpenRPC document.
+type OpenRPCInfo struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// OpenRPCMethod describes a single RPC method.
+type OpenRPCMethod struct {
+	Name   string                      `json:"name"`
+	Params []*OpenRPCContentDescriptor `json:"params"`
+	Result *OpenRPCContentDescriptor   `json:"result"`
+}
+
+// OpenRPCContentDescriptor describes a method parameter or result.
+type OpenRPCContentDescriptor struct {
+	Name     string      `json:"name"`
+	Required bool        `json:"required,omitempty"`
+	Schema   *JSONSchema `json:"schema"`
+}
+
+// OpenRPCComponents holds the schemas of named types, referenced from methods.
+type OpenRPCComponents struct {
+	Schemas map[string]*JSONSchema `json:"schemas"`
+}
+
+// JSONSchema is the subset of JSON Schema used to describe RPC values.
+type JSONSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+}
+
+// Patterns of the hex encodings used throughout the RPC APIs.
+const (
+	quantityPattern = "^0x(0|[1-9a-f][0-9a-f]*)$"
+	bytesPattern    = "^0x([0-9a-f][0-9a-f])*$"
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	// blockNumberSchema describes a block number or block tag.
+	blockNumberSchema = &JSONSchema{
+		Title: "BlockNumber",
+		OneOf: []*JSONSchema{
+			{Type: "string", Pattern: quantityPattern},
+			{Type: "string", Enum: []string{"earliest", "latest", "pending"}},
+		},
+	}
+
+	// knownSchemas are the schemas of types with custom JSON encodings.
+	knownSchemas = map[reflect.Type]*JSONSchema{
+		reflect.TypeOf(hexutil.Big{}):     {Title: "Quantity", Type: "string", Pattern: quantityPattern},
+		reflect.TypeOf(hexutil.Uint64(0)): {Title: "Quantity", Type: "string", Pattern: quantityPattern},
+		reflect.TypeOf(hexutil.Uint(0)):   {Title: "Quantity", Type: "string", Pattern: quantityPattern},
+		reflect.TypeOf(hexutil.Bytes{}):   {Title: "Bytes", Type: "string", Pattern: bytesPattern},
+		reflect.TypeOf(common.Hash{}):     {Title: "Hash", Type: "string", Pattern: "^0x[0-9a-f]{64}$"},
+		reflect.TypeOf(common.Address{}):  {Title: "Address", Type: "string", Pattern: "^0x[0-9a-fA-F]{40}$"},
+		reflect.TypeOf(big.Int{}):         {Type: "integer"},
+		reflect.TypeOf(json.RawMessage{}): {},
+		reflect.TypeOf(ID("")):            {Title: "SubscriptionID", Type: "string"},
+		reflect.TypeOf(BlockNumber(0)):    blockNumberSchema,
+		reflect.TypeOf(BlockNumberOrHash{}): {
+			Title: "BlockNumberOrHash",
+			OneOf: []*JSONSchema{
+				blockNumberSchema,
+				{Type: "string", Pattern: "^0x[0-9a-f]{64}$"},
+				{
+					Type: "object",
+					Properties: map[string]*JSONSchema{
+						"blockNumber":      {Type: "string", Pattern: quantityPattern},
+						"blockHash":        {Type: "string", Pattern: "^0x[0-9a-f]{64}$"},
+						"requireCanonical": {Type: "boolean"},
+					},
+				},
+			},
+		},
+	}
+)
+
+// Discover returns an OpenRPC document describing all methods available on
+// the server.
+func (s *RPCService) Discover() *OpenRPCDocument {
+	s.server.services.mu.Lock()
+	defer s.server.services.mu.Unlock()
+
+	return newOpenRPCDocument(s.server.services.services)
+}
+
+// newOpenRPCDocument assembles the OpenRPC document of the given services.
+func newOpenRPCDocument(services map[string]service) *OpenRPCDocument {
+	var (
+		b   = newSchemaBuilder()
+		doc = &OpenRPCDocument{
+			OpenRPC: openRPCVersion,
+			Info:    OpenRPCInfo{Title: "Ethereum JSON-RPC API", Version: "1.0"},
+		}
+	)
+	// Iterate in a stable order to keep the component names deterministic
+	for _, namespace := range sortedKeys(services) {
+		svc := services[namespace]
+		for _, name := range sortedKeys(svc.callbacks) {
+			doc.Methods = append(doc.Methods, b.method(namespace+serviceMethodSeparator+name, svc.callbacks[name]))
+		}
+		if len(svc.subscriptions) > 0 {
+			doc.Methods = append(doc.Methods, b.subscribeMethod(namespace, svc.subscriptions), b.unsubscribeMethod(namespace))
+		}
+	}
+	sort.Slice(doc.Methods, func(i, j int) bool { return doc.Methods[i].Name < doc.Methods[j].Name })
+	doc.Components.Schemas = b.defs
+	return doc
+}
+
+// sortedKeys returns the keys of a service or callback map in sorted order.
+func sortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+	names := make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key.String()
+	}
+	sort.Strings(names)
+	return names
+}
+
+// schemaBuilder derives JSON schemas from Go types, collecting the schemas of
+// named struct types as reusable components.
+type schemaBuilder struct {
+	defs  map[string]*JSONSchema  // Schemas of named types by component name
+	names map[reflect.Type]string // Component names of the already described types
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{
+		defs:  make(map[string]*JSONSchema),
+		names: make(map[reflect.Type]string),
+	}
+}
+
+// method describes an RPC method callback.
+func (b *schemaBuilder) method(name string, cb *callback) *OpenRPCMethod {
+	m := &OpenRPCMethod{Name: name, Params: b.params(cb.argTypes)}
+
+	result := &OpenRPCContentDescriptor{Name: "result", Schema: &JSONSchema{Type: "null"}}
+	if fntype := cb.fn.Type(); fntype.NumOut() > 0 && cb.errPos != 0 {
+		result.Schema = b.schema(fntype.Out(0))
+	}
+	m.Result = result
+	return m
+}
+
+// subscribeMethod describes the subscription method of a namespace. The actual
+// subscription is selected by the first parameter.
+func (b *schemaBuilder) subscribeMethod(namespace string, subscriptions map[string]*callback) *OpenRPCMethod {
+	names := sortedKeys(subscriptions)
+	return &OpenRPCMethod{
+		Name: namespace + subscribeMethodSuffix,
+		Params: []*OpenRPCContentDescriptor{
+			{Name: "subscription", Required: true, Schema: &JSONSchema{Type: "string", Enum: names}},
+			{Name: "params", Schema: &JSONSchema{}},
+		},
+		Result: &OpenRPCContentDescriptor{Name: "subscriptionID", Schema: b.schema(reflect.TypeOf(ID("")))},
+	}
+}
+
+// unsubscribeMethod describes the method cancelling subscriptions of a namespace.
+func (b *schemaBuilder) unsubscribeMethod(namespace string) *OpenRPCMethod {
+	return &OpenRPCMethod{
+		Name:   namespace + unsubscribeMethodSuffix,
+		Params: []*OpenRPCContentDescriptor{{Name: "subscriptionID", Required: true, Schema: b.schema(reflect.TypeOf(ID("")))}},
+		Result: &OpenRPCContentDescriptor{Name: "result", Schema: &JSONSchema{Type: "boolean"}},
+	}
+}
+
+// params describes the parameters of a method. Trailing pointer parameters are
+// optional, matching the argument parsing of the server.
+func (b *schemaBuilder) params(types []reflect.Type) []*OpenRPCContentDescriptor {
+	var (
+		params   = make([]*OpenRPCContentDescriptor, len(types))
+		names    = make(map[string]int)
+		optional = true
+	)
+	for i := len(types) - 1; i >= 0; i-- {
+		optional = optional && types[i].Kind() == reflect.Ptr
+		params[i] = &OpenRPCContentDescriptor{
+			Required: !optional,
+			Schema:   b.schema(types[i]),
+		}
+	}
+	for i, typ := range types {
+		name := paramName(typ, i)
+		if names[name]++; names[name] > 1 {
+			name = fmt.Sprintf("%s%d", name, names[name])
+		}
+		params[i].Name = name
+	}
+	return params
+}
+
+// paramName derives a descriptive parameter name from its type, since Go does
+// not retain the names of function parameters.
+func paramName(typ reflect.Type, index int) string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	suffix := ""
+	if typ.Kind() == reflect.Slice && typ.Name() == "" {
+		typ, suffix = typ.Elem(), "s"
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	if typ.Name() == "" || typ.PkgPath() == "" {
+		return fmt.Sprintf("arg%d", index)
+	}
+	name := []rune(typ.Name())
+	name[0] = unicode.ToLower(name[0])
+	return string(name) + suffix
+}
+
+// schema returns the JSON schema of values of the given type.
+func (b *schemaBuilder) schema(typ reflect.Type) *JSONSchema {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if schema, ok := knownSchemas[typ]; ok {
+		return schema
+	}
+	// Types with custom encodings can't be described by reflection
+	ptr := reflect.PtrTo(typ)
+	if typ.Implements(jsonMarshalerType) || ptr.Implements(jsonMarshalerType) {
+		return &JSONSchema{Title: typ.Name()}
+	}
+	if typ.Implements(textMarshalerType) || ptr.Implements(textMarshalerType) {
+		return &JSONSchema{Title: typ.Name(), Type: "string"}
+	}
+	switch typ.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string"} // base64 encoded by encoding/json
+		}
+		return &JSONSchema{Type: "array", Items: b.schema(typ.Elem())}
+	case reflect.Array:
+		return &JSONSchema{Type: "array", Items: b.schema(typ.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: b.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			return b.structSchema(typ)
+		}
+		return b.namedSchema(typ)
+	default:
+		return &JSONSchema{} // interfaces, channels and functions: any value
+	}
+}
+
+// namedSchema returns a reference to the component describing a named struct
+// type, creating the component if needed.
+func (b *schemaBuilder) namedSchema(typ reflect.Type) *JSONSchema {
+	name, ok := b.names[typ]
+	if !ok {
+		name = path.Base(typ.PkgPath()) + "." + typ.Name()
+		for i := 2; b.defs[name] != nil; i++ {
+			name = fmt.Sprintf("%s.%s%d", path.Base(typ.PkgPath()), typ.Name(), i)
+		}
+		// Register the name before describing the fields to support recursion
+		b.names[typ] = name
+		b.defs[name] = &JSONSchema{}
+		schema := b.structSchema(typ)
+		schema.Title = typ.Name()
+		b.defs[name] = schema
+	}
+	return &JSONSchema{Ref: "#/components/schemas/" + name}
+}
+
+// structSchema describes the fields of a struct, following the field naming and
+// embedding rules of encoding/json.
+func (b *schemaBuilder) structSchema(typ reflect.Type) *JSONSchema {
+	schema := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema)}
+	b.addFields(schema, typ)
+	sort.Strings(schema.Required)
+	return schema
+}
+
+func (b *schemaBuilder) addFields(schema *JSONSchema, typ reflect.Type) {
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+		// Inline the fields of untagged embedded structs
+		if field.Anonymous && name == "" {
+			ftyp := field.Type
+			if ftyp.Kind() == reflect.Ptr {
+				ftyp = ftyp.Elem()
+			}
+			if ftyp.Kind() == reflect.Struct {
+				b.addFields(schema, ftyp)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue // unexported
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = b.schema(field.Type)
+		if field.Type.Kind() != reflect.Ptr && !strings.Contains(opts, "omitempty") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+type discoverTestService struct{}
+
+type discoverTestArgs struct {
+	Value    hexutil.Uint64      `json:"value"`
+	Data     *hexutil.Bytes      `json:"data"`
+	Note     string              `json:"note,omitempty"`
+	Children []*discoverTestArgs `json:"children"`
+}
+
+func (s *discoverTestService) Get(number BlockNumber, args *discoverTestArgs, full *bool) (*discoverTestArgs, error) {
+	return nil, nil
+}
+
+func TestDiscover(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	if err := server.RegisterName("disc", new(discoverTestService)); err != nil {
+		t.Fatal(err)
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var doc OpenRPCDocument
+	if err := client.Call(&doc, "rpc_discover"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.OpenRPC != openRPCVersion {
+		t.Errorf("wrong OpenRPC version: %q", doc.OpenRPC)
+	}
+	methods := make(map[string]*OpenRPCMethod)
+	for _, m := range doc.Methods {
+		methods[m.Name] = m
+	}
+	for _, name := range []string{"disc_get", "rpc_discover", "rpc_modules", "test_echo", "nftest_subscribe", "nftest_unsubscribe"} {
+		if methods[name] == nil {
+			t.Errorf("method %s missing", name)
+		}
+	}
+	if methods["test_subscription"] != nil {
+		t.Errorf("subscription listed as method")
+	}
+	// Check the parameters and result of a method
+	get := methods["disc_get"]
+	if get == nil {
+		t.FailNow()
+	}
+	var (
+		names    []string
+		required []bool
+	)
+	for _, param := range get.Params {
+		names, required = append(names, param.Name), append(required, param.Required)
+	}
+	if want := []string{"blockNumber", "discoverTestArgs", "arg2"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("wrong param names: have %v, want %v", names, want)
+	}
+	if want := []bool{true, false, false}; !reflect.DeepEqual(required, want) {
+		t.Errorf("wrong param requirements: have %v, want %v", required, want)
+	}
+	if get.Params[0].Schema.Title != "BlockNumber" || len(get.Params[0].Schema.OneOf) != 2 {
+		t.Errorf("wrong block number schema: %+v", get.Params[0].Schema)
+	}
+	const ref = "#/components/schemas/rpc.discoverTestArgs"
+	if get.Result.Schema.Ref != ref {
+		t.Errorf("wrong result reference: %q", get.Result.Schema.Ref)
+	}
+	// Check the schema of the named type, including its recursive reference
+	schema := doc.Components.Schemas["rpc.discoverTestArgs"]
+	if schema == nil {
+		t.Fatal("missing component schema")
+	}
+	if schema.Properties["value"].Pattern != quantityPattern {
+		t.Errorf("wrong schema of quantity field: %+v", schema.Properties["value"])
+	}
+	if schema.Properties["children"].Items.Ref != ref {
+		t.Errorf("wrong schema of recursive field: %+v", schema.Properties["children"])
+	}
+	if want := []string{"children", "value"}; !reflect.DeepEqual(schema.Required, want) {
+		t.Errorf("wrong required fields: have %v, want %v", schema.Required, want)
+	}
+}
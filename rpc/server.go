@@ -19,7 +19,9 @@ package rpc
 import (
 	"context"
 	"io"
+	"sync"
 	"sync/atomic"
+	"time"
 
 	mapset "github.com/deckarep/golang-set"
 	"github.com/ethereum/go-ethereum/log"
@@ -47,6 +49,10 @@ type Server struct {
 	idgen    func() ID
 	run      int32
 	codecs   mapset.Set
+
+	limiter  callLimiterRef // shared with the handlers of all connections
+	configMu sync.Mutex
+	config   handlerConfig // limits applied to newly served connections
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -67,6 +73,77 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 	return s.services.registerName(name, receiver)
 }
 
+// CallLimiter decides whether a method call may be executed. It is consulted by the
+// server before running any method or subscription call, the error returned for a
+// rejected call is sent back to the client as the response.
+type CallLimiter interface {
+	AllowCall(method string) error
+}
+
+// SetCallLimiter installs a limiter which is consulted before executing calls.
+// Passing nil removes the limiter. Unlike the other limits, the limiter also
+// applies to connections established before the call.
+func (s *Server) SetCallLimiter(limiter CallLimiter) {
+	s.limiter.set(limiter)
+}
+
+// callLimiterRef holds the call limiter of a server.
+type callLimiterRef struct {
+	mu      sync.RWMutex
+	limiter CallLimiter
+}
+
+func (r *callLimiterRef) set(limiter CallLimiter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.limiter = limiter
+}
+
+// get returns the current limiter, nil if there is none.
+func (r *callLimiterRef) get() CallLimiter {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.limiter
+}
+
+// SetBatchLimits sets the maximum number of requests in a batch and the maximum
+// size of responses in bytes. For batches, the size limit applies to the sum of
+// all responses, calls exceeding it are answered with an error instead. Zero
+// disables the respective limit.
+//
+// The limits apply to connections established after the call.
+func (s *Server) SetBatchLimits(itemLimit, responseSizeLimit int) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config.batchItemLimit = itemLimit
+	s.config.responseSizeLimit = responseSizeLimit
+}
+
+// SetBatchConcurrency configures the parallel execution of batches. Up to the given
+// number of calls in a batch are executed concurrently, while the responses keep
+// the order of the batch. If timeout is non-zero, calls that didn't finish within
+// it after the start of the batch are canceled and answered with an error.
+//
+// The settings apply to connections established after the call.
+func (s *Server) SetBatchConcurrency(concurrency int, timeout time.Duration) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config.batchConcurrency = concurrency
+	s.config.batchTimeout = timeout
+}
+
+// handlerConfig returns the limits applied to a new connection.
+func (s *Server) handlerConfig() handlerConfig {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	cfg := s.config
+	cfg.limiter = &s.limiter
+	return cfg
+}
+
 // ServeCodec reads incoming requests from codec, calls the appropriate callback and writes
 // the response back using the given codec. It will block until the codec is closed or the
 // server is stopped. In either case the codec is closed.
@@ -84,7 +161,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, s.handlerConfig())
 	<-codec.closed()
 	c.Close()
 }
@@ -98,7 +175,7 @@ func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec) {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.handlerConfig())
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
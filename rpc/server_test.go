@@ -152,3 +152,82 @@ func TestServerShortLivedConn(t *testing.T) {
 		}
 	}
 }
+
+// Tests that batch calls are executed concurrently when enabled, the responses
+// keep the batch order and calls exceeding the batch timeout are canceled.
+func TestServerBatchConcurrency(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetBatchConcurrency(8, 500*time.Millisecond)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	batch := make([]BatchElem, 16)
+	for i := range batch {
+		if i%2 == 0 {
+			batch[i] = BatchElem{Method: "test_sleep", Args: []interface{}{100 * time.Millisecond}, Result: new(interface{})}
+		} else {
+			batch[i] = BatchElem{Method: "test_echo", Args: []interface{}{"x", i, nil}, Result: new(echoResult)}
+		}
+	}
+	start := time.Now()
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Errorf("batch not executed concurrently, took %v", elapsed)
+	}
+	for i, elem := range batch {
+		if elem.Error != nil {
+			t.Fatalf("batch element %d failed: %v", i, elem.Error)
+		}
+		if i%2 == 1 {
+			if res := elem.Result.(*echoResult); res.Int != i {
+				t.Errorf("batch element %d: wrong result %d", i, res.Int)
+			}
+		}
+	}
+	// Check that blocking calls are canceled on timeout
+	batch = []BatchElem{
+		{Method: "test_block", Result: new(interface{})},
+		{Method: "test_echo", Args: []interface{}{"x", 1, nil}, Result: new(echoResult)},
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatal(err)
+	}
+	if rpcErr, ok := batch[0].Error.(Error); !ok || rpcErr.ErrorCode() != -32002 {
+		t.Errorf("wrong error for timed out call: %v", batch[0].Error)
+	}
+	if batch[1].Error != nil {
+		t.Errorf("call finished in time failed: %v", batch[1].Error)
+	}
+}
+
+type rejectingLimiter struct{}
+
+func (rejectingLimiter) AllowCall(method string) error {
+	return &LimitExceededError{Method: method, RetryAfter: time.Second}
+}
+
+// Tests that changes of the call limiter apply to established connections.
+func TestServerSetCallLimiter(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	var res echoResult
+	if err := client.Call(&res, "test_echo", "x", 1, nil); err != nil {
+		t.Fatal(err)
+	}
+	server.SetCallLimiter(rejectingLimiter{})
+	if err := client.Call(&res, "test_echo", "x", 1, nil); err == nil || err.Error() != "rate limit exceeded for test_echo, retry in 1s" {
+		t.Fatalf("wrong error with limiter: %v", err)
+	}
+	server.SetCallLimiter(nil)
+	if err := client.Call(&res, "test_echo", "x", 1, nil); err != nil {
+		t.Fatalf("call failed after removing limiter: %v", err)
+	}
+}
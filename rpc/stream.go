@@ -0,0 +1,214 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+)
+
+// streamThreshold is the encoded size above which list results are not kept in
+// memory. The elements of such results are encoded while the response is written.
+const streamThreshold = 4 * 1024 * 1024
+
+// streamedResult is a list result which is too large to be buffered. The elements
+// encoded before the threshold was reached are kept, the remaining ones are encoded
+// while the response is written.
+type streamedResult struct {
+	head []byte        // encoding of the leading elements, including the opening bracket
+	list reflect.Value // slice holding the result elements
+	next int           // index of the first element not contained in head
+}
+
+// writeTo writes the encoding of the whole list to w.
+func (s *streamedResult) writeTo(w io.Writer) error {
+	if _, err := w.Write(s.head); err != nil {
+		return err
+	}
+	for i := s.next; i < s.list.Len(); i++ {
+		enc, err := encodeElement(s.list, i)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := w.Write([]byte{','}); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(enc); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{']'})
+	return err
+}
+
+// streamable reports whether a result value can be encoded element by element.
+// This is the case for non-nil slices without a custom encoding.
+func streamable(v reflect.Value) bool {
+	if !v.IsValid() || v.Kind() != reflect.Slice || v.IsNil() {
+		return false
+	}
+	typ := v.Type()
+	if typ.Elem().Kind() == reflect.Uint8 {
+		return false // byte slices encode as strings
+	}
+	for _, t := range []reflect.Type{typ, reflect.PtrTo(typ)} {
+		if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+			return false
+		}
+	}
+	return true
+}
+
+// encodeElement encodes a list element. The element is passed by reference to
+// retain the behavior of encoding/json, which encodes addressable slice elements
+// using their pointer methods.
+func encodeElement(list reflect.Value, i int) ([]byte, error) {
+	return json.Marshal(list.Index(i).Addr().Interface())
+}
+
+// encodeResult encodes the result of a call. Every element of a list result is
+// encoded exactly once:
+//
+// Without a response size limit, lists whose encoding exceeds streamThreshold are
+// not returned in encoded form, but as a streamedResult finishing the encoding
+// while the response is written.
+//
+// With a limit, the result is buffered, which is bounded by the limit: encoding
+// stops with an error as soon as the limit is exceeded.
+func encodeResult(result interface{}, limit int) (json.RawMessage, *streamedResult, error) {
+	list := reflect.ValueOf(result)
+	if !streamable(list) {
+		enc, err := json.Marshal(result)
+		return enc, nil, err
+	}
+	buf := []byte{'['}
+	for i := 0; i < list.Len(); i++ {
+		if limit > 0 && len(buf) > limit {
+			return nil, nil, &responseTooLargeError{limit}
+		}
+		if limit == 0 && len(buf) > streamThreshold {
+			return nil, &streamedResult{head: buf, list: list, next: i}, nil
+		}
+		enc, err := encodeElement(list, i)
+		if err != nil {
+			return nil, nil, err
+		}
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, enc...)
+	}
+	return append(buf, ']'), nil, nil
+}
+
+// hasStreamedResult reports whether a response or batch of responses contains a
+// streamed result.
+func hasStreamedResult(v interface{}) bool {
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		return v.stream != nil
+	case []*jsonrpcMessage:
+		for _, msg := range v {
+			if msg.stream != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// materializeResults encodes all streamed results of a response or batch in
+// memory, for transports which can't stream.
+func materializeResults(v interface{}) error {
+	var msgs []*jsonrpcMessage
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		msgs = []*jsonrpcMessage{v}
+	case []*jsonrpcMessage:
+		msgs = v
+	}
+	for _, msg := range msgs {
+		if msg.stream == nil {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := msg.stream.writeTo(&buf); err != nil {
+			return err
+		}
+		msg.Result, msg.stream = buf.Bytes(), nil
+	}
+	return nil
+}
+
+// writeMessages writes a response or batch of responses, encoding streamed
+// results element by element.
+func writeMessages(w io.Writer, v interface{}) error {
+	bw := bufio.NewWriterSize(w, 64*1024)
+	switch v := v.(type) {
+	case *jsonrpcMessage:
+		if err := writeMessage(bw, v); err != nil {
+			return err
+		}
+	case []*jsonrpcMessage:
+		bw.WriteByte('[')
+		for i, msg := range v {
+			if i > 0 {
+				bw.WriteByte(',')
+			}
+			if err := writeMessage(bw, msg); err != nil {
+				return err
+			}
+		}
+		bw.WriteByte(']')
+	}
+	bw.WriteByte('\n')
+	return bw.Flush()
+}
+
+// writeMessage writes a single message to the buffered writer.
+func writeMessage(w *bufio.Writer, msg *jsonrpcMessage) error {
+	if msg.stream == nil {
+		enc, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(enc)
+		return err
+	}
+	id := msg.ID
+	if len(id) == 0 {
+		id = null
+	}
+	w.WriteString(`{"jsonrpc":"` + vsn + `","id":`)
+	w.Write(id)
+	w.WriteString(`,"result":`)
+	if err := msg.stream.writeTo(w); err != nil {
+		return err
+	}
+	_, err := w.WriteString("}")
+	return err
+}
+
+// nopWriteCloser turns a writer into an io.WriteCloser with a no-op Close method.
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
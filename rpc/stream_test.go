@@ -0,0 +1,171 @@
+// Copyright 2022 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type streamTestItem struct {
+	Index int    `json:"index"`
+	Data  string `json:"data"`
+}
+
+type streamTestService struct{}
+
+// Items returns n items carrying the given amount of padding each.
+func (s *streamTestService) Items(n, padding int) []*streamTestItem {
+	items := make([]*streamTestItem, n)
+	for i := range items {
+		items[i] = &streamTestItem{Index: i, Data: strings.Repeat("x", padding)}
+	}
+	return items
+}
+
+func newStreamTestServer(t *testing.T) *Server {
+	server := newTestServer()
+	if err := server.RegisterName("stream", new(streamTestService)); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+// Tests that large list results are streamed with the same encoding as buffered
+// ones, over all transports.
+func TestStreamedResult(t *testing.T) {
+	want := new(streamTestService).Items(1000, 8*1024)
+
+	// Check that the result is actually streamed, buffering only its head
+	enc, stream, err := encodeResult(want, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc != nil || stream == nil {
+		t.Fatal("large result not streamed")
+	}
+	if stream.next == 0 || stream.next == len(want) || len(stream.head) > streamThreshold+16*1024 {
+		t.Fatalf("wrong streamed head: %d bytes, %d elements", len(stream.head), stream.next)
+	}
+	full, _ := json.Marshal(want)
+	var buf bytes.Buffer
+	if err := writeMessages(&buf, &jsonrpcMessage{Version: vsn, ID: json.RawMessage("1"), stream: stream}); err != nil {
+		t.Fatal(err)
+	}
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(buf.Bytes(), &msg); err != nil {
+		t.Fatalf("invalid streamed message: %v", err)
+	}
+	if !bytes.Equal(msg.Result, full) {
+		t.Fatal("streamed encoding differs from buffered encoding")
+	}
+	// Check that results are buffered up to the response size limit, if any
+	if _, _, err := encodeResult(want, 1024*1024); !errors.As(err, new(*responseTooLargeError)) {
+		t.Fatalf("oversized result not rejected: %v", err)
+	}
+	if enc, stream, err := encodeResult(want, 2*len(full)); err != nil || stream != nil || !bytes.Equal(enc, full) {
+		t.Fatalf("limited result not buffered: %v", err)
+	}
+
+	// Check that clients receive the result over all transports
+	server := newStreamTestServer(t)
+	defer server.Stop()
+
+	httpsrv := httptest.NewServer(server)
+	defer httpsrv.Close()
+	wssrv := httptest.NewServer(server.WebsocketHandler([]string{"*"}))
+	defer wssrv.Close()
+
+	for _, url := range []string{httpsrv.URL, "ws" + strings.TrimPrefix(wssrv.URL, "http")} {
+		client, err := Dial(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var have []*streamTestItem
+		if err := client.Call(&have, "stream_items", 1000, 8*1024); err != nil {
+			t.Fatalf("%s: call failed: %v", url, err)
+		}
+		if !reflect.DeepEqual(have, want) {
+			t.Fatalf("%s: wrong result", url)
+		}
+		client.Close()
+	}
+	client := DialInProc(server)
+	defer client.Close()
+
+	var have []*streamTestItem
+	if err := client.Call(&have, "stream_items", 1000, 8*1024); err != nil {
+		t.Fatalf("in-process call failed: %v", err)
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatal("in-process: wrong result")
+	}
+}
+
+// Tests that the server enforces the batch item and response size limits.
+func TestServerLimits(t *testing.T) {
+	server := newStreamTestServer(t)
+	defer server.Stop()
+	server.SetBatchLimits(3, 64*1024)
+
+	client := DialInProc(server)
+	defer client.Close()
+
+	// Single responses exceeding the limit are replaced by an error
+	var items []*streamTestItem
+	if err := client.Call(&items, "stream_items", 4, 1024); err != nil {
+		t.Fatalf("small call failed: %v", err)
+	}
+	err := client.Call(&items, "stream_items", 100, 1024)
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32003 {
+		t.Fatalf("wrong error for large response: %v", err)
+	}
+	// Batches exceeding the item limit are rejected
+	batch := make([]BatchElem, 4)
+	for i := range batch {
+		batch[i] = BatchElem{Method: "stream_items", Args: []interface{}{1, 1}, Result: new([]*streamTestItem)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	for i, elem := range batch {
+		if elem.Error == nil {
+			t.Errorf("batch element %d exceeding item limit succeeded", i)
+		}
+	}
+	// Calls beyond the batch response size limit are answered with errors
+	batch = batch[:3]
+	for i := range batch {
+		batch[i] = BatchElem{Method: "stream_items", Args: []interface{}{25, 1024}, Result: new([]*streamTestItem)}
+	}
+	if err := client.BatchCall(batch); err != nil {
+		t.Fatalf("batch failed: %v", err)
+	}
+	for i, elem := range batch {
+		if i < 2 && elem.Error != nil {
+			t.Errorf("batch element %d failed: %v", i, elem.Error)
+		}
+		if i == 2 && elem.Error == nil {
+			t.Errorf("batch element %d exceeding size limit succeeded", i)
+		}
+	}
+}
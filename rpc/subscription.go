@@ -29,6 +29,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ethereum/go-ethereum/log"
 )
 
 var (
@@ -97,6 +99,7 @@ type Notifier struct {
 	mu           sync.Mutex
 	sub          *Subscription
 	buffer       []json.RawMessage
+	failure      *jsonError
 	callReturned bool
 	activated    bool
 }
@@ -134,6 +137,9 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	} else if n.sub.ID != id {
 		panic("Notify with wrong ID")
 	}
+	if n.failure != nil {
+		return nil
+	}
 	if n.activated {
 		return n.send(n.sub, enc)
 	}
@@ -141,6 +147,27 @@ func (n *Notifier) Notify(id ID, data interface{}) error {
 	return nil
 }
 
+// Fail ends the subscription with the given error, which is sent to the client in
+// a final notification. Notifications sent afterwards are dropped.
+func (n *Notifier) Fail(id ID, err error) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.sub == nil {
+		panic("can't Fail before subscription is created")
+	} else if n.sub.ID != id {
+		panic("Fail with wrong ID")
+	}
+	if n.failure != nil {
+		return nil
+	}
+	n.failure = errorMessage(err).Error
+	if n.activated {
+		return n.write(&subscriptionResult{ID: string(n.sub.ID), Error: n.failure})
+	}
+	return nil
+}
+
 // Closed returns a channel that is closed when the RPC connection is closed.
 // Deprecated: use subscription error channel
 func (n *Notifier) Closed() <-chan interface{} {
@@ -169,11 +196,18 @@ func (n *Notifier) activate() error {
 		}
 	}
 	n.activated = true
+	if n.failure != nil {
+		return n.write(&subscriptionResult{ID: string(n.sub.ID), Error: n.failure})
+	}
 	return nil
 }
 
 func (n *Notifier) send(sub *Subscription, data json.RawMessage) error {
-	params, _ := json.Marshal(&subscriptionResult{ID: string(sub.ID), Result: data})
+	return n.write(&subscriptionResult{ID: string(sub.ID), Result: data})
+}
+
+func (n *Notifier) write(result *subscriptionResult) error {
+	params, _ := json.Marshal(result)
 	ctx := context.Background()
 	return n.h.conn.writeJSON(ctx, &jsonrpcMessage{
 		Version: vsn,
@@ -203,6 +237,7 @@ func (s *Subscription) MarshalJSON() ([]byte, error) {
 // ClientSubscription is a subscription established through the Client's Subscribe or
 // EthSubscribe methods.
 type ClientSubscription struct {
+	mu        sync.Mutex // protects client and subid, which change when resumed
 	client    *Client
 	etype     reflect.Type
 	channel   reflect.Value
@@ -223,11 +258,31 @@ type ClientSubscription struct {
 	quit        chan error
 	forwardDone chan struct{}
 	unsubDone   chan struct{}
+
+	// Resumable subscriptions are re-established on connection loss using the
+	// arguments returned by resume, which is given the last value received.
+	resume  func(last interface{}) []interface{}
+	last    interface{}
+	running bool // set by the dispatcher once the forwarding loop is started, protected by mu
+
+	// Subscriptions of failover clients move to the client returned by reroute
+	// when their connection fails.
+	reroute func(failed *Client, err error) *Client
 }
 
 // This is the sentinel value sent on sub.quit when Unsubscribe is called.
 var errUnsubscribed = errors.New("unsubscribed")
 
+const (
+	// resubscribeAttempts is the number of times a resumable subscription tries to
+	// re-establish itself after a connection loss before giving up.
+	resubscribeAttempts = 5
+
+	// resubscribeBackoff is the initial delay between two resubscription attempts,
+	// doubled after every failed attempt.
+	resubscribeBackoff = 250 * time.Millisecond
+)
+
 func newClientSubscription(c *Client, namespace string, channel reflect.Value) *ClientSubscription {
 	sub := &ClientSubscription{
 		client:      c,
@@ -278,7 +333,8 @@ func (sub *ClientSubscription) deliver(result json.RawMessage) (ok bool) {
 	}
 }
 
-// close is called by the client's message dispatcher when the connection is closed.
+// close is called by the client's message dispatcher when the connection is closed
+// or the server failed the subscription.
 func (sub *ClientSubscription) close(err error) {
 	select {
 	case sub.quit <- err:
@@ -344,7 +400,25 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 				// Exiting because Unsubscribe was called, unsubscribe on server.
 				return true, nil
 			}
-			return false, err
+			if _, ok := err.(*jsonError); ok {
+				// The server failed the subscription, release it there as well.
+				return true, err
+			}
+			if sub.resume == nil || err == nil || err == ErrClientQuit {
+				return false, err
+			}
+			// The connection was lost, try to re-establish the subscription.
+			if err = sub.resubscribe(err); err != nil {
+				if err == errUnsubscribed {
+					// The subscription might have been re-established in the
+					// meantime, make sure it's removed on the server too.
+					return true, nil
+				}
+				if _, ok := err.(*jsonError); ok {
+					return true, err
+				}
+				return false, err
+			}
 
 		case 1: // <-sub.in
 			val, err := sub.unmarshal(recv.Interface().(json.RawMessage))
@@ -355,6 +429,7 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 				return true, ErrSubscriptionQueueOverflow
 			}
 			buffer.PushBack(val)
+			sub.last = val
 
 		case 2: // sub.channel<-
 			cases[2].Send = reflect.Value{} // Don't hold onto the value.
@@ -363,13 +438,113 @@ func (sub *ClientSubscription) forward() (unsubscribeServer bool, err error) {
 	}
 }
 
+// resubscribe re-establishes a resumable subscription after its connection was
+// lost with the given error. The subscribe request triggers the client's automatic
+// reconnect. It returns errUnsubscribed if Unsubscribe is called in the meantime,
+// ErrClientQuit if the client is closed, the server's error if it fails the new
+// subscription and the original cause if all attempts fail.
+func (sub *ClientSubscription) resubscribe(cause error) error {
+	log.Debug("Resuming RPC subscription", "namespace", sub.namespace, "id", sub.id(), "err", cause)
+
+	var (
+		backoff = resubscribeBackoff
+		last    = cause
+	)
+	for i := 0; i < resubscribeAttempts; i++ {
+		client, errc := sub.currentClient(), make(chan error, 1)
+		if sub.reroute != nil {
+			client = sub.reroute(client, last)
+			sub.mu.Lock()
+			sub.client = client
+			sub.mu.Unlock()
+		}
+		args := sub.resume(sub.last)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), defaultDialTimeout)
+			defer cancel()
+			errc <- client.subscribe(ctx, sub, args...)
+		}()
+		// Wait for the resubscription, accepting quit requests in the meantime
+		// to avoid stalling the client's dispatcher or Unsubscribe.
+		var err error
+	wait:
+		for {
+			select {
+			case err = <-errc:
+				break wait
+			case <-client.closing:
+				<-errc
+				return ErrClientQuit
+			case qerr := <-sub.quit:
+				<-errc
+				if _, ok := qerr.(*jsonError); ok || qerr == errUnsubscribed || qerr == ErrClientQuit {
+					return qerr
+				}
+				// The new connection was lost as well.
+				err = qerr
+				break wait
+			}
+		}
+		if err == nil {
+			return nil
+		}
+		if err == ErrClientQuit {
+			return err
+		}
+		last = err
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-client.closing:
+			return ErrClientQuit
+		case qerr := <-sub.quit:
+			if _, ok := qerr.(*jsonError); ok || qerr == errUnsubscribed || qerr == ErrClientQuit {
+				return qerr
+			}
+		}
+	}
+	log.Debug("Failed to resume RPC subscription", "namespace", sub.namespace, "err", last)
+	return cause
+}
+
 func (sub *ClientSubscription) unmarshal(result json.RawMessage) (interface{}, error) {
 	val := reflect.New(sub.etype)
 	err := json.Unmarshal(result, val.Interface())
 	return val.Elem().Interface(), err
 }
 
+// established is called by the client's message dispatcher with the ID assigned
+// to the subscription by the server. It returns whether the forwarding loop needs
+// to be started.
+func (sub *ClientSubscription) established(id string) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+
+	sub.subid = id
+	start := !sub.running
+	sub.running = true
+	return start
+}
+
+// id returns the current server-side ID of the subscription.
+func (sub *ClientSubscription) id() string {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.subid
+}
+
+// currentClient returns the client the subscription is established on.
+func (sub *ClientSubscription) currentClient() *Client {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	return sub.client
+}
+
 func (sub *ClientSubscription) requestUnsubscribe() error {
+	sub.mu.Lock()
+	client, id := sub.client, sub.subid
+	sub.mu.Unlock()
+
 	var result interface{}
-	return sub.client.Call(&result, sub.namespace+unsubscribeMethodSuffix, sub.subid)
+	return client.Call(&result, sub.namespace+unsubscribeMethodSuffix, id)
 }
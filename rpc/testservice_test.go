@@ -184,6 +184,23 @@ func (s *notificationTestService) SomeSubscription(ctx context.Context, n, val i
 	return subscription, nil
 }
 
+// FailingSubscription sends n notifications, then fails the subscription.
+func (s *notificationTestService) FailingSubscription(ctx context.Context, n, val int) (*Subscription, error) {
+	notifier, supported := NotifierFromContext(ctx)
+	if !supported {
+		return nil, ErrNotificationsUnsupported
+	}
+	subscription := notifier.CreateSubscription()
+	go func() {
+		for i := 0; i < n; i++ {
+			notifier.Notify(subscription.ID, val+i)
+		}
+		notifier.Fail(subscription.ID, &CustomError{Code: -32000, ValidationError: "subscription failed"})
+		notifier.Notify(subscription.ID, val+n)
+	}()
+	return subscription, nil
+}
+
 // HangSubscription blocks on s.unblockHangSubscription before sending anything.
 func (s *notificationTestService) HangSubscription(ctx context.Context, val int) (*Subscription, error) {
 	notifier, supported := NotifierFromContext(ctx)
@@ -20,6 +20,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"os"
@@ -184,10 +185,21 @@ func parseOriginURL(origin string) (string, string, string, error) {
 // DialWebsocketWithDialer creates a new RPC client that communicates with a JSON-RPC server
 // that is listening on the given endpoint using the provided dialer.
 func DialWebsocketWithDialer(ctx context.Context, endpoint, origin string, dialer websocket.Dialer) (*Client, error) {
+	return dialWebsocket(ctx, endpoint, origin, dialer, nil)
+}
+
+// dialWebsocket creates a WebSocket client, sending the given extra headers in
+// the handshake.
+func dialWebsocket(ctx context.Context, endpoint, origin string, dialer websocket.Dialer, extra http.Header) (*Client, error) {
 	endpoint, header, err := wsClientHeaders(endpoint, origin)
 	if err != nil {
 		return nil, err
 	}
+	for key, values := range extra {
+		for _, value := range values {
+			header.Add(key, value)
+		}
+	}
 	return newClient(ctx, func(ctx context.Context) (ServerCodec, error) {
 		conn, resp, err := dialer.DialContext(ctx, endpoint, header)
 		if err != nil {
@@ -256,6 +268,9 @@ func newWebsocketCodec(conn *websocket.Conn, host string, req http.Header) Serve
 			RemoteAddr: conn.RemoteAddr().String(),
 		},
 	}
+	wc.jsonCodec.stream = func() (io.WriteCloser, error) {
+		return conn.NextWriter(websocket.TextMessage)
+	}
 	// Fill in connection details.
 	wc.info.HTTP.Host = host
 	wc.info.HTTP.Origin = req.Get("Origin")